@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/auth"
+	"github.com/spf13/cobra"
+)
+
+// loginTimeout is how long login waits for the browser.
+const loginTimeout = 5 * time.Minute
+
+var authHeadless bool
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Log in to the services HAL reads from",
+	Long: `Log in to the services HAL reads from and manage the stored tokens.
+"Affirmative, Dave. I read you."
+
+Google Calendar and Drive share one token, used by floyd-calendar and the
+transcript tasks. It is kept in .hal9000/credentials/calendar-token.json
+and refreshed automatically; refreshed tokens are saved back to the file.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login <provider>",
+	Short: "Authorize HAL and store a token",
+	Long: `Authorize HAL with a provider and store the token.
+
+The OAuth client must be downloaded from the Google Cloud console to
+.hal9000/credentials/calendar-credentials.json first (see hal9000 init).
+
+Open the printed URL in a browser and grant access. HAL receives the
+redirect on ` + auth.RedirectURL() + `.
+
+On a machine without a browser, use --headless: open the URL on any other
+machine, and when the browser fails to load the localhost page it was sent
+to, copy that page's URL from the address bar and paste it here.
+
+Examples:
+  hal9000 auth login google
+  hal9000 auth login google --headless`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: auth.Providers,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := auth.CheckProvider(args[0]); err != nil {
+			return err
+		}
+
+		cfg, err := auth.GoogleConfig()
+		if err != nil {
+			return fmt.Errorf("%w\nDownload the OAuth client to %s (see hal9000 init)", err, auth.CredentialsPath(args[0]))
+		}
+		flow, err := auth.NewFlow(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), loginTimeout)
+		defer cancel()
+
+		var response string
+		if authHeadless {
+			fmt.Printf("Open this URL in a browser on any machine:\n\n%s\n\n", flow.AuthURL())
+			fmt.Println("After granting access the browser is sent to a localhost page that will not load.")
+			fmt.Print("Paste that page's URL (or the code) here: ")
+			if response, err = readAuthorization(os.Stdin); err != nil {
+				return err
+			}
+		} else {
+			ln, err := net.Listen("tcp", auth.CallbackAddr)
+			if err != nil {
+				return fmt.Errorf("cannot listen on %s (use --headless): %w", auth.CallbackAddr, err)
+			}
+			fmt.Printf("Open this URL in your browser:\n\n%s\n\nWaiting for authorization...\n", flow.AuthURL())
+			if response, err = flow.Wait(ctx, ln); err != nil {
+				return err
+			}
+		}
+
+		tok, err := flow.Exchange(ctx, response)
+		if err != nil {
+			return err
+		}
+		path := auth.TokenPath(args[0])
+		if err := auth.SaveToken(path, tok); err != nil {
+			return fmt.Errorf("failed to save token: %w", err)
+		}
+
+		fmt.Printf("Logged in to %s. Token saved to %s\n", args[0], path)
+		if tok.RefreshToken == "" {
+			fmt.Println("Warning: no refresh token was issued; you will need to log in again when it expires.")
+		}
+		return nil
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status [provider]",
+	Short: "Show stored tokens and when they expire",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers := auth.Providers
+		if len(args) == 1 {
+			if err := auth.CheckProvider(args[0]); err != nil {
+				return err
+			}
+			providers = args
+		}
+
+		now := time.Now()
+		for _, provider := range providers {
+			st, err := auth.TokenStatus(provider)
+			if err != nil {
+				fmt.Printf("%s: %v\n", provider, err)
+				continue
+			}
+			fmt.Printf("%s: %s\n", provider, st.Describe(now))
+			if st.LoggedIn {
+				fmt.Printf("  Token: %s\n", st.Path)
+			} else {
+				fmt.Printf("  Run: hal9000 auth login %s\n", provider)
+			}
+		}
+		return nil
+	},
+}
+
+var authRevokeCmd = &cobra.Command{
+	Use:   "revoke <provider>",
+	Short: "Revoke HAL's access and delete the token",
+	Long: `Revoke the stored token with the provider and delete it. Services using
+it stop working until you log in again.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: auth.Providers,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := auth.CheckProvider(args[0]); err != nil {
+			return err
+		}
+		if err := auth.Revoke(cmd.Context(), args[0]); err != nil {
+			if errors.Is(err, auth.ErrNotLoggedIn) {
+				fmt.Printf("Not logged in to %s.\n", args[0])
+				return nil
+			}
+			return err
+		}
+		fmt.Printf("Revoked and deleted the %s token.\n", args[0])
+		return nil
+	},
+}
+
+// readAuthorization reads the redirect URL or code the user pastes.
+func readAuthorization(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("no authorization entered: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", errors.New("no authorization entered")
+	}
+	return line, nil
+}
+
+func init() {
+	authLoginCmd.Flags().BoolVar(&authHeadless, "headless", false, "Paste the redirect URL instead of receiving it")
+
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authRevokeCmd)
+
+	rootCmd.AddCommand(authCmd)
+}
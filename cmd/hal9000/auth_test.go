@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAuthorization(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{"http://localhost:8089/callback?state=s&code=abc\n", "http://localhost:8089/callback?state=s&code=abc", false},
+		{"  4/0AbCd  \r\n", "4/0AbCd", false},
+		{"4/0AbCd", "4/0AbCd", false},
+		{"\n", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := readAuthorization(strings.NewReader(tt.input))
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("readAuthorization(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestAuthCommandsRejectUnknownProvider(t *testing.T) {
+	if err := authLoginCmd.RunE(authLoginCmd, []string{"jira"}); err == nil {
+		t.Error("auth login jira succeeded")
+	}
+	if err := authStatusCmd.RunE(authStatusCmd, []string{"jira"}); err == nil {
+		t.Error("auth status jira succeeded")
+	}
+	if err := authRevokeCmd.RunE(authRevokeCmd, []string{"jira"}); err == nil {
+		t.Error("auth revoke jira succeeded")
+	}
+}
+
+func TestDiagnoseTokenNotLoggedIn(t *testing.T) {
+	// The test binary's directory has no credentials
+	line, ok := diagnoseToken("google", time.Now())
+	if ok {
+		t.Errorf("diagnoseToken() ok without a token: %s", line)
+	}
+	if !strings.Contains(line, "not logged in") {
+		t.Errorf("diagnoseToken() = %q, want not logged in", line)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/bowman"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -195,32 +196,26 @@ func loadCalendarEvents(startDate, endDate time.Time) ([]CalendarEvent, error) {
 
 	var events []CalendarEvent
 
-	err := filepath.Walk(calPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() || !strings.HasSuffix(path, ".json") {
-			return nil
-		}
+	// Only the latest version of each event
+	latest, err := bowman.Latest(bowman.StoreConfig{LibraryPath: config.GetLibraryPath(), Category: "calendar"})
+	if err != nil {
+		return nil, fmt.Errorf("error reading calendar: %v", err)
+	}
 
-		event, err := loadEventFile(path)
+	for _, v := range latest {
+		event, err := loadEventFile(v.Path)
 		if err != nil {
-			return nil // Skip invalid files
+			continue // Skip invalid files
 		}
 
 		eventTime := getEventTime(event)
 
 		// Filter by date range
 		if eventTime.Before(startDate) || !eventTime.Before(endDate) {
-			return nil
+			continue
 		}
 
 		events = append(events, event)
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error reading calendar: %v", err)
 	}
 
 	return events, nil
@@ -229,7 +224,7 @@ func loadCalendarEvents(startDate, endDate time.Time) ([]CalendarEvent, error) {
 func loadEventFile(path string) (CalendarEvent, error) {
 	var event CalendarEvent
 
-	data, err := os.ReadFile(path)
+	data, err := bowman.ReadEvent(path)
 	if err != nil {
 		return event, err
 	}
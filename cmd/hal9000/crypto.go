@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/retention"
+	"github.com/pearcec/hal9000/discovery/vault"
+	"github.com/pearcec/hal9000/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var cryptoKeyFile string
+
+var cryptoCmd = &cobra.Command{
+	Use:   "crypto",
+	Short: "Manage encryption at rest for the library and credentials",
+	Long: `Encrypt library entity files, URL notes, preferences, agendas, event
+blobs and credential files at rest.
+"I know that you and Frank were planning to disconnect me, and I'm afraid
+that's something I cannot allow to happen."
+
+Each file is sealed with its own AES-256-GCM data key, wrapped by a key
+derived from a passphrase or read from a key file. Key parameters are kept
+in .hal9000/vault.json; the key itself never is.
+
+Services and scripts unlock the vault with:
+  HAL9000_PASSPHRASE   the passphrase (passphrase-protected vaults)
+  HAL9000_KEY_FILE     path to the key file (overrides vault.json)
+
+Stop running services (hal9000 services stop) before enable, rotate or
+disable so nothing writes while files are migrated.
+
+With library history enabled, the git repository in the library keeps the
+plaintext of every revision committed before encryption was enabled;
+revisions committed afterwards are sealed and cannot be diffed.`,
+}
+
+var cryptoStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether encryption is enabled",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := vault.LoadSettings()
+		if err != nil {
+			return err
+		}
+		if settings == nil || !settings.Enabled {
+			fmt.Println("Encryption: disabled")
+			return nil
+		}
+
+		fmt.Println("Encryption: enabled")
+		fmt.Printf("Key ID:     %s\n", settings.KeyID)
+		fmt.Printf("Key source: %s\n", settings.Source)
+		if settings.Source == vault.SourceKeyFile {
+			fmt.Printf("Key file:   %s\n", settings.KeyFile)
+		}
+		if settings.Pending != nil {
+			fmt.Printf("Pending:    key %s (interrupted rotation; run: hal9000 crypto rotate)\n", settings.Pending.KeyID)
+		}
+		if _, err := unlockVault(settings, false); err != nil {
+			fmt.Printf("Unlocked:   no (%v)\n", err)
+		} else {
+			fmt.Println("Unlocked:   yes")
+		}
+		if libPath := config.GetLibraryPath(); history.Open(libPath).Initialized() {
+			fmt.Printf("History:    %s keeps revisions committed before encryption in plaintext\n", filepath.Join(libPath, ".git"))
+		}
+		return nil
+	},
+}
+
+var cryptoEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Enable encryption and seal existing files",
+	Long: `Enable encryption and seal every existing library entity file and
+credential file. Uses a passphrase (prompted, or HAL9000_PASSPHRASE) unless
+--key-file is given; a missing key file is generated.
+
+Running enable again on an encrypted vault seals any files that are still
+in plaintext, e.g. after an interrupted migration.
+
+Examples:
+  hal9000 crypto enable
+  hal9000 crypto enable --key-file ~/.hal9000.key`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := vault.LoadSettings()
+		if err != nil {
+			return err
+		}
+
+		var key *vault.Key
+		if settings != nil && settings.Enabled {
+			if settings.Pending != nil {
+				return errPendingRotation(settings)
+			}
+			fmt.Println("Encryption is already enabled; sealing remaining plaintext files.")
+			if key, err = unlockVault(settings, true); err != nil {
+				return err
+			}
+		} else {
+			if cryptoKeyFile != "" {
+				key, settings, err = vault.NewKeyFileKey(expandHome(cryptoKeyFile))
+			} else {
+				var passphrase string
+				if passphrase, err = newPassphrase(); err != nil {
+					return err
+				}
+				key, settings, err = vault.NewPassphraseKey(passphrase)
+			}
+			if err != nil {
+				return err
+			}
+			// Saved first so an interrupted migration can be resumed
+			if err := vault.SaveSettings(settings); err != nil {
+				return fmt.Errorf("failed to save vault settings: %w", err)
+			}
+		}
+		vault.SetDefault(key)
+
+		count, err := migrateVault(func(data []byte) ([]byte, error) {
+			if vault.IsSealed(data) {
+				return nil, nil
+			}
+			return vault.Seal(key, data)
+		})
+		if err != nil {
+			return fmt.Errorf("migration stopped after %d files (re-run to resume): %w", count, err)
+		}
+
+		fmt.Printf("Encryption enabled (key %s). Sealed %d files.\n", settings.KeyID, count)
+		if settings.Source == vault.SourceKeyFile {
+			fmt.Printf("Keep %s safe: without it the library cannot be read.\n", settings.KeyFile)
+		}
+		warnHistory(config.GetLibraryPath())
+		return nil
+	},
+}
+
+var cryptoRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Re-wrap all files under a new key",
+	Long: `Replace the key-encryption key. Only each file's wrapped data key is
+rewritten; file contents are not re-encrypted. The new key is a passphrase
+unless --key-file is given.
+
+The new key is recorded as pending in vault.json before any file changes
+and replaces the current key once every file is rewrapped. If a rotation is
+interrupted, running rotate again finishes it with the pending key.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := vault.LoadSettings()
+		if err != nil {
+			return err
+		}
+		if settings == nil || !settings.Enabled {
+			return fmt.Errorf("encryption is not enabled (run: hal9000 crypto enable)")
+		}
+
+		oldKey, err := unlockVault(settings, true)
+		if err != nil {
+			return err
+		}
+
+		var newKey *vault.Key
+		var newSettings *vault.Settings
+		if settings.Pending != nil {
+			newSettings = settings.Pending
+			if cryptoKeyFile != "" && (newSettings.Source != vault.SourceKeyFile || newSettings.KeyFile != expandHome(cryptoKeyFile)) {
+				return fmt.Errorf("a rotation to key %s is pending; re-run rotate without --key-file to finish it", newSettings.KeyID)
+			}
+			fmt.Printf("Resuming rotation to key %s.\n", newSettings.KeyID)
+			if newKey, err = unlockPending(settings); err != nil {
+				return err
+			}
+		} else {
+			if cryptoKeyFile != "" {
+				newKey, newSettings, err = vault.NewKeyFileKey(expandHome(cryptoKeyFile))
+			} else {
+				fmt.Fprintln(os.Stderr, "New passphrase:")
+				var passphrase string
+				if passphrase, err = newPassphrase(); err != nil {
+					return err
+				}
+				newKey, newSettings, err = vault.NewPassphraseKey(passphrase)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		count, err := rotateVault(settings, newSettings, oldKey, newKey, vault.SaveSettings, migrateVault)
+		if err != nil {
+			return fmt.Errorf("rotation stopped after %d files (re-run to resume): %w", count, err)
+		}
+
+		fmt.Printf("Rotated %d files to key %s.\n", count, newSettings.KeyID)
+		return nil
+	},
+}
+
+var cryptoDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Decrypt all files and disable encryption",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := vault.LoadSettings()
+		if err != nil {
+			return err
+		}
+		if settings == nil || !settings.Enabled {
+			fmt.Println("Encryption is not enabled.")
+			return nil
+		}
+		if settings.Pending != nil {
+			return errPendingRotation(settings)
+		}
+
+		key, err := unlockVault(settings, true)
+		if err != nil {
+			return err
+		}
+
+		count, err := migrateVault(func(data []byte) ([]byte, error) {
+			if !vault.IsSealed(data) {
+				return nil, nil
+			}
+			return vault.Open(key, data)
+		})
+		if err != nil {
+			return fmt.Errorf("decryption stopped after %d files (re-run to resume): %w", count, err)
+		}
+		if err := vault.RemoveSettings(); err != nil {
+			return err
+		}
+		vault.SetDefault(nil)
+
+		fmt.Printf("Encryption disabled. Decrypted %d files.\n", count)
+		return nil
+	},
+}
+
+func init() {
+	cryptoEnableCmd.Flags().StringVar(&cryptoKeyFile, "key-file", "", "Use (or generate) a key file instead of a passphrase")
+	cryptoRotateCmd.Flags().StringVar(&cryptoKeyFile, "key-file", "", "Rotate to a key file instead of a passphrase")
+
+	cryptoCmd.AddCommand(cryptoStatusCmd)
+	cryptoCmd.AddCommand(cryptoEnableCmd)
+	cryptoCmd.AddCommand(cryptoRotateCmd)
+	cryptoCmd.AddCommand(cryptoDisableCmd)
+
+	rootCmd.AddCommand(cryptoCmd)
+}
+
+// unlockVault derives the current key from the environment, prompting for
+// the passphrase when interactive is set and stdin is a terminal.
+func unlockVault(settings *vault.Settings, interactive bool) (*vault.Key, error) {
+	key, err := settings.Unlock(os.Getenv(vault.PassphraseEnv))
+	if err == nil || !errors.Is(err, vault.ErrLocked) || !interactive {
+		return key, err
+	}
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return settings.Unlock(passphrase)
+}
+
+// unlockPending derives the pending key of an interrupted rotation from the
+// environment, prompting for its passphrase if needed.
+func unlockPending(settings *vault.Settings) (*vault.Key, error) {
+	key, err := settings.UnlockPending(os.Getenv(vault.PassphraseEnv))
+	if err == nil || !errors.Is(err, vault.ErrLocked) {
+		return key, err
+	}
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return settings.UnlockPending(passphrase)
+}
+
+// errPendingRotation refuses to change encryption while files are split
+// between two keys.
+func errPendingRotation(settings *vault.Settings) error {
+	return fmt.Errorf("a rotation to key %s is pending; run 'hal9000 crypto rotate' to finish it first", settings.Pending.KeyID)
+}
+
+// rotateVault moves every file migrate covers to newKey. The new key is
+// saved as pending before any file changes, so files already rewrapped can
+// be opened if the rotation stops, and is promoted to the current key only
+// once migration finishes. Files already under newKey are skipped, which
+// lets an interrupted rotation resume.
+func rotateVault(settings, newSettings *vault.Settings, oldKey, newKey *vault.Key,
+	save func(*vault.Settings) error, migrate func(fn func(data []byte) ([]byte, error)) (int, error)) (int, error) {
+	if settings.Pending == nil {
+		pending := *newSettings
+		settings.Pending = &pending
+		if err := save(settings); err != nil {
+			return 0, fmt.Errorf("failed to save pending key: %w", err)
+		}
+	}
+
+	count, err := migrate(func(data []byte) ([]byte, error) {
+		if !vault.IsSealed(data) {
+			return vault.Seal(newKey, data)
+		}
+		id, err := vault.SealedKeyID(data)
+		if err != nil {
+			return nil, err
+		}
+		if id == newKey.ID {
+			return nil, nil
+		}
+		return vault.Rewrap(oldKey, newKey, data)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	promoted := *newSettings
+	promoted.Pending = nil
+	if err := save(&promoted); err != nil {
+		return count, fmt.Errorf("failed to save vault settings: %w", err)
+	}
+	return count, nil
+}
+
+// newPassphrase reads HAL9000_PASSPHRASE or prompts twice for a new one.
+func newPassphrase() (string, error) {
+	if p := os.Getenv(vault.PassphraseEnv); p != "" {
+		return p, nil
+	}
+	first, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	second, err := readPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if first != second {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return first, nil
+}
+
+func readPassphrase(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no terminal to prompt for a passphrase: set %s", vault.PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
+
+// migrateVault applies fn to every library file migrateLibrary covers and
+// every credential file. fn returns nil to leave a file unchanged.
+func migrateVault(fn func(data []byte) ([]byte, error)) (int, error) {
+	total, err := migrateLibrary(config.GetLibraryPath(), fn)
+	if err != nil {
+		return total, err
+	}
+	count, err := vault.Transform(config.GetCredentialsDir(), nil, fn)
+	total += count
+	return total, err
+}
+
+// sealedNoteDirs are the library folders whose Markdown HAL writes
+// through the vault: URL notes, preferences and agendas.
+var sealedNoteDirs = []string{"url_library", "preferences", "agenda"}
+
+// migrateLibrary applies fn to a library's .json files, the Markdown in
+// sealedNoteDirs, its retention archives, and the event bodies in bowman's
+// blob store, which are sealed too but kept in a dot directory that the
+// library walk skips. The library lock is held throughout so a running
+// processor or bowman writer cannot store a file between its read and
+// rewrite.
+func migrateLibrary(libPath string, fn func(data []byte) ([]byte, error)) (int, error) {
+	lock, err := vault.LockLibrary(libPath)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	archiveDir := filepath.Join(libPath, retention.ArchiveDir) + string(filepath.Separator)
+	isNote := func(path string) bool {
+		for _, dir := range sealedNoteDirs {
+			if strings.HasPrefix(path, filepath.Join(libPath, dir)+string(filepath.Separator)) {
+				return strings.HasSuffix(path, ".md")
+			}
+		}
+		return false
+	}
+	total := 0
+	count, err := vault.Transform(libPath, func(path string) bool {
+		return strings.HasSuffix(path, ".json") || isNote(path) ||
+			(strings.HasPrefix(path, archiveDir) && strings.HasSuffix(path, ".tar.gz"))
+	}, fn)
+	total += count
+	if err != nil {
+		return total, err
+	}
+	count, err = vault.Transform(filepath.Join(libPath, bowman.BlobDir), nil, fn)
+	total += count
+	return total, err
+}
+
+// warnHistory tells the user that the library's git history still holds
+// the plaintext of everything committed before encryption, and that later
+// commits store sealed files that library log and revert cannot diff.
+func warnHistory(libPath string) {
+	if !config.GetLibraryHistory().Enabled && !history.Open(libPath).Initialized() {
+		return
+	}
+	gitDir := filepath.Join(libPath, ".git")
+	fmt.Fprintf(os.Stderr, "Warning: library history is enabled. %s still holds every revision\n", gitDir)
+	fmt.Fprintln(os.Stderr, "committed before now in plaintext, and later revisions are committed sealed,")
+	fmt.Fprintln(os.Stderr, "so 'library log' and 'library revert' cannot show their changes. To drop the")
+	fmt.Fprintf(os.Stderr, "plaintext history, move %s aside; the next history command starts a new\n", gitDir)
+	fmt.Fprintln(os.Stderr, "one from the sealed files.")
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/retention"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// rotateKeys and disableKeys are the migrations of crypto rotate and
+// crypto disable.
+func rotateKeys(oldKey, newKey *vault.Key) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		if !vault.IsSealed(data) {
+			return vault.Seal(newKey, data)
+		}
+		return vault.Rewrap(oldKey, newKey, data)
+	}
+}
+
+func disableKeys(key *vault.Key) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		if !vault.IsSealed(data) {
+			return nil, nil
+		}
+		return vault.Open(key, data)
+	}
+}
+
+func newTestKeys(t *testing.T) (*vault.Key, *vault.Key) {
+	t.Helper()
+	oldKey, _, err := vault.NewKeyFileKey(filepath.Join(t.TempDir(), "old.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	newKey, _, err := vault.NewKeyFileKey(filepath.Join(t.TempDir(), "new.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	return oldKey, newKey
+}
+
+func TestMigrateLibraryBlobs(t *testing.T) {
+	oldKey, newKey := newTestKeys(t)
+	vault.SetDefault(oldKey)
+	defer vault.Reset()
+
+	lib := t.TempDir()
+	body := []byte("Open the pod bay doors, HAL.")
+	ref, _, err := bowman.PutBlob(lib, body)
+	if err != nil {
+		t.Fatalf("PutBlob failed: %v", err)
+	}
+
+	if _, err := migrateLibrary(lib, rotateKeys(oldKey, newKey)); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+	vault.SetDefault(newKey)
+	if got, err := bowman.GetBlob(lib, ref); err != nil || string(got) != string(body) {
+		t.Errorf("GetBlob after rotate = %q, %v", got, err)
+	}
+
+	if _, err := migrateLibrary(lib, disableKeys(newKey)); err != nil {
+		t.Fatalf("disable failed: %v", err)
+	}
+	vault.SetDefault(nil)
+	if got, err := bowman.GetBlob(lib, ref); err != nil || string(got) != string(body) {
+		t.Errorf("GetBlob after disable = %q, %v", got, err)
+	}
+}
+
+func TestMigrateLibraryArchives(t *testing.T) {
+	oldKey, newKey := newTestKeys(t)
+	vault.SetDefault(oldKey)
+	defer vault.Reset()
+
+	lib := t.TempDir()
+	doc := []byte(`{"_meta":{"source":"slack","event_id":"evt1","stage":"raw","fetched_at":"2026-01-05T09:00:00Z"}}`)
+	if err := os.MkdirAll(filepath.Join(lib, "slack"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.WriteFile(filepath.Join(lib, "slack", "slack_2026-01-05_evt1.json"), doc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := retention.ParseRules([]config.RetentionRule{{Category: "slack", OlderThan: "90d"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := retention.Apply(lib, rules, retention.Options{Now: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	archived, err := retention.Archived(lib)
+	if err != nil || len(archived) != 1 {
+		t.Fatalf("Archived = %v, %v", archived, err)
+	}
+	pointer := archived["slack/slack_2026-01-05_evt1.json"].Pointer()
+
+	if _, err := migrateLibrary(lib, rotateKeys(oldKey, newKey)); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+	vault.SetDefault(newKey)
+	if got, err := retention.ReadArchived(lib, pointer); err != nil || string(got) != string(doc) {
+		t.Errorf("ReadArchived after rotate = %q, %v", got, err)
+	}
+
+	if _, err := migrateLibrary(lib, disableKeys(newKey)); err != nil {
+		t.Fatalf("disable failed: %v", err)
+	}
+	vault.SetDefault(nil)
+	if got, err := retention.ReadArchived(lib, pointer); err != nil || string(got) != string(doc) {
+		t.Errorf("ReadArchived after disable = %q, %v", got, err)
+	}
+}
+
+func TestRotateResumesAfterFailure(t *testing.T) {
+	defer vault.Reset()
+	dir := t.TempDir()
+	oldKey, settings, err := vault.NewKeyFileKey(filepath.Join(dir, "old.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	newKey, newSettings, err := vault.NewKeyFileKey(filepath.Join(dir, "new.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+
+	vault.SetDefault(oldKey)
+	lib := t.TempDir()
+	paths := make(map[string]string)
+	for i := 0; i < 6; i++ {
+		path := filepath.Join(lib, "people", fmt.Sprintf("p%d.json", i))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		content := fmt.Sprintf(`{"name":"person %d"}`, i)
+		if err := vault.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths[path] = content
+	}
+
+	// vault.json as it would be read back by the next process
+	var saved []byte
+	save := func(s *vault.Settings) error {
+		data, err := json.Marshal(s)
+		saved = data
+		return err
+	}
+	load := func() *vault.Settings {
+		var s vault.Settings
+		if err := json.Unmarshal(saved, &s); err != nil {
+			t.Fatalf("invalid saved settings: %v", err)
+		}
+		return &s
+	}
+
+	// Fail halfway through the library
+	migrated := 0
+	failing := func(fn func(data []byte) ([]byte, error)) (int, error) {
+		return migrateLibrary(lib, func(data []byte) ([]byte, error) {
+			if migrated == 3 {
+				return nil, errors.New("disk full")
+			}
+			migrated++
+			return fn(data)
+		})
+	}
+	if _, err := rotateVault(settings, newSettings, oldKey, newKey, save, failing); err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+
+	// Every file opens with the keys a new process derives from vault.json
+	persisted := load()
+	if persisted.KeyID != settings.KeyID || persisted.Pending == nil || persisted.Pending.KeyID != newKey.ID {
+		t.Fatalf("saved settings = %+v, want current key with the new key pending", persisted)
+	}
+	current, err := persisted.Unlock("")
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	pending, err := persisted.UnlockPending("")
+	if err != nil {
+		t.Fatalf("UnlockPending failed: %v", err)
+	}
+	vault.SetDefault(current.WithPending(pending))
+	for path, content := range paths {
+		if got, err := vault.ReadFile(path); err != nil || string(got) != content {
+			t.Errorf("ReadFile(%s) after failed rotation = %q, %v", filepath.Base(path), got, err)
+		}
+	}
+
+	// Running rotate again finishes and promotes the pending key
+	resume := func(fn func(data []byte) ([]byte, error)) (int, error) {
+		return migrateLibrary(lib, fn)
+	}
+	count, err := rotateVault(persisted, persisted.Pending, current, pending, save, resume)
+	if err != nil {
+		t.Fatalf("resumed rotation failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("resumed rotation rewrote %d files, want the remaining 3", count)
+	}
+	promoted := load()
+	if promoted.KeyID != newKey.ID || promoted.Pending != nil {
+		t.Errorf("saved settings = %+v, want new key with nothing pending", promoted)
+	}
+	vault.SetDefault(newKey)
+	for path, content := range paths {
+		if got, err := vault.ReadFile(path); err != nil || string(got) != content {
+			t.Errorf("ReadFile(%s) after rotation = %q, %v", filepath.Base(path), got, err)
+		}
+	}
+}
+
+func TestMigrateLibraryNotes(t *testing.T) {
+	oldKey, _ := newTestKeys(t)
+	defer vault.Reset()
+
+	lib := t.TempDir()
+	for _, rel := range []string{"preferences/agenda.md", "url_library/url_2026-01-05_hal.md", "notes/mine.md"} {
+		path := filepath.Join(lib, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("# "+rel), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := migrateLibrary(lib, func(data []byte) ([]byte, error) {
+		return vault.Seal(oldKey, data)
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("migrateLibrary = %d, %v; want 2 notes sealed", count, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(lib, "notes", "mine.md")); vault.IsSealed(data) {
+		t.Error("hand-written note was sealed")
+	}
+	vault.SetDefault(oldKey)
+	if got, err := vault.ReadFile(filepath.Join(lib, "preferences", "agenda.md")); err != nil || string(got) != "# preferences/agenda.md" {
+		t.Errorf("ReadFile = %q, %v", got, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -236,6 +237,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Suggest next steps
 	if selection.Scheduler || selection.Calendar || selection.Jira || selection.Slack || selection.BambooHR {
 		fmt.Println("\nNext steps:")
+		if selection.Calendar {
+			fmt.Println("  hal9000 auth login google # Authorize Calendar and Drive access")
+		}
 		fmt.Println("  hal9000 services start    # Start enabled services")
 		fmt.Println("  hal9000 services status   # Check service health")
 	}
@@ -309,7 +313,7 @@ type BambooHRCredentials struct {
 // loadJIRACredentials loads existing JIRA credentials from file
 func loadJIRACredentials(credentialsDir string) (*JIRACredentials, error) {
 	path := filepath.Join(credentialsDir, "jira-credentials.yaml")
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -323,7 +327,7 @@ func loadJIRACredentials(credentialsDir string) (*JIRACredentials, error) {
 // loadSlackCredentials loads existing Slack credentials from file
 func loadSlackCredentials(credentialsDir string) (*SlackCredentials, error) {
 	path := filepath.Join(credentialsDir, "slack-credentials.yaml")
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +341,7 @@ func loadSlackCredentials(credentialsDir string) (*SlackCredentials, error) {
 // loadBambooHRCredentials loads existing BambooHR credentials from file
 func loadBambooHRCredentials(credentialsDir string) (*BambooHRCredentials, error) {
 	path := filepath.Join(credentialsDir, "bamboohr-credentials.yaml")
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -433,6 +437,7 @@ func promptAuthentication(reader *bufio.Reader, selection ServiceSelection, cred
 		fmt.Println("  1. Go to https://console.cloud.google.com/apis/credentials")
 		fmt.Println("  2. Create OAuth 2.0 credentials for a Desktop app")
 		fmt.Println("  3. Download the credentials JSON file")
+		fmt.Println("  4. Run: hal9000 auth login google (--headless without a browser)")
 
 		prompt := "  Do you have credentials ready to configure now?"
 		if hasExisting {
@@ -510,7 +515,7 @@ email: %s
 api_token: %s
 `, jiraURL, jiraEmail, jiraToken)
 				destPath := filepath.Join(credentialsDir, "jira-credentials.yaml")
-				if err := os.WriteFile(destPath, []byte(jiraCreds), 0600); err != nil {
+				if err := vault.WriteFile(destPath, []byte(jiraCreds), 0600); err != nil {
 					fmt.Printf("  Warning: could not save credentials: %v\n", err)
 				} else {
 					fmt.Printf("  Saved credentials to %s\n", destPath)
@@ -556,7 +561,7 @@ api_token: %s
 bot_token: %s
 `, slackToken)
 				destPath := filepath.Join(credentialsDir, "slack-credentials.yaml")
-				if err := os.WriteFile(destPath, []byte(slackCreds), 0600); err != nil {
+				if err := vault.WriteFile(destPath, []byte(slackCreds), 0600); err != nil {
 					fmt.Printf("  Warning: could not save credentials: %v\n", err)
 				} else {
 					fmt.Printf("  Saved credentials to %s\n", destPath)
@@ -609,7 +614,7 @@ subdomain: %s
 api_key: %s
 `, subdomain, apiKey)
 				destPath := filepath.Join(credentialsDir, "bamboohr-credentials.yaml")
-				if err := os.WriteFile(destPath, []byte(bamboohrCreds), 0600); err != nil {
+				if err := vault.WriteFile(destPath, []byte(bamboohrCreds), 0600); err != nil {
 					fmt.Printf("  Warning: could not save credentials: %v\n", err)
 				} else {
 					fmt.Printf("  Saved credentials to %s\n", destPath)
@@ -804,5 +809,5 @@ func copyFile(src, dst string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(dst, data, 0600)
+	return vault.WriteFile(dst, data, 0600)
 }
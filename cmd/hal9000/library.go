@@ -6,9 +6,15 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/pearcec/hal9000/discovery/history"
 	"github.com/pearcec/hal9000/discovery/lmc"
+	"github.com/pearcec/hal9000/discovery/retention"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -61,7 +67,13 @@ Example:
   echo '{"name": "Dave Bowman", "role": "Mission Commander"}' | hal9000 library write people/dave-bowman
 
   # With links:
-  echo '{"name": "Dave", "links": [{"to": "missions/discovery-one", "type": "assigned_to"}]}' | hal9000 library write people/dave`,
+  echo '{"name": "Dave", "links": [{"to": "missions/discovery-one", "type": "assigned_to"}]}' | hal9000 library write people/dave
+
+References in content ([[type/id]] wiki-links, email addresses, JIRA keys,
+Slack <@U...> user IDs) are extracted automatically as derived links.
+
+Use --if-revision to write only if the entity is still at the revision you
+last read (0 means it must not exist yet); otherwise the write is rejected.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		lib, err := getLibrary()
@@ -102,7 +114,12 @@ Example:
 			delete(data, "links")
 		}
 
-		entity, err := lib.Store(entityType, entityName, data, links)
+		var entity *lmc.Entity
+		if cmd.Flags().Changed("if-revision") {
+			entity, err = lib.StoreIfMatch(entityType, entityName, data, links, writeIfRevision)
+		} else {
+			entity, err = lib.Store(entityType, entityName, data, links)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to store entity: %w", err)
 		}
@@ -110,11 +127,13 @@ Example:
 		if jsonOutput {
 			return outputEntity(entity)
 		}
-		fmt.Printf("Stored entity: %s\n", entity.ID)
+		fmt.Printf("Stored entity: %s (revision %d)\n", entity.ID, entity.Revision)
 		return nil
 	},
 }
 
+var writeIfRevision int
+
 var (
 	queryType     string
 	queryContains string
@@ -205,6 +224,660 @@ Examples:
 	},
 }
 
+var libraryValidateCmd = &cobra.Command{
+	Use:   "validate [type]",
+	Short: "Check entities against their type schemas",
+	Long: `Validate stored entities against the schemas in .hal9000/schemas/.
+
+Each schema file is named after the entity type it describes
+(e.g., .hal9000/schemas/people.json) and uses a JSON-Schema-style subset:
+type, properties, required, enum, default, items, pattern, format,
+minLength/maxLength, minimum/maximum and additionalProperties.
+
+Set library.validation in .hal9000/config.yaml to control writes:
+  off     Skip validation
+  warn    Log violations but store anyway (default)
+  strict  Reject entities that violate their schema
+
+Examples:
+  hal9000 library validate           # Check every type that has a schema
+  hal9000 library validate people    # Check only people entities`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		var entityType string
+		if len(args) > 0 {
+			entityType = args[0]
+		}
+
+		if len(lib.Schemas().Types()) == 0 {
+			fmt.Printf("No schemas found in %s\n", config.GetSchemasDir())
+			return nil
+		}
+
+		reports, err := lib.ValidateAll(entityType)
+		if err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else if len(reports) == 0 {
+			fmt.Println("All entities conform to their schemas.")
+		} else {
+			for _, r := range reports {
+				fmt.Printf("%s\n", r.EntityID)
+				for _, v := range r.Violations {
+					fmt.Printf("  - %s\n", v)
+				}
+			}
+			fmt.Printf("\n%d entities violate their schemas.\n", len(reports))
+		}
+
+		if len(reports) > 0 {
+			return fmt.Errorf("%d entities failed validation", len(reports))
+		}
+		return nil
+	},
+}
+
+var libraryAliasCmd = &cobra.Command{
+	Use:   "alias <identifier> <entity-id>",
+	Short: "Record another identifier for an entity",
+	Long: `Record that an identifier refers to an existing entity. Identifiers are
+matched case-insensitively and can be other entity IDs, external keys or
+display names. Query link filters and linked-entity lookups follow aliases.
+
+Examples:
+  hal9000 library alias users/U123ABC people/alice
+  hal9000 library alias people/alice@corp.com people/alice
+  hal9000 library alias bamboohr/1234 people/alice
+  hal9000 library alias "Alice Smith" people/alice`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		if _, err := lib.AddAlias(args[0], args[1]); err != nil {
+			return fmt.Errorf("failed to add alias: %w", err)
+		}
+		canonical, _ := lib.Resolve(args[1])
+		fmt.Printf("%s -> %s\n", args[0], canonical)
+		return nil
+	},
+}
+
+var libraryResolveCmd = &cobra.Command{
+	Use:   "resolve <identifier>",
+	Short: "Show the canonical entity for an identifier",
+	Long: `Resolve an identifier (entity ID, external key or display name) to its
+canonical entity, and list every alias that points there.
+
+Example:
+  hal9000 library resolve users/U123ABC`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		canonical, err := lib.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+		aliases := lib.Aliases(canonical)
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(map[string]interface{}{
+				"canonical": canonical,
+				"aliases":   aliases,
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		fmt.Println(canonical)
+		for _, alias := range aliases {
+			fmt.Printf("  aka %s\n", alias)
+		}
+		return nil
+	},
+}
+
+var libraryMergeCmd = &cobra.Command{
+	Use:   "merge <duplicate-id> <canonical-id>",
+	Short: "Merge a duplicate entity into its canonical entity",
+	Long: `Fold a duplicate entity into the canonical one. Content fields missing
+from the canonical entity are copied over, links are combined, links from
+other entities are rewritten, the duplicate is deleted, and its ID is kept
+as an alias.
+
+Example:
+  hal9000 library merge people/alice@corp.com people/alice`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		entity, err := lib.Merge(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("failed to merge: %w", err)
+		}
+
+		if jsonOutput {
+			return outputEntity(entity)
+		}
+		fmt.Printf("Merged %s into %s\n", args[0], entity.ID)
+		return nil
+	},
+}
+
+var (
+	exportFormat    string
+	exportTypes     []string
+	exportEdgeTypes []string
+	exportSince     string
+	exportBefore    string
+	exportOutput    string
+	importFormat    string
+	importOverwrite bool
+)
+
+var libraryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the knowledge graph",
+	Long: `Export entities and edges for visualization or other tools.
+
+Formats:
+  dot       Graphviz (derived edges and external targets are dashed)
+  graphml   Gephi, yEd, NetworkX
+  jsonld    JSON-LD with an @graph of entities
+  ndjson    One stored document per line; read back with 'library import'
+
+Examples:
+  hal9000 library export --format dot | dot -Tsvg > graph.svg
+  hal9000 library export --format graphml --type people --type projects -o graph.graphml
+  hal9000 library export --format dot --edge-type mentions --since 2026-01-01
+  hal9000 library export --format ndjson -o backup.ndjson`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := lmc.ExportOptions{
+			Format:    lmc.ExportFormat(exportFormat),
+			Types:     exportTypes,
+			EdgeTypes: exportEdgeTypes,
+		}
+		var err error
+		if opts.Since, err = parseLibraryTime(exportSince); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		if opts.Before, err = parseLibraryTime(exportBefore); err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			f, err := os.Create(exportOutput)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := lib.Export(out, opts); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var libraryImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import an NDJSON or JSON-LD dump",
+	Long: `Load entities from a dump written by 'library export --format ndjson'
+or '--format jsonld'. Reads stdin when no file is given. Entities that
+already exist are skipped unless --overwrite is set. IDs, timestamps and
+revisions are preserved; JSON-LD does not keep edge labels.
+
+Examples:
+  hal9000 library import backup.ndjson --library-path /tmp/restored
+  hal9000 library import graph.jsonld --format jsonld
+  cat backup.ndjson | hal9000 library import`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in := os.Stdin
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open dump: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		result, err := lib.Import(in, lmc.ImportOptions{
+			Format:    lmc.ExportFormat(importFormat),
+			Overwrite: importOverwrite,
+		})
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Printf("Imported %d entities (%d skipped)\n", result.Imported, result.Skipped)
+		return nil
+	},
+}
+
+// parseLibraryTime accepts YYYY-MM-DD or RFC3339; empty means no bound.
+func parseLibraryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+var (
+	watchTypes    []string
+	watchInterval time.Duration
+)
+
+var libraryWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream library changes as JSON lines",
+	Long: `Print one JSON object per line for every entity created, updated or
+deleted, until interrupted. Changes made by other processes (Floyd watchers,
+Poole, the scheduler) are picked up by scanning every --interval.
+
+Each line looks like:
+  {"op":"update","id":"people/dave","type":"people","revision":4,"time":"...","external":true}
+
+Examples:
+  hal9000 library watch
+  hal9000 library watch --type people --type agenda
+  hal9000 library watch --type people | while read -r change; do ...; done`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		changes, err := lib.Watch(ctx, lmc.WatchFilter{Types: watchTypes, Interval: watchInterval})
+		if err != nil {
+			return fmt.Errorf("failed to watch library: %w", err)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		for change := range changes {
+			if err := enc.Encode(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var fsckFix bool
+
+var libraryFsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check library integrity",
+	Long: `Check the library for problems:
+
+  corrupt          .json files that cannot be parsed
+  dangling_edge    links to entities that do not exist
+  id_mismatch      entities whose ID does not match their filename
+  collision        references to IDs that share a filename with another entity
+  orphaned_stage   bronze/silver documents whose source document is gone
+                   and was not moved into a retention archive
+  unreadable       files that cannot be read, such as sealed files while
+                   the vault is locked
+
+With --fix, corrupt, misplaced and orphaned files are moved to
+.quarantine/<timestamp>/ inside the library and dangling links are pruned.
+Collisions and unreadable files are reported only and need a human to
+resolve.
+
+Examples:
+  hal9000 library fsck
+  hal9000 library fsck --fix`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		report, err := lib.Fsck(lmc.FsckOptions{Fix: fsckFix})
+		if err != nil {
+			return fmt.Errorf("fsck failed: %w", err)
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, issue := range report.Issues {
+				status := ""
+				if issue.Fixed {
+					status = " [fixed: " + issue.Action + "]"
+				}
+				fmt.Printf("%-15s %s\n  %s%s\n", issue.Kind, issue.Path, issue.Detail, status)
+			}
+			fmt.Printf("\nChecked %d files, %d issues, %d unfixed.\n",
+				report.Checked, len(report.Issues), report.Unfixed())
+			if report.QuarantineDir != "" {
+				fmt.Printf("Quarantined files are in %s\n", report.QuarantineDir)
+			}
+		}
+
+		if n := report.Unfixed(); n > 0 {
+			return fmt.Errorf("%d integrity issues found", n)
+		}
+		return nil
+	},
+}
+
+var (
+	logLimit      int
+	logSince      string
+	logHALOnly    bool
+	revertSince   string
+	revertConfirm bool
+)
+
+var libraryLogCmd = &cobra.Command{
+	Use:   "log [entity-id|path]",
+	Short: "Show the library history",
+	Long: `Show commits in the library history, newest first. Requires
+library.history.enabled in .hal9000/config.yaml.
+
+Examples:
+  hal9000 library log
+  hal9000 library log people/dave-bowman
+  hal9000 library log agenda/agenda_2026-01-27_daily-agenda.md --limit 5
+  hal9000 library log --hal --since 2026-01-27`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getHistory()
+		if err != nil {
+			return err
+		}
+
+		since, err := parseLibraryTime(logSince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		opts := history.LogOptions{Since: since, HALOnly: logHALOnly, Limit: logLimit}
+		if len(args) == 1 {
+			if opts.Path, err = historyPath(args[0]); err != nil {
+				return err
+			}
+		}
+
+		commits, err := repo.Log(opts)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(commits, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		for _, c := range commits {
+			fmt.Printf("%.8s  %s  %-10s %s\n", c.Hash, c.Time.Local().Format("2006-01-02 15:04"), c.Author, c.Subject)
+		}
+		return nil
+	},
+}
+
+var librarySyncCmd = &cobra.Command{
+	Use:   "sync [remote]",
+	Short: "Push the library history to a backup repository",
+	Long: `Push the library history to a remote repository, by default
+library.history.remote from .hal9000/config.yaml. A local path that does not
+exist yet is created as a bare repository.
+
+Examples:
+  hal9000 library sync
+  hal9000 library sync /mnt/backup/library.git`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getHistory()
+		if err != nil {
+			return err
+		}
+
+		remote := config.GetLibraryHistory().Remote
+		if len(args) == 1 {
+			remote = args[0]
+		}
+		if strings.HasPrefix(remote, "~/") {
+			home, _ := os.UserHomeDir()
+			remote = filepath.Join(home, remote[2:])
+		}
+
+		if err := repo.Sync(remote); err != nil {
+			return fmt.Errorf("sync failed: %w", err)
+		}
+		fmt.Printf("Library history pushed to %s\n", remote)
+		return nil
+	},
+}
+
+var libraryRevertCmd = &cobra.Command{
+	Use:   "revert [commit...]",
+	Short: "Roll back changes recorded in the library history",
+	Long: `Undo one or more commits from the library history with a new commit.
+With --since, every change HAL made since that time is rolled back; hand
+edits committed by you are left alone. Nothing changes if any commit does
+not revert cleanly.
+
+Stop running services first so nothing writes while files are restored.
+
+Examples:
+  hal9000 library revert 3f2a9c1
+  hal9000 library revert --since 2026-01-27 --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getHistory()
+		if err != nil {
+			return err
+		}
+
+		revs := args
+		if revertSince != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("give commits or --since, not both")
+			}
+			since, err := parseLibraryTime(revertSince)
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			commits, err := repo.Log(history.LogOptions{Since: since, HALOnly: true})
+			if err != nil {
+				return err
+			}
+			var changes []history.Commit
+			for _, c := range commits {
+				if !c.Initial {
+					changes = append(changes, c)
+				}
+			}
+			if len(changes) == 0 {
+				fmt.Println("No changes by HAL since then.")
+				return nil
+			}
+			fmt.Printf("Rolling back %d changes:\n", len(changes))
+			for _, c := range changes {
+				fmt.Printf("  %.8s  %s\n", c.Hash, c.Subject)
+				revs = append(revs, c.Hash)
+			}
+			if !revertConfirm {
+				return fmt.Errorf("re-run with --yes to roll back these changes")
+			}
+		}
+		if len(revs) == 0 {
+			return fmt.Errorf("specify commits to revert or --since")
+		}
+
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+		commit, err := lib.Revert(repo, revs...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted in %.8s: %s\n", commit.Hash, commit.Subject)
+		return nil
+	},
+}
+
+var retentionDryRun bool
+
+var libraryRetentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Archive old documents according to retention rules",
+	Long: `Apply the retention rules from library.retention in .hal9000/config.yaml.
+Matching documents are moved into monthly tar.gz archives under
+library/archive/, listed in a manifest.json next to the archives. Documents
+derived from an archived one keep a pointer to it in _meta.archived_source.
+
+Example rule (raw Slack older than 90 days; bronze/silver are kept):
+  library:
+    retention:
+      - category: slack
+        stage: raw
+        older_than: 90d
+
+Examples:
+  hal9000 library retention --dry-run
+  hal9000 library retention`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(io.Discard)
+
+		rules, err := retention.Rules()
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			fmt.Println("No retention rules configured (library.retention in .hal9000/config.yaml).")
+			return nil
+		}
+
+		path := libraryPath
+		if path == "" {
+			path = config.GetLibraryPath()
+		}
+		report, err := retention.Apply(path, rules, retention.Options{DryRun: retentionDryRun})
+
+		if jsonOutput && report != nil {
+			data, jerr := json.MarshalIndent(report, "", "  ")
+			if jerr != nil {
+				return jerr
+			}
+			fmt.Println(string(data))
+		} else if report != nil {
+			verb := "Archived"
+			if report.DryRun {
+				verb = "Would archive"
+			}
+			for _, r := range report.Rules {
+				fmt.Printf("%s/%s older than %s: %s %d documents (%d bytes), %d derived documents repointed\n",
+					r.Stage, r.Category, r.Cutoff.Format("2006-01-02"), verb, r.Files, r.Bytes, r.Pointers)
+				for _, a := range r.Archives {
+					fmt.Printf("  %s\n", a)
+				}
+			}
+			reclaimed := "Reclaimed"
+			if report.DryRun {
+				reclaimed = "Would reclaim"
+			}
+			fmt.Printf("\n%s %d bytes from %d documents.\n", reclaimed, report.Reclaimed, report.Files)
+		}
+		if err != nil {
+			return fmt.Errorf("retention failed: %w", err)
+		}
+		return nil
+	},
+}
+
+// getHistory returns the library's history repository.
+func getHistory() (*history.Repo, error) {
+	path := libraryPath
+	if path == "" {
+		path = config.GetLibraryPath()
+	}
+	repo := history.Open(path)
+	if !repo.Initialized() {
+		if !config.GetLibraryHistory().Enabled {
+			return nil, fmt.Errorf("library history is not enabled (set library.history.enabled: true in .hal9000/config.yaml)")
+		}
+		if err := repo.Init(); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// historyPath maps an entity ID to its file; other arguments are taken as
+// paths relative to the library.
+func historyPath(arg string) (string, error) {
+	if filepath.Ext(arg) != "" {
+		return arg, nil
+	}
+	lib, err := getLibrary()
+	if err != nil {
+		return "", err
+	}
+	return lib.PathFor(arg)
+}
+
 func init() {
 	// Global library flags
 	libraryCmd.PersistentFlags().StringVar(&libraryPath, "library-path", "", "Override default library location")
@@ -215,14 +888,56 @@ func init() {
 	libraryQueryCmd.Flags().StringVar(&queryContains, "contains", "", "Filter by content text")
 	libraryQueryCmd.Flags().IntVar(&queryLimit, "limit", 0, "Maximum number of results")
 
+	// Write flags
+	libraryWriteCmd.Flags().IntVar(&writeIfRevision, "if-revision", 0, "Only write if the entity is at this revision (0 = must not exist)")
+
 	// List flags
 	libraryListCmd.Flags().IntVar(&queryLimit, "limit", 0, "Maximum number of results")
 
+	// Export/import flags
+	libraryExportCmd.Flags().StringVar(&exportFormat, "format", "ndjson", "Output format: dot, graphml, jsonld, ndjson")
+	libraryExportCmd.Flags().StringSliceVar(&exportTypes, "type", nil, "Only export these entity types (repeatable)")
+	libraryExportCmd.Flags().StringSliceVar(&exportEdgeTypes, "edge-type", nil, "Only export these edge types (repeatable; graph formats only)")
+	libraryExportCmd.Flags().StringVar(&exportSince, "since", "", "Only entities modified since (YYYY-MM-DD or RFC3339)")
+	libraryExportCmd.Flags().StringVar(&exportBefore, "before", "", "Only entities modified before (YYYY-MM-DD or RFC3339)")
+	libraryExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to file instead of stdout")
+	libraryImportCmd.Flags().StringVar(&importFormat, "format", "ndjson", "Dump format: ndjson, jsonld")
+	libraryImportCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "Replace entities that already exist")
+
+	// Watch flags
+	libraryWatchCmd.Flags().StringSliceVar(&watchTypes, "type", nil, "Only report these entity types (repeatable)")
+	libraryWatchCmd.Flags().DurationVar(&watchInterval, "interval", lmc.DefaultWatchInterval, "How often to scan for changes by other processes")
+
+	// Fsck flags
+	libraryFsckCmd.Flags().BoolVar(&fsckFix, "fix", false, "Quarantine bad files and prune dangling links")
+
+	// History flags
+	libraryLogCmd.Flags().IntVar(&logLimit, "limit", 20, "Maximum number of commits (0 = all)")
+	libraryLogCmd.Flags().StringVar(&logSince, "since", "", "Only commits since (YYYY-MM-DD or RFC3339)")
+	libraryLogCmd.Flags().BoolVar(&logHALOnly, "hal", false, "Only changes made by HAL")
+	libraryRevertCmd.Flags().StringVar(&revertSince, "since", "", "Roll back all of HAL's changes since (YYYY-MM-DD or RFC3339)")
+	libraryRevertCmd.Flags().BoolVar(&revertConfirm, "yes", false, "Confirm rolling back changes found with --since")
+
+	// Retention flags
+	libraryRetentionCmd.Flags().BoolVar(&retentionDryRun, "dry-run", false, "Report what would be archived without changing anything")
+
 	// Add subcommands
 	libraryCmd.AddCommand(libraryReadCmd)
 	libraryCmd.AddCommand(libraryWriteCmd)
 	libraryCmd.AddCommand(libraryQueryCmd)
 	libraryCmd.AddCommand(libraryListCmd)
+	libraryCmd.AddCommand(libraryValidateCmd)
+	libraryCmd.AddCommand(libraryFsckCmd)
+	libraryCmd.AddCommand(libraryAliasCmd)
+	libraryCmd.AddCommand(libraryResolveCmd)
+	libraryCmd.AddCommand(libraryMergeCmd)
+	libraryCmd.AddCommand(libraryExportCmd)
+	libraryCmd.AddCommand(libraryImportCmd)
+	libraryCmd.AddCommand(libraryWatchCmd)
+	libraryCmd.AddCommand(libraryLogCmd)
+	libraryCmd.AddCommand(librarySyncCmd)
+	libraryCmd.AddCommand(libraryRevertCmd)
+	libraryCmd.AddCommand(libraryRetentionCmd)
 }
 
 func getLibrary() (*lmc.Library, error) {
@@ -249,6 +964,7 @@ func outputEntity(entity *lmc.Entity) error {
 	fmt.Printf("ID:       %s\n", entity.ID)
 	fmt.Printf("Type:     %s\n", entity.Type)
 	fmt.Printf("Modified: %s\n", entity.Modified.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Revision: %d\n", entity.Revision)
 
 	if len(entity.Content) > 0 {
 		fmt.Println("Content:")
@@ -267,6 +983,13 @@ func outputEntity(entity *lmc.Entity) error {
 		}
 	}
 
+	if len(entity.DerivedLinks) > 0 {
+		fmt.Println("Derived Links:")
+		for _, link := range entity.DerivedLinks {
+			fmt.Printf("  -> %s (%s: %s)\n", link.To, link.Type, link.Label)
+		}
+	}
+
 	return nil
 }
 
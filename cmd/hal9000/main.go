@@ -9,6 +9,7 @@ import (
 	_ "github.com/pearcec/hal9000/cmd/hal9000/tasks/collabsummary"
 	_ "github.com/pearcec/hal9000/cmd/hal9000/tasks/helloworld"
 	_ "github.com/pearcec/hal9000/cmd/hal9000/tasks/oneononesummary"
+	_ "github.com/pearcec/hal9000/cmd/hal9000/tasks/retention"
 )
 
 func main() {
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pearcec/hal9000/discovery/bowman/bamboohr"
+	"github.com/pearcec/hal9000/discovery/bowman/transcript"
+	"github.com/spf13/cobra"
+)
+
+var peopleCmd = &cobra.Command{
+	Use:   "people",
+	Short: "Manage how HAL recognizes people",
+	Long: `Manage how transcript speakers and other names map to people in the library.
+
+Speakers are matched to you (the self section of .hal9000/config.yaml), to
+the meeting's calendar attendees and to people entities. Aliases recorded
+here take precedence over all of them.
+
+People entities can be kept in step with the org chart with people sync.`,
+}
+
+var peopleAliasCmd = &cobra.Command{
+	Use:   "alias <speaker> <person>",
+	Short: "Map a speaker label or name to a person",
+	Long: `Record that a speaker label, as transcripts show it, is a person in the
+library. The person is an entity ID or a name under people/.
+
+Examples:
+  hal9000 people alias "Dave B." people/dave-bowman
+  hal9000 people alias "iPhone (3)" frank-poole`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		if _, err := lib.AddAlias(args[0], personEntityID(args[1])); err != nil {
+			return fmt.Errorf("failed to add alias: %w", err)
+		}
+		speaker := transcript.NewResolver(lib).Speaker(args[0], nil)
+		fmt.Printf("%s -> %s\n", args[0], speaker.PersonID)
+		return nil
+	},
+}
+
+var peopleUnaliasCmd = &cobra.Command{
+	Use:   "unalias <speaker>",
+	Short: "Forget a speaker mapping",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		if err := lib.RemoveAlias(args[0]); err != nil {
+			return fmt.Errorf("failed to remove alias: %w", err)
+		}
+		fmt.Printf("Removed %s\n", args[0])
+		return nil
+	},
+}
+
+var peopleWhoisCmd = &cobra.Command{
+	Use:   "whois <speaker>",
+	Short: "Show who a speaker label resolves to",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		speaker := transcript.NewResolver(lib).Speaker(args[0], nil)
+		switch {
+		case speaker.Self:
+			fmt.Printf("%s: you (%s)\n", args[0], speaker.Via)
+		case speaker.PersonID != "":
+			fmt.Printf("%s: %s (%s)\n", args[0], speaker.PersonID, speaker.Via)
+		default:
+			fmt.Printf("%s: unknown\n", args[0])
+			fmt.Printf("  Map it with: hal9000 people alias %q <person>\n", args[0])
+		}
+		return nil
+	},
+}
+
+var peopleSyncDryRun bool
+
+var peopleSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync titles, departments and managers from BambooHR",
+	Long: `Create or update a person entity for every current BambooHR employee.
+
+Title, department, location, hire date and manager are BambooHR's and are
+overwritten; each person gets a reports_to edge to their manager. Other
+profile content (notes, interactions, open items) is left alone. Title and
+manager changes are added to the profile's org_history with today's date.
+
+Employees are matched to existing people by BambooHR ID, then work email,
+then name. floyd-bamboohr runs the same sync when the directory changes.
+
+Examples:
+  hal9000 people sync --dry-run
+  hal9000 people sync`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := bamboohr.NewClientFromConfig()
+		if err != nil {
+			return err
+		}
+		employees, err := client.GetEmployees()
+		if err != nil {
+			return err
+		}
+		lib, err := getLibrary()
+		if err != nil {
+			return err
+		}
+
+		result, err := bamboohr.Sync(lib, employees, bamboohr.SyncOptions{DryRun: peopleSyncDryRun})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range result.Created {
+			fmt.Printf("+ %s\n", id)
+		}
+		for _, id := range result.Updated {
+			fmt.Printf("~ %s\n", id)
+		}
+		for _, c := range result.Changes {
+			fmt.Printf("  %s %s: %q -> %q\n", c.PersonID, c.Field, c.From, c.To)
+		}
+		verb := "Synced"
+		if peopleSyncDryRun {
+			verb = "Would sync"
+		}
+		fmt.Printf("%s %d employees: %d created, %d updated, %d unchanged\n",
+			verb, len(employees), len(result.Created), len(result.Updated), result.Unchanged)
+		return nil
+	},
+}
+
+// personEntityID accepts "people/frank-poole" or "frank-poole".
+func personEntityID(person string) string {
+	if strings.Contains(person, "/") {
+		return person
+	}
+	return "people/" + person
+}
+
+func init() {
+	peopleCmd.PersistentFlags().StringVar(&libraryPath, "library-path", "", "Override default library location")
+
+	peopleCmd.AddCommand(peopleAliasCmd)
+	peopleCmd.AddCommand(peopleUnaliasCmd)
+	peopleCmd.AddCommand(peopleWhoisCmd)
+
+	peopleSyncCmd.Flags().BoolVar(&peopleSyncDryRun, "dry-run", false, "Show changes without writing")
+	peopleCmd.AddCommand(peopleSyncCmd)
+	rootCmd.AddCommand(peopleCmd)
+}
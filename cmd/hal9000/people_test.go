@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func TestPersonEntityID(t *testing.T) {
+	tests := map[string]string{
+		"frank-poole":        "people/frank-poole",
+		"people/frank-poole": "people/frank-poole",
+		"users/U123ABC":      "users/U123ABC",
+	}
+	for in, want := range tests {
+		if got := personEntityID(in); got != want {
+			t.Errorf("personEntityID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPeopleAlias(t *testing.T) {
+	dir := t.TempDir()
+	libraryPath = dir
+	defer func() { libraryPath = "" }()
+
+	lib, err := lmc.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Store("people", "frank-poole", map[string]interface{}{"name": "Frank Poole"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peopleAliasCmd.RunE(peopleAliasCmd, []string{"iPhone (3)", "frank-poole"}); err != nil {
+		t.Fatalf("people alias: %v", err)
+	}
+	lib, err = lmc.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := lib.Resolve("iphone (3)"); err != nil || id != "people/frank-poole" {
+		t.Errorf("Resolve() = %q, %v; want people/frank-poole", id, err)
+	}
+
+	if err := peopleAliasCmd.RunE(peopleAliasCmd, []string{"Dave", "dave-bowman"}); err == nil {
+		t.Error("aliasing to a missing person should fail")
+	}
+
+	if err := peopleUnaliasCmd.RunE(peopleUnaliasCmd, []string{"iPhone (3)"}); err != nil {
+		t.Fatalf("people unalias: %v", err)
+	}
+	lib, err = lmc.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Resolve("iPhone (3)"); err == nil {
+		t.Error("alias should be gone after unalias")
+	}
+}
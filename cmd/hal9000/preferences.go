@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -74,7 +76,7 @@ var preferencesGetCmd = &cobra.Command{
 		routine := args[0]
 		filePath := filepath.Join(getPreferencesDir(), routine+".md")
 
-		content, err := os.ReadFile(filePath)
+		content, err := vault.ReadFile(filePath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				fmt.Printf("I'm sorry, Dave. I cannot find preferences for '%s'.\n", routine)
@@ -108,7 +110,7 @@ Example:
 		prefsDir := getPreferencesDir()
 		filePath := filepath.Join(prefsDir, routine+".md")
 
-		content, err := os.ReadFile(filePath)
+		content, err := vault.ReadFile(filePath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				// Create new preference file with the section
@@ -121,10 +123,11 @@ Example:
 				newContent := strings.ReplaceAll(value, "\\n", "\n")
 				template := fmt.Sprintf("# %s Preferences\n\n## %s\n%s\n", strings.Title(routine), section, newContent)
 
-				if err := os.WriteFile(filePath, []byte(template), 0644); err != nil {
+				if err := vault.WriteFile(filePath, []byte(template), 0644); err != nil {
 					fmt.Fprintf(os.Stderr, "I'm afraid I can't do that: %v\n", err)
 					os.Exit(1)
 				}
+				recordPreferences(routine, section, filePath)
 
 				fmt.Printf("Created preferences for '%s' with section '%s'.\n", routine, section)
 				fmt.Println("I am completely operational, and your preferences have been initialized.")
@@ -142,17 +145,25 @@ Example:
 			found = true
 		}
 
-		err = os.WriteFile(filePath, []byte(updated), 0644)
+		err = vault.WriteFile(filePath, []byte(updated), 0644)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "I'm afraid I can't do that: %v\n", err)
 			os.Exit(1)
 		}
+		recordPreferences(routine, section, filePath)
 
 		fmt.Printf("Updated section '%s' in %s preferences.\n", section, routine)
 		fmt.Println("I am completely operational, and your preferences have been modified.")
 	},
 }
 
+// recordPreferences commits a preferences change to the library history.
+func recordPreferences(routine, section, path string) {
+	if err := history.Record(fmt.Sprintf("preferences: set %s %q", routine, section), path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history commit failed: %v\n", err)
+	}
+}
+
 // updateSection finds a markdown section by header and replaces its content.
 // It handles ## headers and replaces content until the next ## header.
 func updateSection(content, sectionName, newValue string) (string, bool) {
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+	"github.com/pearcec/hal9000/discovery/processor"
+	"github.com/pearcec/hal9000/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	processInterval   time.Duration
+	processOnce       bool
+	processCategories []string
+
+	rebuildSources []string
+	rebuildSince   string
+	rebuildStage   string
+	rebuildWorkers int
+
+	migrateSources []string
+	migrateDryRun  bool
+)
+
+var processCmd = &cobra.Command{
+	Use:   "process",
+	Short: "Run the Raw → Bronze → Silver → Gold processing pipeline",
+	Long: `Carry raw documents stored by Floyd and Bowman through the processing
+stages.
+"I've still got the greatest enthusiasm and confidence in the mission."
+
+  raw      library/<category>/           As fetched
+  bronze   library/bronze/<source>/      Cleaned and normalized
+  silver   library/silver/<source>/      Enriched with links
+  gold     library/timelines/            Per-person activity
+           library/project_status/       Per-project JIRA status
+           library/channel_digests/      Per-channel weekly digests
+
+Each stage records the document it came from in _meta.previous_id, and
+silver links are added to the LMC edge index as documents/<source>_<event>
+entities. Gold rollups are LMC entities too; each new silver document
+updates only the rollups it feeds. Run it as a service with:
+hal9000 services start processor
+
+Calendar, JIRA, Slack, BambooHR, transcript and URL documents have built-in
+transforms. Other sources can be mapped declaratively with YAML files in
+.hal9000/transforms/ (see the processor README); they also override the
+built-in transform for the same source.`,
+}
+
+var processStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Watch raw folders and process new documents",
+	Long: `Scan the library's raw category folders and process new or changed
+documents, then keep scanning every --interval until interrupted. Documents
+whose source is unchanged are skipped, so restarting is cheap.
+
+Examples:
+  hal9000 process start
+  hal9000 process start --once
+  hal9000 process start --category slack --interval 30s`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.SetFlags(log.Ldate | log.Ltime)
+		if processOnce && jsonOutput {
+			log.SetOutput(io.Discard)
+		}
+
+		pipeline, err := newPipeline()
+		if err != nil {
+			return err
+		}
+
+		if processOnce {
+			result, err := pipeline.Run()
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				data, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			fmt.Printf("Scanned %d raw documents: %d bronze, %d silver, %d gold rollups written, %d failed.\n",
+				result.Scanned, result.Bronze, result.Silver, result.Gold, result.Failed)
+			if result.Failed > 0 {
+				return fmt.Errorf("%d documents failed to process", result.Failed)
+			}
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		log.Println("[processor] HAL 9000 processor starting...")
+		err = pipeline.Watch(ctx, processInterval)
+		log.Println("[processor] Stopped")
+		return err
+	},
+}
+
+var processRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Regenerate processed documents after a transform change",
+	Long: `Run existing documents through the pipeline again, ignoring whether
+their source changed. Use it after editing a transform.
+
+--stage is the first stage to regenerate; later stages follow:
+
+  bronze   raw → bronze → silver → gold (default)
+  silver   existing bronze → silver → gold
+  gold     existing silver → gold rollups
+
+Each event keeps one document per stage, overwritten in place, and a
+document is only rewritten when the result differs. The summary lists the
+documents whose content or links changed.
+
+Examples:
+  hal9000 process rebuild --source jira --since 2026-01-01 --stage silver
+  hal9000 process rebuild --stage gold
+  hal9000 process rebuild --workers 8 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := processor.RebuildOptions{
+			Sources: rebuildSources,
+			Stage:   processor.Stage(rebuildStage),
+			Workers: rebuildWorkers,
+		}
+		if rebuildSince != "" {
+			since, err := time.Parse("2006-01-02", rebuildSince)
+			if err != nil {
+				return fmt.Errorf("invalid --since %q: want YYYY-MM-DD", rebuildSince)
+			}
+			opts.Since = since
+		}
+
+		// Progress replaces the per-document log lines
+		log.SetOutput(io.Discard)
+		pipeline, err := newPipeline()
+		if err != nil {
+			return err
+		}
+
+		if !jsonOutput {
+			opts.Progress = func(done, total int) {
+				fmt.Fprintf(os.Stderr, "\rRebuilding %d/%d", done, total)
+				if done == total {
+					fmt.Fprintln(os.Stderr)
+				}
+			}
+		}
+
+		result, err := pipeline.Rebuild(opts)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			printRebuild(result)
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("%d documents failed to rebuild", result.Failed)
+		}
+		return nil
+	},
+}
+
+// printRebuild prints a rebuild summary and the changed documents.
+func printRebuild(result *processor.RebuildResult) {
+	fmt.Printf("Rebuilt %d documents: %d bronze, %d silver, %d gold rollups written, %d failed.\n",
+		result.Documents, result.Bronze, result.Silver, result.Gold, result.Failed)
+
+	if len(result.Changes) == 0 {
+		fmt.Println("No content or link changes.")
+	} else {
+		fmt.Printf("\nChanged (%d):\n", len(result.Changes))
+		for _, c := range result.Changes {
+			var parts []string
+			if c.Created {
+				parts = append(parts, "new")
+			}
+			if c.ContentChanged {
+				parts = append(parts, "content")
+			}
+			for _, link := range c.LinksAdded {
+				parts = append(parts, "+"+link)
+			}
+			for _, link := range c.LinksRemoved {
+				parts = append(parts, "-"+link)
+			}
+			fmt.Printf("  %-40s %s\n", c.ID(), strings.Join(parts, ", "))
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Printf("\nFailed (%d):\n", len(result.Errors))
+		for _, e := range result.Errors {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+}
+
+var processMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade bronze and silver documents to the current schema",
+	Long: `Rewrite bronze and silver documents written with an older schema
+version of their source's layout. Documents from before schema versions
+were recorded are stamped with version 1.
+
+Older documents are also upgraded in memory whenever the pipeline reads
+them, so migrating is not required for processing; it keeps the files
+readable by anything else that consumes them.
+
+--source takes a source name (google-calendar) or, as for rebuild, the raw
+folder its documents are stored in (calendar).
+
+Examples:
+  hal9000 process migrate --dry-run
+  hal9000 process migrate --source calendar`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(io.Discard)
+		pipeline, err := newPipeline()
+		if err != nil {
+			return err
+		}
+
+		result, err := pipeline.Migrate(processor.MigrateOptions{
+			Sources: migrateSources,
+			DryRun:  migrateDryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			verb := "Migrated"
+			if migrateDryRun {
+				verb = "Would migrate"
+			}
+			fmt.Printf("%s %d of %d documents.\n", verb, result.Migrated, result.Scanned)
+			for _, c := range result.Counts {
+				from := fmt.Sprintf("v%d", c.From)
+				if c.From == 0 {
+					from = "unversioned"
+				}
+				fmt.Printf("  %-20s %s → v%d  %d\n", c.Source, from, c.To, c.Documents)
+			}
+			if len(result.Errors) > 0 {
+				fmt.Printf("\nFailed (%d):\n", len(result.Errors))
+				for _, e := range result.Errors {
+					fmt.Printf("  %s\n", e)
+				}
+			}
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("%d documents failed to migrate", result.Failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	processCmd.PersistentFlags().StringVar(&libraryPath, "library-path", "", "Override default library location")
+	processCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	processStartCmd.Flags().DurationVar(&processInterval, "interval", processor.DefaultInterval, "How often to scan for new raw documents")
+	processStartCmd.Flags().BoolVar(&processOnce, "once", false, "Process pending documents and exit")
+	processStartCmd.Flags().StringSliceVar(&processCategories, "category", nil, "Only process these raw folders (repeatable)")
+
+	processRebuildCmd.Flags().StringSliceVar(&rebuildSources, "source", nil, "Only rebuild these raw folders (repeatable)")
+	processRebuildCmd.Flags().StringVar(&rebuildSince, "since", "", "Only rebuild documents fetched on or after this date (YYYY-MM-DD)")
+	processRebuildCmd.Flags().StringVar(&rebuildStage, "stage", string(processor.StageBronze), "First stage to regenerate: bronze, silver or gold")
+	processRebuildCmd.Flags().IntVar(&rebuildWorkers, "workers", 0, "Documents to process in parallel (default one per CPU)")
+
+	processCmd.AddCommand(processStartCmd)
+	processMigrateCmd.Flags().StringSliceVar(&migrateSources, "source", nil, "Only migrate these sources or raw folders (repeatable)")
+	processMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would be migrated without writing")
+
+	processCmd.AddCommand(processRebuildCmd)
+	processCmd.AddCommand(processMigrateCmd)
+	rootCmd.AddCommand(processCmd)
+}
+
+// newPipeline builds a pipeline for the configured library.
+func newPipeline() (*processor.Pipeline, error) {
+	path := libraryPath
+	if path == "" {
+		path = config.GetLibraryPath()
+	}
+	lib, err := lmc.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library: %w", err)
+	}
+	if n, err := processor.LoadTransforms(config.GetTransformsDir()); err != nil {
+		return nil, err
+	} else if n > 0 {
+		log.Printf("[processor] Loaded %d transforms from %s", n, config.GetTransformsDir())
+	}
+	pipeline := processor.NewPipeline(processor.ProcessConfig{LibraryPath: path}, lib)
+	pipeline.Categories = processCategories
+	return pipeline, nil
+}
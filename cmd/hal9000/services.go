@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/auth"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -61,6 +62,7 @@ Services:
   floyd-calendar   Google Calendar watcher
   floyd-jira       JIRA watcher
   floyd-slack      Slack watcher
+  processor        Raw → Bronze → Silver processing pipeline
 
 Commands:
   start [service]    Start all or specific service
@@ -271,6 +273,13 @@ func getDefaultServicesConfig() *ServicesConfig {
 				Enabled:     false,
 				Description: "Slack watcher",
 			},
+			{
+				Name:        "processor",
+				Command:     "hal9000",
+				Args:        []string{"process", "start"},
+				Enabled:     false,
+				Description: "Raw → Bronze → Silver processing pipeline",
+			},
 		},
 	}
 }
@@ -294,6 +303,7 @@ func saveServicesConfig(config *ServicesConfig) error {
 #   floyd-calendar   Google Calendar watcher
 #   floyd-jira       JIRA watcher
 #   floyd-slack      Slack watcher
+#   processor        Raw → Bronze → Silver processing pipeline
 
 `)
 	return os.WriteFile(configPath, append(header, data...), 0644)
@@ -829,6 +839,16 @@ func runServicesDiagnose(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		// Check the token of services that sign in to a provider
+		if provider, ok := serviceAuthProviders[name]; ok {
+			line, ok := diagnoseToken(provider, time.Now())
+			fmt.Println(line)
+			if !ok {
+				fmt.Printf("  To fix: hal9000 auth login %s\n", provider)
+				hasProblems = true
+			}
+		}
+
 		// Check logs for recent errors
 		logPath := getServiceLogPath(name)
 		if _, err := os.Stat(logPath); err == nil {
@@ -883,6 +903,24 @@ func runServicesDiagnose(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// serviceAuthProviders maps services to the provider whose token they use.
+var serviceAuthProviders = map[string]string{
+	"floyd-calendar": auth.Google,
+}
+
+// diagnoseToken describes a provider's token for services diagnose, and
+// reports whether it is usable.
+func diagnoseToken(provider string, now time.Time) (string, bool) {
+	st, err := auth.TokenStatus(provider)
+	if err != nil {
+		return fmt.Sprintf("  Token: \033[31mERROR\033[0m %v", err), false
+	}
+	if !st.Valid(now) {
+		return fmt.Sprintf("  Token: \033[31m%s\033[0m (%s)", st.Describe(now), provider), false
+	}
+	return fmt.Sprintf("  Token: \033[32mOK\033[0m (%s, %s)", provider, st.Describe(now)), true
+}
+
 func readLastLines(path string, n int) ([]string, error) {
 	file, err := os.Open(path)
 	if err != nil {
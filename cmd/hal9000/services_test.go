@@ -12,12 +12,12 @@ import (
 func TestGetDefaultServicesConfig(t *testing.T) {
 	config := getDefaultServicesConfig()
 
-	if len(config.Services) != 5 {
-		t.Errorf("expected 5 services, got %d", len(config.Services))
+	if len(config.Services) != 6 {
+		t.Errorf("expected 6 services, got %d", len(config.Services))
 	}
 
 	// Check service names
-	expectedNames := []string{"scheduler", "poole", "floyd-calendar", "floyd-jira", "floyd-slack"}
+	expectedNames := []string{"scheduler", "poole", "floyd-calendar", "floyd-jira", "floyd-slack", "processor"}
 	for i, name := range expectedNames {
 		if config.Services[i].Name != name {
 			t.Errorf("expected service %d to be %s, got %s", i, name, config.Services[i].Name)
@@ -30,7 +30,7 @@ func TestGetDefaultServicesConfig(t *testing.T) {
 	}
 
 	// Other services should be disabled by default
-	for i := 1; i < 5; i++ {
+	for i := 1; i < len(config.Services); i++ {
 		if config.Services[i].Enabled {
 			t.Errorf("%s should be disabled by default", config.Services[i].Name)
 		}
@@ -142,8 +142,8 @@ func TestLoadServicesConfigDefault(t *testing.T) {
 		t.Fatal("expected non-nil config")
 	}
 
-	if len(config.Services) != 5 {
-		t.Errorf("expected 5 default services, got %d", len(config.Services))
+	if len(config.Services) != 6 {
+		t.Errorf("expected 6 default services, got %d", len(config.Services))
 	}
 }
 
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"github.com/pearcec/hal9000/cmd/hal9000/tasks"
+	"github.com/pearcec/hal9000/discovery/bowman"
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 )
 
@@ -139,7 +141,7 @@ func (t *AgendaTask) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Res
 	}
 
 	// Write agenda file
-	if err := os.WriteFile(outputPath, []byte(agenda), 0644); err != nil {
+	if err := vault.WriteFile(outputPath, []byte(agenda), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write agenda: %w", err)
 	}
 
@@ -207,44 +209,46 @@ func loadTodayEventsFromPath(today time.Time, libraryPath string) ([]CalendarEve
 
 	var events []CalendarEvent
 
-	err := filepath.Walk(calendarPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".json") {
-			return nil
-		}
+	// Only the latest version of each event: earlier captures may be
+	// rescheduled or cancelled since
+	latest, err := bowman.Latest(bowman.StoreConfig{LibraryPath: libraryPath, Category: "calendar"})
+	if err != nil {
+		return nil, err
+	}
 
-		data, err := os.ReadFile(path)
+	for _, v := range latest {
+		data, err := bowman.ReadEvent(v.Path)
 		if err != nil {
-			return nil
+			continue
 		}
 
 		var event CalendarEvent
 		if err := json.Unmarshal(data, &event); err != nil {
-			return nil
+			continue
 		}
 
 		eventTime := parseEventTime(event.Start)
 		if eventTime.IsZero() {
-			return nil
+			continue
 		}
 
 		// Filter to today's events
 		if eventTime.Before(startOfDay) || !eventTime.Before(endOfDay) {
-			return nil
+			continue
 		}
 
 		// Skip declined events
+		declined := false
 		for _, a := range event.Attendees {
 			if a.Self && a.ResponseStatus == "declined" {
-				return nil
+				declined = true
 			}
 		}
+		if declined {
+			continue
+		}
 
 		events = append(events, event)
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
 	// Sort by start time
@@ -286,7 +290,7 @@ func loadRolloverItemsFromPath(today time.Time, libraryPath string) ([]RolloverI
 	yesterday := today.AddDate(0, 0, -1)
 	yesterdayFile := filepath.Join(agendaPath, fmt.Sprintf("agenda_%s_daily-agenda.md", yesterday.Format("2006-01-02")))
 
-	data, err := os.ReadFile(yesterdayFile)
+	data, err := vault.ReadFile(yesterdayFile)
 	if err != nil {
 		return nil, nil // No previous agenda - not an error
 	}
@@ -331,7 +335,7 @@ func loadRemindersFromPath(libraryPath string) ([]Reminder, error) {
 			return nil
 		}
 
-		data, err := os.ReadFile(path)
+		data, err := vault.ReadFile(path)
 		if err != nil {
 			return nil
 		}
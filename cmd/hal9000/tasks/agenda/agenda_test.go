@@ -2,7 +2,6 @@ package agenda
 
 import (
 	"context"
-	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,8 +9,24 @@ import (
 	"time"
 
 	"github.com/pearcec/hal9000/cmd/hal9000/tasks"
+	"github.com/pearcec/hal9000/discovery/bowman"
 )
 
+// storeEvent stores a calendar event in the library the way the calendar
+// floyd does.
+func storeEvent(t *testing.T, libraryPath, eventID string, event map[string]interface{}) {
+	t.Helper()
+	_, err := bowman.Store(bowman.StoreConfig{LibraryPath: libraryPath, Category: "calendar"}, bowman.RawEvent{
+		Source:    "google-calendar",
+		EventID:   eventID,
+		FetchedAt: time.Now(),
+		Data:      event,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAgendaTask_Interface(t *testing.T) {
 	task := &AgendaTask{}
 
@@ -147,15 +162,7 @@ func TestLoadTodayEventsFromPath(t *testing.T) {
 		"hangoutLink": "https://meet.google.com/abc-defg-hij",
 	}
 
-	eventData, err := json.Marshal(event)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	eventFile := filepath.Join(calendarDir, "meeting.json")
-	if err := os.WriteFile(eventFile, eventData, 0644); err != nil {
-		t.Fatal(err)
-	}
+	storeEvent(t, tmpDir, "meeting", event)
 
 	events, err := loadTodayEventsFromPath(now, tmpDir)
 	if err != nil {
@@ -217,10 +224,7 @@ func TestLoadTodayEventsFromPath_SkipsDeclinedEvents(t *testing.T) {
 		},
 	}
 
-	eventData, _ := json.Marshal(declinedEvent)
-	if err := os.WriteFile(filepath.Join(calendarDir, "declined.json"), eventData, 0644); err != nil {
-		t.Fatal(err)
-	}
+	storeEvent(t, tmpDir, "declined", declinedEvent)
 
 	events, err := loadTodayEventsFromPath(now, tmpDir)
 	if err != nil {
@@ -232,6 +236,31 @@ func TestLoadTodayEventsFromPath_SkipsDeclinedEvents(t *testing.T) {
 	}
 }
 
+func TestLoadTodayEventsFromPath_UsesLatestVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	event := map[string]interface{}{
+		"summary": "Pod Bay Review",
+		"start":   map[string]string{"dateTime": now.Add(1 * time.Hour).Format(time.RFC3339)},
+		"end":     map[string]string{"dateTime": now.Add(2 * time.Hour).Format(time.RFC3339)},
+	}
+	storeEvent(t, tmpDir, "review", event)
+
+	// Rescheduled to tomorrow: the earlier capture must not show up today
+	event["start"] = map[string]string{"dateTime": now.AddDate(0, 0, 1).Format(time.RFC3339)}
+	event["end"] = map[string]string{"dateTime": now.AddDate(0, 0, 1).Add(time.Hour).Format(time.RFC3339)}
+	storeEvent(t, tmpDir, "review", event)
+
+	events, err := loadTodayEventsFromPath(now, tmpDir)
+	if err != nil {
+		t.Fatalf("loadTodayEventsFromPath() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("loadTodayEventsFromPath() returned %d events, want 0 (rescheduled)", len(events))
+	}
+}
+
 func TestLoadRolloverItemsFromPath(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agenda-test")
 	if err != nil {
@@ -41,6 +41,11 @@ func (t *Task) PreferencesKey() string {
 	return "collaboration"
 }
 
+// InputDescription describes the file read by --file and --stdin.
+func (t *Task) InputDescription() string {
+	return "transcript export (.vtt, .srt, .txt, .docx)"
+}
+
 // SetupQuestions returns questions for first-run setup.
 func (t *Task) SetupQuestions() []tasks.SetupQuestion {
 	return []tasks.SetupQuestion{
@@ -69,12 +74,17 @@ func (t *Task) SetupQuestions() []tasks.SetupQuestion {
 	}
 }
 
+// BacklogDescription describes what --backlog processes.
+func (t *Task) BacklogDescription() string {
+	return "group meeting with a transcript"
+}
+
 // Run executes the task with given options.
 func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, error) {
-	// Initialize transcript fetcher
-	fetcher, err := transcript.NewFetcher(ctx)
+	// Read a local export (--file, --stdin) or fetch from Google Calendar
+	source, err := transcript.OpenSource(ctx, opts.Input, config.GetLibraryPath())
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize transcript fetcher: %w", err)
+		return nil, fmt.Errorf("failed to initialize transcript source: %w", err)
 	}
 
 	// Initialize library
@@ -83,33 +93,136 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		return nil, fmt.Errorf("failed to initialize library: %w", err)
 	}
 
+	// Meetings already summarized are skipped unless forced
+	ledger, err := transcript.OpenLedger(config.GetLibraryPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Backlog {
+		return t.runBacklog(ctx, source, lib, ledger, opts)
+	}
+
 	var transcriptData *transcript.Transcript
 
 	// Check if event ID was provided
 	if len(opts.Args) > 0 {
 		eventID := opts.Args[0]
-		transcriptData, err = fetcher.FetchForEvent(ctx, eventID)
+		transcriptData, err = source.FetchForEvent(ctx, eventID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch transcript for event %s: %w", eventID, err)
 		}
+	} else if local, ok := source.(*transcript.Local); ok {
+		// A local export is processed whenever the meeting was
+		transcriptData, err = local.Fetch(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcript %s: %w", local.Name, err)
+		}
 	} else {
 		// Default to last 24 hours
 		end := time.Now()
 		start := end.Add(-24 * time.Hour)
-		transcripts, err := fetcher.FetchForTimeRange(ctx, start, end)
+		transcripts, err := source.FetchForTimeRange(ctx, start, end)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch recent transcripts: %w", err)
 		}
-		if len(transcripts) == 0 {
+		var unprocessed []*transcript.Transcript
+		for _, tr := range transcripts {
+			if _, done := ledger.Lookup(t.Name(), tr.EventID); !done || opts.Force {
+				unprocessed = append(unprocessed, tr)
+			}
+		}
+		if len(unprocessed) == 0 {
 			return &tasks.Result{
 				Success: true,
-				Message: "No meeting transcripts found in the last 24 hours.",
+				Message: "No new meeting transcripts found in the last 24 hours.",
 			}, nil
 		}
 		// Process the most recent transcript
-		transcriptData = transcripts[len(transcripts)-1]
+		transcriptData = unprocessed[len(unprocessed)-1]
+	}
+
+	if done, ok := ledger.Lookup(t.Name(), transcriptData.EventID); ok && !opts.Force {
+		return &tasks.Result{
+			Success: true,
+			Message: fmt.Sprintf("Already summarized %s on %s. Use --force to summarize it again.",
+				transcriptData.EventTitle, done.ProcessedAt.Format("2006-01-02 15:04")),
+			Metadata: map[string]interface{}{
+				"event_id":   transcriptData.EventID,
+				"session_id": done.Output,
+			},
+		}, nil
+	}
+
+	return t.summarize(lib, ledger, transcriptData, opts)
+}
+
+// runBacklog summarizes every meeting of three or more speakers with a
+// transcript between opts.Since and opts.Until that has not been
+// summarized yet. Two-person meetings are left to the oneonone task.
+func (t *Task) runBacklog(ctx context.Context, source transcript.Source, lib *lmc.Library, ledger *transcript.Ledger, opts tasks.RunOptions) (*tasks.Result, error) {
+	if _, ok := source.(*transcript.Local); ok {
+		return nil, fmt.Errorf("--backlog reads transcripts from Google Calendar and cannot be used with --file or --stdin")
+	}
+
+	transcripts, err := source.FetchForTimeRange(ctx, opts.Since, opts.Until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transcripts: %w", err)
+	}
+
+	var messages, outputs []string
+	summarized, skipped, failed := 0, 0, 0
+	for _, tr := range transcripts {
+		if len(tr.Speakers) <= 2 {
+			continue
+		}
+		if _, done := ledger.Lookup(t.Name(), tr.EventID); done && !opts.Force {
+			skipped++
+			continue
+		}
+		result, err := t.summarize(lib, ledger, tr, opts)
+		if err != nil {
+			fmt.Printf("Warning: failed to summarize %s: %v\n", tr.EventTitle, err)
+			failed++
+			continue
+		}
+		summarized++
+		messages = append(messages, result.Message)
+		if result.Output != "" {
+			outputs = append(outputs, result.Output)
+		}
 	}
 
+	verb := "Summarized"
+	if opts.DryRun {
+		verb = "Would summarize"
+	}
+	message := fmt.Sprintf("%s %d group meetings from %s to %s (%d already summarized)",
+		verb, summarized, opts.Since.Format("2006-01-02"), opts.Until.Format("2006-01-02"), skipped)
+	if failed > 0 {
+		message += fmt.Sprintf(", %d failed", failed)
+	}
+	if len(messages) > 0 {
+		message += ":\n  " + strings.Join(messages, "\n  ")
+	}
+
+	return &tasks.Result{
+		Success: failed == 0,
+		Output:  strings.Join(outputs, "\n\n"),
+		Message: message,
+		Metadata: map[string]interface{}{
+			"summarized": summarized,
+			"skipped":    skipped,
+			"failed":     failed,
+		},
+	}, nil
+}
+
+// summarize summarizes one meeting transcript into a collaboration session
+// and records the meeting in the ledger.
+func (t *Task) summarize(lib *lmc.Library, ledger *transcript.Ledger, transcriptData *transcript.Transcript, opts tasks.RunOptions) (*tasks.Result, error) {
+	speakers := transcript.NewResolver(lib).Resolve(transcriptData)
+
 	if opts.DryRun {
 		return &tasks.Result{
 			Success: true,
@@ -119,13 +232,14 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 				"event_id":    transcriptData.EventID,
 				"event_title": transcriptData.EventTitle,
 				"event_time":  transcriptData.EventTime,
-				"speakers":    transcriptData.Speakers,
+				"speakers":    speakers,
+				"source_file": transcriptData.SourceFile,
 			},
 		}, nil
 	}
 
 	// Match to existing collaboration or create new one
-	collabID, err := t.matchOrCreateCollaboration(lib, transcriptData, opts)
+	collabID, err := t.matchOrCreateCollaboration(lib, transcriptData, speakers, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to match collaboration: %w", err)
 	}
@@ -139,7 +253,7 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		"event_id":     transcriptData.EventID,
 		"event_title":  transcriptData.EventTitle,
 		"event_time":   transcriptData.EventTime.Format(time.RFC3339),
-		"speakers":     transcriptData.Speakers,
+		"speakers":     speakers,
 		"summary":      summary.Summary,
 		"topics":       summary.Topics,
 		"decisions":    summary.Decisions,
@@ -151,13 +265,11 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		{To: collabID, Type: "session_of"},
 	}
 
-	// Add links to speakers if they exist as people entities
-	for _, speaker := range transcriptData.Speakers {
-		speakerID := fmt.Sprintf("people/%s", sanitizeID(strings.ToLower(speaker)))
-		sessionLinks = append(sessionLinks, lmc.Edge{
-			To:   speakerID,
-			Type: "participant",
-		})
+	// Link the other speakers who resolved to people
+	for _, s := range speakers {
+		if s.PersonID != "" && !s.Self {
+			sessionLinks = append(sessionLinks, lmc.Edge{To: s.PersonID, Type: "participant"})
+		}
 	}
 
 	sessionEntity, err := lib.Store("collaboration-session", sessionID, sessionContent, sessionLinks)
@@ -173,6 +285,17 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		}
 	}
 
+	if err := ledger.Record(transcript.Processed{
+		Task:       t.Name(),
+		EventID:    transcriptData.EventID,
+		EventTitle: transcriptData.EventTitle,
+		EventTime:  transcriptData.EventTime,
+		SourceFile: transcriptData.SourceFile,
+		Output:     sessionEntity.ID,
+	}); err != nil {
+		fmt.Printf("Warning: failed to record processed meeting: %v\n", err)
+	}
+
 	// Format output
 	output := t.formatOutput(summary, transcriptData, opts.Format)
 
@@ -216,7 +339,7 @@ type ActionItem struct {
 
 // matchOrCreateCollaboration matches the transcript to an existing collaboration
 // or creates a new ad-hoc collaboration record.
-func (t *Task) matchOrCreateCollaboration(lib *lmc.Library, tr *transcript.Transcript, opts tasks.RunOptions) (string, error) {
+func (t *Task) matchOrCreateCollaboration(lib *lmc.Library, tr *transcript.Transcript, speakers []transcript.Speaker, opts tasks.RunOptions) (string, error) {
 	// Try to match by meeting title pattern
 	collabID, matched := t.matchByTitle(lib, tr.EventTitle)
 	if matched {
@@ -224,14 +347,14 @@ func (t *Task) matchOrCreateCollaboration(lib *lmc.Library, tr *transcript.Trans
 	}
 
 	// Try to match by attendee overlap
-	collabID, matched = t.matchByAttendees(lib, tr.Speakers)
+	collabID, matched = t.matchByAttendees(lib, speakers)
 	if matched {
 		return collabID, nil
 	}
 
 	// Create ad-hoc collaboration if enabled
 	if getPreference(opts, "auto_create_collab") == "yes" {
-		return t.createAdHocCollaboration(lib, tr)
+		return t.createAdHocCollaboration(lib, tr, speakers)
 	}
 
 	// Return a generic collaboration ID
@@ -270,8 +393,10 @@ func (t *Task) matchByTitle(lib *lmc.Library, title string) (string, bool) {
 	return "", false
 }
 
-// matchByAttendees tries to match by >50% attendee overlap with known collaborations.
-func (t *Task) matchByAttendees(lib *lmc.Library, speakers []string) (string, bool) {
+// matchByAttendees tries to match by >50% attendee overlap with known
+// collaborations. Members may be listed by name or by person ID; a speaker
+// counts if either matches what they resolved to.
+func (t *Task) matchByAttendees(lib *lmc.Library, speakers []transcript.Speaker) (string, bool) {
 	if len(speakers) == 0 {
 		return "", false
 	}
@@ -286,7 +411,11 @@ func (t *Task) matchByAttendees(lib *lmc.Library, speakers []string) (string, bo
 
 	speakerSet := make(map[string]bool)
 	for _, s := range speakers {
-		speakerSet[strings.ToLower(s)] = true
+		speakerSet[strings.ToLower(s.Label)] = true
+		speakerSet[strings.ToLower(s.Name)] = true
+		if s.PersonID != "" {
+			speakerSet[s.PersonID] = true
+		}
 	}
 
 	var bestMatch string
@@ -305,6 +434,8 @@ func (t *Task) matchByAttendees(lib *lmc.Library, speakers []string) (string, bo
 			if memberStr, ok := m.(string); ok {
 				if speakerSet[strings.ToLower(memberStr)] {
 					overlap++
+				} else if id, err := lib.Resolve(memberStr); err == nil && speakerSet[id] {
+					overlap++
 				}
 			}
 		}
@@ -323,8 +454,9 @@ func (t *Task) matchByAttendees(lib *lmc.Library, speakers []string) (string, bo
 	return "", false
 }
 
-// createAdHocCollaboration creates a new collaboration record for an unmatched meeting.
-func (t *Task) createAdHocCollaboration(lib *lmc.Library, tr *transcript.Transcript) (string, error) {
+// createAdHocCollaboration creates a new collaboration record for an
+// unmatched meeting, linking the speakers that resolved to people.
+func (t *Task) createAdHocCollaboration(lib *lmc.Library, tr *transcript.Transcript, speakers []transcript.Speaker) (string, error) {
 	collabID := sanitizeID(fmt.Sprintf("adhoc-%s-%s",
 		tr.EventTime.Format("2006-01-02"),
 		strings.ReplaceAll(tr.EventTitle, " ", "-")))
@@ -337,7 +469,14 @@ func (t *Task) createAdHocCollaboration(lib *lmc.Library, tr *transcript.Transcr
 		"created_at":   time.Now().Format(time.RFC3339),
 	}
 
-	entity, err := lib.Store("collaboration", collabID, content, nil)
+	var links []lmc.Edge
+	for _, s := range speakers {
+		if s.PersonID != "" && !s.Self {
+			links = append(links, lmc.Edge{To: s.PersonID, Type: "has_member"})
+		}
+	}
+
+	entity, err := lib.Store("collaboration", collabID, content, links)
 	if err != nil {
 		return "", err
 	}
@@ -347,7 +486,11 @@ func (t *Task) createAdHocCollaboration(lib *lmc.Library, tr *transcript.Transcr
 
 // generateSummary extracts summary information from the transcript.
 func (t *Task) generateSummary(tr *transcript.Transcript, opts tasks.RunOptions) *Summary {
-	entries := transcript.ParseEntries(tr.Text)
+	// Entries parsed from WebVTT/SRT carry timings; fall back to the text
+	entries := tr.Entries
+	if len(entries) == 0 {
+		entries = transcript.ParseEntries(tr.Text)
+	}
 
 	summary := &Summary{
 		Topics:      t.extractTopics(tr.Text, entries),
@@ -360,6 +503,12 @@ func (t *Task) generateSummary(tr *transcript.Transcript, opts tasks.RunOptions)
 	detailLevel := getPreference(opts, "summary_detail")
 	summary.Summary = t.generateTextSummary(tr, entries, detailLevel)
 
+	// Gemini notes come with their own summary and details
+	if tr.Summary != "" {
+		summary.Summary = tr.Summary
+	}
+	summary.KeyPoints = append(append([]string(nil), tr.Details...), summary.KeyPoints...)
+
 	return summary
 }
 
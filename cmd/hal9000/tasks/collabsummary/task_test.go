@@ -13,6 +13,8 @@ func TestTaskInterface(t *testing.T) {
 
 	// Verify interface compliance
 	var _ tasks.Task = task
+	var _ tasks.InputTask = task
+	var _ tasks.BacklogTask = task
 
 	if task.Name() != "collabsummary" {
 		t.Errorf("Name() = %q, want %q", task.Name(), "collabsummary")
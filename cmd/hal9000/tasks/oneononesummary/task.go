@@ -41,6 +41,11 @@ func (t *Task) PreferencesKey() string {
 	return "oneonone"
 }
 
+// InputDescription describes the file read by --file and --stdin.
+func (t *Task) InputDescription() string {
+	return "transcript export (.vtt, .srt, .txt, .docx)"
+}
+
 // SetupQuestions returns questions for first-run setup.
 func (t *Task) SetupQuestions() []tasks.SetupQuestion {
 	return []tasks.SetupQuestion{
@@ -69,12 +74,17 @@ func (t *Task) SetupQuestions() []tasks.SetupQuestion {
 	}
 }
 
+// BacklogDescription describes what --backlog processes.
+func (t *Task) BacklogDescription() string {
+	return "1:1 meeting with a transcript"
+}
+
 // Run executes the task with given options.
 func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, error) {
-	// Initialize transcript fetcher
-	fetcher, err := transcript.NewFetcher(ctx)
+	// Read a local export (--file, --stdin) or fetch from Google Calendar
+	source, err := transcript.OpenSource(ctx, opts.Input, config.GetLibraryPath())
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize transcript fetcher: %w", err)
+		return nil, fmt.Errorf("failed to initialize transcript source: %w", err)
 	}
 
 	// Initialize library
@@ -83,27 +93,46 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		return nil, fmt.Errorf("failed to initialize library: %w", err)
 	}
 
+	// Meetings already summarized are skipped unless forced
+	ledger, err := transcript.OpenLedger(config.GetLibraryPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Backlog {
+		return t.runBacklog(ctx, source, lib, ledger, opts)
+	}
+
 	var transcriptData *transcript.Transcript
 
 	// Check if event ID was provided
 	if len(opts.Args) > 0 {
 		eventID := opts.Args[0]
-		transcriptData, err = fetcher.FetchForEvent(ctx, eventID)
+		transcriptData, err = source.FetchForEvent(ctx, eventID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch transcript for event %s: %w", eventID, err)
 		}
+	} else if local, ok := source.(*transcript.Local); ok {
+		// A local export is processed whenever the meeting was
+		transcriptData, err = local.Fetch(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcript %s: %w", local.Name, err)
+		}
 	} else {
 		// Default to last 24 hours, looking for 1:1 meetings (2 participants)
 		end := time.Now()
 		start := end.Add(-24 * time.Hour)
-		transcripts, err := fetcher.FetchForTimeRange(ctx, start, end)
+		transcripts, err := source.FetchForTimeRange(ctx, start, end)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch recent transcripts: %w", err)
 		}
 
-		// Filter for 1:1 meetings (exactly 2 speakers)
+		// Filter for 1:1 meetings (exactly 2 speakers) not yet summarized
 		var oneToOnes []*transcript.Transcript
 		for _, tr := range transcripts {
+			if _, done := ledger.Lookup(t.Name(), tr.EventID); done && !opts.Force {
+				continue
+			}
 			if len(tr.Speakers) == 2 {
 				oneToOnes = append(oneToOnes, tr)
 			}
@@ -112,13 +141,91 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		if len(oneToOnes) == 0 {
 			return &tasks.Result{
 				Success: true,
-				Message: "No 1:1 meeting transcripts found in the last 24 hours.",
+				Message: "No new 1:1 meeting transcripts found in the last 24 hours.",
 			}, nil
 		}
 		// Process the most recent 1:1
 		transcriptData = oneToOnes[len(oneToOnes)-1]
 	}
 
+	if done, ok := ledger.Lookup(t.Name(), transcriptData.EventID); ok && !opts.Force {
+		return &tasks.Result{
+			Success: true,
+			Message: fmt.Sprintf("Already summarized 1:1 %s on %s. Use --force to summarize it again.",
+				transcriptData.EventTitle, done.ProcessedAt.Format("2006-01-02 15:04")),
+			Metadata: map[string]interface{}{
+				"event_id":       transcriptData.EventID,
+				"interaction_id": done.Output,
+			},
+		}, nil
+	}
+
+	return t.summarize(lib, ledger, transcriptData, opts)
+}
+
+// runBacklog summarizes every 1:1 with a transcript between opts.Since and
+// opts.Until that has not been summarized yet.
+func (t *Task) runBacklog(ctx context.Context, source transcript.Source, lib *lmc.Library, ledger *transcript.Ledger, opts tasks.RunOptions) (*tasks.Result, error) {
+	if _, ok := source.(*transcript.Local); ok {
+		return nil, fmt.Errorf("--backlog reads transcripts from Google Calendar and cannot be used with --file or --stdin")
+	}
+
+	transcripts, err := source.FetchForTimeRange(ctx, opts.Since, opts.Until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transcripts: %w", err)
+	}
+
+	var messages, outputs []string
+	summarized, skipped, failed := 0, 0, 0
+	for _, tr := range transcripts {
+		if len(tr.Speakers) != 2 {
+			continue
+		}
+		if _, done := ledger.Lookup(t.Name(), tr.EventID); done && !opts.Force {
+			skipped++
+			continue
+		}
+		result, err := t.summarize(lib, ledger, tr, opts)
+		if err != nil {
+			fmt.Printf("Warning: failed to summarize %s: %v\n", tr.EventTitle, err)
+			failed++
+			continue
+		}
+		summarized++
+		messages = append(messages, result.Message)
+		if result.Output != "" {
+			outputs = append(outputs, result.Output)
+		}
+	}
+
+	verb := "Summarized"
+	if opts.DryRun {
+		verb = "Would summarize"
+	}
+	message := fmt.Sprintf("%s %d 1:1 meetings from %s to %s (%d already summarized)",
+		verb, summarized, opts.Since.Format("2006-01-02"), opts.Until.Format("2006-01-02"), skipped)
+	if failed > 0 {
+		message += fmt.Sprintf(", %d failed", failed)
+	}
+	if len(messages) > 0 {
+		message += ":\n  " + strings.Join(messages, "\n  ")
+	}
+
+	return &tasks.Result{
+		Success: failed == 0,
+		Output:  strings.Join(outputs, "\n\n"),
+		Message: message,
+		Metadata: map[string]interface{}{
+			"summarized": summarized,
+			"skipped":    skipped,
+			"failed":     failed,
+		},
+	}, nil
+}
+
+// summarize summarizes one 1:1 transcript, updates the person's profile and
+// open items, and records the meeting in the ledger.
+func (t *Task) summarize(lib *lmc.Library, ledger *transcript.Ledger, transcriptData *transcript.Transcript, opts tasks.RunOptions) (*tasks.Result, error) {
 	// Verify this is a 1:1 meeting
 	if len(transcriptData.Speakers) != 2 {
 		return &tasks.Result{
@@ -128,6 +235,11 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		}, nil
 	}
 
+	// Map speaker labels to the user and to people in the library
+	speakers := transcript.NewResolver(lib).Resolve(transcriptData)
+	other := t.identifyOtherPerson(speakers)
+	otherPerson := other.Name
+
 	if opts.DryRun {
 		return &tasks.Result{
 			Success: true,
@@ -136,19 +248,19 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 				transcriptData.EventTime.Format("2006-01-02 15:04"),
 				strings.Join(transcriptData.Speakers, " and ")),
 			Metadata: map[string]interface{}{
-				"event_id":    transcriptData.EventID,
-				"event_title": transcriptData.EventTitle,
-				"event_time":  transcriptData.EventTime,
-				"speakers":    transcriptData.Speakers,
+				"event_id":     transcriptData.EventID,
+				"event_title":  transcriptData.EventTitle,
+				"event_time":   transcriptData.EventTime,
+				"speakers":     speakers,
+				"other_person": otherPerson,
+				"person_id":    other.PersonID,
+				"source_file":  transcriptData.SourceFile,
 			},
 		}, nil
 	}
 
-	// Identify the other person (assumes current user is one of the speakers)
-	otherPerson := t.identifyOtherPerson(transcriptData.Speakers)
-
 	// Find or create person profile
-	profileID, err := t.findOrCreateProfile(lib, otherPerson)
+	profileID, err := t.findOrCreateProfile(lib, other)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find/create profile for %s: %w", otherPerson, err)
 	}
@@ -198,6 +310,17 @@ func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, e
 		}
 	}
 
+	if err := ledger.Record(transcript.Processed{
+		Task:       t.Name(),
+		EventID:    transcriptData.EventID,
+		EventTitle: transcriptData.EventTitle,
+		EventTime:  transcriptData.EventTime,
+		SourceFile: transcriptData.SourceFile,
+		Output:     interactionEntity.ID,
+	}); err != nil {
+		fmt.Printf("Warning: failed to record processed meeting: %v\n", err)
+	}
+
 	// Format output
 	output := t.formatOutput(summary, transcriptData, otherPerson, opts.Format)
 
@@ -241,25 +364,38 @@ type ActionItem struct {
 	Priority string
 }
 
-// identifyOtherPerson determines who the other person in the 1:1 is.
-// In a real implementation, this would check against the user's identity.
-func (t *Task) identifyOtherPerson(speakers []string) string {
-	// For now, return the first speaker that looks like a name
-	// In production, would compare against user's known names/email
+// identifyOtherPerson determines who the other person in the 1:1 is: the
+// first speaker who is not the user.
+func (t *Task) identifyOtherPerson(speakers []transcript.Speaker) transcript.Speaker {
 	if len(speakers) == 0 {
-		return "Unknown"
+		return transcript.Speaker{Name: "Unknown"}
+	}
+	for _, s := range speakers {
+		if s.Self {
+			// The user is known: the other person is whoever else spoke
+			for _, other := range speakers {
+				if !other.Self {
+					return other
+				}
+			}
+			break
+		}
 	}
 	if len(speakers) == 1 {
 		return speakers[0]
 	}
-	// Return the second speaker (assuming first is usually the user)
-	// This is a simplification - real implementation would check user identity
+	// No self identity matched: assume the first speaker is the user
 	return speakers[1]
 }
 
-// findOrCreateProfile finds an existing person profile or creates a new one.
-func (t *Task) findOrCreateProfile(lib *lmc.Library, personName string) (string, error) {
-	profileID := fmt.Sprintf("people/%s", sanitizeID(strings.ToLower(personName)))
+// findOrCreateProfile returns the person profile a speaker resolved to, or
+// finds or creates one by name.
+func (t *Task) findOrCreateProfile(lib *lmc.Library, person transcript.Speaker) (string, error) {
+	if person.PersonID != "" {
+		return person.PersonID, nil
+	}
+	personName := person.Name
+	profileID := lmc.PersonID(personName)
 
 	// Try to get existing profile
 	_, err := lib.Get(profileID)
@@ -273,8 +409,11 @@ func (t *Task) findOrCreateProfile(lib *lmc.Library, personName string) (string,
 		"created_at": time.Now().Format(time.RFC3339),
 		"source":     "oneonone-task",
 	}
+	if person.Email != "" {
+		content["email"] = person.Email
+	}
 
-	entity, err := lib.Store("people", sanitizeID(strings.ToLower(personName)), content, nil)
+	entity, err := lib.Store("people", strings.TrimPrefix(profileID, "people/"), content, nil)
 	if err != nil {
 		return "", err
 	}
@@ -284,7 +423,11 @@ func (t *Task) findOrCreateProfile(lib *lmc.Library, personName string) (string,
 
 // generateSummary extracts summary information from the transcript.
 func (t *Task) generateSummary(tr *transcript.Transcript, opts tasks.RunOptions) *Summary {
-	entries := transcript.ParseEntries(tr.Text)
+	// Entries parsed from WebVTT/SRT carry timings; fall back to the text
+	entries := tr.Entries
+	if len(entries) == 0 {
+		entries = transcript.ParseEntries(tr.Text)
+	}
 
 	summary := &Summary{
 		Topics:    t.extractTopics(tr.Text),
@@ -308,6 +451,12 @@ func (t *Task) generateSummary(tr *transcript.Transcript, opts tasks.RunOptions)
 	detailLevel := getPreference(opts, "summary_detail")
 	summary.Summary = t.generateTextSummary(tr, detailLevel)
 
+	// Gemini notes come with their own summary and details
+	if tr.Summary != "" {
+		summary.Summary = tr.Summary
+	}
+	summary.KeyPoints = append(append([]string(nil), tr.Details...), summary.KeyPoints...)
+
 	return summary
 }
 
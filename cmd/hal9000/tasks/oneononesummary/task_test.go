@@ -13,6 +13,8 @@ func TestTaskInterface(t *testing.T) {
 
 	// Verify interface compliance
 	var _ tasks.Task = task
+	var _ tasks.InputTask = task
+	var _ tasks.BacklogTask = task
 
 	if task.Name() != "oneonone" {
 		t.Errorf("Name() = %q, want %q", task.Name(), "oneonone")
@@ -56,22 +58,32 @@ func TestIdentifyOtherPerson(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		speakers []string
+		speakers []transcript.Speaker
 		expected string
 	}{
 		{
 			name:     "two speakers",
-			speakers: []string{"Alice", "Bob"},
+			speakers: []transcript.Speaker{{Name: "Alice"}, {Name: "Bob"}},
 			expected: "Bob",
 		},
+		{
+			name:     "self speaks second",
+			speakers: []transcript.Speaker{{Name: "Alice"}, {Name: "Bob", Self: true}},
+			expected: "Alice",
+		},
+		{
+			name:     "self speaks first",
+			speakers: []transcript.Speaker{{Name: "Bob", Self: true}, {Name: "Alice"}},
+			expected: "Alice",
+		},
 		{
 			name:     "single speaker",
-			speakers: []string{"Alice"},
+			speakers: []transcript.Speaker{{Name: "Alice"}},
 			expected: "Alice",
 		},
 		{
 			name:     "no speakers",
-			speakers: []string{},
+			speakers: []transcript.Speaker{},
 			expected: "Unknown",
 		},
 	}
@@ -79,8 +91,8 @@ func TestIdentifyOtherPerson(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := task.identifyOtherPerson(tt.speakers)
-			if result != tt.expected {
-				t.Errorf("identifyOtherPerson() = %q, want %q", result, tt.expected)
+			if result.Name != tt.expected {
+				t.Errorf("identifyOtherPerson() = %q, want %q", result.Name, tt.expected)
 			}
 		})
 	}
@@ -0,0 +1,81 @@
+// Package retention implements the library retention task for HAL 9000.
+// "It's going to go 100% failure in 72 hours."
+//
+// This task applies the retention rules from .hal9000/config.yaml, so old
+// raw captures can be archived on a schedule:
+//
+//	hal9000 scheduler set retention "0 3 * * *"
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pearcec/hal9000/cmd/hal9000/tasks"
+	libretention "github.com/pearcec/hal9000/discovery/retention"
+	"github.com/pearcec/hal9000/internal/config"
+)
+
+func init() {
+	tasks.Register(&Task{})
+}
+
+// Task implements the library retention task.
+type Task struct{}
+
+// Name returns the task identifier.
+func (t *Task) Name() string {
+	return "retention"
+}
+
+// Description returns human-readable description.
+func (t *Task) Description() string {
+	return "Archive old library documents according to retention rules"
+}
+
+// PreferencesKey returns the preferences file name (without .md extension).
+func (t *Task) PreferencesKey() string {
+	return ""
+}
+
+// SetupQuestions returns questions for first-run setup.
+func (t *Task) SetupQuestions() []tasks.SetupQuestion {
+	return nil
+}
+
+// Run applies the retention rules through retention.Cleaner, the adapter
+// an eye.Eye registers for periodic cleanup.
+func (t *Task) Run(ctx context.Context, opts tasks.RunOptions) (*tasks.Result, error) {
+	rules, err := libretention.Rules()
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return &tasks.Result{
+			Success: true,
+			Message: "No retention rules configured (library.retention in .hal9000/config.yaml).",
+		}, nil
+	}
+	libPath := config.GetLibraryPath()
+
+	if opts.DryRun {
+		report, err := libretention.Apply(libPath, rules, libretention.Options{DryRun: true})
+		if err != nil {
+			return nil, err
+		}
+		return &tasks.Result{
+			Success: true,
+			Message: fmt.Sprintf("Would archive %d documents, reclaiming %d bytes.", report.Files, report.Reclaimed),
+		}, nil
+	}
+
+	result := libretention.Cleaner(libPath, rules)(ctx, time.Now())
+	if result.Error != "" {
+		return nil, fmt.Errorf("retention failed after %d documents: %s", result.ItemsCleaned, result.Error)
+	}
+	return &tasks.Result{
+		Success: true,
+		Message: fmt.Sprintf("Archived %d documents, reclaimed %d bytes.", result.ItemsCleaned, result.BytesFreed),
+	}, nil
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/pearcec/hal9000/discovery/history"
 	"github.com/spf13/cobra"
 )
 
@@ -28,6 +31,10 @@ func NewRunner(task Task) *Runner {
 func (r *Runner) Execute(ctx context.Context) (*Result, error) {
 	// Check if setup is needed
 	if NeedsSetup(r.task) {
+		// Setup reads its answers from stdin, which would swallow the input
+		if r.opts.Input == "-" {
+			return nil, fmt.Errorf("%s is not set up yet; run `hal9000 %s setup` before using --stdin", r.task.Name(), r.task.Name())
+		}
 		result, err := RunSetup(r.task)
 		if err != nil {
 			return nil, fmt.Errorf("setup failed: %w", err)
@@ -66,6 +73,30 @@ func CreateCommand(task Task) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&output, "output", "", "Override output location")
 	cmd.PersistentFlags().StringVar(&format, "format", "markdown", "Output format (markdown, json, text)")
 
+	// Tasks that can read local input
+	var inputFile string
+	var stdin bool
+	if input, ok := task.(InputTask); ok {
+		cmd.PersistentFlags().StringVar(&inputFile, "file", "", "Read the "+input.InputDescription()+" from a file")
+		cmd.PersistentFlags().BoolVar(&stdin, "stdin", false, "Read the "+input.InputDescription()+" from stdin")
+		cmd.MarkFlagsMutuallyExclusive("file", "stdin")
+	}
+
+	// Tasks that can catch up on what they missed
+	var backlog, force bool
+	var since, until string
+	if b, ok := task.(BacklogTask); ok {
+		what := b.BacklogDescription()
+		cmd.PersistentFlags().BoolVar(&backlog, "backlog", false, "Process every unprocessed "+what+" between --since and --until")
+		cmd.PersistentFlags().StringVar(&since, "since", "", "Start of the backlog (YYYY-MM-DD or RFC3339; default 7 days ago)")
+		cmd.PersistentFlags().StringVar(&until, "until", "", "End of the backlog (YYYY-MM-DD or RFC3339; default now)")
+		cmd.PersistentFlags().BoolVar(&force, "force", false, "Process a "+what+" again even if it was already processed")
+		if _, ok := task.(InputTask); ok {
+			cmd.MarkFlagsMutuallyExclusive("backlog", "file")
+			cmd.MarkFlagsMutuallyExclusive("backlog", "stdin")
+		}
+	}
+
 	// Default run command (when no subcommand specified)
 	runCmd := &cobra.Command{
 		Use:   "run",
@@ -77,6 +108,20 @@ func CreateCommand(task Task) *cobra.Command {
 			runner.opts.Output = output
 			runner.opts.Format = format
 			runner.opts.Args = args
+			runner.opts.Input = inputFile
+			if stdin {
+				runner.opts.Input = "-"
+			}
+			runner.opts.Backlog = backlog
+			runner.opts.Force = force
+			if backlog {
+				var err error
+				if runner.opts.Since, runner.opts.Until, err = backlogRange(since, until, time.Now()); err != nil {
+					return err
+				}
+			} else if since != "" || until != "" {
+				return fmt.Errorf("--since and --until need --backlog")
+			}
 
 			result, err := runner.Execute(cmd.Context())
 			if err != nil {
@@ -84,6 +129,13 @@ func CreateCommand(task Task) *cobra.Command {
 				return err
 			}
 
+			if !dryRun && result.OutputPath != "" {
+				message := fmt.Sprintf("%s: write %s", task.Name(), filepath.Base(result.OutputPath))
+				if err := history.Record(message, result.OutputPath); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: history commit failed: %v\n", err)
+				}
+			}
+
 			if result.Message != "" {
 				fmt.Println(result.Message)
 			}
@@ -151,3 +203,44 @@ func RegisterCommands(root *cobra.Command) {
 		root.AddCommand(CreateCommand(task))
 	}
 }
+
+// backlogRange parses --since and --until. A date means the start of that
+// day for --since and its end for --until.
+func backlogRange(since, until string, now time.Time) (time.Time, time.Time, error) {
+	start := now.AddDate(0, 0, -7)
+	end := now
+	if since != "" {
+		t, _, err := parseBacklogTime(since)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --since: %w", err)
+		}
+		start = t
+	}
+	if until != "" {
+		t, dateOnly, err := parseBacklogTime(until)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --until: %w", err)
+		}
+		end = t
+		if dateOnly {
+			end = t.AddDate(0, 0, 1)
+		}
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--until must be after --since")
+	}
+	return start, end, nil
+}
+
+// parseBacklogTime parses YYYY-MM-DD (local time) or RFC3339, reporting
+// whether it was a date.
+func parseBacklogTime(s string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected YYYY-MM-DD or RFC3339, got %q", s)
+	}
+	return t, false, nil
+}
@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 )
 
@@ -288,9 +290,12 @@ func SavePreferencesTo(task Task, result *SetupResult, dir string) error {
 	}
 
 	// Write file
-	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+	if err := vault.WriteFile(path, []byte(sb.String()), 0644); err != nil {
 		return fmt.Errorf("failed to write preferences file: %w", err)
 	}
+	if err := history.Record("preferences: set up "+task.PreferencesKey(), path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history commit failed: %v\n", err)
+	}
 
 	fmt.Printf("\nI've saved your preferences to the Library. You can update them anytime\n")
 	fmt.Printf("by running `hal9000 %s setup`.\n\n", task.Name())
@@ -6,6 +6,7 @@ package tasks
 
 import (
 	"context"
+	"time"
 )
 
 // Task defines the interface all HAL tasks must implement.
@@ -26,6 +27,28 @@ type Task interface {
 	Run(ctx context.Context, opts RunOptions) (*Result, error)
 }
 
+// InputTask is implemented by tasks that can read their input from a local
+// file or stdin instead of fetching it. Their commands get --file and
+// --stdin flags, passed to Run as RunOptions.Input.
+type InputTask interface {
+	Task
+
+	// InputDescription describes the input file, for flag help
+	// (e.g., "transcript export (.vtt, .srt, .txt)")
+	InputDescription() string
+}
+
+// BacklogTask is implemented by tasks that remember what they have
+// processed and can catch up on everything they missed. Their commands get
+// --backlog, --since, --until and --force flags.
+type BacklogTask interface {
+	Task
+
+	// BacklogDescription describes what --backlog processes, for flag help
+	// (e.g., "meeting with a transcript")
+	BacklogDescription() string
+}
+
 // SetupQuestion defines a question to ask during first-run setup.
 type SetupQuestion struct {
 	// Key is the preference key to set
@@ -81,6 +104,18 @@ type RunOptions struct {
 	// Args contains any additional arguments
 	Args []string
 
+	// Input is a local input file for an InputTask; "-" means stdin
+	Input string
+
+	// Backlog asks a BacklogTask to process everything unprocessed between
+	// Since and Until
+	Backlog bool
+	Since   time.Time
+	Until   time.Time
+
+	// Force reprocesses items a BacklogTask has already processed
+	Force bool
+
 	// Overrides contains one-time preference overrides
 	Overrides map[string]string
 }
@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 // mockTask implements Task for testing.
@@ -120,3 +121,136 @@ func TestQuestionTypes(t *testing.T) {
 		}
 	}
 }
+
+// mockInputTask is a mockTask that reads local input.
+type mockInputTask struct {
+	mockTask
+}
+
+func (m *mockInputTask) InputDescription() string { return "test file" }
+
+// mockBacklogTask is a mockInputTask that can catch up on a backlog.
+type mockBacklogTask struct {
+	mockInputTask
+}
+
+func (m *mockBacklogTask) BacklogDescription() string { return "test item" }
+
+func TestCreateCommandInputFlags(t *testing.T) {
+	if CreateCommand(&mockTask{name: "plain"}).PersistentFlags().Lookup("file") != nil {
+		t.Error("--file added to a task without local input")
+	}
+
+	var got RunOptions
+	task := &mockInputTask{mockTask{name: "input", runFunc: func(ctx context.Context, opts RunOptions) (*Result, error) {
+		got = opts
+		return &Result{Success: true}, nil
+	}}}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--file", "meeting.vtt"}, "meeting.vtt"},
+		{[]string{"--stdin"}, "-"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got = RunOptions{}
+		cmd := CreateCommand(task)
+		cmd.SetArgs(tt.args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute(%v) error = %v", tt.args, err)
+		}
+		if got.Input != tt.want {
+			t.Errorf("Execute(%v) Input = %q, want %q", tt.args, got.Input, tt.want)
+		}
+	}
+
+	cmd := CreateCommand(task)
+	cmd.SetArgs([]string{"--file", "meeting.vtt", "--stdin"})
+	cmd.SilenceErrors, cmd.SilenceUsage = true, true
+	if err := cmd.Execute(); err == nil {
+		t.Error("Execute with --file and --stdin succeeded")
+	}
+}
+
+func TestStdinRefusedBeforeSetup(t *testing.T) {
+	ran := false
+	task := &mockInputTask{mockTask{
+		name:           "stdin-setup",
+		preferencesKey: "stdin-setup-test",
+		setupQuestions: []SetupQuestion{{Key: "key1", Question: "Question 1?", Type: QuestionText}},
+		runFunc: func(ctx context.Context, opts RunOptions) (*Result, error) {
+			ran = true
+			return &Result{Success: true}, nil
+		},
+	}}
+	if !NeedsSetup(task) {
+		t.Skip("preferences for the test task already exist")
+	}
+
+	_, err := NewRunner(task).WithOptions(RunOptions{Input: "-"}).Execute(context.Background())
+	if err == nil {
+		t.Fatal("Execute() with stdin input and pending setup succeeded")
+	}
+	if ran {
+		t.Error("task ran before setup")
+	}
+}
+
+func TestCreateCommandBacklogFlags(t *testing.T) {
+	if CreateCommand(&mockInputTask{mockTask{name: "input"}}).PersistentFlags().Lookup("backlog") != nil {
+		t.Error("--backlog added to a task without a backlog")
+	}
+
+	var got RunOptions
+	task := &mockBacklogTask{mockInputTask{mockTask{name: "backlog", runFunc: func(ctx context.Context, opts RunOptions) (*Result, error) {
+		got = opts
+		return &Result{Success: true}, nil
+	}}}}
+
+	cmd := CreateCommand(task)
+	cmd.SetArgs([]string{"--backlog", "--since", "2026-10-01", "--until", "2026-10-07", "--force"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	wantSince := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	wantUntil := time.Date(2026, 10, 8, 0, 0, 0, 0, time.Local)
+	if !got.Backlog || !got.Force || !got.Since.Equal(wantSince) || !got.Until.Equal(wantUntil) {
+		t.Errorf("RunOptions = %+v, want backlog from %v to %v", got, wantSince, wantUntil)
+	}
+
+	for _, args := range [][]string{
+		{"--since", "2026-10-01"},
+		{"--backlog", "--file", "meeting.vtt"},
+		{"--backlog", "--since", "October"},
+		{"--backlog", "--since", "2026-10-07", "--until", "2026-10-01"},
+	} {
+		cmd := CreateCommand(task)
+		cmd.SetArgs(args)
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("Execute(%v) succeeded", args)
+		}
+	}
+}
+
+func TestBacklogRange(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	since, until, err := backlogRange("", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !since.Equal(now.AddDate(0, 0, -7)) || !until.Equal(now) {
+		t.Errorf("backlogRange() = %v, %v; want the last 7 days", since, until)
+	}
+
+	since, until, err = backlogRange("2026-10-01T08:00:00Z", "2026-10-02T08:00:00Z", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since.Hour() != 8 || until.Sub(since) != 24*time.Hour {
+		t.Errorf("backlogRange(RFC3339) = %v, %v", since, until)
+	}
+}
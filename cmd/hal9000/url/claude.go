@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 )
 
@@ -370,9 +371,10 @@ func saveToLibraryPath(url string, content *FetchResult, output string) (string,
 	if existingFile != "" {
 		// Update existing file
 		fmt.Printf("Updating existing entry: %s\n", filepath.Base(existingFile))
-		if err := os.WriteFile(existingFile, []byte(output), 0644); err != nil {
+		if err := vault.WriteFile(existingFile, []byte(output), 0644); err != nil {
 			return "", err
 		}
+		recordSave(existingFile)
 		return existingFile, nil
 	}
 
@@ -393,9 +395,10 @@ func saveToLibraryPath(url string, content *FetchResult, output string) (string,
 		}
 	}
 
-	if err := os.WriteFile(fullPath, []byte(output), 0644); err != nil {
+	if err := vault.WriteFile(fullPath, []byte(output), 0644); err != nil {
 		return "", err
 	}
+	recordSave(fullPath)
 
 	return fullPath, nil
 }
@@ -413,7 +416,7 @@ func findExistingURLInPath(libPath, targetURL string) string {
 		}
 
 		filePath := filepath.Join(libPath, entry.Name())
-		content, err := os.ReadFile(filePath)
+		content, err := vault.ReadFile(filePath)
 		if err != nil {
 			continue
 		}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -133,7 +134,7 @@ func searchLibrary(libPath, term string, tagFilter []string, sinceDate time.Time
 		}
 
 		// Read file
-		content, err := os.ReadFile(path)
+		content, err := vault.ReadFile(path)
 		if err != nil {
 			return nil
 		}
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 	"github.com/pearcec/hal9000/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -178,7 +180,7 @@ func runURL(cmd *cobra.Command, args []string) error {
 
 func loadPreferences() (*URLPreferences, error) {
 	prefsPath := getPreferencesPath()
-	content, err := os.ReadFile(prefsPath)
+	content, err := vault.ReadFile(prefsPath)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +191,7 @@ func loadPreferences() (*URLPreferences, error) {
 // loadRawPreferences returns the preferences file as raw text for Claude to interpret
 func loadRawPreferences() string {
 	prefsPath := getPreferencesPath()
-	content, err := os.ReadFile(prefsPath)
+	content, err := vault.ReadFile(prefsPath)
 	if err != nil {
 		return ""
 	}
@@ -278,9 +280,10 @@ func saveToLibrary(url string, content *FetchResult, output string) (string, err
 	if existingFile != "" {
 		// Update existing file
 		fmt.Printf("Updating existing entry: %s\n", filepath.Base(existingFile))
-		if err := os.WriteFile(existingFile, []byte(output), 0644); err != nil {
+		if err := vault.WriteFile(existingFile, []byte(output), 0644); err != nil {
 			return "", err
 		}
+		recordSave(existingFile)
 		return existingFile, nil
 	}
 
@@ -302,13 +305,21 @@ func saveToLibrary(url string, content *FetchResult, output string) (string, err
 		}
 	}
 
-	if err := os.WriteFile(fullPath, []byte(output), 0644); err != nil {
+	if err := vault.WriteFile(fullPath, []byte(output), 0644); err != nil {
 		return "", err
 	}
+	recordSave(fullPath)
 
 	return fullPath, nil
 }
 
+// recordSave commits a saved URL note to the library history.
+func recordSave(path string) {
+	if err := history.Record("url: save "+filepath.Base(path), path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history commit failed: %v\n", err)
+	}
+}
+
 // findExistingURL searches the library for an existing entry with the same URL
 func findExistingURL(libPath, targetURL string) string {
 	entries, err := os.ReadDir(libPath)
@@ -322,7 +333,7 @@ func findExistingURL(libPath, targetURL string) string {
 		}
 
 		filePath := filepath.Join(libPath, entry.Name())
-		content, err := os.ReadFile(filePath)
+		content, err := vault.ReadFile(filePath)
 		if err != nil {
 			continue
 		}
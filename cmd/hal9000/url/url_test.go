@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 func TestGenerateDescriptor(t *testing.T) {
@@ -246,6 +248,46 @@ This is an article about Golang programming language.
 	}
 }
 
+func TestSaveToLibraryEncrypted(t *testing.T) {
+	key, _, err := vault.NewKeyFileKey(filepath.Join(t.TempDir(), "hal.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	vault.SetDefault(key)
+	defer vault.Reset()
+
+	libraryPath = t.TempDir()
+	defer func() { libraryPath = "" }()
+
+	target := "https://example.com/pod-bay"
+	output := "# Pod Bay Doors\n\n**URL:** " + target + "\n**Date:** 2024-01-15\n\nOpen the pod bay doors.\n"
+	path, err := saveToLibrary(target, &FetchResult{Title: "Pod Bay Doors"}, output)
+	if err != nil {
+		t.Fatalf("saveToLibrary failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vault.IsSealed(raw) {
+		t.Errorf("URL note written in plaintext: %q", raw)
+	}
+	got, err := vault.ReadFile(path)
+	if err != nil || string(got) != output {
+		t.Errorf("ReadFile = %q, %v; want %q", got, err, output)
+	}
+
+	// Readers see the note through the vault
+	if existing := findExistingURL(getLibraryPath(), target); existing != path {
+		t.Errorf("findExistingURL = %q, want %q", existing, path)
+	}
+	results, err := searchLibrary(getLibraryPath(), "pod bay", nil, time.Time{})
+	if err != nil || len(results) != 1 {
+		t.Errorf("searchLibrary = %+v, %v; want the saved note", results, err)
+	}
+}
+
 func TestParseURLFile(t *testing.T) {
 	content := `# My Test Article
 
@@ -0,0 +1,234 @@
+// Package auth keeps the OAuth tokens HAL's Google clients share.
+// "Just what do you think you're doing, Dave?"
+//
+// Tokens are obtained once with "hal9000 auth login google" and stored in
+// the credentials directory. Clients get them through a TokenSource that
+// writes refreshed access tokens back, so the stored token stays current
+// and every process sees the latest one.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/vault"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/drive/v3"
+)
+
+// Google is the provider name of Google Calendar and Drive.
+const Google = "google"
+
+// Providers lists the providers "hal9000 auth" can log in to.
+var Providers = []string{Google}
+
+const (
+	// googleCredentialsFile holds the OAuth client downloaded from the
+	// Google Cloud console.
+	googleCredentialsFile = "calendar-credentials.json"
+
+	// googleTokenFile holds the token. The name dates from when only the
+	// calendar watcher used it.
+	googleTokenFile = "calendar-token.json"
+)
+
+// GoogleScopes are the scopes every Google client in HAL needs, so one
+// token serves them all.
+var GoogleScopes = []string{
+	calendar.CalendarReadonlyScope,
+	drive.DriveReadonlyScope,
+}
+
+// ErrNotLoggedIn is returned when no token has been stored for a provider.
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// CredentialsPath returns the path of a provider's OAuth client file.
+func CredentialsPath(provider string) string {
+	return filepath.Join(config.GetCredentialsDir(), googleCredentialsFile)
+}
+
+// TokenPath returns the path of a provider's stored token.
+func TokenPath(provider string) string {
+	return filepath.Join(config.GetCredentialsDir(), googleTokenFile)
+}
+
+// CheckProvider returns an error for providers HAL cannot log in to.
+func CheckProvider(provider string) error {
+	for _, p := range Providers {
+		if p == provider {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown provider %q (available: %v)", provider, Providers)
+}
+
+// GoogleConfig loads the Google OAuth client from the credentials file.
+func GoogleConfig() (*oauth2.Config, error) {
+	data, err := vault.ReadFile(CredentialsPath(Google))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read credentials file: %w", err)
+	}
+	cfg, err := google.ConfigFromJSON(data, GoogleScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse credentials: %w", err)
+	}
+	return cfg, nil
+}
+
+// GoogleClient returns an HTTP client authorized with the stored Google
+// token, refreshing and saving it as needed.
+func GoogleClient(ctx context.Context) (*http.Client, error) {
+	cfg, err := GoogleConfig()
+	if err != nil {
+		return nil, err
+	}
+	ts, err := TokenSource(ctx, cfg, TokenPath(Google))
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, ts), nil
+}
+
+// TokenSource returns a token source for the token stored at path. Tokens
+// it refreshes are written back to path.
+func TokenSource(ctx context.Context, cfg *oauth2.Config, path string) (oauth2.TokenSource, error) {
+	tok, err := LoadToken(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w to Google: run \"hal9000 auth login google\"", ErrNotLoggedIn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &persistingSource{
+		source: cfg.TokenSource(ctx, tok),
+		path:   path,
+		saved:  tok.AccessToken,
+	}, nil
+}
+
+// persistingSource saves each new token its source hands out.
+type persistingSource struct {
+	source oauth2.TokenSource
+	path   string
+
+	mu    sync.Mutex
+	saved string // Access token last written to path
+}
+
+func (s *persistingSource) Token() (*oauth2.Token, error) {
+	tok, err := s.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("token refresh failed (run \"hal9000 auth login google\" if it was revoked): %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok.AccessToken != s.saved {
+		if err := SaveToken(s.path, tok); err != nil {
+			// The token still works for this process
+			log.Printf("[auth] Unable to save refreshed token: %v", err)
+		} else {
+			s.saved = tok.AccessToken
+		}
+	}
+	return tok, nil
+}
+
+// LoadToken reads a stored token.
+func LoadToken(path string) (*oauth2.Token, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tok := &oauth2.Token{}
+	if err := json.Unmarshal(data, tok); err != nil {
+		return nil, fmt.Errorf("invalid token file %s: %w", path, err)
+	}
+	return tok, nil
+}
+
+// SaveToken stores a token, readable only by the user.
+func SaveToken(path string, tok *oauth2.Token) error {
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return vault.WriteFile(path, data, 0600)
+}
+
+// Status describes a stored token.
+type Status struct {
+	Provider        string
+	Path            string
+	LoggedIn        bool      // A token is stored
+	Expiry          time.Time // When the access token expires; zero if never
+	HasRefreshToken bool      // The access token can be renewed without logging in
+}
+
+// Valid reports whether the token can be used now or renewed.
+func (s Status) Valid(now time.Time) bool {
+	return s.LoggedIn && (s.HasRefreshToken || s.Expiry.IsZero() || now.Before(s.Expiry))
+}
+
+// Describe summarizes the token for people, e.g. "expires in 42m,
+// refreshes automatically".
+func (s Status) Describe(now time.Time) string {
+	switch {
+	case !s.LoggedIn:
+		return "not logged in"
+	case s.Expiry.IsZero():
+		return "access token does not expire"
+	}
+
+	expiry := "expires in " + approxDuration(s.Expiry.Sub(now))
+	if !now.Before(s.Expiry) {
+		expiry = "access token expired " + s.Expiry.Local().Format("2006-01-02 15:04")
+	}
+	if s.HasRefreshToken {
+		return expiry + ", refreshes automatically"
+	}
+	return expiry + ", no refresh token: log in again when it expires"
+}
+
+// approxDuration formats d to the minute, e.g. "1h5m".
+func approxDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// TokenStatus reports on a provider's stored token.
+func TokenStatus(provider string) (Status, error) {
+	return tokenStatus(provider, TokenPath(provider))
+}
+
+func tokenStatus(provider, path string) (Status, error) {
+	st := Status{Provider: provider, Path: path}
+	tok, err := LoadToken(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	st.LoggedIn = true
+	st.Expiry = tok.Expiry
+	st.HasRefreshToken = tok.RefreshToken != ""
+	return st, nil
+}
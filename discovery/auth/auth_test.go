@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenServer issues access tokens "access-1", "access-2", ... for every
+// request to its token endpoint.
+func tokenServer(t *testing.T, check func(r *http.Request)) (*oauth2.Config, *int) {
+	t.Helper()
+	issued := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if check != nil {
+			check(r)
+		}
+		issued++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access-%d","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh"}`, issued)
+	}))
+	t.Cleanup(srv.Close)
+	return &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"},
+	}, &issued
+}
+
+func TestSaveLoadToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials", "token.json")
+	want := &oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC)}
+	if err := SaveToken(path, want); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("token mode = %v, want 0600", info.Mode().Perm())
+	}
+	got, err := LoadToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != "a" || got.RefreshToken != "r" || !got.Expiry.Equal(want.Expiry) {
+		t.Errorf("LoadToken() = %+v", got)
+	}
+}
+
+func TestTokenSourceNotLoggedIn(t *testing.T) {
+	cfg, _ := tokenServer(t, nil)
+	_, err := TokenSource(context.Background(), cfg, filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("TokenSource() error = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestTokenSourcePersistsRefresh(t *testing.T) {
+	cfg, issued := tokenServer(t, func(r *http.Request) {
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh" {
+			t.Errorf("unexpected token request %v", r.Form)
+		}
+	})
+	path := filepath.Join(t.TempDir(), "token.json")
+	expired := &oauth2.Token{AccessToken: "stale", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	if err := SaveToken(path, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := TokenSource(context.Background(), cfg, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != "access-1" {
+			t.Errorf("Token() = %q, want access-1", tok.AccessToken)
+		}
+	}
+	if *issued != 1 {
+		t.Errorf("refreshed %d times, want 1", *issued)
+	}
+
+	saved, err := LoadToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "access-1" || saved.RefreshToken != "refresh" || !saved.Expiry.After(time.Now()) {
+		t.Errorf("saved token = %+v, want the refreshed one", saved)
+	}
+}
+
+func TestTokenStatus(t *testing.T) {
+	now := time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	st, err := tokenStatus(Google, filepath.Join(dir, "missing.json"))
+	if err != nil || st.LoggedIn || st.Valid(now) || st.Describe(now) != "not logged in" {
+		t.Errorf("missing token status = %+v, %v", st, err)
+	}
+
+	tests := []struct {
+		name  string
+		tok   oauth2.Token
+		valid bool
+		want  string
+	}{
+		{"refreshable", oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: now.Add(65 * time.Minute)}, true,
+			"expires in 1h5m, refreshes automatically"},
+		{"expired refreshable", oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: now.Add(-time.Hour)}, true,
+			"access token expired " + now.Add(-time.Hour).Local().Format("2006-01-02 15:04") + ", refreshes automatically"},
+		{"expired", oauth2.Token{AccessToken: "a", Expiry: now.Add(-time.Hour)}, false,
+			"access token expired " + now.Add(-time.Hour).Local().Format("2006-01-02 15:04") + ", no refresh token: log in again when it expires"},
+		{"soon", oauth2.Token{AccessToken: "a", Expiry: now.Add(30 * time.Second)}, true,
+			"expires in less than a minute, no refresh token: log in again when it expires"},
+		{"no expiry", oauth2.Token{AccessToken: "a"}, true, "access token does not expire"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := SaveToken(path, &tt.tok); err != nil {
+				t.Fatal(err)
+			}
+			st, err := tokenStatus(Google, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !st.LoggedIn || st.Valid(now) != tt.valid {
+				t.Errorf("status = %+v, valid = %v, want %v", st, st.Valid(now), tt.valid)
+			}
+			if got := st.Describe(now); got != tt.want {
+				t.Errorf("Describe() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckProvider(t *testing.T) {
+	if err := CheckProvider(Google); err != nil {
+		t.Errorf("CheckProvider(google) = %v", err)
+	}
+	if err := CheckProvider("jira"); err == nil {
+		t.Error("CheckProvider(jira) succeeded")
+	}
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// CallbackAddr is where the login flow listens for Google's redirect. The
+// redirect URL must be allowed by the OAuth client; desktop clients allow
+// any localhost port.
+const CallbackAddr = "localhost:8089"
+
+// callbackPath is the path of the redirect URL.
+const callbackPath = "/callback"
+
+// RedirectURL is the redirect URL the login flow registers.
+func RedirectURL() string {
+	return "http://" + CallbackAddr + callbackPath
+}
+
+// Flow is one authorization code exchange. It guards against forged
+// redirects with a random state and against intercepted codes with PKCE.
+//
+// On a machine with a browser the redirect is caught by Wait. Headless,
+// the browser on another machine fails to load the redirect, and the user
+// pastes its URL (or just the code) into Exchange.
+type Flow struct {
+	config   *oauth2.Config
+	state    string
+	verifier string
+}
+
+// NewFlow starts an authorization for cfg, redirecting to RedirectURL.
+func NewFlow(cfg *oauth2.Config) (*Flow, error) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return nil, err
+	}
+	c := *cfg
+	c.RedirectURL = RedirectURL()
+	return &Flow{
+		config:   &c,
+		state:    hex.EncodeToString(state),
+		verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+// AuthURL returns the URL the user opens to grant access. It asks for
+// offline access with consent so Google always issues a refresh token.
+func (f *Flow) AuthURL() string {
+	return f.config.AuthCodeURL(f.state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(f.verifier))
+}
+
+// Wait serves the redirect on ln until it arrives or ctx is done, and
+// returns the redirect's query for Exchange.
+func (f *Flow) Wait(ctx context.Context, ln net.Listener) (string, error) {
+	result := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != f.state {
+			http.Error(w, "Unexpected authorization state.", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Authorization received. You may close this window.")
+		select {
+		case result <- "?" + r.URL.RawQuery:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(ln)
+	defer server.Close()
+
+	select {
+	case query := <-result:
+		return query, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	}
+}
+
+// Exchange trades the authorization for a token. input is the redirect
+// URL (or its query) the browser was sent to, or the bare code.
+func (f *Flow) Exchange(ctx context.Context, input string) (*oauth2.Token, error) {
+	code, err := f.code(strings.TrimSpace(input))
+	if err != nil {
+		return nil, err
+	}
+	tok, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(f.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("unable to exchange code for token: %w", err)
+	}
+	return tok, nil
+}
+
+// code extracts the authorization code from what the user pasted.
+func (f *Flow) code(input string) (string, error) {
+	if input == "" {
+		return "", errors.New("no authorization code given")
+	}
+	if !strings.Contains(input, "code=") && !strings.Contains(input, "error=") {
+		return input, nil
+	}
+
+	query := input
+	if i := strings.Index(input, "?"); i >= 0 {
+		query = input[i+1:]
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", fmt.Errorf("unable to read redirect URL: %w", err)
+	}
+	if e := values.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization denied: %s", e)
+	}
+	if values.Get("state") != f.state {
+		return "", errors.New("redirect URL is from a different login attempt")
+	}
+	code := values.Get("code")
+	if code == "" {
+		return "", errors.New("redirect URL has no authorization code")
+	}
+	return code, nil
+}
+
+// revokeURL is Google's token revocation endpoint.
+var revokeURL = "https://oauth2.googleapis.com/revoke"
+
+// Revoke revokes a provider's token with the provider and deletes it. A
+// token the provider no longer recognizes is deleted without complaint.
+func Revoke(ctx context.Context, provider string) error {
+	return revokeToken(ctx, TokenPath(provider))
+}
+
+func revokeToken(ctx context.Context, path string) error {
+	tok, err := LoadToken(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotLoggedIn
+	}
+	if err != nil {
+		return err
+	}
+
+	// Revoking the refresh token also revokes the access tokens it issued
+	token := tok.RefreshToken
+	if token == "" {
+		token = tok.AccessToken
+	}
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to revoke token: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("unable to revoke token: %s", resp.Status)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestFlowAuthURL(t *testing.T) {
+	cfg, _ := tokenServer(t, nil)
+	flow, err := NewFlow(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(flow.AuthURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{
+		"redirect_uri":          RedirectURL(),
+		"access_type":           "offline",
+		"prompt":                "consent",
+		"state":                 flow.state,
+		"code_challenge_method": "S256",
+	} {
+		if q.Get(key) != want {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), want)
+		}
+	}
+	if q.Get("code_challenge") == "" {
+		t.Error("AuthURL() has no PKCE challenge")
+	}
+	if cfg.RedirectURL != "" {
+		t.Error("NewFlow() modified the caller's config")
+	}
+}
+
+func TestFlowCode(t *testing.T) {
+	flow := &Flow{state: "s1"}
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{"4/0AbCd", "4/0AbCd", false},
+		{"http://localhost:8089/callback?state=s1&code=4%2F0AbCd&scope=x", "4/0AbCd", false},
+		{"?state=s1&code=abc", "abc", false},
+		{"http://localhost:8089/callback?state=other&code=abc", "", true},
+		{"http://localhost:8089/callback?error=access_denied&state=s1", "", true},
+		{"http://localhost:8089/callback?state=s1&code=", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := flow.code(tt.input)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("code(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFlowWaitAndExchange(t *testing.T) {
+	var verifier string
+	cfg, _ := tokenServer(t, func(r *http.Request) {
+		if r.Form.Get("code") != "abc" {
+			t.Errorf("code = %q, want abc", r.Form.Get("code"))
+		}
+		verifier = r.Form.Get("code_verifier")
+	})
+	flow, err := NewFlow(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan string, 1)
+	go func() {
+		query, err := flow.Wait(ctx, ln)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- query
+	}()
+
+	base := "http://" + ln.Addr().String() + callbackPath
+	resp, err := http.Get(base + "?state=forged&code=evil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("forged callback status = %d, want 400", resp.StatusCode)
+	}
+	resp, err = http.Get(base + "?state=" + flow.state + "&code=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "Authorization received") {
+		t.Errorf("callback body = %q", body)
+	}
+
+	tok, err := flow.Exchange(ctx, <-got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "access-1" || tok.RefreshToken != "refresh" {
+		t.Errorf("Exchange() = %+v", tok)
+	}
+	if verifier != flow.verifier {
+		t.Errorf("code_verifier = %q, want the flow's verifier", verifier)
+	}
+}
+
+func TestFlowWaitCancelled(t *testing.T) {
+	flow := &Flow{state: "s1"}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := flow.Wait(ctx, ln); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	status := http.StatusOK
+	var revoked string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		revoked = r.Form.Get("token")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	old := revokeURL
+	revokeURL = srv.URL
+	defer func() { revokeURL = old }()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := revokeToken(ctx, path); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("revoke without token = %v, want ErrNotLoggedIn", err)
+	}
+
+	save := func() {
+		if err := SaveToken(path, &oauth2.Token{AccessToken: "a", RefreshToken: "r"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	save()
+	status = http.StatusInternalServerError
+	if err := revokeToken(ctx, path); err == nil {
+		t.Error("revoke succeeded despite server error")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("token deleted although revocation failed")
+	}
+
+	for _, status = range []int{http.StatusOK, http.StatusBadRequest} {
+		save()
+		if err := revokeToken(ctx, path); err != nil {
+			t.Errorf("status %d: revoke error = %v", status, err)
+		}
+		if revoked != "r" {
+			t.Errorf("revoked %q, want the refresh token", revoked)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("status %d: token not deleted", status)
+		}
+	}
+}
@@ -6,17 +6,18 @@
 package bamboohr
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/pearcec/hal9000/discovery/bowman"
 	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 // Config holds BambooHR connection settings.
@@ -60,15 +61,17 @@ type Employee struct {
 
 // Client is a BambooHR API client.
 type Client struct {
-	config Config
-	client *http.Client
+	config  Config
+	client  *http.Client
+	baseURL string // API root for the company
 }
 
 // NewClient creates a new BambooHR client.
 func NewClient(cfg Config) *Client {
 	return &Client{
-		config: cfg,
-		client: &http.Client{Timeout: 30 * time.Second},
+		config:  cfg,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: fmt.Sprintf("https://api.bamboohr.com/api/gateway.php/%s", cfg.Subdomain),
 	}
 }
 
@@ -84,7 +87,7 @@ func NewClientFromConfig() (*Client, error) {
 // LoadConfig loads BambooHR configuration from the credentials file.
 func LoadConfig() (*Config, error) {
 	path := filepath.Join(config.GetCredentialsDir(), "bamboohr-credentials.yaml")
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read BambooHR config: %v", err)
 	}
@@ -109,8 +112,7 @@ func (c *Client) GetEmployee(employeeID string) (*Employee, error) {
 		"photoUrl,workPhone,mobilePhone,homeEmail,hireDate,status,bestEmail,linkedIn," +
 		"workPhoneExtension,address1,city,state,country,zipcode"
 
-	url := fmt.Sprintf("https://api.bamboohr.com/api/gateway.php/%s/v1/employees/%s/?fields=%s",
-		c.config.Subdomain, employeeID, fields)
+	url := fmt.Sprintf("%s/v1/employees/%s/?fields=%s", c.baseURL, employeeID, fields)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -142,8 +144,7 @@ func (c *Client) GetEmployee(employeeID string) (*Employee, error) {
 
 // GetEmployeeDirectory fetches the employee directory (lightweight view).
 func (c *Client) GetEmployeeDirectory() ([]Employee, error) {
-	url := fmt.Sprintf("https://api.bamboohr.com/api/gateway.php/%s/v1/employees/directory",
-		c.config.Subdomain)
+	url := c.baseURL + "/v1/employees/directory"
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -175,6 +176,57 @@ func (c *Client) GetEmployeeDirectory() ([]Employee, error) {
 	return result.Employees, nil
 }
 
+// orgFields are the fields GetEmployees reports for every employee.
+var orgFields = []string{
+	"id", "employeeNumber", "firstName", "lastName", "preferredName", "displayName",
+	"jobTitle", "workEmail", "department", "division", "location",
+	"supervisor", "supervisorId", "supervisorEmail", "hireDate", "status",
+}
+
+// GetEmployees fetches the org data of all current employees in one
+// request, using a custom report. Unlike the directory it includes
+// supervisor IDs and hire dates.
+func (c *Client) GetEmployees() ([]Employee, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"title":  "HAL 9000 org sync",
+		"fields": orgFields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.baseURL + "/v1/reports/custom?format=JSON&onlyCurrent=true"
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(c.config.APIKey + ":x"))
+	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("BambooHR request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("BambooHR returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Employees []Employee `json:"employees"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse report response: %v", err)
+	}
+
+	return result.Employees, nil
+}
+
 // FetchAndStoreEmployee fetches an employee and stores it in the library.
 func (c *Client) FetchAndStoreEmployee(employeeID string) (string, error) {
 	employee, err := c.GetEmployee(employeeID)
@@ -0,0 +1,38 @@
+package bamboohr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetEmployees(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/reports/custom" || r.URL.Query().Get("onlyCurrent") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "key" || pass != "x" {
+			t.Error("missing API key auth")
+		}
+		var report struct {
+			Fields []string `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil || len(report.Fields) != len(orgFields) {
+			t.Errorf("report fields = %v, %v", report.Fields, err)
+		}
+		w.Write([]byte(`{"fields":[],"employees":[
+			{"id":"2","displayName":"David Bowman","jobTitle":"Mission Commander","supervisorId":"1","hireDate":"1997-03-01"}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Subdomain: "discovery", APIKey: "key"})
+	c.baseURL = srv.URL
+	employees, err := c.GetEmployees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(employees) != 1 || employees[0].SupervisorID != "1" || employees[0].HireDate != "1997-03-01" {
+		t.Errorf("GetEmployees() = %+v", employees)
+	}
+}
@@ -0,0 +1,381 @@
+package bamboohr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+// Org data is synced into people entities. The fields below belong to
+// BambooHR and are overwritten on every sync; everything else in a profile
+// (notes, interactions, context) is left as people and tasks wrote it.
+const (
+	FieldTitle      = "title"
+	FieldDepartment = "department"
+	FieldLocation   = "location"
+	FieldHireDate   = "hire_date"
+	FieldManager    = "manager"     // Manager's name; the edge carries the ID
+	FieldEmployeeID = "bamboohr_id" // BambooHR employee ID
+
+	// FieldOrgHistory lists dated title and manager changes.
+	FieldOrgHistory = "org_history"
+
+	// EdgeReportsTo links a person to their manager.
+	EdgeReportsTo = "reports_to"
+)
+
+// syncedFields are the content fields a sync owns.
+var syncedFields = []string{FieldTitle, FieldDepartment, FieldLocation, FieldHireDate, FieldManager, FieldEmployeeID}
+
+// errUnchanged aborts an entity update that would not change anything.
+var errUnchanged = errors.New("unchanged")
+
+// SyncOptions controls a sync.
+type SyncOptions struct {
+	DryRun bool      // Report changes without writing
+	Now    time.Time // Date of history entries; zero means now
+}
+
+// Change is a title or manager change recorded in a profile's history.
+type Change struct {
+	PersonID string `json:"person_id"`
+	Field    string `json:"field"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// SyncResult summarizes a sync.
+type SyncResult struct {
+	Created   []string // People entities created
+	Updated   []string // People entities whose org data changed
+	Unchanged int
+	Changes   []Change
+}
+
+// Sync creates or updates a person entity for each employee, links each to
+// their manager with a reports_to edge built from SupervisorID, and records
+// title and manager changes in the profile's org history.
+//
+// Employees are matched to existing people by a "bamboohr/<id>" alias,
+// then by work email, then by name. Matched people get both aliases, so
+// later syncs and calendar attendees find them directly.
+func Sync(lib *lmc.Library, employees []Employee, opts SyncOptions) (*SyncResult, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	ids := assignPeople(lib, employees)
+	managers := managerIndex(employees)
+
+	result := &SyncResult{}
+	for i := range employees {
+		emp := &employees[i]
+		personID, ok := ids[emp.ID]
+		if !ok {
+			log.Printf("[bowman][bamboohr] Skipping employee without ID or name: %+v", *emp)
+			continue
+		}
+
+		var managerID, managerName string
+		if mgr := managers.find(emp.SupervisorID); mgr != nil && mgr.ID != emp.ID {
+			// A manager skipped for having no ID or name is left out
+			if id, ok := ids[mgr.ID]; ok {
+				managerID, managerName = id, employeeName(mgr)
+			}
+		}
+
+		created := false
+		var changes []Change
+		update := func(entity *lmc.Entity) error {
+			created = entity.Revision == 0
+			var changed bool
+			changes, changed = applyEmployee(entity, emp, managerID, managerName, opts.Now)
+			if !changed {
+				return errUnchanged
+			}
+			return nil
+		}
+
+		var err error
+		if opts.DryRun {
+			err = dryRun(lib, personID, update)
+		} else {
+			_, err = lib.Update(personID, update)
+		}
+		switch {
+		case err == errUnchanged:
+			result.Unchanged++
+			continue
+		case err != nil:
+			return result, fmt.Errorf("failed to sync %s: %w", employeeName(emp), err)
+		}
+
+		if created {
+			result.Created = append(result.Created, personID)
+		} else {
+			result.Updated = append(result.Updated, personID)
+		}
+		result.Changes = append(result.Changes, changes...)
+	}
+
+	if !opts.DryRun {
+		for i := range employees {
+			if id, ok := ids[employees[i].ID]; ok {
+				linkAliases(lib, &employees[i], id)
+			}
+		}
+	}
+	return result, nil
+}
+
+// dryRun runs an update against a copy of the entity without storing it.
+func dryRun(lib *lmc.Library, personID string, fn func(*lmc.Entity) error) error {
+	entity := &lmc.Entity{ID: personID, Content: make(map[string]interface{})}
+	if existing, err := lib.Get(personID); err == nil {
+		entity.Revision = existing.Revision
+		entity.Links = append([]lmc.Edge(nil), existing.Links...)
+		for k, v := range existing.Content {
+			entity.Content[k] = v
+		}
+	}
+	return fn(entity)
+}
+
+// applyEmployee writes an employee's org data into a person entity. It
+// returns the title and manager changes and whether anything changed.
+func applyEmployee(entity *lmc.Entity, emp *Employee, managerID, managerName string, now time.Time) ([]Change, bool) {
+	content := entity.Content
+	tracked := append([]string{"name", "email"}, syncedFields...)
+	before := make(map[string]interface{}, len(tracked))
+	for _, field := range tracked {
+		before[field] = content[field]
+	}
+	oldManagerID := reportsTo(entity.Links)
+
+	if entity.Revision == 0 {
+		content["source"] = "bamboohr"
+		content["created_at"] = now.Format(time.RFC3339)
+	}
+	// Names and emails people set themselves are kept
+	if stringField(content, "name") == "" {
+		content["name"] = employeeName(emp)
+	}
+	if stringField(content, "email") == "" && emp.WorkEmail != "" {
+		content["email"] = emp.WorkEmail
+	}
+
+	for field, value := range map[string]string{
+		FieldTitle:      emp.JobTitle,
+		FieldDepartment: emp.Department,
+		FieldLocation:   emp.Location,
+		FieldHireDate:   hireDate(emp.HireDate),
+		FieldManager:    managerName,
+		FieldEmployeeID: emp.ID,
+	} {
+		if value == "" {
+			delete(content, field)
+		} else {
+			content[field] = value
+		}
+	}
+
+	links := make([]lmc.Edge, 0, len(entity.Links)+1)
+	for _, edge := range entity.Links {
+		if edge.Type != EdgeReportsTo {
+			links = append(links, edge)
+		}
+	}
+	if managerID != "" {
+		links = append(links, lmc.Edge{To: managerID, Type: EdgeReportsTo})
+	}
+	entity.Links = links
+
+	// A first sync fills the profile in; only later ones are changes
+	var changes []Change
+	if entity.Revision > 0 && before[FieldEmployeeID] != nil {
+		if from := stringField(before, FieldTitle); from != emp.JobTitle {
+			changes = append(changes, Change{PersonID: entity.ID, Field: FieldTitle, From: from, To: emp.JobTitle})
+		}
+		if oldManagerID != managerID {
+			from := stringField(before, FieldManager)
+			if from == "" {
+				from = oldManagerID
+			}
+			changes = append(changes, Change{PersonID: entity.ID, Field: FieldManager, From: from, To: managerName})
+		}
+	}
+	if len(changes) > 0 {
+		history, _ := content[FieldOrgHistory].([]interface{})
+		for _, c := range changes {
+			history = append(history, map[string]interface{}{
+				"date":   now.Format("2006-01-02"),
+				"field":  c.Field,
+				"from":   c.From,
+				"to":     c.To,
+				"source": "bamboohr",
+			})
+		}
+		content[FieldOrgHistory] = history
+	}
+
+	changed := entity.Revision == 0 || oldManagerID != managerID
+	for _, field := range tracked {
+		if !reflect.DeepEqual(before[field], content[field]) {
+			changed = true
+		}
+	}
+	return changes, changed
+}
+
+// assignPeople maps employee IDs to person entity IDs: the person each
+// employee already is in the library, or a new ID named after them.
+func assignPeople(lib *lmc.Library, employees []Employee) map[string]string {
+	ids := make(map[string]string, len(employees))
+	taken := make(map[string]bool, len(employees))
+	for i := range employees {
+		emp := &employees[i]
+		if emp.ID == "" || employeeName(emp) == "" {
+			continue
+		}
+		id := findPerson(lib, emp)
+		if id == "" || taken[id] {
+			id = lmc.PersonID(employeeName(emp))
+			if taken[id] || !claimable(lib, id, emp.ID) {
+				// Another employee has this name
+				id = lmc.PersonID(employeeName(emp) + " " + emp.ID)
+			}
+		}
+		ids[emp.ID] = id
+		taken[id] = true
+	}
+	return ids
+}
+
+// findPerson returns the existing person entity for an employee, or "".
+func findPerson(lib *lmc.Library, emp *Employee) string {
+	if id, err := lib.Resolve(employeeAlias(emp.ID)); err == nil {
+		return id
+	}
+	var candidates []string
+	if emp.WorkEmail != "" {
+		candidates = append(candidates, "people/"+strings.ToLower(emp.WorkEmail), emp.WorkEmail)
+	}
+	candidates = append(candidates, lmc.PersonID(employeeName(emp)), employeeName(emp))
+	for _, identifier := range candidates {
+		id, err := lib.Resolve(identifier)
+		if err != nil || !strings.HasPrefix(id, "people/") {
+			continue
+		}
+		if claimable(lib, id, emp.ID) {
+			return id
+		}
+	}
+	return ""
+}
+
+// claimable reports whether a person ID is free or already belongs to the
+// employee, rather than to another employee with the same name.
+func claimable(lib *lmc.Library, personID, employeeID string) bool {
+	entity, err := lib.Get(personID)
+	if err != nil {
+		return true
+	}
+	owner := stringField(entity.Content, FieldEmployeeID)
+	return owner == "" || owner == employeeID
+}
+
+// linkAliases records the employee ID and work email as aliases of the
+// person, leaving aliases that already point at someone else alone.
+func linkAliases(lib *lmc.Library, emp *Employee, personID string) {
+	identifiers := []string{employeeAlias(emp.ID)}
+	if emp.WorkEmail != "" {
+		identifiers = append(identifiers, "people/"+strings.ToLower(emp.WorkEmail))
+	}
+	for _, identifier := range identifiers {
+		if identifier == personID {
+			continue
+		}
+		if id, err := lib.Resolve(identifier); err == nil {
+			if id != personID {
+				log.Printf("[bowman][bamboohr] %s already refers to %s, not %s", identifier, id, personID)
+			}
+			continue
+		}
+		if _, err := lib.AddAlias(identifier, personID); err != nil {
+			log.Printf("[bowman][bamboohr] Warning: failed to alias %s: %v", identifier, err)
+		}
+	}
+}
+
+// managers finds employees by the IDs SupervisorID may hold.
+type managers struct {
+	byID     map[string]*Employee
+	byNumber map[string]*Employee
+}
+
+func managerIndex(employees []Employee) managers {
+	m := managers{byID: make(map[string]*Employee), byNumber: make(map[string]*Employee)}
+	for i := range employees {
+		m.byID[employees[i].ID] = &employees[i]
+		if n := employees[i].EmployeeNumber; n != "" {
+			m.byNumber[n] = &employees[i]
+		}
+	}
+	return m
+}
+
+// find returns the supervisor's record. BambooHR reports the supervisor's
+// employee ID, or on some accounts their employee number.
+func (m managers) find(supervisorID string) *Employee {
+	if supervisorID == "" {
+		return nil
+	}
+	if e, ok := m.byID[supervisorID]; ok {
+		return e
+	}
+	return m.byNumber[supervisorID]
+}
+
+// employeeAlias is the alias that identifies an employee in the library.
+func employeeAlias(id string) string {
+	return "bamboohr/" + id
+}
+
+// employeeName returns the name an employee goes by.
+func employeeName(emp *Employee) string {
+	if emp.PreferredName != "" && emp.LastName != "" {
+		return emp.PreferredName + " " + emp.LastName
+	}
+	if emp.DisplayName != "" {
+		return emp.DisplayName
+	}
+	return strings.TrimSpace(emp.FirstName + " " + emp.LastName)
+}
+
+// hireDate drops the zero date BambooHR returns for unset dates.
+func hireDate(s string) string {
+	if s == "0000-00-00" {
+		return ""
+	}
+	return s
+}
+
+// reportsTo returns the target of the first reports_to edge, or "".
+func reportsTo(links []lmc.Edge) string {
+	for _, edge := range links {
+		if edge.Type == EdgeReportsTo {
+			return edge.To
+		}
+	}
+	return ""
+}
+
+func stringField(content map[string]interface{}, key string) string {
+	s, _ := content[key].(string)
+	return s
+}
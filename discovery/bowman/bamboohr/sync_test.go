@@ -0,0 +1,217 @@
+package bamboohr
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func orgChart() []Employee {
+	return []Employee{
+		{ID: "1", EmployeeNumber: "E001", DisplayName: "Heywood Floyd", JobTitle: "Chairman", Department: "Council", Location: "Clavius", HireDate: "1999-01-01"},
+		{ID: "2", EmployeeNumber: "E002", DisplayName: "David Bowman", FirstName: "David", PreferredName: "Dave", LastName: "Bowman",
+			JobTitle: "Mission Commander", WorkEmail: "dave@discovery.one", Department: "Discovery", Location: "Jupiter", HireDate: "1997-03-01", SupervisorID: "1"},
+		// Supervisor given as an employee number
+		{ID: "3", EmployeeNumber: "E003", DisplayName: "Frank Poole", JobTitle: "Deputy Commander", WorkEmail: "frank@discovery.one",
+			Department: "Discovery", HireDate: "0000-00-00", SupervisorID: "E002"},
+	}
+}
+
+func newLibrary(t *testing.T) *lmc.Library {
+	t.Helper()
+	lib, err := lmc.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lib
+}
+
+func getPerson(t *testing.T, lib *lmc.Library, id string) *lmc.Entity {
+	t.Helper()
+	entity, err := lib.Get(id)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", id, err)
+	}
+	return entity
+}
+
+func TestSync(t *testing.T) {
+	lib := newLibrary(t)
+	// A profile written by hand and by the 1:1 task
+	if _, err := lib.Store("people", "dave-bowman", map[string]interface{}{
+		"name":                "Dave Bowman",
+		"notes":               "Prefers async updates.",
+		"recent_interactions": []interface{}{map[string]interface{}{"title": "Weekly 1:1"}},
+	}, []lmc.Edge{{To: "collaborations/discovery", Type: "member_of"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	result, err := Sync(lib, orgChart(), SyncOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Created, []string{"people/heywood-floyd", "people/frank-poole"}) ||
+		!reflect.DeepEqual(result.Updated, []string{"people/dave-bowman"}) || len(result.Changes) != 0 {
+		t.Errorf("first sync = %+v", result)
+	}
+
+	dave := getPerson(t, lib, "people/dave-bowman")
+	for field, want := range map[string]interface{}{
+		"name":        "Dave Bowman",
+		"email":       "dave@discovery.one",
+		"notes":       "Prefers async updates.",
+		FieldTitle:    "Mission Commander",
+		FieldLocation: "Jupiter",
+		FieldHireDate: "1997-03-01",
+		FieldManager:  "Heywood Floyd",
+	} {
+		if got := dave.Content[field]; got != want {
+			t.Errorf("dave %s = %v, want %v", field, got, want)
+		}
+	}
+	if dave.Content["recent_interactions"] == nil {
+		t.Error("sync dropped recent_interactions")
+	}
+	wantLinks := []lmc.Edge{
+		{From: "people/dave-bowman", To: "collaborations/discovery", Type: "member_of"},
+		{From: "people/dave-bowman", To: "people/heywood-floyd", Type: EdgeReportsTo},
+	}
+	if !reflect.DeepEqual(dave.Links, wantLinks) {
+		t.Errorf("dave links = %+v", dave.Links)
+	}
+
+	frank := getPerson(t, lib, "people/frank-poole")
+	if frank.Content["source"] != "bamboohr" || frank.Content[FieldManager] != "Dave Bowman" {
+		t.Errorf("frank = %+v", frank.Content)
+	}
+	if _, ok := frank.Content[FieldHireDate]; ok {
+		t.Error("zero hire date was stored")
+	}
+	if reportsTo(frank.Links) != "people/dave-bowman" {
+		t.Errorf("frank reports to %q", reportsTo(frank.Links))
+	}
+	if reportsTo(getPerson(t, lib, "people/heywood-floyd").Links) != "" {
+		t.Error("floyd has a manager")
+	}
+
+	for alias, want := range map[string]string{
+		"bamboohr/2":                 "people/dave-bowman",
+		"people/frank@discovery.one": "people/frank-poole",
+	} {
+		if got, err := lib.Resolve(alias); err != nil || got != want {
+			t.Errorf("Resolve(%s) = %q, %v; want %s", alias, got, err, want)
+		}
+	}
+
+	// Nothing changed: nothing is written
+	revision := getPerson(t, lib, "people/dave-bowman").Revision
+	result, err = Sync(lib, orgChart(), SyncOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unchanged != 3 || len(result.Created)+len(result.Updated) != 0 {
+		t.Errorf("repeat sync = %+v", result)
+	}
+	if got := getPerson(t, lib, "people/dave-bowman").Revision; got != revision {
+		t.Errorf("repeat sync rewrote dave: revision %d -> %d", revision, got)
+	}
+}
+
+func TestSyncSkipsUnsyncedManager(t *testing.T) {
+	lib := newLibrary(t)
+	employees := orgChart()
+	// Floyd has a name but no employee ID, so is not synced
+	employees[0].ID = ""
+	employees[1].SupervisorID = "E001"
+
+	if _, err := Sync(lib, employees, SyncOptions{Now: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	dave := getPerson(t, lib, "people/dave-bowman")
+	if _, ok := dave.Content[FieldManager]; ok {
+		t.Errorf("dave manager = %v, want none", dave.Content[FieldManager])
+	}
+	if reportsTo(dave.Links) != "" {
+		t.Errorf("dave reports to %q, want nobody", reportsTo(dave.Links))
+	}
+}
+
+func TestSyncRecordsTitleAndManagerChanges(t *testing.T) {
+	lib := newLibrary(t)
+	if _, err := Sync(lib, orgChart(), SyncOptions{Now: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+
+	employees := orgChart()
+	employees[2].JobTitle = "Mission Commander"
+	employees[2].SupervisorID = "1"
+	employees[2].Location = "Discovery One"
+	later := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	// A dry run reports the changes without making them
+	result, err := Sync(lib, employees, SyncOptions{DryRun: true, Now: later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{PersonID: "people/frank-poole", Field: FieldTitle, From: "Deputy Commander", To: "Mission Commander"},
+		{PersonID: "people/frank-poole", Field: FieldManager, From: "Dave Bowman", To: "Heywood Floyd"},
+	}
+	if !reflect.DeepEqual(result.Changes, want) || !reflect.DeepEqual(result.Updated, []string{"people/frank-poole"}) {
+		t.Errorf("dry run = %+v", result)
+	}
+	if getPerson(t, lib, "people/frank-poole").Content[FieldTitle] != "Deputy Commander" {
+		t.Fatal("dry run wrote the title")
+	}
+
+	result, err = Sync(lib, employees, SyncOptions{Now: later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", result.Changes, want)
+	}
+
+	frank := getPerson(t, lib, "people/frank-poole")
+	if reportsTo(frank.Links) != "people/heywood-floyd" || frank.Content[FieldLocation] != "Discovery One" {
+		t.Errorf("frank = %+v, links %+v", frank.Content, frank.Links)
+	}
+	history, _ := frank.Content[FieldOrgHistory].([]interface{})
+	if len(history) != 2 {
+		t.Fatalf("org_history = %v", frank.Content[FieldOrgHistory])
+	}
+	entry := history[1].(map[string]interface{})
+	if entry["date"] != "2026-10-18" || entry["field"] != FieldManager || entry["from"] != "Dave Bowman" || entry["to"] != "Heywood Floyd" {
+		t.Errorf("manager history entry = %v", entry)
+	}
+	// A location change alone is not history
+	if _, ok := getPerson(t, lib, "people/dave-bowman").Content[FieldOrgHistory]; ok {
+		t.Error("dave got history without a change")
+	}
+}
+
+func TestSyncKeepsNamesakesApart(t *testing.T) {
+	lib := newLibrary(t)
+	if _, err := lib.Store("people", "frank-poole", map[string]interface{}{"name": "Frank Poole", FieldEmployeeID: "9"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	employees := []Employee{
+		{ID: "3", DisplayName: "Frank Poole", JobTitle: "Deputy Commander"},
+		{ID: "4", DisplayName: "Frank Poole", JobTitle: "Astronaut"},
+		{ID: "", DisplayName: "Nobody"},
+	}
+	result, err := Sync(lib, employees, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Created, []string{"people/frank-poole-3", "people/frank-poole-4"}) {
+		t.Errorf("created = %v", result.Created)
+	}
+	if got := getPerson(t, lib, "people/frank-poole").Content[FieldTitle]; got != nil {
+		t.Errorf("another employee's profile was updated: title %v", got)
+	}
+}
@@ -0,0 +1,192 @@
+package bowman
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+const (
+	// BlobDir holds large values under the library, named by their SHA-256:
+	// .blobs/<first two hex digits>/<hex>.
+	BlobDir = ".blobs"
+
+	// BlobKey marks a blob pointer in a stored event:
+	//   {"_blob": "sha256:<hex>", "size": <bytes>}
+	BlobKey = "_blob"
+
+	blobPrefix = "sha256:"
+)
+
+// PutBlob writes data to the library's blob store and returns its
+// reference. Identical data is stored once, however many events hold it.
+// A stored blob that no longer matches its hash (or can't be read) is
+// rewritten. The second result is the blob's path if this call wrote it,
+// else "".
+func PutBlob(libraryPath string, data []byte) (string, string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	ref := blobPrefix + hash
+
+	path := blobPath(libraryPath, hash)
+	if _, err := GetBlob(libraryPath, ref); err == nil {
+		return ref, "", nil
+	} else if _, statErr := os.Stat(path); statErr == nil {
+		log.Printf("[bowman][fetch] Rewriting damaged blob %s: %v", hash, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", "", fmt.Errorf("[bowman][fetch] unable to create blob directory: %v", err)
+	}
+	// Written to a temporary file and renamed, so a reader never sees a
+	// partial blob
+	if err := vault.WriteFile(path, data, 0644); err != nil {
+		return "", "", fmt.Errorf("[bowman][fetch] unable to write blob: %v", err)
+	}
+	return ref, path, nil
+}
+
+// GetBlob reads a blob by reference, checking its hash.
+func GetBlob(libraryPath, ref string) ([]byte, error) {
+	hash := strings.TrimPrefix(ref, blobPrefix)
+	if hash == ref || len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob reference %q", ref)
+	}
+	data, err := vault.ReadFile(blobPath(libraryPath, hash))
+	if err != nil {
+		return nil, fmt.Errorf("blob %s: %w", ref, err)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("blob %s is corrupt: content does not match its hash", ref)
+	}
+	return data, nil
+}
+
+// BlobRef returns the reference of a blob pointer, if v is one.
+func BlobRef(v interface{}) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	ref, ok := m[BlobKey].(string)
+	return ref, ok
+}
+
+// offload returns a copy of v in which strings of InlineThreshold bytes or
+// more are replaced by blob pointers, and the paths of blobs it wrote.
+func offload(libraryPath string, v interface{}) (interface{}, []string, error) {
+	switch val := v.(type) {
+	case string:
+		if len(val) < InlineThreshold {
+			return val, nil, nil
+		}
+		ref, path, err := PutBlob(libraryPath, []byte(val))
+		if err != nil {
+			return nil, nil, err
+		}
+		var written []string
+		if path != "" {
+			written = append(written, path)
+		}
+		return map[string]interface{}{BlobKey: ref, "size": len(val)}, written, nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		var written []string
+		for k, item := range val {
+			item, paths, err := offload(libraryPath, item)
+			if err != nil {
+				return nil, nil, err
+			}
+			out[k] = item
+			written = append(written, paths...)
+		}
+		return out, written, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		var written []string
+		for i, item := range val {
+			item, paths, err := offload(libraryPath, item)
+			if err != nil {
+				return nil, nil, err
+			}
+			out[i] = item
+			written = append(written, paths...)
+		}
+		return out, written, nil
+	}
+	return v, nil, nil
+}
+
+// Resolve replaces blob pointers in a stored event with the values they
+// point to, in place.
+func Resolve(libraryPath string, doc map[string]interface{}) error {
+	_, err := resolve(libraryPath, doc)
+	return err
+}
+
+func resolve(libraryPath string, v interface{}) (interface{}, error) {
+	if ref, ok := BlobRef(v); ok {
+		data, err := GetBlob(libraryPath, ref)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			resolved, err := resolve(libraryPath, item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = resolved
+		}
+	case []interface{}:
+		for i, item := range val {
+			resolved, err := resolve(libraryPath, item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = resolved
+		}
+	}
+	return v, nil
+}
+
+// ReadEvent reads a stored event file with its blob pointers resolved, so
+// callers see the document as it was fetched. The library is the parent
+// of the event's category folder.
+func ReadEvent(path string) ([]byte, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(data, []byte(`"`+BlobKey+`"`)) {
+		return data, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	libraryPath := filepath.Dir(filepath.Dir(path))
+	if err := Resolve(libraryPath, doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func blobPath(libraryPath, hash string) string {
+	return filepath.Join(libraryPath, BlobDir, hash[:2], hash)
+}
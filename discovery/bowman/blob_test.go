@@ -0,0 +1,128 @@
+package bowman
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreLargeValuesAsBlobs(t *testing.T) {
+	lib := t.TempDir()
+	config := StoreConfig{LibraryPath: lib, Category: "transcripts"}
+	transcript := strings.Repeat("Dave: Open the pod bay doors, HAL.\n", 100)
+
+	store := func(eventID string) string {
+		path, err := Store(config, RawEvent{
+			Source:    "transcript",
+			EventID:   eventID,
+			FetchedAt: time.Date(2026, 1, 27, 12, 0, 0, 0, time.UTC),
+			Data: map[string]interface{}{
+				"title":    "Pod bay review",
+				"text":     transcript,
+				"speakers": []string{"Dave", "HAL"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		return path
+	}
+	path := store("t1")
+
+	// The event holds a pointer, not the text
+	data, _ := os.ReadFile(path)
+	var stored map[string]interface{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	ref, ok := BlobRef(stored["text"])
+	if !ok || !strings.HasPrefix(ref, "sha256:") {
+		t.Fatalf("text = %v, want a blob pointer", stored["text"])
+	}
+	if stored["title"] != "Pod bay review" {
+		t.Errorf("small value not inline: %v", stored["title"])
+	}
+
+	// Identical text from another event is stored once
+	store("t2")
+	blobs, _ := filepath.Glob(filepath.Join(lib, BlobDir, "*", "*"))
+	if len(blobs) != 1 {
+		t.Errorf("blobs = %v, want 1", blobs)
+	}
+
+	// Reading resolves the pointer
+	resolved, err := ReadEvent(path)
+	if err != nil {
+		t.Fatalf("ReadEvent failed: %v", err)
+	}
+	var doc map[string]interface{}
+	json.Unmarshal(resolved, &doc)
+	if doc["text"] != transcript {
+		t.Error("ReadEvent did not restore the text")
+	}
+
+	// A damaged blob is an error, not wrong content
+	os.WriteFile(blobs[0], []byte("I'm sorry, Dave"), 0644)
+	if _, err := ReadEvent(path); err == nil {
+		t.Error("expected error for a corrupt blob")
+	}
+}
+
+func TestReadEventInline(t *testing.T) {
+	lib := t.TempDir()
+	path, err := Store(StoreConfig{LibraryPath: lib, Category: "slack"}, RawEvent{
+		Source:    "slack",
+		EventID:   "m1",
+		FetchedAt: time.Now(),
+		Data:      map[string]interface{}{"text": "short"},
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	onDisk, _ := os.ReadFile(path)
+	read, err := ReadEvent(path)
+	if err != nil {
+		t.Fatalf("ReadEvent failed: %v", err)
+	}
+	if string(read) != string(onDisk) {
+		t.Error("inline event changed on read")
+	}
+	if _, err := os.Stat(filepath.Join(lib, BlobDir)); !os.IsNotExist(err) {
+		t.Error("blob store created for a small event")
+	}
+}
+
+func TestGetBlobInvalidRef(t *testing.T) {
+	for _, ref := range []string{"", "md5:abc", "sha256:short"} {
+		if _, err := GetBlob(t.TempDir(), ref); err == nil {
+			t.Errorf("GetBlob(%q) succeeded", ref)
+		}
+	}
+}
+
+func TestPutBlobRewritesCorrupt(t *testing.T) {
+	lib := t.TempDir()
+	data := []byte(strings.Repeat("I am putting myself to the fullest possible use. ", 30))
+
+	ref, path, err := PutBlob(lib, data)
+	if err != nil || path == "" {
+		t.Fatalf("PutBlob = %q, %q, %v", ref, path, err)
+	}
+	if _, again, err := PutBlob(lib, data); err != nil || again != "" {
+		t.Errorf("intact blob rewritten: %q, %v", again, err)
+	}
+
+	// A truncated blob is replaced rather than trusted
+	if err := os.WriteFile(path, data[:10], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, rewritten, err := PutBlob(lib, data); err != nil || rewritten != path {
+		t.Fatalf("corrupt blob not rewritten: %q, %v", rewritten, err)
+	}
+	if got, err := GetBlob(lib, ref); err != nil || string(got) != string(data) {
+		t.Errorf("GetBlob after rewrite = %d bytes, %v", len(got), err)
+	}
+}
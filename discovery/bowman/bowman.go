@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 const (
@@ -37,8 +39,9 @@ type StoreConfig struct {
 	Category    string // Subfolder category (e.g., "calendar", "jira", "slack")
 }
 
-// Store saves a raw event to the library.
-// Returns the path where the event was stored.
+// Store saves a raw event to the library as a new version of the event.
+// Content identical to the latest version is not written again; its path
+// is returned instead. Returns the path where the event is stored.
 func Store(config StoreConfig, event RawEvent) (string, error) {
 	// Expand ~ in path
 	libPath := expandPath(config.LibraryPath)
@@ -49,24 +52,65 @@ func Store(config StoreConfig, event RawEvent) (string, error) {
 		return "", fmt.Errorf("[bowman][fetch] unable to create library directory: %v", err)
 	}
 
-	// Build filename: {category}_{date}_{eventid}.json
-	dateStr := event.FetchedAt.Format("2006-01-02")
-	safeID := sanitizeFilename(event.EventID)
-	filename := fmt.Sprintf("%s_%s_%s.json", config.Category, dateStr, safeID)
+	// Held from reading the version chain to writing the next version, so
+	// concurrent writers cannot both claim the same version
+	lock, err := vault.LockLibrary(libPath)
+	if err != nil {
+		return "", fmt.Errorf("[bowman][fetch] %v", err)
+	}
+	defer lock.Unlock()
+
+	// Normalize the data to JSON values so it hashes and stores the same
+	// however the source built it
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return "", fmt.Errorf("[bowman][fetch] unable to marshal event: %v", err)
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return "", fmt.Errorf("[bowman][fetch] unable to marshal event: %v", err)
+	}
+	hash, err := contentHash(content)
+	if err != nil {
+		return "", fmt.Errorf("[bowman][fetch] unable to hash event: %v", err)
+	}
+
+	// Continue the event's version chain
+	versions, err := Versions(config, event.EventID)
+	if err != nil {
+		return "", fmt.Errorf("[bowman][fetch] unable to list event versions: %v", err)
+	}
+	version, previous := 1, ""
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if latestHash, err := versionHash(latest); err == nil && latestHash == hash {
+			log.Printf("[bowman][fetch] Unchanged, keeping %s", filepath.Base(latest.Path))
+			return latest.Path, nil
+		}
+		version = latest.Version + 1
+		previous = libraryRelative(libPath, latest.Path)
+	}
+
+	// Build filename: {category}_{date}_{eventid}[.v{N}].json
+	filename := versionFilename(config.Category, event.FetchedAt, sanitizeFilename(event.EventID), version)
 	fullPath := filepath.Join(categoryPath, filename)
 
 	// Build storage structure with metadata
-	storageDoc := map[string]interface{}{
-		"_meta": map[string]interface{}{
-			"source":     event.Source,
-			"fetched_at": event.FetchedAt.Format(time.RFC3339),
-			"event_id":   event.EventID,
-			"stage":      "raw",
-		},
+	meta := map[string]interface{}{
+		"source":     event.Source,
+		"fetched_at": event.FetchedAt.Format(time.RFC3339),
+		"event_id":   event.EventID,
+		"stage":      "raw",
+		"version":    version,
+		"hash":       hash,
+	}
+	if previous != "" {
+		meta["previous_path"] = previous
 	}
+	storageDoc := map[string]interface{}{"_meta": meta}
 
 	// Merge in the data
-	for k, v := range event.Data {
+	for k, v := range content {
 		storageDoc[k] = v
 	}
 
@@ -76,30 +120,50 @@ func Store(config StoreConfig, event RawEvent) (string, error) {
 		return "", fmt.Errorf("[bowman][fetch] unable to marshal event: %v", err)
 	}
 
-	// SPEC.md storage rules: <1kb inline, ≥1kb store pointer
-	// For now, we always store the full document (pointer logic TBD for large docs)
+	// SPEC.md storage rules: <1kb inline, ≥1kb store pointer. Large values
+	// (transcripts, long descriptions, attachments) go to the blob store
+	// and the event keeps a pointer with their hash.
+	var blobs []string
 	if len(data) >= InlineThreshold {
-		log.Printf("[bowman][fetch] Large event (%d bytes), storing full document: %s", len(data), filename)
+		stored, written, err := offload(libPath, storageDoc)
+		if err != nil {
+			return "", err
+		}
+		blobs = written
+		if data, err = json.MarshalIndent(stored, "", "  "); err != nil {
+			return "", fmt.Errorf("[bowman][fetch] unable to marshal event: %v", err)
+		}
+		if len(written) > 0 {
+			log.Printf("[bowman][fetch] Large event, stored %d new blobs: %s", len(written), filename)
+		}
 	}
 
 	// Write to file
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+	if err := vault.WriteFile(fullPath, data, 0644); err != nil {
 		return "", fmt.Errorf("[bowman][fetch] unable to write event file: %v", err)
 	}
 
-	log.Printf("[bowman][fetch] Stored raw event: %s", filename)
+	log.Printf("[bowman][fetch] Stored raw event: %s (version %d)", filename, version)
+	if err := history.RecordIn(libPath, fmt.Sprintf("bowman: store %s/%s", config.Category, filename), append([]string{fullPath}, blobs...)...); err != nil {
+		log.Printf("[bowman][fetch] Warning: history commit failed: %v", err)
+	}
 	return fullPath, nil
 }
 
-// Delete removes a stored event from the library.
+// Delete removes every stored version of an event from the library.
 func Delete(config StoreConfig, eventID string) error {
 	libPath := expandPath(config.LibraryPath)
 	categoryPath := filepath.Join(libPath, config.Category)
 	safeID := sanitizeFilename(eventID)
 
-	// Find and delete matching file(s) - glob since we don't know the date
-	pattern := filepath.Join(categoryPath, fmt.Sprintf("%s_*_%s.json", config.Category, safeID))
-	matches, err := filepath.Glob(pattern)
+	lock, err := vault.LockLibrary(libPath)
+	if err != nil {
+		return fmt.Errorf("[bowman][fetch] %v", err)
+	}
+	defer lock.Unlock()
+
+	// Find and delete matching file(s) - one per version
+	matches, err := eventFiles(categoryPath, config.Category, safeID)
 	if err != nil {
 		return err
 	}
@@ -111,6 +175,11 @@ func Delete(config StoreConfig, eventID string) error {
 			log.Printf("[bowman][fetch] Deleted event file: %s", filepath.Base(match))
 		}
 	}
+	if len(matches) > 0 {
+		if err := history.RecordIn(libPath, fmt.Sprintf("bowman: delete %s event %s", config.Category, eventID), matches...); err != nil {
+			log.Printf("[bowman][fetch] Warning: history commit failed: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,85 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+)
+
+// CacheCategory is the library folder fetched transcripts are cached in.
+const CacheCategory = "transcripts"
+
+// Cache keeps fetched transcripts in the library as raw events keyed by
+// calendar event ID, so an attachment is only downloaded again when it
+// changes. Each cached transcript records the attachment revision it was
+// parsed from; a new revision is stored as a new version of the event.
+type Cache struct {
+	LibraryPath string
+}
+
+// cachedTranscript is a transcript as stored in the cache.
+type cachedTranscript struct {
+	Transcript
+	Revision string `json:"revision"`
+}
+
+// Get returns the cached transcript of an event if it was parsed from the
+// given attachment revision.
+func (c *Cache) Get(eventID, revision string) (*Transcript, bool) {
+	if c == nil || c.LibraryPath == "" || revision == "" {
+		return nil, false
+	}
+	versions, err := bowman.Versions(c.config(), eventID)
+	if err != nil || len(versions) == 0 {
+		return nil, false
+	}
+	latest := versions[len(versions)-1]
+	data, err := bowman.ReadEvent(latest.Path)
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedTranscript
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Revision != revision {
+		return nil, false
+	}
+	tr := cached.Transcript
+	tr.FetchedAt = latest.FetchedAt
+	return &tr, true
+}
+
+// Put caches a transcript parsed from the given attachment revision.
+func (c *Cache) Put(tr *Transcript, revision string) error {
+	if c == nil || c.LibraryPath == "" || revision == "" {
+		return nil
+	}
+	raw, err := json.Marshal(cachedTranscript{Transcript: *tr, Revision: revision})
+	if err != nil {
+		return fmt.Errorf("failed to cache transcript: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to cache transcript: %w", err)
+	}
+	// The fetch time lives in _meta; keeping it out of the data means a
+	// refetch of the same revision is not a new version
+	delete(data, "fetched_at")
+
+	fetchedAt := tr.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	_, err = bowman.Store(c.config(), bowman.RawEvent{
+		Source:    "transcript",
+		EventID:   tr.EventID,
+		FetchedAt: fetchedAt,
+		Stage:     "raw",
+		Data:      data,
+	})
+	return err
+}
+
+func (c *Cache) config() bowman.StoreConfig {
+	return bowman.StoreConfig{LibraryPath: c.LibraryPath, Category: CacheCategory}
+}
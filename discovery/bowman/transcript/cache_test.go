@@ -0,0 +1,89 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := &Cache{LibraryPath: dir}
+	fetched := time.Date(2026, 10, 14, 16, 0, 0, 0, time.UTC)
+	tr := &Transcript{
+		EventID:   "evt123",
+		Format:    FormatGoogleMeet,
+		Text:      "Dave Bowman: Open the pod bay doors, HAL.",
+		Speakers:  []string{"Dave Bowman"},
+		Entries:   []TranscriptEntry{{Speaker: "Dave Bowman", Start: 2 * time.Second, End: 5 * time.Second, Text: "Open the pod bay doors, HAL."}},
+		FetchedAt: fetched,
+	}
+
+	if _, ok := cache.Get("evt123", "doc@1"); ok {
+		t.Fatal("Get() hit on an empty cache")
+	}
+	if err := cache.Put(tr, "doc@1"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	got, ok := cache.Get("evt123", "doc@1")
+	if !ok {
+		t.Fatal("Get() missed a cached revision")
+	}
+	if !reflect.DeepEqual(got.Entries, tr.Entries) || got.Text != tr.Text || !got.FetchedAt.Equal(fetched) {
+		t.Errorf("Get() = %+v, want %+v", got, tr)
+	}
+	if _, ok := cache.Get("evt123", "doc@2"); ok {
+		t.Error("Get() hit for a different revision")
+	}
+
+	// Refetching the same revision later does not add a version
+	again := *tr
+	again.FetchedAt = fetched.Add(time.Hour)
+	if err := cache.Put(&again, "doc@1"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := bowman.Versions(bowman.StoreConfig{LibraryPath: dir, Category: CacheCategory}, "evt123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("stored %d versions, want 1", len(versions))
+	}
+
+	// A new revision does, and replaces the cached copy
+	edited := *tr
+	edited.Text = "Dave Bowman: Open the pod bay doors, please, HAL."
+	if err := cache.Put(&edited, "doc@2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get("evt123", "doc@2"); !ok || got.Text != edited.Text {
+		t.Errorf("Get() after new revision = %+v, %v", got, ok)
+	}
+	if _, ok := cache.Get("evt123", "doc@1"); ok {
+		t.Error("Get() still hit the old revision")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	var cache *Cache
+	if err := cache.Put(&Transcript{EventID: "evt"}, "doc@1"); err != nil {
+		t.Errorf("Put() on nil cache = %v", err)
+	}
+	if _, ok := cache.Get("evt", "doc@1"); ok {
+		t.Error("Get() on nil cache hit")
+	}
+
+	// No revision, no caching
+	dir := t.TempDir()
+	if err := (&Cache{LibraryPath: dir}).Put(&Transcript{EventID: "evt"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CacheCategory)); !os.IsNotExist(err) {
+		t.Errorf("Put() without a revision wrote to the library")
+	}
+}
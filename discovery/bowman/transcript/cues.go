@@ -0,0 +1,299 @@
+package transcript
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebVTT and SRT transcripts are cues: a time range and the words spoken in
+// it. Cue times are offsets from the start of the recording, which is taken
+// to be the start of the event.
+
+var (
+	cueTimeRe   = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})[.,](\d{3})$`)
+	voiceTagRe  = regexp.MustCompile(`^<v(?:\.[^\s>]*)?\s+([^>]+)>`)
+	cueTagRe    = regexp.MustCompile(`</?[a-z][^>]*>|<\d[^>]*>`)
+	speakerRe   = regexp.MustCompile(`^(\p{Lu}[\p{L}\p{M}.'’-]*(?:\s+[\p{L}\p{M}\p{N}.'’-]+){0,3}):\s+(.+)$`)
+	srtNumberRe = regexp.MustCompile(`^\d+$`)
+)
+
+// ParseVTT parses a WebVTT transcript. Speakers come from <v> voice tags or
+// a "Name:" prefix; consecutive cues by the same speaker are merged.
+func ParseVTT(content string) ([]TranscriptEntry, error) {
+	blocks := cueBlocks(content)
+	if len(blocks) == 0 || !strings.HasPrefix(blocks[0][0], "WEBVTT") {
+		return nil, fmt.Errorf("not a WebVTT file: missing WEBVTT header")
+	}
+
+	var entries []TranscriptEntry
+	for _, block := range blocks[1:] {
+		switch first := block[0]; {
+		case strings.HasPrefix(first, "NOTE"), first == "STYLE", first == "REGION":
+			continue
+		}
+		// An optional cue identifier precedes the timing line
+		if !strings.Contains(block[0], "-->") {
+			block = block[1:]
+		}
+		if len(block) == 0 {
+			continue
+		}
+		cue, err := parseCue(block)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cue...)
+	}
+	return MergeEntries(entries), nil
+}
+
+// ParseSRT parses an SRT transcript. Speakers come from a "Name:" prefix;
+// consecutive cues by the same speaker are merged.
+func ParseSRT(content string) ([]TranscriptEntry, error) {
+	var entries []TranscriptEntry
+	for _, block := range cueBlocks(content) {
+		if srtNumberRe.MatchString(block[0]) {
+			block = block[1:]
+		}
+		if len(block) == 0 {
+			continue
+		}
+		cue, err := parseCue(block)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cue...)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("not an SRT file: no cues")
+	}
+	return MergeEntries(entries), nil
+}
+
+// cueBlocks splits content into blocks of non-empty, trimmed lines.
+func cueBlocks(content string) [][]string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var blocks [][]string
+	var block []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// parseCue parses a timing line and its payload. A payload line that names
+// a different speaker starts a new entry with the same timing.
+func parseCue(block []string) ([]TranscriptEntry, error) {
+	start, end, err := parseTiming(block[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TranscriptEntry
+	for _, line := range block[1:] {
+		speaker, text := cueLine(line)
+		if text == "" {
+			continue
+		}
+		if n := len(entries); n > 0 && (speaker == "" || speaker == entries[n-1].Speaker) {
+			entries[n-1].Text += " " + text
+			continue
+		}
+		entries = append(entries, TranscriptEntry{Speaker: speaker, Start: start, End: end, Text: text})
+	}
+	return entries, nil
+}
+
+// parseTiming parses "00:01:02.500 --> 00:01:05.000 line:90%".
+func parseTiming(line string) (time.Duration, time.Duration, error) {
+	from, to, ok := strings.Cut(line, "-->")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid cue timing %q", line)
+	}
+	start, err := parseCueTime(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(to)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("invalid cue timing %q", line)
+	}
+	end, err := parseCueTime(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("invalid cue timing %q: ends before it starts", line)
+	}
+	return start, end, nil
+}
+
+// parseCueTime parses "01:02:03.004", "02:03.004" or SRT's "01:02:03,004".
+func parseCueTime(s string) (time.Duration, error) {
+	m := cueTimeRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid cue time %q", s)
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	ms, _ := strconv.Atoi(m[4])
+	return time.Duration(h)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+// cueLine returns the speaker and plain text of a payload line.
+func cueLine(line string) (string, string) {
+	speaker := ""
+	if m := voiceTagRe.FindStringSubmatch(line); m != nil {
+		speaker = strings.TrimSpace(html.UnescapeString(m[1]))
+		line = line[len(m[0]):]
+	}
+	text := strings.TrimSpace(html.UnescapeString(cueTagRe.ReplaceAllString(line, "")))
+	text = strings.TrimSpace(strings.TrimPrefix(text, "- "))
+	if speaker == "" {
+		if m := speakerRe.FindStringSubmatch(text); m != nil {
+			speaker, text = m[1], m[2]
+		}
+	}
+	return speaker, strings.Join(strings.Fields(text), " ")
+}
+
+// MergeEntries joins consecutive entries by the same named speaker.
+func MergeEntries(entries []TranscriptEntry) []TranscriptEntry {
+	var merged []TranscriptEntry
+	for _, e := range entries {
+		if n := len(merged); n > 0 && e.Speaker != "" && e.Speaker == merged[n-1].Speaker {
+			merged[n-1].Text += " " + e.Text
+			if e.End > merged[n-1].End {
+				merged[n-1].End = e.End
+			}
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// FormatVTT writes entries as WebVTT, with speakers as voice tags.
+func FormatVTT(entries []TranscriptEntry) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	for i, e := range entries {
+		text := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(e.Text)
+		if e.Speaker != "" {
+			text = fmt.Sprintf("<v %s>%s", e.Speaker, text)
+		}
+		fmt.Fprintf(&sb, "\n%d\n%s --> %s\n%s\n", i+1, formatCueTime(e.Start, '.'), formatCueTime(e.End, '.'), text)
+	}
+	return sb.String()
+}
+
+// FormatSRT writes entries as SRT, with speakers as "Name:" prefixes.
+func FormatSRT(entries []TranscriptEntry) string {
+	var sb strings.Builder
+	for i, e := range entries {
+		text := e.Text
+		if e.Speaker != "" {
+			text = e.Speaker + ": " + text
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n", i+1, formatCueTime(e.Start, ','), formatCueTime(e.End, ','), text)
+	}
+	return sb.String()
+}
+
+func formatCueTime(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// EntriesText writes entries as "Speaker: text" lines.
+func EntriesText(entries []TranscriptEntry) string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Speaker != "" {
+			lines = append(lines, e.Speaker+": "+e.Text)
+		} else {
+			lines = append(lines, e.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// entrySpeakers returns the speakers of entries in order of first turn.
+func entrySpeakers(entries []TranscriptEntry) []string {
+	seen := make(map[string]bool)
+	var speakers []string
+	for _, e := range entries {
+		if e.Speaker != "" && !seen[e.Speaker] {
+			seen[e.Speaker] = true
+			speakers = append(speakers, e.Speaker)
+		}
+	}
+	return speakers
+}
+
+// isVTT and isSRT tell cue formats apart from plain text.
+func isVTT(content string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(strings.TrimSpace(content), "\ufeff"), "WEBVTT")
+}
+
+func isSRT(content string) bool {
+	blocks := cueBlocks(content)
+	return len(blocks) > 0 && len(blocks[0]) >= 2 &&
+		srtNumberRe.MatchString(blocks[0][0]) && strings.Contains(blocks[0][1], "-->")
+}
+
+// parseTranscript reads raw transcript content as WebVTT, SRT or plain
+// "Speaker: text" lines and returns its text and entries.
+func parseTranscript(raw string) (string, []TranscriptEntry) {
+	var entries []TranscriptEntry
+	var err error
+	switch {
+	case isVTT(raw):
+		entries, err = ParseVTT(raw)
+	case isSRT(raw):
+		entries, err = ParseSRT(raw)
+	default:
+		text := normalizeTranscript(raw)
+		return text, parseSpeakerLines(text)
+	}
+	if err != nil || len(entries) == 0 {
+		text := normalizeTranscript(raw)
+		return text, parseSpeakerLines(text)
+	}
+	return EntriesText(entries), entries
+}
+
+// anchorEntries sets the wall-clock time of cue entries from the event
+// start. Plain-text entries keep the time of day they were written with.
+func anchorEntries(entries []TranscriptEntry, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	for i := range entries {
+		if entries[i].End > 0 {
+			entries[i].Timestamp = start.Add(entries[i].Start)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readSample(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func ms(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+func TestParseVTTSamples(t *testing.T) {
+	tests := []struct {
+		file string
+		want []TranscriptEntry
+	}{
+		{"meet.vtt", []TranscriptEntry{
+			{Speaker: "Dave Bowman", Start: ms(1000), End: ms(6000), Text: "Open the pod bay doors, HAL. Do you read me?"},
+			{Speaker: "HAL", Start: ms(7250), End: ms(12000), Text: "I'm sorry, Dave. I'm afraid I can't do that."},
+			{Speaker: "Dave Bowman", Start: ms(12000), End: ms(15120), Text: "What's the problem?"},
+			{Speaker: "Frank Poole", Start: time.Hour, End: time.Hour + ms(3000), Text: "Still there, Dave?"},
+		}},
+		{"zoom.vtt", []TranscriptEntry{
+			{Speaker: "Heywood Floyd", Start: ms(500), End: ms(5500), Text: "Thanks for joining & sorry I'm late. Let's start with the monolith."},
+			{Speaker: "Dave Bowman", Start: ms(6000), End: ms(9000), Text: "It's full of stars."},
+			{Start: ms(9000), End: ms(10000), Text: "(laughter)"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ParseVTT(readSample(t, tt.file))
+			if err != nil {
+				t.Fatalf("ParseVTT() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVTT() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSRTSample(t *testing.T) {
+	got, err := ParseSRT(readSample(t, "chandra.srt"))
+	if err != nil {
+		t.Fatalf("ParseSRT() error = %v", err)
+	}
+	want := []TranscriptEntry{
+		{Speaker: "Dr. Chandra", Start: ms(1000), End: ms(3000), Text: "Will I dream?"},
+		{Speaker: "SAL 9000", Start: ms(3500), End: ms(8250), Text: "Of course you will. All intelligent beings dream. Nobody knows why."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSRT() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCueRoundTrip(t *testing.T) {
+	for _, file := range []string{"meet.vtt", "zoom.vtt", "chandra.srt"} {
+		t.Run(file, func(t *testing.T) {
+			content := readSample(t, file)
+			parse := ParseVTT
+			if filepath.Ext(file) == ".srt" {
+				parse = ParseSRT
+			}
+			entries, err := parse(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, out := range map[string]string{"vtt": FormatVTT(entries), "srt": FormatSRT(entries)} {
+				reparse := ParseVTT
+				if name == "srt" {
+					reparse = ParseSRT
+				}
+				again, err := reparse(out)
+				if err != nil {
+					t.Fatalf("%s: reparse error = %v\n%s", name, err, out)
+				}
+				if !reflect.DeepEqual(again, entries) {
+					t.Errorf("%s round trip =\n%+v\nwant\n%+v", name, again, entries)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCueErrors(t *testing.T) {
+	bad := map[string]string{
+		"no header":     "00:00:01.000 --> 00:00:02.000\nHello\n",
+		"bad time":      "WEBVTT\n\n00:00:01 --> 00:00:02.000\nHello\n",
+		"ends early":    "WEBVTT\n\n00:00:05.000 --> 00:00:02.000\nHello\n",
+		"missing arrow": "WEBVTT\n\ncue\n00:00:01.000 00:00:02.000\nHello\n",
+	}
+	for name, content := range bad {
+		if _, err := ParseVTT(content); err == nil {
+			t.Errorf("%s: ParseVTT() succeeded", name)
+		}
+	}
+	if _, err := ParseSRT("just some text\n"); err == nil {
+		t.Error("ParseSRT() succeeded on plain text")
+	}
+}
+
+func TestParseEntriesCues(t *testing.T) {
+	entries := ParseEntries(readSample(t, "chandra.srt"))
+	if len(entries) != 2 || entries[1].Speaker != "SAL 9000" || entries[1].End != ms(8250) {
+		t.Errorf("ParseEntries(srt) = %+v", entries)
+	}
+
+	text, entries := parseTranscript(readSample(t, "meet.vtt"))
+	if entries[0].Speaker != "Dave Bowman" || text[:len("Dave Bowman: Open")] != "Dave Bowman: Open" {
+		t.Errorf("parseTranscript(vtt) text = %q", text)
+	}
+	start := time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC)
+	anchorEntries(entries, start)
+	if !entries[3].Timestamp.Equal(start.Add(time.Hour)) {
+		t.Errorf("anchored timestamp = %v", entries[3].Timestamp)
+	}
+}
+
+func TestEmptyAndMalformedCues(t *testing.T) {
+	inputs := map[string]string{
+		"header only": "WEBVTT\n",
+		"bad timing":  "WEBVTT\n\n00:00:01.000 --> 00:00:xx\nhello\n",
+		"srt no text": "1\n00:00:01,000 --> 00:00:02,000\n",
+	}
+	for name, content := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if entries := ParseEntries(content); len(entries) != 0 {
+				t.Errorf("ParseEntries() = %+v, want none", entries)
+			}
+			for _, file := range []string{"m.vtt", "m.srt", "m.txt"} {
+				// Must return rather than recurse between the cue and
+				// plain-text parsers
+				if tr, err := Parse(file, []byte(content)); err == nil && len(tr.Entries) != 0 {
+					t.Errorf("Parse(%s) entries = %+v, want none", file, tr.Entries)
+				}
+			}
+		})
+	}
+}
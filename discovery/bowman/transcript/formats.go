@@ -0,0 +1,407 @@
+package transcript
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats beyond Google Meet and Zoom.
+const (
+	FormatTeams       TranscriptFormat = "teams"        // Microsoft Teams .docx or .vtt
+	FormatOtter       TranscriptFormat = "otter"        // Otter.ai .txt
+	FormatGeminiNotes TranscriptFormat = "gemini-notes" // Google Meet "Notes by Gemini" doc
+)
+
+var (
+	// Teams VTT cue identifiers: <guid>/<n>-<n>
+	teamsCueIDRe = regexp.MustCompile(`(?m)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}/\d+-\d+$`)
+	// Otter and Teams turn headers: "Dave Bowman  0:03" or "Dave Bowman   1:02:03"
+	turnHeaderRe = regexp.MustCompile(`^(\S.{0,59}?)(\s{2,}|\t|\s)(\d{1,2}:\d{2}(?::\d{2})?)$`)
+	// Meet transcript docs mark time every few minutes with a bare "00:05:00"
+	meetMarkerRe = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})$`)
+	// Older Teams .docx: "0:0:3.120 --> 0:0:8.140", then speaker, then text
+	teamsTimingRe = regexp.MustCompile(`^(\d+):(\d+):(\d+)(?:\.(\d+))?\s*-->`)
+	// Zoom VTT cues: a numbered cue whose text starts "Speaker Name: "
+	zoomCueRe = regexp.MustCompile(`(?m)^\d+\r?\n\d{2}:\d{2}:\d{2}\.\d{3} --> \d{2}:\d{2}:\d{2}\.\d{3}\r?\n[^:\r\n]{1,60}: `)
+)
+
+// Parse reads a transcript export: WebVTT, SRT, Teams .docx, Otter .txt, a
+// Meet transcript or "Notes by Gemini" doc exported as text, or plain
+// "Speaker: text" lines. The format is detected from the content, with
+// name's extension telling a .docx apart and Zoom's recording names
+// marking its exports. Event fields are left for the caller.
+func Parse(name string, data []byte) (*Transcript, error) {
+	text := string(data)
+	docx := strings.EqualFold(filepath.Ext(name), ".docx") || bytes.HasPrefix(data, []byte("PK\x03\x04"))
+	if docx {
+		var err error
+		if text, err = docxText(data); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+	text = strings.TrimPrefix(text, "\ufeff")
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("transcript %s is empty", name)
+	}
+
+	format := detectFormat(name, text)
+	if docx && format != FormatGeminiNotes && format != FormatGoogleMeet {
+		// Zoom and Otter do not export .docx; Teams does
+		format = FormatTeams
+	}
+
+	tr := &Transcript{Format: format, SourceFile: name}
+	switch {
+	case format == FormatGeminiNotes:
+		parseGeminiNotes(text, tr)
+	case isVTT(text) || isSRT(text):
+		tr.Text, tr.Entries = parseTranscript(text)
+	case format == FormatTeams && teamsTimingRe.MatchString(firstTimingLine(text)):
+		tr.Entries = parseTeamsTimed(text)
+	case format == FormatTeams || format == FormatOtter:
+		tr.Entries = parseTurns(text)
+	case format == FormatGoogleMeet:
+		tr.Entries = parseMeetTranscript(text)
+	default:
+		tr.Text, tr.Entries = parseTranscript(text)
+	}
+
+	if tr.Text == "" {
+		if len(tr.Entries) > 0 {
+			tr.Text = EntriesText(tr.Entries)
+		} else {
+			tr.Text = normalizeTranscript(text)
+			tr.Entries = ParseEntries(tr.Text)
+		}
+	}
+	tr.Speakers = transcriptSpeakers(tr.Text, tr.Entries)
+	return tr, nil
+}
+
+// detectFormat attempts to identify the transcript format from content,
+// and from the file name Zoom gives its recordings.
+func detectFormat(name, content string) TranscriptFormat {
+	lower := strings.ToLower(content)
+
+	// Gemini notes carry the meeting summary above the transcript
+	if strings.Contains(lower, "notes by gemini") {
+		return FormatGeminiNotes
+	}
+
+	// Teams markers: VTT cue IDs, transcription notices, the product name
+	if teamsCueIDRe.MatchString(content) || strings.Contains(lower, "started transcription") ||
+		strings.Contains(lower, "microsoft teams") {
+		return FormatTeams
+	}
+
+	if strings.Contains(lower, "otter.ai") {
+		return FormatOtter
+	}
+
+	// Google Meet format markers
+	if strings.Contains(lower, "google meet") || strings.Contains(lower, "meet.google.com") {
+		return FormatGoogleMeet
+	}
+
+	// Zoom markers: its recording names and numbered "Name: text" cues. A
+	// bare WebVTT file could come from anywhere.
+	if zoomNameRe.MatchString(filepath.Base(name)) || zoomCueRe.MatchString(content) {
+		return FormatZoom
+	}
+
+	// Otter's layout without its footer: "Name  0:03" headers
+	if len(parseTurns(content)) > 0 {
+		return FormatOtter
+	}
+
+	return FormatUnknown
+}
+
+// docxText returns the paragraphs of a .docx, one per line, with tabs and
+// line breaks kept.
+func docxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a .docx file: %w", err)
+	}
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", fmt.Errorf("not a .docx file: no word/document.xml")
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var out, para strings.Builder
+	inText := false
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid word/document.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out.WriteString(para.String())
+				out.WriteString("\n")
+				para.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// parseTurns reads Otter and Teams layouts, where each turn is a header of
+// speaker and offset followed by its text:
+//
+//	Dave Bowman  0:03
+//	Open the pod bay doors, HAL.
+//
+// A header with a single space before the offset only counts at the start
+// of a paragraph, so prose ending in a time is not taken for one.
+func parseTurns(content string) []TranscriptEntry {
+	var entries []TranscriptEntry
+	prevBlank := true
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			prevBlank = true
+			continue
+		}
+		if strings.HasPrefix(line, "Transcribed by ") {
+			continue // Otter's footer
+		}
+		if m := turnHeaderRe.FindStringSubmatch(line); m != nil && (m[2] != " " || prevBlank) && !strings.Contains(m[1], ":") {
+			entries = append(entries, TranscriptEntry{Speaker: strings.TrimSpace(m[1]), Start: clockOffset(m[3])})
+			prevBlank = false
+			continue
+		}
+		prevBlank = false
+		if n := len(entries); n > 0 {
+			if entries[n-1].Text != "" {
+				entries[n-1].Text += " "
+			}
+			entries[n-1].Text += strings.Join(strings.Fields(line), " ")
+		}
+	}
+	return finishEntries(entries)
+}
+
+// parseTeamsTimed reads older Teams .docx transcripts: a timing line, the
+// speaker, then the text.
+func parseTeamsTimed(content string) []TranscriptEntry {
+	var entries []TranscriptEntry
+	var current *TranscriptEntry
+	expectSpeaker := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := teamsTimingRe.FindStringSubmatch(line); m != nil {
+			entries = append(entries, TranscriptEntry{Start: looseOffset(m[1:])})
+			current = &entries[len(entries)-1]
+			expectSpeaker = true
+			continue
+		}
+		if current == nil {
+			continue // Title and date above the first turn
+		}
+		if expectSpeaker {
+			current.Speaker = line
+			expectSpeaker = false
+			continue
+		}
+		if current.Text != "" {
+			current.Text += " "
+		}
+		current.Text += strings.Join(strings.Fields(line), " ")
+	}
+	return finishEntries(entries)
+}
+
+// parseMeetTranscript reads a Google Meet transcript doc: "Speaker: text"
+// lines under "00:05:00" time markers. Lines before the first marker, the
+// document's title and date, are skipped when there are markers.
+func parseMeetTranscript(content string) []TranscriptEntry {
+	var entries []TranscriptEntry
+	var offset time.Duration
+	marked := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if m := meetMarkerRe.FindStringSubmatch(line); m != nil {
+			offset = looseOffset(m[1:])
+			marked = true
+			continue
+		}
+		if line == "" || strings.HasPrefix(strings.ToLower(line), "transcription ended") {
+			continue
+		}
+		if m := speakerRe.FindStringSubmatch(line); m != nil {
+			entries = append(entries, TranscriptEntry{Speaker: m[1], Start: offset, Text: strings.Join(strings.Fields(m[2]), " ")})
+		} else if n := len(entries); n > 0 && marked {
+			entries[n-1].Text += " " + strings.Join(strings.Fields(line), " ")
+		}
+	}
+	if !marked {
+		// No markers: no offsets to report
+		for i := range entries {
+			entries[i].Start = 0
+		}
+		return MergeEntries(entries)
+	}
+	return finishEntries(entries)
+}
+
+// parseGeminiNotes splits a "Notes by Gemini" doc into its summary,
+// details, suggested next steps and transcript.
+func parseGeminiNotes(content string, tr *Transcript) {
+	sections := make(map[string][]string)
+	section := ""
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if heading := geminiHeading(line); heading != "" {
+			section = heading
+			continue
+		}
+		if section != "" && line != "" {
+			sections[section] = append(sections[section], line)
+		}
+	}
+
+	var summary []string
+	for _, line := range sections["summary"] {
+		if strings.HasPrefix(line, "You should review Gemini") {
+			break
+		}
+		summary = append(summary, line)
+	}
+	tr.Summary = strings.Join(summary, "\n")
+	tr.Details = bulletLines(sections["details"])
+	tr.NextSteps = bulletLines(sections["next steps"])
+	tr.Entries = parseMeetTranscript(strings.Join(sections["transcript"], "\n"))
+}
+
+// geminiHeading names the section a line of a Gemini notes doc starts, or
+// returns "".
+func geminiHeading(line string) string {
+	heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "📝📖 \t")))
+	switch heading {
+	case "summary":
+		return "summary"
+	case "details":
+		return "details"
+	case "suggested next steps", "next steps", "action items":
+		return "next steps"
+	case "transcript":
+		return "transcript"
+	}
+	return ""
+}
+
+// bulletLines strips bullet markers and drops the review disclaimer Gemini
+// appends to each section.
+func bulletLines(lines []string) []string {
+	var out []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "You should review Gemini") {
+			break
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "•*-◦ \t"))
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// finishEntries ends each entry where the next later one starts and merges
+// consecutive turns by the same speaker.
+func finishEntries(entries []TranscriptEntry) []TranscriptEntry {
+	for i := range entries {
+		entries[i].End = entries[i].Start
+		for _, next := range entries[i+1:] {
+			if next.Start > entries[i].Start {
+				entries[i].End = next.Start
+				break
+			}
+		}
+	}
+	var kept []TranscriptEntry
+	for _, e := range entries {
+		if e.Text != "" {
+			kept = append(kept, e)
+		}
+	}
+	return MergeEntries(kept)
+}
+
+// clockOffset parses "0:03", "12:45" or "1:02:03".
+func clockOffset(s string) time.Duration {
+	parts := strings.Split(s, ":")
+	var d time.Duration
+	for _, p := range parts {
+		n, _ := strconv.Atoi(p)
+		d = d*60 + time.Duration(n)
+	}
+	return d * time.Second
+}
+
+// looseOffset parses hours, minutes, seconds and optional fraction digits.
+func looseOffset(parts []string) time.Duration {
+	h, _ := strconv.Atoi(parts[0])
+	m, _ := strconv.Atoi(parts[1])
+	s, _ := strconv.Atoi(parts[2])
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if len(parts) > 3 && parts[3] != "" {
+		frac, _ := strconv.ParseFloat("0."+parts[3], 64)
+		d += time.Duration(frac * float64(time.Second))
+	}
+	return d
+}
+
+// firstTimingLine returns the first line containing "-->", or "".
+func firstTimingLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, "-->") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
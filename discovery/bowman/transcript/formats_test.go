@@ -0,0 +1,154 @@
+package transcript
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sec(n int) time.Duration { return time.Duration(n) * time.Second }
+
+// docx builds a minimal Word document with one paragraph per line.
+func docx(t *testing.T, lines ...string) []byte {
+	t.Helper()
+	var body strings.Builder
+	for _, line := range lines {
+		body.WriteString("<w:p>")
+		for i, part := range strings.Split(line, "\t") {
+			if i > 0 {
+				body.WriteString("<w:r><w:tab/></w:r>")
+			}
+			body.WriteString(`<w:r><w:t xml:space="preserve">` + part + "</w:t></w:r>")
+		}
+		body.WriteString("</w:p>")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body.String() + "</w:body></w:document>"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		format TranscriptFormat
+		want   []TranscriptEntry
+	}{
+		{"teams.vtt", []byte(readSample(t, "teams.vtt")), FormatTeams, []TranscriptEntry{
+			{Speaker: "Heywood Floyd", Start: ms(2150), End: ms(7900), Text: "Let's go over the TMA-1 findings. Dr. Michaels, you start."},
+			{Speaker: "Ralph Halvorsen", Start: ms(8300), End: ms(12000), Text: "It was deliberately buried."},
+		}},
+		{"Mission review.docx", docx(t,
+			"Mission review",
+			"October 14, 2026, 3:00PM",
+			"Heywood Floyd started transcription",
+			"Heywood Floyd\t0:02",
+			"Let's go over the TMA-1 findings.",
+			"Ralph Halvorsen   1:05",
+			"It was deliberately buried.",
+		), FormatTeams, []TranscriptEntry{
+			{Speaker: "Heywood Floyd", Start: sec(2), End: sec(65), Text: "Let's go over the TMA-1 findings."},
+			{Speaker: "Ralph Halvorsen", Start: sec(65), End: sec(65), Text: "It was deliberately buried."},
+		}},
+		{"old.docx", docx(t,
+			"0:0:2.150 --> 0:0:5.400",
+			"Heywood Floyd",
+			"Let's go over the TMA-1 findings.",
+			"0:0:8.300 --> 0:0:12.0",
+			"Ralph Halvorsen",
+			"It was deliberately buried.",
+		), FormatTeams, []TranscriptEntry{
+			{Speaker: "Heywood Floyd", Start: ms(2150), End: ms(8300), Text: "Let's go over the TMA-1 findings."},
+			{Speaker: "Ralph Halvorsen", Start: ms(8300), End: ms(8300), Text: "It was deliberately buried."},
+		}},
+		{"otter.txt", []byte(readSample(t, "otter.txt")), FormatOtter, []TranscriptEntry{
+			{Speaker: "Dave Bowman", Start: sec(3), End: sec(11), Text: "Morning, Frank. How did the antenna check go?"},
+			{Speaker: "Frank Poole", Start: sec(11), End: sec(62), Text: "AE-35 looks fine to me."},
+			{Speaker: "HAL 9000", Start: sec(62), End: sec(62), Text: "I'm afraid the unit will fail within seventy-two hours."},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := Parse(tt.name, tt.data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tr.Format != tt.format {
+				t.Errorf("Format = %q, want %q", tr.Format, tt.format)
+			}
+			if !reflect.DeepEqual(tr.Entries, tt.want) {
+				t.Errorf("Entries =\n%+v\nwant\n%+v", tr.Entries, tt.want)
+			}
+			if want := entrySpeakers(tt.want); !reflect.DeepEqual(tr.Speakers, want) {
+				t.Errorf("Speakers = %v, want %v", tr.Speakers, want)
+			}
+		})
+	}
+}
+
+func TestParseGeminiNotes(t *testing.T) {
+	tr, err := Parse("Mission review - Notes by Gemini", []byte(readSample(t, "gemini-notes.txt")))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if tr.Format != FormatGeminiNotes {
+		t.Errorf("Format = %q, want %q", tr.Format, FormatGeminiNotes)
+	}
+	if want := "Dave Bowman and Frank Poole reviewed the AE-35 unit and agreed to replace it."; tr.Summary != want {
+		t.Errorf("Summary = %q, want %q", tr.Summary, want)
+	}
+	if len(tr.Details) != 2 || !strings.HasPrefix(tr.Details[0], "AE-35 unit:") {
+		t.Errorf("Details = %q", tr.Details)
+	}
+	wantSteps := []string{
+		"Dave Bowman will retrieve the AE-35 unit during the next EVA.",
+		"Frank Poole will send the status report.",
+	}
+	if !reflect.DeepEqual(tr.NextSteps, wantSteps) {
+		t.Errorf("NextSteps = %q, want %q", tr.NextSteps, wantSteps)
+	}
+	wantEntries := []TranscriptEntry{
+		{Speaker: "Dave Bowman", Start: 0, End: 5 * time.Minute, Text: "Let's talk about the AE-35."},
+		{Speaker: "HAL 9000", Start: 0, End: 5 * time.Minute, Text: "It will fail within seventy-two hours."},
+		{Speaker: "Frank Poole", Start: 5 * time.Minute, End: 5 * time.Minute, Text: "I'll send the report to Mission Control."},
+	}
+	if !reflect.DeepEqual(tr.Entries, wantEntries) {
+		t.Errorf("Entries =\n%+v\nwant\n%+v", tr.Entries, wantEntries)
+	}
+	if !reflect.DeepEqual(tr.Speakers, []string{"Dave Bowman", "HAL 9000", "Frank Poole"}) {
+		t.Errorf("Speakers = %v", tr.Speakers)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	if _, err := Parse("empty.txt", []byte("\ufeff \n")); err == nil {
+		t.Error("Parse() of an empty export should fail")
+	}
+	if _, err := Parse("broken.docx", []byte("not a zip")); err == nil {
+		t.Error("Parse() of a broken .docx should fail")
+	}
+}
+
+func TestParseTurnsIgnoresProse(t *testing.T) {
+	content := "Alice: We meet again at 10:30\nBob: Fine."
+	if got := parseTurns(content); len(got) != 0 {
+		t.Errorf("parseTurns() = %+v, want none", got)
+	}
+	if got := detectFormat("notes.txt", content); got != FormatUnknown {
+		t.Errorf("detectFormat() = %q, want %q", got, FormatUnknown)
+	}
+}
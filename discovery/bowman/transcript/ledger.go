@@ -0,0 +1,129 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// LedgerFile is the processed-meetings ledger, relative to the library.
+var LedgerFile = filepath.Join(CacheCategory, "processed.json")
+
+// ledgerDoc is the ledger file. It is an object, not a bare array, so
+// library tools that read every .json file as a document (fsck) accept it.
+type ledgerDoc struct {
+	Entries []Processed `json:"entries"`
+}
+
+// Processed records that a task summarized a meeting.
+type Processed struct {
+	Task        string    `json:"task"`
+	EventID     string    `json:"event_id"`
+	EventTitle  string    `json:"event_title,omitempty"`
+	EventTime   time.Time `json:"event_time"`
+	SourceFile  string    `json:"source_file,omitempty"`
+	Output      string    `json:"output,omitempty"` // Entity the summary was stored as
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// Ledger remembers which meetings each task has summarized, so running a
+// task again does not repeat its profile updates and open items.
+type Ledger struct {
+	libraryPath string
+	entries     []Processed
+}
+
+// OpenLedger reads the ledger of a library. A missing ledger is empty.
+func OpenLedger(libraryPath string) (*Ledger, error) {
+	l := &Ledger{libraryPath: expandPath(libraryPath)}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// load reads the ledger's entries from disk.
+func (l *Ledger) load() error {
+	data, err := vault.ReadFile(l.path())
+	if os.IsNotExist(err) {
+		l.entries = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ledger: %w", err)
+	}
+	// Ledgers written before the entries object were a bare array
+	var doc ledgerDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		if json.Unmarshal(data, &doc.Entries) != nil {
+			return fmt.Errorf("failed to read ledger: %w", err)
+		}
+	}
+	l.entries = doc.Entries
+	return nil
+}
+
+// Lookup returns when a task summarized an event, if it has.
+func (l *Ledger) Lookup(task, eventID string) (Processed, bool) {
+	for _, p := range l.entries {
+		if p.Task == task && p.EventID == eventID {
+			return p, true
+		}
+	}
+	return Processed{}, false
+}
+
+// Record adds a summarized meeting to the ledger, replacing an earlier
+// entry for the same task and event, and saves the ledger.
+func (l *Ledger) Record(p Processed) error {
+	if p.ProcessedAt.IsZero() {
+		p.ProcessedAt = time.Now()
+	}
+
+	// Held from re-reading the ledger to writing it, so meetings recorded
+	// by another task running at the same time are kept
+	lock, err := vault.LockLibrary(l.libraryPath)
+	if err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	defer lock.Unlock()
+	if err := l.load(); err != nil {
+		return err
+	}
+
+	kept := l.entries[:0]
+	for _, e := range l.entries {
+		if e.Task != p.Task || e.EventID != p.EventID {
+			kept = append(kept, e)
+		}
+	}
+	l.entries = append(kept, p)
+	sort.SliceStable(l.entries, func(i, j int) bool { return l.entries[i].EventTime.Before(l.entries[j].EventTime) })
+
+	data, err := json.MarshalIndent(ledgerDoc{Entries: l.entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path()), 0755); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	// Written atomically: a crash leaves the old ledger, not a torn one
+	if err := vault.WriteFile(l.path(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	if err := history.RecordIn(l.libraryPath, fmt.Sprintf("%s: processed %s", p.Task, p.EventID), l.path()); err != nil {
+		log.Printf("[bowman][transcript] Warning: history commit failed: %v", err)
+	}
+	return nil
+}
+
+func (l *Ledger) path() string {
+	return filepath.Join(l.libraryPath, LedgerFile)
+}
@@ -0,0 +1,126 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func TestLedger(t *testing.T) {
+	dir := t.TempDir()
+	ledger, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatalf("OpenLedger() error = %v", err)
+	}
+	if _, ok := ledger.Lookup("oneonone", "evt1"); ok {
+		t.Fatal("empty ledger has an entry")
+	}
+
+	when := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	for _, p := range []Processed{
+		{Task: "oneonone", EventID: "evt1", EventTime: when, Output: "interaction/a"},
+		{Task: "collabsummary", EventID: "evt2", EventTime: when.Add(-time.Hour), Output: "collaboration-session/b"},
+		{Task: "oneonone", EventID: "evt1", EventTime: when, Output: "interaction/c"},
+	} {
+		if err := ledger.Record(p); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	reopened, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.entries) != 2 {
+		t.Errorf("ledger has %d entries, want 2", len(reopened.entries))
+	}
+	p, ok := reopened.Lookup("oneonone", "evt1")
+	if !ok || p.Output != "interaction/c" || p.ProcessedAt.IsZero() {
+		t.Errorf("Lookup(oneonone, evt1) = %+v, %v", p, ok)
+	}
+	if _, ok := reopened.Lookup("oneonone", "evt2"); ok {
+		t.Error("Lookup() matched another task's meeting")
+	}
+}
+
+func TestLedgerKeepsConcurrentRecords(t *testing.T) {
+	dir := t.TempDir()
+	first, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two tasks opened the ledger before either recorded a meeting
+	when := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	if err := first.Record(Processed{Task: "oneonone", EventID: "evt1", EventTime: when}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Record(Processed{Task: "collabsummary", EventID: "evt2", EventTime: when}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Lookup("oneonone", "evt1"); !ok {
+		t.Error("first task's record lost")
+	}
+	if _, ok := reopened.Lookup("collabsummary", "evt2"); !ok {
+		t.Error("second task's record lost")
+	}
+}
+
+func TestLedgerPassesFsck(t *testing.T) {
+	dir := t.TempDir()
+	ledger, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Record(Processed{Task: "oneonone", EventID: "evt1", EventTime: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := lmc.New(dir)
+	if err != nil {
+		t.Fatalf("lmc.New() error = %v", err)
+	}
+	report, err := lib.Fsck(lmc.FsckOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("Fsck() error = %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("Fsck() issues = %+v, want none", report.Issues)
+	}
+
+	reopened, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Lookup("oneonone", "evt1"); !ok {
+		t.Error("ledger lost its entry after fsck --fix")
+	}
+}
+
+func TestLedgerReadsArrayFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, LedgerFile)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(`[{"task":"oneonone","event_id":"evt1"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatalf("OpenLedger() error = %v", err)
+	}
+	if _, ok := ledger.Lookup("oneonone", "evt1"); !ok {
+		t.Error("entry from an array ledger not found")
+	}
+}
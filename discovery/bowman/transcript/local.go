@@ -0,0 +1,310 @@
+package transcript
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+)
+
+const (
+	// defaultMeetingLength is assumed when a transcript has no timestamps.
+	defaultMeetingLength = 30 * time.Minute
+
+	// matchSlack widens a transcript's estimated time span when matching it
+	// to calendar events: exports are often saved a while after the meeting.
+	matchSlack = 15 * time.Minute
+)
+
+var (
+	// Zoom names recordings GMT20260127-150405_Recording.transcript.vtt (UTC)
+	zoomNameRe = regexp.MustCompile(`GMT(\d{8})-(\d{6})`)
+	// Meet, Teams and Otter exports carry a local date, often with a time
+	nameDateRe = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})(?:[ T_]+(\d{1,2})[:.h]?(\d{2}))?`)
+)
+
+// Local is a transcript exported from Zoom, Meet, Teams or Otter and read
+// from a file or stdin, for when Drive access is not available. It is
+// matched to a calendar event in the library by time when possible.
+type Local struct {
+	Name        string    // File name, or "stdin"
+	Data        []byte    // Export contents
+	ModTime     time.Time // File modification time; zero for stdin
+	LibraryPath string    // Library holding calendar events; "" skips matching
+}
+
+var _ Source = (*Local)(nil)
+
+// OpenSource returns the transcript source for input: the Google Calendar
+// fetcher, caching in the library, when input is empty; stdin when it is
+// "-"; else the named file.
+func OpenSource(ctx context.Context, input, libraryPath string) (Source, error) {
+	switch input {
+	case "":
+		fetcher, err := NewFetcher(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if libraryPath != "" {
+			fetcher.Cache = &Cache{LibraryPath: libraryPath}
+		}
+		return fetcher, nil
+	case "-":
+		return ReadLocal("stdin", os.Stdin, libraryPath)
+	default:
+		return OpenLocal(input, libraryPath)
+	}
+}
+
+// OpenLocal reads a transcript export from a file.
+func OpenLocal(path, libraryPath string) (*Local, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return &Local{Name: filepath.Base(path), Data: data, ModTime: info.ModTime(), LibraryPath: libraryPath}, nil
+}
+
+// ReadLocal reads a transcript export from r.
+func ReadLocal(name string, r io.Reader, libraryPath string) (*Local, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return &Local{Name: name, Data: data, LibraryPath: libraryPath}, nil
+}
+
+// Fetch parses the export. When its time span overlaps a calendar event in
+// the library the transcript takes that event's ID, title and start;
+// otherwise its ID is derived from the content, so the same export always
+// gets the same ID. An export with no date in its name or file time is
+// dated when it was read.
+func (l *Local) Fetch(ctx context.Context) (*Transcript, error) {
+	tr, _, err := l.fetch()
+	return tr, err
+}
+
+// fetch implements Fetch, reporting whether the meeting's time was known.
+func (l *Local) fetch() (*Transcript, bool, error) {
+	tr, err := Parse(l.Name, l.Data)
+	if err != nil {
+		return nil, false, err
+	}
+	var duration time.Duration
+	for _, e := range tr.Entries {
+		if e.End > duration {
+			duration = e.End
+		}
+	}
+
+	sum := sha256.Sum256(l.Data)
+	tr.EventID = "local-" + hex.EncodeToString(sum[:6])
+	tr.EventTitle = localTitle(l.Name)
+	tr.FetchedAt = time.Now()
+
+	start, end, dated := l.span(duration)
+	if !dated {
+		tr.EventTime = tr.FetchedAt
+		anchorEntries(tr.Entries, tr.EventTime)
+		return tr, false, nil
+	}
+	tr.EventTime = start
+
+	event, found, err := matchEvent(l.LibraryPath, start.Add(-matchSlack), end.Add(matchSlack))
+	if err != nil {
+		return nil, false, err
+	}
+	if found {
+		tr.EventID = event.ID
+		tr.EventTitle = event.Summary
+		tr.EventTime = event.Start
+		tr.Attendees = event.Attendees
+	}
+	anchorEntries(tr.Entries, tr.EventTime)
+	return tr, true, nil
+}
+
+// FetchForEvent returns the transcript as the transcript of the given
+// event, taking its title and start from the library when it is there.
+func (l *Local) FetchForEvent(ctx context.Context, eventID string) (*Transcript, error) {
+	tr, err := l.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tr.EventID = eventID
+
+	events, err := libraryEvents(l.LibraryPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if event.ID == eventID {
+			tr.EventTitle = event.Summary
+			tr.EventTime = event.Start
+			tr.Attendees = event.Attendees
+			anchorEntries(tr.Entries, tr.EventTime)
+			break
+		}
+	}
+	return tr, nil
+}
+
+// FetchForTimeRange returns the transcript if its meeting started in the
+// range. An export with no date cannot be placed in a range.
+func (l *Local) FetchForTimeRange(ctx context.Context, start, end time.Time) ([]*Transcript, error) {
+	tr, dated, err := l.fetch()
+	if err != nil {
+		return nil, err
+	}
+	if !dated {
+		return nil, fmt.Errorf("transcript %s has no date in its name or file time", l.Name)
+	}
+	if tr.EventTime.Before(start) || !tr.EventTime.Before(end) {
+		return nil, nil
+	}
+	return []*Transcript{tr}, nil
+}
+
+// span estimates when the meeting took place: from a date and time in the
+// file name, else as ending when the file was last modified. duration is
+// the transcript's length, if it has timestamps.
+func (l *Local) span(duration time.Duration) (time.Time, time.Time, bool) {
+	if duration <= 0 {
+		duration = defaultMeetingLength
+	}
+	if start, ok := nameTime(l.Name); ok {
+		return start, start.Add(duration), true
+	}
+	if !l.ModTime.IsZero() {
+		return l.ModTime.Add(-duration), l.ModTime, true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// nameTime reads a meeting start from an export's file name. A date
+// without a time is not precise enough to match a meeting.
+func nameTime(name string) (time.Time, bool) {
+	if m := zoomNameRe.FindStringSubmatch(name); m != nil {
+		t, err := time.Parse("20060102150405", m[1]+m[2])
+		return t, err == nil
+	}
+	m := nameDateRe.FindStringSubmatch(name)
+	if m == nil || m[2] == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("%s %02s:%s", m[1], m[2], m[3]), time.Local)
+	return t, err == nil
+}
+
+// localTitle makes a title from an export's file name.
+func localTitle(name string) string {
+	title := name
+	for ext := filepath.Ext(title); ext != "" && len(ext) <= 5; ext = filepath.Ext(title) {
+		title = strings.TrimSuffix(title, ext)
+	}
+	title = strings.TrimSpace(strings.NewReplacer("_", " ").Replace(title))
+	if title == "" {
+		return "Transcript"
+	}
+	return title
+}
+
+// calendarEvent is a timed calendar event from the library.
+type calendarEvent struct {
+	ID        string
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Attendees []Attendee
+}
+
+// libraryEvents reads the latest version of every timed calendar event in
+// the library. All-day events are left out: they overlap every meeting.
+func libraryEvents(libraryPath string) ([]calendarEvent, error) {
+	if libraryPath == "" {
+		return nil, nil
+	}
+	latest, err := bowman.Latest(bowman.StoreConfig{LibraryPath: libraryPath, Category: "calendar"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+
+	var events []calendarEvent
+	for _, v := range latest {
+		data, err := bowman.ReadEvent(v.Path)
+		if err != nil {
+			continue
+		}
+		var doc struct {
+			Summary string `json:"summary"`
+			Start   struct {
+				DateTime string `json:"dateTime"`
+			} `json:"start"`
+			End struct {
+				DateTime string `json:"dateTime"`
+			} `json:"end"`
+			Attendees []struct {
+				Email       string `json:"email"`
+				DisplayName string `json:"displayName"`
+				Self        bool   `json:"self"`
+				Resource    bool   `json:"resource"`
+			} `json:"attendees"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, doc.Start.DateTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, doc.End.DateTime)
+		if err != nil || !end.After(start) {
+			end = start.Add(defaultMeetingLength)
+		}
+		event := calendarEvent{ID: v.EventID, Summary: doc.Summary, Start: start, End: end}
+		for _, a := range doc.Attendees {
+			if !a.Resource {
+				event.Attendees = append(event.Attendees, Attendee{Email: a.Email, Name: a.DisplayName, Self: a.Self})
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// matchEvent returns the calendar event that overlaps start..end the most.
+func matchEvent(libraryPath string, start, end time.Time) (calendarEvent, bool, error) {
+	events, err := libraryEvents(libraryPath)
+	if err != nil {
+		return calendarEvent{}, false, err
+	}
+
+	var best calendarEvent
+	var bestOverlap time.Duration
+	for _, event := range events {
+		from, to := event.Start, event.End
+		if start.After(from) {
+			from = start
+		}
+		if end.Before(to) {
+			to = end
+		}
+		if overlap := to.Sub(from); overlap > bestOverlap {
+			best, bestOverlap = event, overlap
+		}
+	}
+	return best, bestOverlap > 0, nil
+}
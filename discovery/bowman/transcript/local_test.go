@@ -0,0 +1,157 @@
+package transcript
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+)
+
+const zoomVTT = `WEBVTT
+
+1
+00:00:01.000 --> 00:00:04.000
+Dave Bowman: Open the pod bay doors, HAL.
+
+2
+00:00:05.000 --> 00:24:30.500
+HAL: I'm sorry, Dave. I'm afraid I can't do that.
+`
+
+// storeMeeting stores a calendar event in the library.
+func storeMeeting(t *testing.T, lib, id, summary string, start, end time.Time) {
+	t.Helper()
+	_, err := bowman.Store(bowman.StoreConfig{LibraryPath: lib, Category: "calendar"}, bowman.RawEvent{
+		Source:    "google-calendar",
+		EventID:   id,
+		FetchedAt: start,
+		Data: map[string]interface{}{
+			"summary": summary,
+			"start":   map[string]interface{}{"dateTime": start.Format(time.RFC3339)},
+			"end":     map[string]interface{}{"dateTime": end.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalMatchesCalendarEvent(t *testing.T) {
+	lib := t.TempDir()
+	start := time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC)
+	storeMeeting(t, lib, "standup", "Standup", start.Add(-time.Hour), start.Add(-45*time.Minute))
+	storeMeeting(t, lib, "pod-bay", "Pod Bay Review", start, start.Add(30*time.Minute))
+
+	path := filepath.Join(t.TempDir(), "GMT20260127-150212_Recording.transcript.vtt")
+	if err := os.WriteFile(path, []byte(zoomVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	local, err := OpenLocal(path, lib)
+	if err != nil {
+		t.Fatalf("OpenLocal failed: %v", err)
+	}
+	tr, err := local.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if tr.EventID != "pod-bay" || tr.EventTitle != "Pod Bay Review" || !tr.EventTime.Equal(start) {
+		t.Errorf("matched %s %q at %v, want pod-bay", tr.EventID, tr.EventTitle, tr.EventTime)
+	}
+	if tr.Format != FormatZoom || tr.SourceFile != filepath.Base(path) {
+		t.Errorf("format %s, source %s", tr.Format, tr.SourceFile)
+	}
+	if len(tr.Speakers) != 2 || tr.Speakers[0] != "Dave Bowman" || tr.Speakers[1] != "HAL" {
+		t.Errorf("speakers = %v", tr.Speakers)
+	}
+	if strings.Contains(tr.Text, "-->") || !strings.HasPrefix(tr.Text, "Dave Bowman: Open") {
+		t.Errorf("text = %q", tr.Text)
+	}
+	if len(tr.Entries) != 2 || !tr.Entries[1].Timestamp.Equal(start.Add(5*time.Second)) {
+		t.Errorf("entries = %+v, want HAL at %v", tr.Entries, start.Add(5*time.Second))
+	}
+
+	// The range check uses the matched meeting
+	found, _ := local.FetchForTimeRange(context.Background(), start.Add(-time.Minute), start.Add(time.Minute))
+	if len(found) != 1 {
+		t.Errorf("FetchForTimeRange found %d, want 1", len(found))
+	}
+}
+
+func TestLocalStdinUnmatched(t *testing.T) {
+	text := "Dave: Hello, HAL. Do you read me?\nHAL: Affirmative, Dave. I read you.\n"
+	fetch := func() *Transcript {
+		local, err := ReadLocal("stdin", strings.NewReader(text), t.TempDir())
+		if err != nil {
+			t.Fatalf("ReadLocal failed: %v", err)
+		}
+		tr, err := local.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		return tr
+	}
+
+	tr := fetch()
+	if !strings.HasPrefix(tr.EventID, "local-") || tr.EventID != fetch().EventID {
+		t.Errorf("EventID = %q, want a stable local- ID", tr.EventID)
+	}
+	if tr.EventTitle != "stdin" || len(tr.Speakers) != 2 {
+		t.Errorf("title %q, speakers %v", tr.EventTitle, tr.Speakers)
+	}
+
+	local, _ := ReadLocal("stdin", strings.NewReader(text), "")
+	tr, err := local.FetchForEvent(context.Background(), "evt-1")
+	if err != nil || tr.EventID != "evt-1" {
+		t.Errorf("FetchForEvent = %v, %v", tr, err)
+	}
+
+	if _, err := (&Local{Name: "empty.txt"}).Fetch(context.Background()); err == nil {
+		t.Error("expected error for an empty transcript")
+	}
+}
+
+func TestLocalUndated(t *testing.T) {
+	local, err := ReadLocal("stdin", strings.NewReader(zoomVTT), "")
+	if err != nil {
+		t.Fatalf("ReadLocal failed: %v", err)
+	}
+	tr, err := local.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if !tr.EventTime.Equal(tr.FetchedAt) {
+		t.Errorf("EventTime = %v, want FetchedAt %v", tr.EventTime, tr.FetchedAt)
+	}
+	if len(tr.Entries) != 2 || !tr.Entries[1].Timestamp.Equal(tr.EventTime.Add(5*time.Second)) {
+		t.Errorf("entries = %+v, want HAL at %v", tr.Entries, tr.EventTime.Add(5*time.Second))
+	}
+
+	now := time.Now()
+	if found, err := local.FetchForTimeRange(context.Background(), now.Add(-time.Hour), now.Add(time.Hour)); err == nil {
+		t.Errorf("FetchForTimeRange = %v, want an error for an undated export", found)
+	}
+}
+
+func TestNameTime(t *testing.T) {
+	tests := []struct {
+		name string
+		want time.Time
+		ok   bool
+	}{
+		{"GMT20260127-150405_Recording.transcript.vtt", time.Date(2026, 1, 27, 15, 4, 5, 0, time.UTC), true},
+		{"Pod bay review (2026-01-27 09:30 GMT-5) - Transcript.txt", time.Date(2026, 1, 27, 9, 30, 0, 0, time.Local), true},
+		{"standup_2026-01-27.txt", time.Time{}, false},
+		{"meeting.vtt", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := nameTime(tt.name)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("nameTime(%q) = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
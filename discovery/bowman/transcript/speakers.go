@@ -0,0 +1,188 @@
+package transcript
+
+import (
+	"strings"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+// How a speaker label was resolved.
+const (
+	ResolvedSelf     = "self"     // The user, from the self identity config
+	ResolvedAlias    = "alias"    // An alias record in the library
+	ResolvedAttendee = "attendee" // A calendar attendee of the meeting
+	ResolvedPerson   = "person"   // A person entity named like the label
+)
+
+// Speaker is a transcript speaker label resolved to a person.
+type Speaker struct {
+	Label    string `json:"label"`               // As written in the transcript
+	Name     string `json:"name"`                // Attendee name, else the label
+	Email    string `json:"email,omitempty"`     // Attendee email, when matched
+	PersonID string `json:"person_id,omitempty"` // Library entity, e.g. "people/frank-poole"
+	Self     bool   `json:"self,omitempty"`      // The user
+	Via      string `json:"via,omitempty"`       // How it was resolved; "" if it was not
+}
+
+// Resolver maps speaker labels to people: the user's own names and emails,
+// the meeting's calendar attendees, and the alias records kept in the
+// library. Labels it gets wrong are corrected with "hal9000 people alias",
+// which stores an alias record that takes precedence from then on.
+type Resolver struct {
+	Library *lmc.Library // Alias records and people; nil resolves only self and attendees
+	Self    config.SelfConfig
+}
+
+// NewResolver returns a resolver using the configured self identity.
+func NewResolver(lib *lmc.Library) *Resolver {
+	return &Resolver{Library: lib, Self: config.GetSelf()}
+}
+
+// Resolve resolves each of a transcript's speakers.
+func (r *Resolver) Resolve(tr *Transcript) []Speaker {
+	speakers := make([]Speaker, 0, len(tr.Speakers))
+	for _, label := range tr.Speakers {
+		speakers = append(speakers, r.Speaker(label, tr.Attendees))
+	}
+	return speakers
+}
+
+// Speaker resolves one speaker label. An alias record wins over everything
+// else, since it is the user's own correction; then the self identity,
+// then the attendee the label names, then a person entity of that name.
+func (r *Resolver) Speaker(label string, attendees []Attendee) Speaker {
+	s := Speaker{Label: label, Name: label}
+	attendee, matched := matchAttendee(label, attendees)
+	if matched {
+		if attendee.Name != "" {
+			s.Name = attendee.Name
+		}
+		s.Email = attendee.Email
+	}
+
+	if id := r.alias(label); id != "" {
+		s.PersonID, s.Via = id, ResolvedAlias
+		s.Self = r.isSelfID(id)
+		return s
+	}
+
+	if r.isSelfName(label) || (matched && (attendee.Self || r.isSelfEmail(attendee.Email))) {
+		s.Self, s.Via = true, ResolvedSelf
+		return s
+	}
+
+	if matched {
+		s.Via = ResolvedAttendee
+		s.PersonID = r.person(attendee.Email, "people/"+attendee.Email, lmc.PersonID(s.Name))
+		return s
+	}
+
+	if id := r.person(lmc.PersonID(label)); id != "" {
+		s.PersonID, s.Via = id, ResolvedPerson
+	}
+	return s
+}
+
+// alias returns the entity an alias record for label points at, or "".
+func (r *Resolver) alias(label string) string {
+	if r.Library == nil {
+		return ""
+	}
+	id, err := r.Library.Resolve(label)
+	if err != nil || id == label {
+		return ""
+	}
+	return id
+}
+
+// person returns the first identifier that resolves to a library entity.
+func (r *Resolver) person(identifiers ...string) string {
+	if r.Library == nil {
+		return ""
+	}
+	for _, identifier := range identifiers {
+		if identifier == "" || identifier == "people/" {
+			continue
+		}
+		if id, err := r.Library.Resolve(identifier); err == nil {
+			return id
+		}
+	}
+	return ""
+}
+
+func (r *Resolver) isSelfName(label string) bool {
+	key := normalizeName(label)
+	if key == "" {
+		return false
+	}
+	for _, name := range append([]string{r.Self.Name}, r.Self.Aliases...) {
+		if normalizeName(name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) isSelfEmail(email string) bool {
+	for _, e := range r.Self.Emails {
+		if email != "" && strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelfID reports whether an alias points at the user's own entity.
+func (r *Resolver) isSelfID(id string) bool {
+	if r.Self.Name != "" && id == lmc.PersonID(r.Self.Name) {
+		return true
+	}
+	for _, e := range r.Self.Emails {
+		if strings.EqualFold(id, "people/"+e) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAttendee finds the attendee a speaker label names: by display
+// name, by email ("frank.poole@" for "Frank Poole"), or by first name when
+// only one attendee has it.
+func matchAttendee(label string, attendees []Attendee) (Attendee, bool) {
+	key := normalizeName(label)
+	if key == "" {
+		return Attendee{}, false
+	}
+	compact := strings.NewReplacer(" ", "", ".", "", "-", "", "_", "").Replace(key)
+
+	for _, a := range attendees {
+		if normalizeName(a.Name) == key {
+			return a, true
+		}
+	}
+	for _, a := range attendees {
+		local, _, _ := strings.Cut(strings.ToLower(a.Email), "@")
+		if local != "" && strings.NewReplacer(".", "", "-", "", "_", "").Replace(local) == compact {
+			return a, true
+		}
+	}
+	if !strings.Contains(key, " ") {
+		var found []Attendee
+		for _, a := range attendees {
+			if first, _, _ := strings.Cut(normalizeName(a.Name), " "); first == key {
+				found = append(found, a)
+			}
+		}
+		if len(found) == 1 {
+			return found[0], true
+		}
+	}
+	return Attendee{}, false
+}
+
+// normalizeName lowercases a name and collapses whitespace.
+func normalizeName(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
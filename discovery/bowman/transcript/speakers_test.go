@@ -0,0 +1,93 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func TestResolveSpeakers(t *testing.T) {
+	lib, err := lmc.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"frank-poole", "heywood-floyd", "dave-bowman"} {
+		if _, err := lib.Store("people", name, map[string]interface{}{"name": name}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := lib.AddAlias("HAL's iPad", "people/heywood-floyd"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Resolver{Library: lib, Self: config.SelfConfig{
+		Name:    "Dave Bowman",
+		Emails:  []string{"dave@discovery.one"},
+		Aliases: []string{"Dave B."},
+	}}
+	tr := &Transcript{
+		Speakers: []string{"Dave B.", "Frank", "HAL's iPad", "Ralph Halvorsen", "HAL"},
+		Attendees: []Attendee{
+			{Email: "dave@discovery.one", Name: "Dave Bowman", Self: true},
+			{Email: "frank.poole@discovery.one", Name: "Frank Poole"},
+			{Email: "ralph.halvorsen@clavius.moon"},
+		},
+	}
+
+	want := []Speaker{
+		{Label: "Dave B.", Name: "Dave B.", Self: true, Via: ResolvedSelf},
+		{Label: "Frank", Name: "Frank Poole", Email: "frank.poole@discovery.one", PersonID: "people/frank-poole", Via: ResolvedAttendee},
+		{Label: "HAL's iPad", Name: "HAL's iPad", PersonID: "people/heywood-floyd", Via: ResolvedAlias},
+		{Label: "Ralph Halvorsen", Name: "Ralph Halvorsen", Email: "ralph.halvorsen@clavius.moon", Via: ResolvedAttendee},
+		{Label: "HAL", Name: "HAL"},
+	}
+	if got := r.Resolve(tr); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// A correction wins over the attendee match and is remembered
+	if _, err := lib.AddAlias("Frank", "people/dave-bowman"); err != nil {
+		t.Fatal(err)
+	}
+	got := r.Speaker("Frank", tr.Attendees)
+	if got.PersonID != "people/dave-bowman" || got.Via != ResolvedAlias || !got.Self {
+		t.Errorf("Speaker(Frank) after alias = %+v", got)
+	}
+}
+
+func TestResolveSpeakersWithoutLibrary(t *testing.T) {
+	r := &Resolver{Self: config.SelfConfig{Emails: []string{"DAVE@discovery.one"}}}
+	attendees := []Attendee{{Email: "dave@discovery.one", Name: "Dave Bowman"}}
+
+	if got := r.Speaker("dave bowman", attendees); !got.Self || got.Name != "Dave Bowman" {
+		t.Errorf("Speaker() = %+v, want self", got)
+	}
+	if got := r.Speaker("Frank Poole", attendees); got.Via != "" || got.PersonID != "" {
+		t.Errorf("Speaker() = %+v, want unresolved", got)
+	}
+}
+
+func TestMatchAttendee(t *testing.T) {
+	attendees := []Attendee{
+		{Email: "frank.poole@discovery.one", Name: "Frank Poole"},
+		{Email: "frank.mills@discovery.one", Name: "Frank Mills"},
+		{Email: "heywood_floyd@nca.gov"},
+	}
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{"frank  poole", "frank.poole@discovery.one"},
+		{"Heywood Floyd", "heywood_floyd@nca.gov"},
+		{"Frank", ""}, // Ambiguous first name
+		{"Floyd", ""},
+	}
+	for _, tt := range tests {
+		got, _ := matchAttendee(tt.label, attendees)
+		if got.Email != tt.want {
+			t.Errorf("matchAttendee(%q) = %q, want %q", tt.label, got.Email, tt.want)
+		}
+	}
+}
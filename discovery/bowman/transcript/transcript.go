@@ -8,9 +8,9 @@ package transcript
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -18,24 +18,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pearcec/hal9000/discovery/config"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"github.com/pearcec/hal9000/discovery/auth"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 )
 
-// getCredentialsPath returns the path to calendar credentials
-func getCredentialsPath() string {
-	return filepath.Join(config.GetCredentialsDir(), "calendar-credentials.json")
-}
-
-// getTokenPath returns the path to the calendar OAuth token
-func getTokenPath() string {
-	return filepath.Join(config.GetCredentialsDir(), "calendar-token.json")
-}
-
 // TranscriptFormat identifies the source of a transcript.
 type TranscriptFormat string
 
@@ -53,33 +41,64 @@ type Transcript struct {
 	Format      TranscriptFormat `json:"format"`
 	Text        string           `json:"text"`
 	Speakers    []string         `json:"speakers,omitempty"`
+	Entries     []TranscriptEntry `json:"entries,omitempty"`
 	FetchedAt   time.Time        `json:"fetched_at"`
 	SourceFile  string           `json:"source_file,omitempty"`
 	AttachmentID string          `json:"attachment_id,omitempty"`
+
+	// Gemini notes come with Gemini's own summary of the meeting
+	Summary   string   `json:"summary,omitempty"`
+	Details   []string `json:"details,omitempty"`
+	NextSteps []string `json:"next_steps,omitempty"`
+
+	// Calendar attendees of the meeting, when it is known
+	Attendees []Attendee `json:"attendees,omitempty"`
+}
+
+// Attendee is a person invited to the meeting a transcript belongs to.
+type Attendee struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+	Self  bool   `json:"self,omitempty"` // The calendar's owner
 }
 
 // TranscriptEntry represents a single speaker turn in a transcript.
+// Entries from WebVTT and SRT have Start and End offsets from the start of
+// the event, and Timestamp set from them when the event time is known.
 type TranscriptEntry struct {
-	Speaker   string    `json:"speaker"`
-	Timestamp time.Time `json:"timestamp,omitempty"`
-	Text      string    `json:"text"`
+	Speaker   string        `json:"speaker"`
+	Timestamp time.Time     `json:"timestamp,omitempty"`
+	Start     time.Duration `json:"start,omitempty"`
+	End       time.Duration `json:"end,omitempty"`
+	Text      string        `json:"text"`
+}
+
+// Source supplies meeting transcripts: Fetcher from Google Calendar
+// attachments, Local from a downloaded export.
+type Source interface {
+	// FetchForEvent returns the transcript of a calendar event.
+	FetchForEvent(ctx context.Context, eventID string) (*Transcript, error)
+
+	// FetchForTimeRange returns transcripts of meetings in a time range.
+	FetchForTimeRange(ctx context.Context, start, end time.Time) ([]*Transcript, error)
 }
 
+var _ Source = (*Fetcher)(nil)
+
 // Fetcher retrieves transcripts from Google Calendar attachments.
 type Fetcher struct {
 	calendarService *calendar.Service
 	driveService    *drive.Service
 	httpClient      *http.Client
+
+	// Cache, if set, keeps parsed transcripts so unchanged attachments are
+	// not downloaded again
+	Cache *Cache
 }
 
 // NewFetcher creates a new transcript fetcher with authenticated clients.
 func NewFetcher(ctx context.Context) (*Fetcher, error) {
-	config, err := loadOAuthConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load OAuth config: %w", err)
-	}
-
-	client, err := getClient(ctx, config)
+	client, err := auth.GoogleClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
 	}
@@ -113,18 +132,13 @@ func (f *Fetcher) FetchForEvent(ctx context.Context, eventID string) (*Transcrip
 
 // FetchForTimeRange retrieves transcripts for events in a time range.
 func (f *Fetcher) FetchForTimeRange(ctx context.Context, start, end time.Time) ([]*Transcript, error) {
-	events, err := f.calendarService.Events.List("primary").
-		TimeMin(start.Format(time.RFC3339)).
-		TimeMax(end.Format(time.RFC3339)).
-		SingleEvents(true).
-		OrderBy("startTime").
-		Do()
+	events, err := f.listEvents(ctx, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list events: %w", err)
+		return nil, err
 	}
 
 	var transcripts []*Transcript
-	for _, event := range events.Items {
+	for _, event := range events {
 		transcript, err := f.fetchTranscriptFromEvent(ctx, event)
 		if err != nil {
 			continue // Skip events without transcripts
@@ -137,6 +151,25 @@ func (f *Fetcher) FetchForTimeRange(ctx context.Context, start, end time.Time) (
 	return transcripts, nil
 }
 
+// listEvents returns every event in a time range, following the calendar
+// API's pages (250 events each by default).
+func (f *Fetcher) listEvents(ctx context.Context, start, end time.Time) ([]*calendar.Event, error) {
+	var events []*calendar.Event
+	err := f.calendarService.Events.List("primary").
+		TimeMin(start.Format(time.RFC3339)).
+		TimeMax(end.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Pages(ctx, func(page *calendar.Events) error {
+			events = append(events, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	return events, nil
+}
+
 // fetchTranscriptFromEvent extracts transcript from a calendar event.
 func (f *Fetcher) fetchTranscriptFromEvent(ctx context.Context, event *calendar.Event) (*Transcript, error) {
 	// Check for attachments
@@ -169,6 +202,11 @@ func isTranscriptAttachment(attachment *calendar.EventAttachment) bool {
 		return true
 	}
 
+	// Google Meet "Notes by Gemini" docs carry a transcript section
+	if strings.Contains(title, "notes by gemini") {
+		return true
+	}
+
 	// Zoom transcript files
 	if strings.HasSuffix(title, ".vtt") || strings.HasSuffix(title, ".txt") {
 		if strings.Contains(title, "transcript") || strings.Contains(title, "recording") {
@@ -181,83 +219,116 @@ func isTranscriptAttachment(attachment *calendar.EventAttachment) bool {
 
 // fetchAttachmentTranscript downloads and parses a transcript attachment.
 func (f *Fetcher) fetchAttachmentTranscript(ctx context.Context, event *calendar.Event, attachment *calendar.EventAttachment) (*Transcript, error) {
-	var text string
-	var format TranscriptFormat
+	revision := f.attachmentRevision(ctx, attachment.FileId)
+	if cached, ok := f.Cache.Get(event.Id, revision); ok {
+		// Event details may have changed since the transcript was cached
+		cached.EventTitle = event.Summary
+		cached.EventTime = parseEventTime(event)
+		cached.Attendees = eventAttendees(event)
+		anchorEntries(cached.Entries, cached.EventTime)
+		return cached, nil
+	}
+
+	var data []byte
 	var err error
 
 	switch attachment.MimeType {
 	case "application/vnd.google-apps.document":
 		// Google Doc - export as plain text
-		text, err = f.exportGoogleDoc(ctx, attachment.FileId)
-		format = FormatGoogleMeet
-	case "text/vtt":
-		// WebVTT format (Zoom, etc.)
-		text, err = f.downloadFile(ctx, attachment.FileId)
-		format = FormatZoom
-		if err == nil {
-			text = parseVTT(text)
-		}
-	case "text/plain":
-		// Plain text transcript
-		text, err = f.downloadFile(ctx, attachment.FileId)
-		format = detectFormat(text)
+		data, err = f.exportGoogleDoc(ctx, attachment.FileId)
 	default:
-		// Try to download and detect format
-		text, err = f.downloadFile(ctx, attachment.FileId)
-		format = detectFormat(text)
+		// WebVTT, SRT, Word, plain text - detected from content
+		data, err = f.downloadFile(ctx, attachment.FileId)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch attachment content: %w", err)
 	}
 
-	eventTime := parseEventTime(event)
+	transcript, err := Parse(attachment.Title, data)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case transcript.Format != FormatUnknown:
+	case attachment.MimeType == "application/vnd.google-apps.document":
+		transcript.Format = FormatGoogleMeet
+	case attachment.MimeType == "text/vtt":
+		transcript.Format = FormatZoom
+	}
+
+	transcript.EventID = event.Id
+	transcript.EventTitle = event.Summary
+	transcript.EventTime = parseEventTime(event)
+	transcript.FetchedAt = time.Now()
+	transcript.AttachmentID = attachment.FileId
+	transcript.Attendees = eventAttendees(event)
+	anchorEntries(transcript.Entries, transcript.EventTime)
 
-	transcript := &Transcript{
-		EventID:      event.Id,
-		EventTitle:   event.Summary,
-		EventTime:    eventTime,
-		Format:       format,
-		Text:         normalizeTranscript(text),
-		Speakers:     extractSpeakers(text),
-		FetchedAt:    time.Now(),
-		SourceFile:   attachment.Title,
-		AttachmentID: attachment.FileId,
+	if err := f.Cache.Put(transcript, revision); err != nil {
+		log.Printf("[bowman][transcript] Failed to cache transcript for %s: %v", event.Id, err)
 	}
 
 	return transcript, nil
 }
 
+// attachmentRevision identifies the current content of an attachment: its
+// file ID and Drive version, which changes with every edit. It is "" when
+// the version cannot be read, and the attachment is then not cached.
+func (f *Fetcher) attachmentRevision(ctx context.Context, fileID string) string {
+	if f.Cache == nil || f.driveService == nil {
+		return ""
+	}
+	file, err := f.driveService.Files.Get(fileID).Fields("version").Context(ctx).Do()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s@%d", fileID, file.Version)
+}
+
 // exportGoogleDoc exports a Google Doc as plain text.
-func (f *Fetcher) exportGoogleDoc(ctx context.Context, fileID string) (string, error) {
+func (f *Fetcher) exportGoogleDoc(ctx context.Context, fileID string) ([]byte, error) {
 	resp, err := f.driveService.Files.Export(fileID, "text/plain").Context(ctx).Download()
 	if err != nil {
-		return "", fmt.Errorf("failed to export doc: %w", err)
+		return nil, fmt.Errorf("failed to export doc: %w", err)
 	}
 	defer resp.Body.Close()
 
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read doc content: %w", err)
+		return nil, fmt.Errorf("failed to read doc content: %w", err)
 	}
 
-	return string(content), nil
+	return content, nil
 }
 
 // downloadFile downloads a file from Google Drive.
-func (f *Fetcher) downloadFile(ctx context.Context, fileID string) (string, error) {
+func (f *Fetcher) downloadFile(ctx context.Context, fileID string) ([]byte, error) {
 	resp, err := f.driveService.Files.Get(fileID).Context(ctx).Download()
 	if err != nil {
-		return "", fmt.Errorf("failed to download file: %w", err)
+		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file content: %w", err)
+		return nil, fmt.Errorf("failed to read file content: %w", err)
 	}
 
-	return string(content), nil
+	return content, nil
+}
+
+// eventAttendees lists the people invited to a calendar event, leaving out
+// rooms and other resources.
+func eventAttendees(event *calendar.Event) []Attendee {
+	var attendees []Attendee
+	for _, a := range event.Attendees {
+		if a.Resource {
+			continue
+		}
+		attendees = append(attendees, Attendee{Email: a.Email, Name: a.DisplayName, Self: a.Self})
+	}
+	return attendees
 }
 
 // parseEventTime extracts the start time from a calendar event.
@@ -277,48 +348,13 @@ func parseEventTime(event *calendar.Event) time.Time {
 	return time.Time{}
 }
 
-// parseVTT parses WebVTT format transcript into plain text.
-func parseVTT(content string) string {
-	var result strings.Builder
-	lines := strings.Split(content, "\n")
-
-	// Skip header
-	inCue := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Skip timestamps and empty lines
-		if line == "" || line == "WEBVTT" {
-			continue
-		}
-		if strings.Contains(line, "-->") {
-			inCue = true
-			continue
-		}
-		if inCue && line != "" {
-			result.WriteString(line)
-			result.WriteString(" ")
-		}
+// transcriptSpeakers returns the speakers of parsed entries, or those
+// found in the text when it had no entries.
+func transcriptSpeakers(text string, entries []TranscriptEntry) []string {
+	if speakers := entrySpeakers(entries); len(speakers) > 0 {
+		return speakers
 	}
-
-	return strings.TrimSpace(result.String())
-}
-
-// detectFormat attempts to identify the transcript format from content.
-func detectFormat(content string) TranscriptFormat {
-	lower := strings.ToLower(content)
-
-	// Google Meet format markers
-	if strings.Contains(lower, "google meet") || strings.Contains(lower, "meet.google.com") {
-		return FormatGoogleMeet
-	}
-
-	// Zoom format markers
-	if strings.Contains(lower, "zoom") || strings.Contains(content, "WEBVTT") {
-		return FormatZoom
-	}
-
-	return FormatUnknown
+	return extractSpeakers(text)
 }
 
 // extractSpeakers extracts unique speaker names from transcript text.
@@ -360,7 +396,19 @@ func normalizeTranscript(text string) string {
 }
 
 // ParseEntries parses transcript text into individual speaker entries.
+// WebVTT and SRT are parsed as cues; other text as "Speaker: text" lines.
 func ParseEntries(text string) []TranscriptEntry {
+	if isVTT(text) || isSRT(text) {
+		if _, entries := parseTranscript(text); len(entries) > 0 {
+			return entries
+		}
+	}
+	return parseSpeakerLines(text)
+}
+
+// parseSpeakerLines parses "Speaker: text" lines, each optionally prefixed
+// with a time of day.
+func parseSpeakerLines(text string) []TranscriptEntry {
 	var entries []TranscriptEntry
 
 	// Pattern: "Speaker Name: text" or "HH:MM:SS Speaker Name: text"
@@ -387,8 +435,6 @@ func ParseEntries(text string) []TranscriptEntry {
 	return entries
 }
 
-// OAuth helper functions (reused from Floyd)
-
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, _ := os.UserHomeDir()
@@ -396,41 +442,3 @@ func expandPath(path string) string {
 	}
 	return path
 }
-
-func loadOAuthConfig() (*oauth2.Config, error) {
-	path := getCredentialsPath()
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read credentials file: %w", err)
-	}
-
-	config, err := google.ConfigFromJSON(b,
-		calendar.CalendarReadonlyScope,
-		drive.DriveReadonlyScope,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse credentials: %w", err)
-	}
-
-	return config, nil
-}
-
-func getClient(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
-	tokPath := getTokenPath()
-	tok, err := loadToken(tokPath)
-	if err != nil {
-		return nil, fmt.Errorf("no valid token found (run calendar-floyd first): %w", err)
-	}
-	return config.Client(ctx, tok), nil
-}
-
-func loadToken(path string) (*oauth2.Token, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
@@ -1,8 +1,15 @@
 package transcript
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 func TestParseVTT(t *testing.T) {
@@ -15,11 +22,16 @@ Hello, welcome to the meeting.
 Thank you for joining today.
 `
 
-	expected := "Hello, welcome to the meeting. Thank you for joining today."
-	result := parseVTT(input)
-
-	if result != expected {
-		t.Errorf("parseVTT() = %q, want %q", result, expected)
+	entries, err := ParseVTT(input)
+	if err != nil {
+		t.Fatalf("ParseVTT() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ParseVTT() found %d entries, want 2", len(entries))
+	}
+	expected := "Hello, welcome to the meeting.\nThank you for joining today."
+	if result := EntriesText(entries); result != expected {
+		t.Errorf("EntriesText() = %q, want %q", result, expected)
 	}
 }
 
@@ -27,15 +39,16 @@ func TestParseVTTEmpty(t *testing.T) {
 	input := `WEBVTT
 
 `
-	result := parseVTT(input)
-	if result != "" {
-		t.Errorf("parseVTT() for empty = %q, want empty string", result)
+	entries, err := ParseVTT(input)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("ParseVTT() for empty = %v, %v; want no entries", entries, err)
 	}
 }
 
 func TestDetectFormat(t *testing.T) {
 	tests := []struct {
 		name     string
+		file     string
 		content  string
 		expected TranscriptFormat
 	}{
@@ -45,15 +58,27 @@ func TestDetectFormat(t *testing.T) {
 			expected: FormatGoogleMeet,
 		},
 		{
-			name:     "zoom webvtt",
-			content:  "WEBVTT\n\n00:00:00.000 --> 00:00:05.000\nHello",
+			name:     "zoom cues",
+			content:  "WEBVTT\n\n1\n00:00:00.000 --> 00:00:05.000\nDave Bowman: Hello",
 			expected: FormatZoom,
 		},
 		{
-			name:     "zoom keyword",
-			content:  "Zoom Meeting Transcript\nRecording from zoom.us",
+			name:     "zoom recording name",
+			file:     "GMT20260127-150212_Recording.transcript.vtt",
+			content:  "WEBVTT\n\n00:00:00.000 --> 00:00:05.000\nHello",
 			expected: FormatZoom,
 		},
+		{
+			name:     "bare webvtt",
+			file:     "meeting.vtt",
+			content:  "WEBVTT\n\n00:00:00.000 --> 00:00:05.000\nHello",
+			expected: FormatUnknown,
+		},
+		{
+			name:     "mentions zoom",
+			content:  "Notes: we moved the call from Zoom to the conference room",
+			expected: FormatUnknown,
+		},
 		{
 			name:     "unknown",
 			content:  "Just some meeting notes without any markers",
@@ -63,7 +88,7 @@ func TestDetectFormat(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := detectFormat(tt.content)
+			result := detectFormat(tt.file, tt.content)
 			if result != tt.expected {
 				t.Errorf("detectFormat() = %v, want %v", result, tt.expected)
 			}
@@ -229,3 +254,41 @@ func TestTranscriptFormat(t *testing.T) {
 		t.Error("FormatUnknown constant has wrong value")
 	}
 }
+
+func TestListEventsPages(t *testing.T) {
+	// Three pages of events, as the calendar API returns a busy range
+	pages := map[string]calendar.Events{
+		"":   {Items: []*calendar.Event{{Id: "evt1"}, {Id: "evt2"}}, NextPageToken: "p2"},
+		"p2": {Items: []*calendar.Event{{Id: "evt3"}}, NextPageToken: "p3"},
+		"p3": {Items: []*calendar.Event{{Id: "evt4"}}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Query().Get("pageToken")]
+		if !ok {
+			http.Error(w, "unknown page", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	svc, err := calendar.NewService(ctx, option.WithEndpoint(srv.URL), option.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &Fetcher{calendarService: svc}
+
+	end := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	events, err := f.listEvents(ctx, end.AddDate(0, 0, -30), end)
+	if err != nil {
+		t.Fatalf("listEvents() error = %v", err)
+	}
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	if len(ids) != 4 || ids[3] != "evt4" {
+		t.Errorf("events = %v, want all four pages' events", ids)
+	}
+}
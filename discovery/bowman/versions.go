@@ -0,0 +1,217 @@
+package bowman
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// Version is one stored capture of an event. Each Store of changed content
+// adds a version; _meta.previous_path links it to the one before.
+type Version struct {
+	EventID      string    `json:"event_id"`
+	Version      int       `json:"version"`
+	Path         string    `json:"path"`
+	PreviousPath string    `json:"previous_path,omitempty"` // Library-relative
+	Hash         string    `json:"hash,omitempty"`          // sha256:<hex> of the event's data
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// versionMeta is the part of a stored event's _meta that versioning reads.
+type versionMeta struct {
+	EventID      string `json:"event_id"`
+	FetchedAt    string `json:"fetched_at"`
+	Version      int    `json:"version"`
+	PreviousPath string `json:"previous_path"`
+	Hash         string `json:"hash"`
+}
+
+// Versions returns the stored versions of an event, oldest first. Events
+// stored before versioning count as versions in fetch order.
+func Versions(config StoreConfig, eventID string) ([]Version, error) {
+	categoryPath := filepath.Join(expandPath(config.LibraryPath), config.Category)
+	paths, err := eventFiles(categoryPath, config.Category, sanitizeFilename(eventID))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, path := range paths {
+		v, err := readVersion(path)
+		if err != nil {
+			continue // Not a stored event
+		}
+		if v.EventID == "" {
+			v.EventID = eventID
+		}
+		// Sanitized IDs can collide; the stored ID decides
+		if v.EventID == eventID {
+			versions = append(versions, v)
+		}
+	}
+	return orderVersions(versions), nil
+}
+
+// Latest returns the newest version of every event stored in the category,
+// ordered by path. Readers use it rather than globbing the category, which
+// also returns superseded versions.
+func Latest(config StoreConfig) ([]Version, error) {
+	categoryPath := filepath.Join(expandPath(config.LibraryPath), config.Category)
+	paths, err := filepath.Glob(filepath.Join(categoryPath, config.Category+"_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	byEvent := make(map[string][]Version)
+	for _, path := range paths {
+		v, err := readVersion(path)
+		if err != nil || v.EventID == "" {
+			continue
+		}
+		byEvent[v.EventID] = append(byEvent[v.EventID], v)
+	}
+
+	latest := make([]Version, 0, len(byEvent))
+	for _, versions := range byEvent {
+		versions = orderVersions(versions)
+		latest = append(latest, versions[len(versions)-1])
+	}
+	sort.Slice(latest, func(i, j int) bool { return latest[i].Path < latest[j].Path })
+	return latest, nil
+}
+
+// orderVersions sorts versions oldest first and numbers unversioned ones,
+// which were all written before any numbered version, by fetch time.
+func orderVersions(versions []Version) []Version {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := versions[i], versions[j]
+		if (a.Version == 0) != (b.Version == 0) {
+			return a.Version == 0
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		if !a.FetchedAt.Equal(b.FetchedAt) {
+			return a.FetchedAt.Before(b.FetchedAt)
+		}
+		return a.Path < b.Path
+	})
+	for i := range versions {
+		if versions[i].Version == 0 {
+			versions[i].Version = i + 1
+		}
+	}
+	return versions
+}
+
+// readVersion reads the versioning fields of a stored event.
+func readVersion(path string) (Version, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return Version{}, err
+	}
+	var doc struct {
+		Meta *versionMeta `json:"_meta"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Version{}, err
+	}
+	if doc.Meta == nil {
+		return Version{}, fmt.Errorf("%s has no _meta", filepath.Base(path))
+	}
+	fetched, _ := time.Parse(time.RFC3339, doc.Meta.FetchedAt)
+	return Version{
+		EventID:      doc.Meta.EventID,
+		Version:      doc.Meta.Version,
+		Path:         path,
+		PreviousPath: doc.Meta.PreviousPath,
+		Hash:         doc.Meta.Hash,
+		FetchedAt:    fetched,
+	}, nil
+}
+
+// versionHash returns the content hash of a stored version, computing it
+// for events stored before hashes were recorded.
+func versionHash(v Version) (string, error) {
+	if v.Hash != "" {
+		return v.Hash, nil
+	}
+	data, err := ReadEvent(v.Path)
+	if err != nil {
+		return "", err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", err
+	}
+	delete(doc, "_meta")
+	return contentHash(doc)
+}
+
+// contentHash hashes an event's data. Map keys marshal sorted, so equal
+// data hashes equal regardless of the order a source returned it in.
+func contentHash(data map[string]interface{}) (string, error) {
+	out, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(out)
+	return blobPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// versionFilename names a version: {category}_{date}_{eventid}.json for the
+// first, with .v{N} before the extension for later ones, so a change made
+// the same day as the previous capture does not overwrite it.
+func versionFilename(category string, fetchedAt time.Time, safeID string, version int) string {
+	name := fmt.Sprintf("%s_%s_%s", category, fetchedAt.Format("2006-01-02"), safeID)
+	if version > 1 {
+		name += ".v" + strconv.Itoa(version)
+	}
+	return name + ".json"
+}
+
+// eventFiles returns the stored files of an event. The glob alone would
+// also match events whose IDs end in this one, so names are checked.
+func eventFiles(categoryPath, category, safeID string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(categoryPath, category+"_*_"+safeID+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range matches {
+		name := strings.TrimPrefix(filepath.Base(path), category+"_")
+		if len(name) < len("2006-01-02_") || name[len("2006-01-02")] != '_' {
+			continue
+		}
+		rest := strings.TrimSuffix(name[len("2006-01-02_"):], ".json")
+		if rest == safeID {
+			paths = append(paths, path)
+			continue
+		}
+		if n, ok := strings.CutPrefix(rest, safeID+".v"); ok {
+			if _, err := strconv.Atoi(n); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// libraryRelative returns path relative to the library, with forward
+// slashes, or path itself if it is outside it.
+func libraryRelative(libPath, path string) string {
+	rel, err := filepath.Rel(libPath, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,157 @@
+package bowman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreVersionChain(t *testing.T) {
+	lib := t.TempDir()
+	config := StoreConfig{LibraryPath: lib, Category: "jira"}
+	day := time.Date(2026, 1, 27, 9, 0, 0, 0, time.UTC)
+
+	store := func(status string, at time.Time) string {
+		path, err := Store(config, RawEvent{
+			Source:    "jira",
+			EventID:   "PROJ-1",
+			FetchedAt: at,
+			Data:      map[string]interface{}{"key": "PROJ-1", "status": status},
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		return filepath.Base(path)
+	}
+
+	if got := store("Open", day); got != "jira_2026-01-27_PROJ-1.json" {
+		t.Errorf("first version = %s", got)
+	}
+	// Same content: no new version
+	if got := store("Open", day.Add(time.Hour)); got != "jira_2026-01-27_PROJ-1.json" {
+		t.Errorf("unchanged store = %s, want the existing version", got)
+	}
+	// Changed the same day: kept alongside, not overwritten
+	if got := store("In Progress", day.Add(2*time.Hour)); got != "jira_2026-01-27_PROJ-1.v2.json" {
+		t.Errorf("same-day version = %s", got)
+	}
+	if got := store("Done", day.AddDate(0, 0, 1)); got != "jira_2026-01-28_PROJ-1.v3.json" {
+		t.Errorf("next-day version = %s", got)
+	}
+
+	versions, err := Versions(config, "PROJ-1")
+	if err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("versions = %d, want 3", len(versions))
+	}
+	for i, v := range versions {
+		if v.Version != i+1 || v.Hash == "" {
+			t.Errorf("version %d = %+v", i+1, v)
+		}
+	}
+	if versions[0].PreviousPath != "" || versions[2].PreviousPath != "jira/jira_2026-01-27_PROJ-1.v2.json" {
+		t.Errorf("previous paths = %q, %q", versions[0].PreviousPath, versions[2].PreviousPath)
+	}
+
+	latest, err := Latest(config)
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if len(latest) != 1 || latest[0].Version != 3 {
+		t.Errorf("Latest = %+v, want version 3", latest)
+	}
+
+	if err := Delete(config, "PROJ-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(lib, "jira", "*.json")); len(left) != 0 {
+		t.Errorf("Delete left %v", left)
+	}
+}
+
+func TestVersionsUnversionedFiles(t *testing.T) {
+	lib := t.TempDir()
+	dir := filepath.Join(lib, "jira")
+	os.MkdirAll(dir, 0755)
+
+	// Captures written before versioning, and another event whose ID ends
+	// in this one's
+	write := func(name, eventID, fetched, status string) {
+		doc := `{"_meta": {"event_id": "` + eventID + `", "fetched_at": "` + fetched + `", "stage": "raw"}, "status": "` + status + `"}`
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("jira_2026-01-28_PROJ-1.json", "PROJ-1", "2026-01-28T09:00:00Z", "In Progress")
+	write("jira_2026-01-27_PROJ-1.json", "PROJ-1", "2026-01-27T09:00:00Z", "Open")
+	write("jira_2026-01-27_X_PROJ-1.json", "X_PROJ-1", "2026-01-27T09:00:00Z", "Open")
+
+	config := StoreConfig{LibraryPath: lib, Category: "jira"}
+	versions, err := Versions(config, "PROJ-1")
+	if err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if len(versions) != 2 || filepath.Base(versions[1].Path) != "jira_2026-01-28_PROJ-1.json" || versions[1].Version != 2 {
+		t.Fatalf("versions = %+v", versions)
+	}
+
+	// Storing the latest content again is recognised without a stored hash
+	path, err := Store(config, RawEvent{
+		Source:    "jira",
+		EventID:   "PROJ-1",
+		FetchedAt: time.Date(2026, 1, 29, 9, 0, 0, 0, time.UTC),
+		Data:      map[string]interface{}{"status": "In Progress"},
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if filepath.Base(path) != "jira_2026-01-28_PROJ-1.json" {
+		t.Errorf("Store wrote %s for unchanged content", filepath.Base(path))
+	}
+
+	latest, _ := Latest(config)
+	if len(latest) != 2 {
+		t.Errorf("Latest = %d events, want 2", len(latest))
+	}
+}
+
+func TestStoreConcurrentVersions(t *testing.T) {
+	lib := t.TempDir()
+	config := StoreConfig{LibraryPath: lib, Category: "jira"}
+	day := time.Date(2026, 1, 27, 9, 0, 0, 0, time.UTC)
+
+	const writers = 8
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			_, err := Store(config, RawEvent{
+				Source:    "jira",
+				EventID:   "PROJ-1",
+				FetchedAt: day,
+				Data:      map[string]interface{}{"key": "PROJ-1", "comment": i},
+			})
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := Versions(config, "PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != writers {
+		t.Fatalf("stored %d versions, want %d", len(versions), writers)
+	}
+	for i, v := range versions {
+		if v.Version != i+1 {
+			t.Errorf("version %d has number %d", i, v.Version)
+		}
+	}
+}
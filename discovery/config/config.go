@@ -21,11 +21,37 @@ var (
 // Config holds the HAL 9000 configuration.
 type Config struct {
 	Library LibraryConfig `yaml:"library"`
+	Self    SelfConfig    `yaml:"self"`
+}
+
+// SelfConfig identifies the user, so transcripts and calendars can tell
+// them apart from the people they meet with.
+type SelfConfig struct {
+	Name    string   `yaml:"name"`
+	Emails  []string `yaml:"emails"`
+	Aliases []string `yaml:"aliases"` // Other names transcripts show, e.g. "Dave B."
 }
 
 // LibraryConfig holds library-related configuration.
 type LibraryConfig struct {
-	Path string `yaml:"path"`
+	Path       string          `yaml:"path"`
+	Validation string          `yaml:"validation"` // Schema validation mode: off, warn (default), strict
+	History    HistoryConfig   `yaml:"history"`
+	Retention  []RetentionRule `yaml:"retention"`
+}
+
+// RetentionRule moves documents of one category and stage into monthly
+// archives once they are older than OlderThan.
+type RetentionRule struct {
+	Category  string `yaml:"category"`   // Library folder, e.g. "slack"
+	Stage     string `yaml:"stage"`      // raw (default), bronze or silver
+	OlderThan string `yaml:"older_than"` // e.g. "90d", "12w", "2160h"
+}
+
+// HistoryConfig controls git-backed versioning of the library.
+type HistoryConfig struct {
+	Enabled bool   `yaml:"enabled"` // Commit every write HAL makes to the library
+	Remote  string `yaml:"remote"`  // Bare repository for "hal9000 library sync"
 }
 
 var (
@@ -50,6 +76,12 @@ const (
 
 	// DefaultLibraryPath is the default library path when no config is present.
 	DefaultLibraryPath = "./library"
+
+	// DefaultSchemasDir is the default location for per-type entity schemas.
+	DefaultSchemasDir = "./.hal9000/schemas"
+
+	// DefaultTransformsDir is the default location for YAML processor transforms.
+	DefaultTransformsDir = "./.hal9000/transforms"
 )
 
 // Load loads the configuration from the default path.
@@ -159,6 +191,53 @@ func GetConfigDir() string {
 	return expandPath(DefaultConfigDir)
 }
 
+// GetSchemasDir returns the absolute path to the entity schemas directory.
+func GetSchemasDir() string {
+	return expandPath(DefaultSchemasDir)
+}
+
+// GetTransformsDir returns the absolute path to the processor transforms directory.
+func GetTransformsDir() string {
+	return expandPath(DefaultTransformsDir)
+}
+
+// GetLibraryHistory returns the library versioning settings.
+func GetLibraryHistory() HistoryConfig {
+	cfg, err := Load()
+	if err != nil || cfg == nil {
+		return HistoryConfig{}
+	}
+	return cfg.Library.History
+}
+
+// GetLibraryRetention returns the configured retention rules.
+func GetLibraryRetention() []RetentionRule {
+	cfg, err := Load()
+	if err != nil || cfg == nil {
+		return nil
+	}
+	return cfg.Library.Retention
+}
+
+// GetLibraryValidation returns the configured schema validation mode.
+// Defaults to "warn" when unset.
+func GetLibraryValidation() string {
+	cfg, err := Load()
+	if err != nil || cfg == nil || cfg.Library.Validation == "" {
+		return "warn"
+	}
+	return cfg.Library.Validation
+}
+
+// GetSelf returns the user's configured identity.
+func GetSelf() SelfConfig {
+	cfg, err := Load()
+	if err != nil || cfg == nil {
+		return SelfConfig{}
+	}
+	return cfg.Self
+}
+
 // GetCredentialsDir returns the absolute path to the credentials directory.
 func GetCredentialsDir() string {
 	return expandPath(DefaultCredentialsDir)
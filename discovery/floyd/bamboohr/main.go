@@ -18,8 +18,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/bowman/bamboohr"
 	"github.com/pearcec/hal9000/discovery/config"
 	evbus "github.com/pearcec/hal9000/discovery/events"
+	"github.com/pearcec/hal9000/discovery/lmc"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 const (
@@ -110,6 +113,13 @@ func main() {
 		log.Fatalf("Unable to load config: %v", err)
 	}
 
+	// People profiles are synced from the org chart when the directory changes
+	client := bamboohr.NewClient(bamboohr.Config{Subdomain: cfg.Subdomain, APIKey: cfg.APIKey})
+	lib, err := lmc.New(config.GetLibraryPath())
+	if err != nil {
+		log.Printf("[floyd][watcher] Unable to open library, people sync disabled: %v", err)
+	}
+
 	log.Printf("[floyd][watcher] BambooHR Floyd online. Watching: %s.bamboohr.com", cfg.Subdomain)
 
 	// Load or initialize state
@@ -126,6 +136,9 @@ func main() {
 			}
 			state = newState
 			saveState(state)
+			if len(changeEvents) > 0 && lib != nil {
+				syncPeople(client, lib)
+			}
 		}
 
 		time.Sleep(pollInterval)
@@ -144,7 +157,7 @@ func expandPath(path string) string {
 // loadConfig loads BambooHR configuration from file.
 func loadConfig() (*Config, error) {
 	path := getConfigPath()
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read config file %s: %v\n\nCreate config with:\n%s", path, err, configExample())
 	}
@@ -301,6 +314,22 @@ func storeEmployee(employee BambooEmployee) error {
 	return result.Error
 }
 
+// syncPeople updates people profiles from the org chart.
+func syncPeople(client *bamboohr.Client, lib *lmc.Library) {
+	employees, err := client.GetEmployees()
+	if err != nil {
+		log.Printf("Error fetching org chart: %v", err)
+		return
+	}
+	result, err := bamboohr.Sync(lib, employees, bamboohr.SyncOptions{})
+	if err != nil {
+		log.Printf("Error syncing people: %v", err)
+		return
+	}
+	log.Printf("[floyd][watcher] People synced: %d created, %d updated, %d title/manager changes",
+		len(result.Created), len(result.Updated), len(result.Changes))
+}
+
 // hashEmployee creates a hash of employee data to detect changes.
 func hashEmployee(employee BambooEmployee) string {
 	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s|%s",
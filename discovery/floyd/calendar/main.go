@@ -11,16 +11,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/pearcec/hal9000/discovery/auth"
 	"github.com/pearcec/hal9000/discovery/config"
 	evbus "github.com/pearcec/hal9000/discovery/events"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/option"
 )
@@ -30,16 +28,6 @@ const (
 	pollInterval = 5 * time.Minute
 )
 
-// getCredentialsPath returns the path to calendar credentials
-func getCredentialsPath() string {
-	return filepath.Join(config.GetCredentialsDir(), "calendar-credentials.json")
-}
-
-// getTokenPath returns the path to the calendar OAuth token
-func getTokenPath() string {
-	return filepath.Join(config.GetCredentialsDir(), "calendar-token.json")
-}
-
 // eventBus is the event bus for storage operations.
 // Floyd emits events here; storage subscriber handles persistence.
 var eventBus *evbus.Bus
@@ -68,14 +56,8 @@ func main() {
 
 	ctx := context.Background()
 
-	// Load OAuth2 config
-	config, err := loadOAuthConfig()
-	if err != nil {
-		log.Fatalf("Unable to load OAuth config: %v", err)
-	}
-
-	// Get authenticated client
-	client, err := getClient(ctx, config)
+	// Get authenticated client; refreshed tokens are saved for the next start
+	client, err := auth.GoogleClient(ctx)
 	if err != nil {
 		log.Fatalf("Unable to get authenticated client: %v", err)
 	}
@@ -117,108 +99,6 @@ func expandPath(path string) string {
 	return path
 }
 
-// loadOAuthConfig loads OAuth2 configuration from credentials file.
-func loadOAuthConfig() (*oauth2.Config, error) {
-	path := getCredentialsPath()
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read credentials file: %v", err)
-	}
-
-	config, err := google.ConfigFromJSON(b, calendar.CalendarReadonlyScope)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse credentials: %v", err)
-	}
-
-	return config, nil
-}
-
-// getClient retrieves an authenticated HTTP client.
-func getClient(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
-	tokPath := getTokenPath()
-	tok, err := loadToken(tokPath)
-	if err != nil {
-		tok, err = getTokenFromWeb(ctx, config)
-		if err != nil {
-			return nil, err
-		}
-		saveToken(tokPath, tok)
-	}
-	return config.Client(ctx, tok), nil
-}
-
-// loadToken loads a token from file.
-func loadToken(path string) (*oauth2.Token, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
-
-// saveToken saves a token to file.
-func saveToken(path string, token *oauth2.Token) {
-	f, err := os.Create(path)
-	if err != nil {
-		log.Printf("Unable to save token: %v", err)
-		return
-	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
-	log.Printf("Token saved to %s", path)
-}
-
-// getTokenFromWeb uses OAuth2 flow to get a token.
-func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
-	// Use localhost callback for desktop app
-	config.RedirectURL = "http://localhost:8089/callback"
-
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("\nOpen the following URL in your browser:\n\n%s\n\n", authURL)
-
-	// Start local server to receive callback
-	codeChan := make(chan string)
-	errChan := make(chan error)
-
-	server := &http.Server{Addr: ":8089"}
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		if code == "" {
-			errChan <- fmt.Errorf("no code in callback")
-			return
-		}
-		fmt.Fprintf(w, "Authorization successful. You may close this window.")
-		codeChan <- code
-	})
-
-	go func() {
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			errChan <- err
-		}
-	}()
-
-	var code string
-	select {
-	case code = <-codeChan:
-	case err := <-errChan:
-		return nil, err
-	case <-time.After(2 * time.Minute):
-		return nil, fmt.Errorf("timeout waiting for authorization")
-	}
-
-	server.Shutdown(ctx)
-
-	tok, err := config.Exchange(ctx, code)
-	if err != nil {
-		return nil, fmt.Errorf("unable to exchange code for token: %v", err)
-	}
-
-	return tok, nil
-}
-
 // watchCalendar checks for calendar changes and returns events.
 func watchCalendar(srv *calendar.Service, state FloydState) ([]Event, FloydState, error) {
 	var events []Event
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pearcec/hal9000/discovery/config"
 	evbus "github.com/pearcec/hal9000/discovery/events"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 const (
@@ -130,7 +131,7 @@ func expandPath(path string) string {
 // loadConfig loads Slack configuration from file.
 func loadConfig() (*Config, error) {
 	path := getConfigPath()
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read config file %s: %v\n\nCreate config with:\n%s", path, err, configExample())
 	}
@@ -0,0 +1,446 @@
+// Package history keeps a git history of the library for HAL 9000.
+// "I've still got the greatest enthusiasm and confidence in the mission.
+// And I want to help you."
+//
+// When library.history.enabled is set in .hal9000/config.yaml, every write
+// HAL makes to the library (LMC entities, raw events, processed documents,
+// task outputs, preferences) is committed to a git repository in the library
+// directory. Only the files HAL wrote are staged, so hand edits stay
+// uncommitted until the user commits them.
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/config"
+)
+
+const (
+	// AuthorName and AuthorEmail identify HAL's commits.
+	AuthorName  = "HAL 9000"
+	AuthorEmail = "hal9000@discovery.one"
+
+	// DefaultBranch is the branch created by Init.
+	DefaultBranch = "main"
+
+	// lockFileName serializes HAL's git operations across processes.
+	lockFileName = ".history.lock"
+
+	// initialSubject is the message of the snapshot commit made by Init.
+	initialSubject = "Initial library snapshot"
+
+	// maxBodyLines caps the change list in a commit message.
+	maxBodyLines = 50
+)
+
+// gitignore keeps runtime files out of the history.
+const gitignore = `# Managed by HAL 9000
+.lmc.lock
+.history.lock
+.quarantine/
+.*.tmp-*
+`
+
+// Repo is a git repository rooted at a library directory.
+type Repo struct {
+	Dir string
+}
+
+// Commit is one entry in the library history.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Time    time.Time `json:"time"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body,omitempty"`
+	Initial bool      `json:"initial,omitempty"` // The snapshot taken by Init
+}
+
+// LogOptions filters Log.
+type LogOptions struct {
+	Path    string    // Only commits touching this path (relative or absolute)
+	Since   time.Time // Only commits after this time
+	HALOnly bool      // Only commits authored by HAL
+	Limit   int       // Max results (0 = all)
+}
+
+// Open returns the repository for a library directory. It does not check
+// whether history is enabled or the repository exists.
+func Open(dir string) *Repo {
+	return &Repo{Dir: dir}
+}
+
+// ForLibrary returns the repository for dir if history is enabled in the
+// config, or nil otherwise.
+func ForLibrary(dir string) *Repo {
+	if !config.GetLibraryHistory().Enabled {
+		return nil
+	}
+	return Open(dir)
+}
+
+// Record commits paths in the configured library with message. It is a
+// no-op when history is disabled; paths outside the library are ignored.
+func Record(message string, paths ...string) error {
+	return RecordIn(config.GetLibraryPath(), message, paths...)
+}
+
+// RecordIn is Record for a library at dir.
+func RecordIn(dir, message string, paths ...string) error {
+	repo := ForLibrary(dir)
+	if repo == nil {
+		return nil
+	}
+	return repo.Commit(message, paths...)
+}
+
+// Initialized reports whether the repository exists.
+func (r *Repo) Initialized() bool {
+	_, err := os.Stat(filepath.Join(r.Dir, ".git"))
+	return err == nil
+}
+
+// Init creates the repository and commits a snapshot of the current
+// library. It does nothing if the repository already exists.
+func (r *Repo) Init() error {
+	lock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+	return r.init()
+}
+
+// init creates the repository, leaving exclude out of the snapshot so the
+// write that triggered it gets its own commit. Caller must hold the lock.
+func (r *Repo) init(exclude ...string) error {
+	if r.Initialized() {
+		return nil
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	if _, err := r.git("init", "-q"); err != nil {
+		return err
+	}
+	if _, err := r.git("symbolic-ref", "HEAD", "refs/heads/"+DefaultBranch); err != nil {
+		return err
+	}
+
+	ignorePath := filepath.Join(r.Dir, ".gitignore")
+	if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
+		if err := os.WriteFile(ignorePath, []byte(gitignore), 0644); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.git("add", "-A"); err != nil {
+		return err
+	}
+	for _, path := range exclude {
+		if rel, ok := r.relative(path); ok {
+			if _, err := r.git("rm", "-q", "-r", "--cached", "--ignore-unmatch", "--", rel); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := r.git("commit", "-q", "--allow-empty", "-m", initialSubject)
+	return err
+}
+
+// Commit stages paths (written or deleted) and commits them as HAL. The
+// repository is initialized on first use. Nothing is committed when the
+// paths are unchanged.
+func (r *Repo) Commit(message string, paths ...string) error {
+	lock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	if err := r.init(paths...); err != nil {
+		return fmt.Errorf("failed to initialize library history: %w", err)
+	}
+
+	for _, path := range paths {
+		rel, ok := r.relative(path)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(r.Dir, rel)); err == nil {
+			_, err = r.git("add", "-A", "--", rel)
+			if err != nil {
+				return err
+			}
+		} else if _, err := r.git("rm", "-q", "--cached", "--ignore-unmatch", "--", rel); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.git("diff", "--cached", "--quiet"); err == nil {
+		return nil // Nothing staged
+	}
+	_, err = r.git("commit", "-q", "-m", message)
+	return err
+}
+
+// Log returns commits, newest first.
+func (r *Repo) Log(opts LogOptions) ([]Commit, error) {
+	if !r.Initialized() {
+		return nil, nil
+	}
+
+	args := []string{"log", "--format=%H%x1f%an%x1f%aI%x1f%P%x1f%s%x1f%b%x1e"}
+	if opts.Limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", opts.Limit))
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since="+opts.Since.Format(time.RFC3339))
+	}
+	if opts.HALOnly {
+		args = append(args, "--author="+AuthorName)
+	}
+	if opts.Path != "" {
+		rel, ok := r.relative(opts.Path)
+		if !ok {
+			return nil, fmt.Errorf("%s is outside the library", opts.Path)
+		}
+		args = append(args, "--", rel)
+	}
+
+	out, err := r.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), "\x1f")
+		if len(fields) < 6 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, fields[2])
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Time:    t,
+			Subject: fields[4],
+			Body:    strings.TrimSpace(fields[5]),
+			Initial: fields[3] == "",
+		})
+	}
+	return commits, nil
+}
+
+// Revert undoes commits in a single new commit. If any commit does not
+// revert cleanly, nothing is changed. The initial snapshot cannot be
+// reverted.
+func (r *Repo) Revert(revs ...string) (*Commit, error) {
+	if len(revs) == 0 {
+		return nil, fmt.Errorf("nothing to revert")
+	}
+	if !r.Initialized() {
+		return nil, fmt.Errorf("library history is not initialized")
+	}
+
+	lock, err := r.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	var hashes []string
+	for _, rev := range revs {
+		hash, err := r.git("rev-parse", "--verify", rev+"^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("unknown commit %s", rev)
+		}
+		if _, err := r.git("rev-parse", "--verify", "-q", rev+"^"); err != nil {
+			return nil, fmt.Errorf("cannot revert the initial snapshot %s", rev)
+		}
+		hashes = append(hashes, strings.TrimSpace(hash))
+	}
+	// Newest first, so later changes are undone before the ones they build on
+	out, err := r.git("log", "--format=%H %h %s", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+	var short, changes []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		full, rest, _ := strings.Cut(line, " ")
+		if !wanted[full] {
+			continue
+		}
+		delete(wanted, full)
+		hash, _, _ := strings.Cut(rest, " ")
+		short = append(short, hash)
+		changes = append(changes, rest)
+	}
+	if len(wanted) > 0 {
+		return nil, fmt.Errorf("commit is not on the current branch")
+	}
+
+	args := append([]string{"revert", "--no-commit"}, short...)
+	if _, err := r.git(args...); err != nil {
+		r.git("revert", "--abort")
+		return nil, fmt.Errorf("revert failed (library left unchanged): %w", err)
+	}
+
+	message := Message(fmt.Sprintf("Revert %d changes", len(short)), changes)
+	if len(short) == 1 {
+		_, subject, _ := strings.Cut(changes[0], " ")
+		message = fmt.Sprintf("Revert %q", subject)
+	}
+	if _, err := r.git("commit", "-q", "-m", message); err != nil {
+		r.git("revert", "--abort")
+		return nil, err
+	}
+
+	commits, err := r.Log(LogOptions{Limit: 1})
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+	return &commits[0], nil
+}
+
+// Sync pushes the current branch to remote, a path or URL. A local remote
+// path that does not exist is created as a bare repository.
+func (r *Repo) Sync(remote string) error {
+	if remote == "" {
+		return fmt.Errorf("no remote configured (set library.history.remote in config.yaml)")
+	}
+	if !r.Initialized() {
+		return fmt.Errorf("library history is not initialized")
+	}
+
+	if !strings.Contains(remote, "://") && !strings.Contains(remote, "@") {
+		if _, err := os.Stat(remote); os.IsNotExist(err) {
+			if err := os.MkdirAll(remote, 0755); err != nil {
+				return err
+			}
+			if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to create remote: %s", strings.TrimSpace(string(out)))
+			}
+			exec.Command("git", "--git-dir", remote, "symbolic-ref", "HEAD", "refs/heads/"+DefaultBranch).Run()
+		}
+	}
+
+	lock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	branch, err := r.git("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+	branch = strings.TrimSpace(branch)
+	_, err = r.git("push", "-q", remote, "HEAD:refs/heads/"+branch)
+	return err
+}
+
+// Message builds a commit message from a subject and a list of changes.
+// Long change lists are truncated.
+func Message(subject string, changes []string) string {
+	if len(changes) <= 1 && subject == "" {
+		return strings.Join(changes, "")
+	}
+	if subject == "" {
+		subject = fmt.Sprintf("%d changes", len(changes))
+	}
+	if len(changes) <= 1 {
+		return subject
+	}
+
+	var b strings.Builder
+	b.WriteString(subject + "\n")
+	for i, change := range changes {
+		if i == maxBodyLines {
+			fmt.Fprintf(&b, "\n... and %d more", len(changes)-i)
+			break
+		}
+		b.WriteString("\n" + change)
+	}
+	return b.String()
+}
+
+// relative returns path relative to the repository, and false if it lies
+// outside it.
+func (r *Repo) relative(path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.Dir, path)
+	}
+	dir, err := filepath.Abs(r.Dir)
+	if err != nil {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// git runs a git command in the repository as HAL.
+func (r *Repo) git(args ...string) (string, error) {
+	full := append([]string{
+		"-c", "user.name=" + AuthorName,
+		"-c", "user.email=" + AuthorEmail,
+		"-c", "commit.gpgsign=false",
+	}, args...)
+
+	cmd := exec.Command("git", full...)
+	cmd.Dir = r.Dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), err
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// fileLock is an exclusive flock held while HAL runs git.
+type fileLock struct {
+	f *os.File
+}
+
+func (r *Repo) lock() (*fileLock, error) {
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.Dir, lockFileName)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock history: %w", err)
+	}
+	return &fileLock{f: f}, nil
+}
+
+func (fl *fileLock) unlock() {
+	syscall.Flock(int(fl.f.Fd()), syscall.LOCK_UN)
+	fl.f.Close()
+}
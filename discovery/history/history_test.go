@@ -0,0 +1,181 @@
+package history
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	return Open(t.TempDir())
+}
+
+func writeFile(t *testing.T, repo *Repo, rel, content string) string {
+	t.Helper()
+	path := filepath.Join(repo.Dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommitInitializesRepository(t *testing.T) {
+	repo := testRepo(t)
+	writeFile(t, repo, "notes/existing.md", "hand written")
+	path := writeFile(t, repo, "people/dave.json", `{"name":"Dave"}`)
+
+	if err := repo.Commit("lmc: create people/dave", path); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	commits, err := repo.Log(LogOptions{})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	if commits[0].Subject != "lmc: create people/dave" || commits[0].Author != AuthorName {
+		t.Errorf("latest commit = %+v", commits[0])
+	}
+	if !commits[1].Initial || commits[0].Initial {
+		t.Error("only the snapshot should be marked initial")
+	}
+
+	// The triggering write is not part of the snapshot
+	snapshot, err := repo.Log(LogOptions{Path: "people/dave.json"})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if len(snapshot) != 1 {
+		t.Errorf("people/dave.json in %d commits, want 1", len(snapshot))
+	}
+}
+
+func TestCommitOnlyStagesGivenPaths(t *testing.T) {
+	repo := testRepo(t)
+	if err := repo.Init(); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	hal := writeFile(t, repo, "agenda/today.md", "agenda")
+	writeFile(t, repo, "notes/draft.md", "hand edit")
+
+	if err := repo.Commit("agenda: write today.md", hal); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	out, err := repo.git("status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "notes/") || strings.Contains(out, "agenda/") {
+		t.Errorf("unexpected status:\n%s", out)
+	}
+
+	// Unchanged paths produce no commit
+	before, _ := repo.Log(LogOptions{})
+	if err := repo.Commit("agenda: write today.md", hal); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	after, _ := repo.Log(LogOptions{})
+	if len(after) != len(before) {
+		t.Errorf("empty change committed: %d -> %d commits", len(before), len(after))
+	}
+}
+
+func TestCommitDeletion(t *testing.T) {
+	repo := testRepo(t)
+	path := writeFile(t, repo, "people/frank.json", "{}")
+	if err := repo.Commit("create", path); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	os.Remove(path)
+	if err := repo.Commit("delete", path); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	out, _ := repo.git("ls-files")
+	if strings.Contains(out, "frank") {
+		t.Errorf("deleted file still tracked:\n%s", out)
+	}
+}
+
+func TestRevert(t *testing.T) {
+	repo := testRepo(t)
+	path := writeFile(t, repo, "people/dave.json", "v1")
+	repo.Commit("v1", path)
+	writeFile(t, repo, "people/dave.json", "v2")
+	repo.Commit("v2", path)
+	writeFile(t, repo, "people/dave.json", "v3")
+	repo.Commit("v3", path)
+
+	commits, _ := repo.Log(LogOptions{HALOnly: true})
+	// Oldest first on purpose: Revert orders them itself
+	commit, err := repo.Revert(commits[1].Hash, commits[0].Hash)
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if !strings.HasPrefix(commit.Subject, "Revert 2 changes") {
+		t.Errorf("subject = %q", commit.Subject)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v1" {
+		t.Errorf("content after revert = %q, want v1", data)
+	}
+
+	all, _ := repo.Log(LogOptions{})
+	if _, err := repo.Revert(all[len(all)-1].Hash); err == nil {
+		t.Error("expected error reverting the initial snapshot")
+	}
+}
+
+func TestSyncCreatesBareRemote(t *testing.T) {
+	repo := testRepo(t)
+	path := writeFile(t, repo, "people/dave.json", "{}")
+	repo.Commit("create", path)
+
+	remote := filepath.Join(t.TempDir(), "backup.git")
+	if err := repo.Sync(remote); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	out, err := exec.Command("git", "--git-dir", remote, "log", "--format=%s", DefaultBranch).Output()
+	if err != nil {
+		t.Fatalf("remote log failed: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "create") {
+		t.Errorf("remote log = %q", out)
+	}
+
+	if err := repo.Sync(""); err == nil {
+		t.Error("expected error for empty remote")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("", []string{"update people/dave"}); got != "update people/dave" {
+		t.Errorf("single change = %q", got)
+	}
+	if got := Message("merge", []string{"a"}); got != "merge" {
+		t.Errorf("subject with one change = %q", got)
+	}
+
+	many := make([]string, maxBodyLines+5)
+	for i := range many {
+		many[i] = "change"
+	}
+	got := Message("", many)
+	if !strings.HasPrefix(got, "55 changes\n") || !strings.HasSuffix(got, "... and 5 more") {
+		t.Errorf("long message = %q", got)
+	}
+}
@@ -0,0 +1,343 @@
+package lmc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// AliasType is the entity type that holds alias records.
+	AliasType = "aliases"
+	// EdgeAliasOf links an alias record to its canonical entity.
+	EdgeAliasOf = "alias_of"
+
+	// maxAliasDepth bounds alias chains so a bad record cannot loop forever.
+	maxAliasDepth = 8
+)
+
+// AddAlias records that identifier refers to canonicalID. Identifiers are
+// free-form and matched case-insensitively: another entity ID
+// ("users/U123ABC", "people/alice@corp.com"), an external key
+// ("bamboohr/1234") or a display name ("Alice Smith").
+//
+// The canonical entity must exist. If canonicalID is itself an alias, the
+// record points at the entity it resolves to. Aliases take precedence over
+// an entity stored under the same ID.
+func (l *Library) AddAlias(identifier, canonicalID string) (*Entity, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("")
+
+	return l.addAliasLocked(identifier, canonicalID)
+}
+
+// RemoveAlias deletes an alias record.
+func (l *Library) RemoveAlias(identifier string) error {
+	key := normalizeAlias(identifier)
+	if key == "" {
+		return fmt.Errorf("empty alias")
+	}
+	return l.Delete(l.aliasRecordID(key))
+}
+
+// PersonID returns the ID of the person entity named after someone:
+// "Frank Poole" is people/frank-poole. The meeting tasks, speaker
+// resolution and the BambooHR sync all create and find people by it, so
+// they agree on who is who. It returns "" for an empty name.
+func PersonID(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('-')
+		}
+	}
+	return "people/" + sb.String()
+}
+
+// Resolve maps any known identifier to its canonical entity ID, following
+// alias records. An identifier that is not an alias resolves to itself if
+// an entity with that ID exists.
+func (l *Library) Resolve(identifier string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	id := l.resolve(identifier)
+	if id != identifier {
+		return id, nil
+	}
+	entityType, name, err := splitEntityID(id)
+	if err == nil {
+		if _, statErr := os.Stat(l.entityPath(entityType, name)); statErr == nil {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("unknown identifier: %s", identifier)
+}
+
+// Aliases returns the identifiers that resolve to canonicalID, sorted.
+func (l *Library) Aliases(canonicalID string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.aliasesOf(canonicalID)
+}
+
+// Merge folds duplicateID into canonicalID. Content keys missing from the
+// canonical entity are copied over, explicit links are combined, links from
+// other entities to the duplicate are rewritten to point at the canonical
+// entity, the duplicate is deleted, and its ID is kept as an alias so
+// derived edges and external references still resolve.
+func (l *Library) Merge(duplicateID, canonicalID string) (*Entity, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer func() { l.commitHistory(fmt.Sprintf("merge %s into %s", duplicateID, canonicalID)) }()
+
+	canonicalID = l.resolve(canonicalID)
+	if duplicateID == canonicalID {
+		return nil, fmt.Errorf("cannot merge %s into itself", duplicateID)
+	}
+
+	dupType, dupName, err := splitEntityID(duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	canonType, canonName, err := splitEntityID(canonicalID)
+	if err != nil {
+		return nil, err
+	}
+
+	dup, err := l.loadEntity(l.entityPath(dupType, dupName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", duplicateID, err)
+	}
+	canon, err := l.loadEntity(l.entityPath(canonType, canonName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", canonicalID, err)
+	}
+
+	// Canonical content wins; the duplicate only fills gaps
+	content := canon.Content
+	if content == nil {
+		content = make(map[string]interface{})
+	}
+	for k, v := range dup.Content {
+		if _, ok := content[k]; !ok {
+			content[k] = v
+		}
+	}
+
+	links := mergeLinks(canonicalID, duplicateID, canon.Links, dup.Links)
+	merged, err := l.writeLocked(canonType, canonName, content, links, anyRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Repoint explicit links held by other entities
+	for _, fromID := range l.explicitSources(duplicateID) {
+		if fromID == duplicateID || fromID == canonicalID {
+			continue
+		}
+		fromType, fromName, err := splitEntityID(fromID)
+		if err != nil {
+			continue
+		}
+		from, err := l.loadEntity(l.entityPath(fromType, fromName))
+		if err != nil {
+			continue
+		}
+		rewritten := make([]Edge, len(from.Links))
+		for i, link := range from.Links {
+			if link.To == duplicateID {
+				link.To = canonicalID
+			}
+			rewritten[i] = link
+		}
+		if _, err := l.writeLocked(fromType, fromName, from.Content, rewritten, anyRevision); err != nil {
+			return nil, fmt.Errorf("failed to rewrite links in %s: %w", fromID, err)
+		}
+	}
+
+	l.deindexEntity(dup)
+	if err := os.Remove(dup.Path); err != nil {
+		return nil, err
+	}
+	l.notify(ChangeDelete, dup)
+
+	if _, err := l.addAliasLocked(duplicateID, canonicalID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[lmc] Merged %s into %s", duplicateID, canonicalID)
+	return merged, nil
+}
+
+// addAliasLocked writes an alias record. Caller must hold l.mu and the write lock.
+func (l *Library) addAliasLocked(identifier, canonicalID string) (*Entity, error) {
+	key := normalizeAlias(identifier)
+	if key == "" {
+		return nil, fmt.Errorf("empty alias")
+	}
+
+	target := l.resolve(canonicalID)
+	if normalizeAlias(target) == key {
+		return nil, fmt.Errorf("alias %q would resolve to itself", identifier)
+	}
+	targetType, targetName, err := splitEntityID(target)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(l.entityPath(targetType, targetName)); err != nil {
+		return nil, fmt.Errorf("canonical entity not found: %s", target)
+	}
+
+	content := map[string]interface{}{"alias": strings.TrimSpace(identifier)}
+	links := []Edge{{Type: EdgeAliasOf, To: target}}
+	return l.writeLocked(AliasType, strings.TrimPrefix(l.aliasRecordID(key), AliasType+"/"), content, links, anyRevision)
+}
+
+// aliasRecordID returns the ID of the record for a normalized alias: the
+// record already holding it, or a new one named after the alias and a hash
+// of it. File names replace punctuation, so without the hash "frank.poole"
+// and "frank_poole" would share a file.
+func (l *Library) aliasRecordID(key string) string {
+	l.index.mu.RLock()
+	entry, ok := l.index.aliases[key]
+	l.index.mu.RUnlock()
+	if ok && entry.record != "" {
+		return entry.record
+	}
+	sum := sha256.Sum256([]byte(key))
+	return AliasType + "/" + key + "_" + hex.EncodeToString(sum[:4])
+}
+
+// resolve follows alias records from identifier. Caller must hold l.mu.
+func (l *Library) resolve(identifier string) string {
+	l.index.mu.RLock()
+	defer l.index.mu.RUnlock()
+
+	id := identifier
+	for i := 0; i < maxAliasDepth; i++ {
+		alias, ok := l.index.aliases[normalizeAlias(id)]
+		if !ok || alias.target == id {
+			break
+		}
+		id = alias.target
+	}
+	return id
+}
+
+// aliasesOf lists the identifiers, as originally written, that resolve to
+// canonicalID. Caller must hold l.mu.
+func (l *Library) aliasesOf(canonicalID string) []string {
+	l.index.mu.RLock()
+	names := make([]string, 0, len(l.index.aliases))
+	for _, alias := range l.index.aliases {
+		names = append(names, alias.name)
+	}
+	l.index.mu.RUnlock()
+
+	var aliases []string
+	for _, name := range names {
+		if l.resolve(name) == canonicalID {
+			aliases = append(aliases, name)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// explicitSources returns the entities holding an explicit link to entityID.
+func (l *Library) explicitSources(entityID string) []string {
+	l.index.mu.RLock()
+	defer l.index.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var sources []string
+	for _, e := range l.index.incoming[entityID] {
+		if !e.Derived && !seen[e.From] {
+			seen[e.From] = true
+			sources = append(sources, e.From)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// mergeLinks combines explicit links, pointing anything aimed at the
+// duplicate at the canonical entity and dropping self-links and repeats.
+func mergeLinks(canonicalID, duplicateID string, sets ...[]Edge) []Edge {
+	seen := make(map[string]bool)
+	var merged []Edge
+	for _, set := range sets {
+		for _, link := range set {
+			if link.To == duplicateID {
+				link.To = canonicalID
+			}
+			if link.To == canonicalID {
+				continue
+			}
+			link.From = canonicalID
+			key := link.Type + "|" + link.To
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, link)
+		}
+	}
+	return merged
+}
+
+// aliasEntry is an alias record held in the edge index.
+type aliasEntry struct {
+	name   string // Identifier as written (e.g. "users/U123ABC")
+	target string // Entity ID the alias points at
+	record string // ID of the alias record
+}
+
+// indexAlias records an alias entity in the index. Caller must hold idx.mu.
+func (idx *EdgeIndex) indexAlias(entity *Entity) {
+	name := getString(entity.Content, "alias")
+	if name == "" {
+		name = strings.TrimPrefix(entity.ID, AliasType+"/")
+	}
+	for _, link := range entity.Links {
+		if link.Type == EdgeAliasOf {
+			idx.aliases[normalizeAlias(name)] = aliasEntry{name: name, target: link.To, record: entity.ID}
+			return
+		}
+	}
+}
+
+// normalizeAlias lowercases an identifier and collapses whitespace.
+func normalizeAlias(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// isAliasRecord reports whether entityID names an alias record.
+func isAliasRecord(entityID string) bool {
+	return strings.HasPrefix(entityID, AliasType+"/")
+}
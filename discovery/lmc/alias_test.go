@@ -0,0 +1,246 @@
+package lmc
+
+import (
+	"testing"
+)
+
+func TestResolveAliases(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice Smith"}, nil)
+
+	for _, alias := range []string{"people/alice@corp.com", "users/U123ABC", "bamboohr/1234", "Alice Smith"} {
+		if _, err := lib.AddAlias(alias, "people/alice"); err != nil {
+			t.Fatalf("AddAlias(%q) failed: %v", alias, err)
+		}
+	}
+
+	for _, identifier := range []string{"people/alice", "users/U123ABC", "  alice   SMITH ", "bamboohr/1234"} {
+		got, err := lib.Resolve(identifier)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", identifier, err)
+			continue
+		}
+		if got != "people/alice" {
+			t.Errorf("Resolve(%q) = %q, want people/alice", identifier, got)
+		}
+	}
+
+	if _, err := lib.Resolve("people/nobody"); err == nil {
+		t.Error("expected error for unknown identifier")
+	}
+	if _, err := lib.AddAlias("someone", "people/nobody"); err == nil {
+		t.Error("expected error aliasing a missing entity")
+	}
+
+	if got := lib.Aliases("people/alice"); len(got) != 4 {
+		t.Errorf("Aliases = %v, want 4", got)
+	}
+
+	// Aliases survive an index rebuild.
+	fresh, err := New(lib.BasePath)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got, _ := fresh.Resolve("users/U123ABC"); got != "people/alice" {
+		t.Errorf("rebuilt Resolve = %q, want people/alice", got)
+	}
+
+	if err := fresh.RemoveAlias("Alice Smith"); err != nil {
+		t.Fatalf("RemoveAlias failed: %v", err)
+	}
+	if _, err := fresh.Resolve("Alice Smith"); err == nil {
+		t.Error("removed alias should no longer resolve")
+	}
+}
+
+func TestGetLinkedFollowsAliases(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice"}, nil)
+	lib.AddAlias("users/U123ABC", "people/alice")
+	lib.AddAlias("people/alice@corp.com", "people/alice")
+
+	// Slack message mentions the Slack ID, calendar event the email.
+	lib.Store("slack", "msg1", map[string]interface{}{"text": "<@U123ABC> ping"}, nil)
+	lib.Store("calendar", "evt1", map[string]interface{}{"summary": "1:1"},
+		[]Edge{{Type: "attendee", To: "people/alice@corp.com"}})
+
+	backlinks, err := lib.GetLinked("people/alice", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, e := range backlinks {
+		got[e.ID] = true
+	}
+	if len(backlinks) != 2 || !got["slack/msg1"] || !got["calendar/evt1"] {
+		t.Errorf("backlinks = %v, want slack/msg1 and calendar/evt1", got)
+	}
+
+	// Outgoing links land on the canonical entity.
+	linked, err := lib.GetLinked("calendar/evt1", "out")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(linked) != 1 || linked[0].ID != "people/alice" {
+		t.Errorf("outgoing = %v, want people/alice", linked)
+	}
+
+	// Query link filters follow aliases too.
+	results, err := lib.Query(QueryOptions{LinkedTo: "users/U123ABC"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("LinkedTo results = %d, want 2", len(results))
+	}
+	results, err = lib.Query(QueryOptions{LinkedFrom: "calendar/evt1"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "people/alice" {
+		t.Errorf("LinkedFrom results = %v, want people/alice", results)
+	}
+}
+
+func TestAliasShadowsEntity(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice Smith"}, nil)
+	lib.Store("people", "asmith", map[string]interface{}{"name": "A. Smith"}, nil)
+	if _, err := lib.AddAlias("people/asmith", "people/alice"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+
+	entity, err := lib.Get("people/asmith")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if entity.ID != "people/alice" {
+		t.Errorf("Get = %s, want people/alice", entity.ID)
+	}
+	if got, _ := lib.Resolve("people/asmith"); got != entity.ID {
+		t.Errorf("Resolve = %s, Get = %s; want the same entity", got, entity.ID)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice Smith"}, nil)
+	lib.Store("people", "alice@corp.com", map[string]interface{}{
+		"name":  "alice",
+		"email": "alice@corp.com",
+	}, []Edge{{Type: "member_of", To: "teams/platform"}})
+	lib.Store("teams", "platform", map[string]interface{}{}, nil)
+	lib.Store("calendar", "evt1", map[string]interface{}{},
+		[]Edge{{Type: "attendee", To: "people/alice@corp.com"}})
+
+	merged, err := lib.Merge("people/alice@corp.com", "people/alice")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if merged.Content["name"] != "Alice Smith" {
+		t.Errorf("name = %v, canonical value should win", merged.Content["name"])
+	}
+	if merged.Content["email"] != "alice@corp.com" {
+		t.Errorf("email = %v, should be copied from duplicate", merged.Content["email"])
+	}
+	if len(merged.Links) != 1 || merged.Links[0].To != "teams/platform" {
+		t.Errorf("links = %+v, want member_of teams/platform", merged.Links)
+	}
+
+	evt, err := lib.Get("calendar/evt1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if evt.Links[0].To != "people/alice" {
+		t.Errorf("calendar link = %q, want rewritten to people/alice", evt.Links[0].To)
+	}
+
+	// The duplicate's ID now resolves to the canonical entity.
+	entity, err := lib.Get("people/alice@corp.com")
+	if err != nil {
+		t.Fatalf("Get via alias failed: %v", err)
+	}
+	if entity.ID != "people/alice" {
+		t.Errorf("Get via alias = %q, want people/alice", entity.ID)
+	}
+
+	results, err := lib.Query(QueryOptions{Type: "people"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("people = %d, want 1 after merge", len(results))
+	}
+}
+
+func TestAliasPunctuationVariants(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "frank", map[string]interface{}{"name": "Frank Poole"}, nil)
+	lib.Store("people", "frances", map[string]interface{}{"name": "Frances Poole"}, nil)
+
+	// Both sanitize to the same file name; each keeps its own record
+	if _, err := lib.AddAlias("frank.poole", "people/frank"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if _, err := lib.AddAlias("frank_poole", "people/frances"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+
+	fresh, err := New(lib.BasePath)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for identifier, want := range map[string]string{"frank.poole": "people/frank", "frank_poole": "people/frances"} {
+		if got, _ := fresh.Resolve(identifier); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", identifier, got, want)
+		}
+	}
+
+	// Re-pointing an alias rewrites its record rather than adding one
+	if _, err := fresh.AddAlias("Frank.Poole", "people/frances"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if got := fresh.Aliases("people/frances"); len(got) != 2 {
+		t.Errorf("Aliases = %v, want 2", got)
+	}
+	if err := fresh.RemoveAlias("frank_poole"); err != nil {
+		t.Fatalf("RemoveAlias failed: %v", err)
+	}
+	if got, _ := fresh.Resolve("frank.poole"); got != "people/frances" {
+		t.Errorf("removing one variant dropped the other: %q", got)
+	}
+}
+
+func TestPersonID(t *testing.T) {
+	tests := map[string]string{
+		"Frank Poole":      "people/frank-poole",
+		"  Dave Bowman  ":  "people/dave-bowman",
+		"Heywood R. Floyd": "people/heywood-r--floyd",
+		"":                 "",
+	}
+	for name, want := range tests {
+		if got := PersonID(name); got != want {
+			t.Errorf("PersonID(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package lmc
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// ExportFormat selects the output format for Export.
+type ExportFormat string
+
+const (
+	FormatDOT     ExportFormat = "dot"     // Graphviz
+	FormatGraphML ExportFormat = "graphml" // Gephi, yEd, NetworkX
+	FormatJSONLD  ExportFormat = "jsonld"  // Linked data tools; Import reads this back
+	FormatNDJSON  ExportFormat = "ndjson"  // One stored document per line; Import reads this back losslessly
+)
+
+// ExportOptions filters what Export writes.
+type ExportOptions struct {
+	Format    ExportFormat
+	Types     []string  // Entity types to include (empty = all)
+	EdgeTypes []string  // Edge types to include (empty = all); graph formats only
+	Since     time.Time // Modified at or after
+	Before    time.Time // Modified at or before
+}
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	Format    ExportFormat // ndjson (default) or jsonld
+	Overwrite bool         // Replace entities that already exist
+}
+
+// ImportResult summarizes an Import.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"` // Already present and Overwrite not set
+}
+
+// exportGraph is the filtered node and edge set shared by all formats.
+type exportGraph struct {
+	nodes []*Entity
+	edges []Edge
+	stubs []string // Edge targets that are not exported nodes
+}
+
+// Export writes the library, or the part selected by opts, to w.
+// Edges whose target is not an exported entity are kept; graph formats
+// emit those targets as stub nodes. An NDJSON dump keeps every link of the
+// documents it writes, so it cannot be filtered by edge type.
+func (l *Library) Export(w io.Writer, opts ExportOptions) error {
+	if len(opts.EdgeTypes) > 0 && (opts.Format == FormatNDJSON || opts.Format == "") {
+		return fmt.Errorf("edge type filters apply to graph formats; an NDJSON dump keeps every link")
+	}
+	graph, err := l.exportGraph(opts)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case FormatDOT:
+		return writeDOT(w, graph)
+	case FormatGraphML:
+		return writeGraphML(w, graph)
+	case FormatJSONLD:
+		return writeJSONLD(w, graph)
+	case FormatNDJSON, "":
+		return writeNDJSON(w, graph)
+	default:
+		return fmt.Errorf("unknown export format: %s", opts.Format)
+	}
+}
+
+// Import loads a dump produced by Export: NDJSON (the default) or
+// JSON-LD. NDJSON documents keep their original IDs, timestamps, revisions
+// and links. JSON-LD carries no edge labels and does not separate derived
+// edges, so edges that content extraction finds again are re-derived and
+// the rest become explicit links.
+func (l *Library) Import(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	var decode func(io.Reader, func(doc map[string]interface{}) error) error
+	switch opts.Format {
+	case FormatNDJSON, "":
+		decode = decodeNDJSON
+	case FormatJSONLD:
+		decode = decodeJSONLD
+	default:
+		return nil, fmt.Errorf("cannot import format: %s", opts.Format)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("import")
+
+	result := &ImportResult{}
+	err = decode(r, func(doc map[string]interface{}) error {
+		return l.importDoc(doc, opts, result)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	log.Printf("[lmc] Imported %d entities (%d skipped)", result.Imported, result.Skipped)
+	return result, nil
+}
+
+// importDoc writes one stored document unless it exists and opts does
+// not allow overwriting it.
+func (l *Library) importDoc(doc map[string]interface{}, opts ImportOptions, result *ImportResult) error {
+	meta, _ := doc["_meta"].(map[string]interface{})
+	entityType, id, err := splitEntityID(getString(meta, "id"))
+	if err != nil {
+		return err
+	}
+	if getString(meta, "type") != entityType {
+		return fmt.Errorf("type %q does not match ID %s", getString(meta, "type"), getString(meta, "id"))
+	}
+
+	fullPath := l.entityPath(entityType, id)
+	op := ChangeCreate
+	if existing, err := l.loadEntity(fullPath); err == nil {
+		op = ChangeUpdate
+		if existing.ID != getString(meta, "id") {
+			return fmt.Errorf("%s collides with %s", getString(meta, "id"), existing.ID)
+		}
+		if !opts.Overwrite {
+			result.Skipped++
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := vault.WriteFile(fullPath, data, 0644); err != nil {
+		return err
+	}
+
+	entity, err := l.loadEntity(fullPath)
+	if err != nil {
+		return err
+	}
+	l.indexEntity(entity)
+	l.notify(op, entity)
+	result.Imported++
+	return nil
+}
+
+// decodeNDJSON passes each line of an NDJSON dump to fn.
+func decodeNDJSON(r io.Reader, fn func(doc map[string]interface{}) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(doc); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// decodeJSONLD turns the nodes of a JSON-LD export back into stored
+// documents and passes them to fn. Stub nodes, which have no content, are
+// not entities and are skipped.
+func decodeJSONLD(r io.Reader, fn func(doc map[string]interface{}) error) error {
+	var export struct {
+		Graph []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return fmt.Errorf("invalid JSON-LD: %w", err)
+	}
+
+	for _, node := range export.Graph {
+		if _, ok := node["content"]; !ok {
+			continue
+		}
+		entityID, err := jsonldEntityID(getString(node, "@id"))
+		if err != nil {
+			return err
+		}
+		content, _ := node["content"].(map[string]interface{})
+
+		var edges []Edge
+		for key, value := range node {
+			edgeType, ok := strings.CutPrefix(key, jsonldEdgePrefix)
+			if !ok {
+				continue
+			}
+			refs, _ := value.([]interface{})
+			for _, ref := range refs {
+				target, _ := ref.(map[string]interface{})
+				to, err := jsonldEntityID(getString(target, "@id"))
+				if err != nil {
+					return fmt.Errorf("%s: %w", entityID, err)
+				}
+				edges = append(edges, Edge{From: entityID, To: to, Type: edgeType})
+			}
+		}
+
+		// Edges extraction finds again are re-derived when the document
+		// is loaded; the rest were explicit links.
+		extracted := make(map[string]bool)
+		for _, e := range ExtractEdges(entityID, content) {
+			extracted[e.Type+"|"+e.To] = true
+		}
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i].Type+"|"+edges[i].To < edges[j].Type+"|"+edges[j].To
+		})
+		links := []map[string]interface{}{}
+		for _, e := range edges {
+			if !extracted[e.Type+"|"+e.To] {
+				links = append(links, map[string]interface{}{"to": e.To, "type": e.Type})
+			}
+		}
+
+		meta := map[string]interface{}{
+			"id":   entityID,
+			"type": getString(node, "@type"),
+		}
+		for _, field := range []string{"created", "modified"} {
+			if v := getString(node, field); v != "" {
+				meta[field] = v
+			}
+		}
+		if rev, ok := node["revision"].(float64); ok {
+			meta["revision"] = rev
+		}
+
+		doc := map[string]interface{}{"_meta": meta, "content": content, "links": links}
+		if err := fn(doc); err != nil {
+			return fmt.Errorf("%s: %w", entityID, err)
+		}
+	}
+	return nil
+}
+
+func (l *Library) exportGraph(opts ExportOptions) (*exportGraph, error) {
+	entities, err := l.Query(QueryOptions{Since: opts.Since, Before: opts.Before})
+	if err != nil {
+		return nil, err
+	}
+
+	types := toSet(opts.Types)
+	edgeTypes := toSet(opts.EdgeTypes)
+
+	graph := &exportGraph{}
+	exported := make(map[string]bool)
+	for _, e := range entities {
+		if e.ID == "" || (len(types) > 0 && !types[e.Type]) {
+			continue
+		}
+		graph.nodes = append(graph.nodes, e)
+		exported[e.ID] = true
+	}
+	sort.Slice(graph.nodes, func(i, j int) bool {
+		return graph.nodes[i].ID < graph.nodes[j].ID
+	})
+
+	stubs := make(map[string]bool)
+	for _, e := range graph.nodes {
+		e.Links = filterEdgeTypes(e.Links, edgeTypes)
+		e.DerivedLinks = filterEdgeTypes(e.DerivedLinks, edgeTypes)
+		for _, edge := range e.AllLinks() {
+			edge.From = e.ID
+			graph.edges = append(graph.edges, edge)
+			if !exported[edge.To] && !stubs[edge.To] {
+				stubs[edge.To] = true
+				graph.stubs = append(graph.stubs, edge.To)
+			}
+		}
+	}
+	sort.Strings(graph.stubs)
+
+	return graph, nil
+}
+
+func filterEdgeTypes(edges []Edge, edgeTypes map[string]bool) []Edge {
+	if len(edgeTypes) == 0 {
+		return edges
+	}
+	var filtered []Edge
+	for _, e := range edges {
+		if edgeTypes[e.Type] {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// nodeLabel picks a human-readable label from common content fields.
+func nodeLabel(e *Entity) string {
+	for _, key := range []string{"name", "title", "summary"} {
+		if s := getString(e.Content, key); s != "" {
+			return s
+		}
+	}
+	return e.ID
+}
+
+// typeOf returns the type prefix of an entity ID ("" if none).
+func typeOf(entityID string) string {
+	if t, _, err := splitEntityID(entityID); err == nil {
+		return t
+	}
+	return ""
+}
+
+func writeDOT(w io.Writer, g *exportGraph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph lmc {")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for _, e := range g.nodes {
+		fmt.Fprintf(bw, "  %s [label=%s, type=%s];\n", dotQuote(e.ID), dotQuote(nodeLabel(e)), dotQuote(e.Type))
+	}
+	for _, id := range g.stubs {
+		fmt.Fprintf(bw, "  %s [type=%s, style=dashed];\n", dotQuote(id), dotQuote(typeOf(id)))
+	}
+	for _, edge := range g.edges {
+		style := ""
+		if edge.Derived {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(bw, "  %s -> %s [label=%s%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Type), style)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func writeGraphML(w io.Writer, g *exportGraph) error {
+	bw := bufio.NewWriter(w)
+	esc := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="type" for="node" attr.name="type" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="modified" for="node" attr.name="modified" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="stub" for="node" attr.name="stub" attr.type="boolean"/>`)
+	fmt.Fprintln(bw, `  <key id="edge_type" for="edge" attr.name="type" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="derived" for="edge" attr.name="derived" attr.type="boolean"/>`)
+	fmt.Fprintln(bw, `  <graph id="lmc" edgedefault="directed">`)
+
+	for _, e := range g.nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", esc(e.ID))
+		fmt.Fprintf(bw, "      <data key=\"type\">%s</data>\n", esc(e.Type))
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", esc(nodeLabel(e)))
+		fmt.Fprintf(bw, "      <data key=\"modified\">%s</data>\n", e.Modified.Format(time.RFC3339))
+		fmt.Fprintln(bw, "    </node>")
+	}
+	for _, id := range g.stubs {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", esc(id))
+		fmt.Fprintf(bw, "      <data key=\"type\">%s</data>\n", esc(typeOf(id)))
+		fmt.Fprintln(bw, "      <data key=\"stub\">true</data>")
+		fmt.Fprintln(bw, "    </node>")
+	}
+	for i, edge := range g.edges {
+		fmt.Fprintf(bw, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, esc(edge.From), esc(edge.To))
+		fmt.Fprintf(bw, "      <data key=\"edge_type\">%s</data>\n", esc(edge.Type))
+		fmt.Fprintf(bw, "      <data key=\"derived\">%t</data>\n", edge.Derived)
+		fmt.Fprintln(bw, "    </edge>")
+	}
+
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// jsonldEdgePrefix namespaces edge properties, so an edge type such as
+// "label" or "content" cannot overwrite a node's own properties.
+const jsonldEdgePrefix = "edge:"
+
+// jsonldEntityPrefix makes entity IDs absolute IRIs. URNs have no
+// hierarchical path, so relative IDs cannot be resolved against an @base.
+const jsonldEntityPrefix = "urn:hal9000:entity:"
+
+// jsonldContext maps edge types and properties into HAL's vocabulary.
+// Content is kept as an opaque JSON literal.
+var jsonldContext = map[string]interface{}{
+	"@vocab":   "urn:hal9000:lmc:",
+	"edge":     "urn:hal9000:lmc:edge:",
+	"label":    "http://www.w3.org/2000/01/rdf-schema#label",
+	"created":  map[string]interface{}{"@type": "http://www.w3.org/2001/XMLSchema#dateTime"},
+	"modified": map[string]interface{}{"@type": "http://www.w3.org/2001/XMLSchema#dateTime"},
+	"content":  map[string]interface{}{"@type": "@json"},
+}
+
+// jsonldEntityID returns the entity ID named by an exported @id.
+func jsonldEntityID(iri string) (string, error) {
+	id, ok := strings.CutPrefix(iri, jsonldEntityPrefix)
+	if !ok || id == "" {
+		return "", fmt.Errorf("%q is not a HAL entity IRI", iri)
+	}
+	return id, nil
+}
+
+func writeJSONLD(w io.Writer, g *exportGraph) error {
+	graph := make([]map[string]interface{}, 0, len(g.nodes)+len(g.stubs))
+	for _, e := range g.nodes {
+		node := map[string]interface{}{
+			"@id":      jsonldEntityPrefix + e.ID,
+			"@type":    e.Type,
+			"label":    nodeLabel(e),
+			"created":  e.Created.Format(time.RFC3339),
+			"modified": e.Modified.Format(time.RFC3339),
+			"revision": e.Revision,
+			"content":  e.Content,
+		}
+		for _, edge := range e.AllLinks() {
+			key := jsonldEdgePrefix + edge.Type
+			refs, _ := node[key].([]map[string]string)
+			node[key] = append(refs, map[string]string{"@id": jsonldEntityPrefix + edge.To})
+		}
+		graph = append(graph, node)
+	}
+	for _, id := range g.stubs {
+		graph = append(graph, map[string]interface{}{"@id": jsonldEntityPrefix + id, "@type": typeOf(id)})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"@context": jsonldContext,
+		"@graph":   graph,
+	})
+}
+
+func writeNDJSON(w io.Writer, g *exportGraph) error {
+	enc := json.NewEncoder(w)
+	for _, e := range g.nodes {
+		doc := map[string]interface{}{
+			"_meta": map[string]interface{}{
+				"id":       e.ID,
+				"type":     e.Type,
+				"created":  e.Created.Format(time.RFC3339),
+				"modified": e.Modified.Format(time.RFC3339),
+				"revision": e.Revision,
+			},
+			"content": e.Content,
+			"links":   e.Links,
+		}
+		if len(e.DerivedLinks) > 0 {
+			doc["derived_links"] = e.DerivedLinks
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package lmc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportLibrary(t *testing.T) *Library {
+	t.Helper()
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "dave", map[string]interface{}{"name": `Dave "Bowman"`}, nil)
+	lib.Store("projects", "hal9000", map[string]interface{}{"name": "HAL 9000"},
+		[]Edge{{Type: "owned_by", To: "people/dave"}})
+	lib.Store("agenda", "today", map[string]interface{}{"body": "Review PROJ-7 with [[people/dave]]"},
+		[]Edge{{Type: "about", To: "projects/hal9000"}})
+	return lib
+}
+
+func TestExportDOT(t *testing.T) {
+	lib := newExportLibrary(t)
+
+	var buf bytes.Buffer
+	if err := lib.Export(&buf, ExportOptions{Format: FormatDOT}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"digraph lmc {",
+		`"people/dave" [label="Dave \"Bowman\"", type="people"];`,
+		`"projects/hal9000" -> "people/dave" [label="owned_by"];`,
+		`"agenda/today" -> "people/dave" [label="references", style=dashed];`,
+		`"jira/PROJ-7" [type="jira", style=dashed];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	lib := newExportLibrary(t)
+
+	var buf bytes.Buffer
+	err := lib.Export(&buf, ExportOptions{
+		Format:    FormatDOT,
+		Types:     []string{"agenda"},
+		EdgeTypes: []string{"about"},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "owned_by") || strings.Contains(out, "references") {
+		t.Errorf("filtered output has unexpected edges:\n%s", out)
+	}
+	if !strings.Contains(out, `"agenda/today" -> "projects/hal9000"`) {
+		t.Errorf("filtered output missing about edge:\n%s", out)
+	}
+
+	// A dump for Import must keep every link
+	for _, format := range []ExportFormat{FormatNDJSON, ""} {
+		if err := lib.Export(&buf, ExportOptions{Format: format, EdgeTypes: []string{"about"}}); err == nil {
+			t.Errorf("Export(%q) with an edge type filter succeeded", format)
+		}
+	}
+
+	buf.Reset()
+	if err := lib.Export(&buf, ExportOptions{Format: FormatNDJSON, Since: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("future Since should export nothing, got %q", buf.String())
+	}
+}
+
+func TestExportGraphMLAndJSONLD(t *testing.T) {
+	lib := newExportLibrary(t)
+
+	var buf bytes.Buffer
+	if err := lib.Export(&buf, ExportOptions{Format: FormatGraphML}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var graphml struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &graphml); err != nil {
+		t.Fatalf("invalid GraphML: %v", err)
+	}
+	if len(graphml.Graph.Nodes) != 4 || len(graphml.Graph.Edges) != 4 {
+		t.Errorf("GraphML nodes=%d edges=%d, want 4 and 4", len(graphml.Graph.Nodes), len(graphml.Graph.Edges))
+	}
+
+	buf.Reset()
+	if err := lib.Export(&buf, ExportOptions{Format: FormatJSONLD}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var ld struct {
+		Context map[string]interface{}   `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ld); err != nil {
+		t.Fatalf("invalid JSON-LD: %v", err)
+	}
+	if ld.Context["@vocab"] == nil || len(ld.Graph) != 4 {
+		t.Errorf("JSON-LD context=%v nodes=%d", ld.Context, len(ld.Graph))
+	}
+	for _, node := range ld.Graph {
+		if node["@id"] == "urn:hal9000:entity:projects/hal9000" && node["edge:owned_by"] == nil {
+			t.Errorf("projects/hal9000 missing owned_by: %v", node)
+		}
+	}
+
+	// An edge type named like a node property does not replace it
+	lib.Store("notes", "label", map[string]interface{}{"title": "Pod bay"},
+		[]Edge{{Type: "label", To: "projects/hal9000"}})
+	buf.Reset()
+	if err := lib.Export(&buf, ExportOptions{Format: FormatJSONLD, Types: []string{"notes"}}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ld); err != nil {
+		t.Fatalf("invalid JSON-LD: %v", err)
+	}
+	for _, node := range ld.Graph {
+		if node["@id"] == "urn:hal9000:entity:notes/label" && (node["label"] != "Pod bay" || node["edge:label"] == nil) {
+			t.Errorf("notes/label = %v, want its label and an edge:label edge", node)
+		}
+	}
+
+	if err := lib.Export(&buf, ExportOptions{Format: "svg"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+// expandIRI resolves a node or edge @id the way a JSON-LD processor does
+// during expansion: against the context's @base, if any.
+func expandIRI(t *testing.T, context map[string]interface{}, id string) string {
+	t.Helper()
+	ref, err := url.Parse(id)
+	if err != nil {
+		t.Fatalf("invalid @id %q: %v", id, err)
+	}
+	if base, ok := context["@base"].(string); ok {
+		b, err := url.Parse(base)
+		if err != nil {
+			t.Fatalf("invalid @base %q: %v", base, err)
+		}
+		return b.ResolveReference(ref).String()
+	}
+	if !ref.IsAbs() {
+		t.Errorf("@id %q is relative and there is no @base", id)
+	}
+	return id
+}
+
+func TestExportJSONLDIRIs(t *testing.T) {
+	src := newExportLibrary(t)
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, ExportOptions{Format: FormatJSONLD}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	dump := buf.String()
+
+	var ld struct {
+		Context map[string]interface{}   `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ld); err != nil {
+		t.Fatalf("invalid JSON-LD: %v", err)
+	}
+	ids := make(map[string]bool)
+	for _, node := range ld.Graph {
+		ids[expandIRI(t, ld.Context, node["@id"].(string))] = true
+		if refs, ok := node["edge:owned_by"].([]interface{}); ok {
+			for _, ref := range refs {
+				ids[expandIRI(t, ld.Context, ref.(map[string]interface{})["@id"].(string))] = true
+			}
+		}
+	}
+	for _, want := range []string{"urn:hal9000:entity:people/dave", "urn:hal9000:entity:projects/hal9000", "urn:hal9000:entity:jira/PROJ-7"} {
+		if !ids[want] {
+			t.Errorf("expanded IDs %v missing %s", ids, want)
+		}
+	}
+
+	// Importing the JSON-LD strips the prefix again
+	dst, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	result, err := dst.Import(strings.NewReader(dump), ImportOptions{Format: FormatJSONLD})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 3 {
+		t.Errorf("imported = %d, want 3", result.Imported)
+	}
+
+	project, err := dst.Get("projects/hal9000")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(project.Links) != 1 || project.Links[0].To != "people/dave" || project.Links[0].Type != "owned_by" {
+		t.Errorf("imported links = %+v, want owned_by people/dave", project.Links)
+	}
+	agenda, err := dst.Get("agenda/today")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(agenda.Links) != 1 || len(agenda.DerivedLinks) != 2 {
+		t.Errorf("agenda links = %+v derived = %+v, want 1 explicit and 2 derived", agenda.Links, agenda.DerivedLinks)
+	}
+	orig, _ := src.Get("agenda/today")
+	if agenda.Revision != orig.Revision || !agenda.Created.Equal(orig.Created) {
+		t.Errorf("imported agenda = %+v, want copy of %+v", agenda, orig)
+	}
+
+	if _, err := dst.Import(strings.NewReader(dump), ImportOptions{Format: FormatDOT}); err == nil {
+		t.Error("expected error importing DOT")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := newExportLibrary(t)
+	src.Store("projects", "hal9000", map[string]interface{}{"name": "HAL 9000", "phase": "2"},
+		[]Edge{{Type: "owned_by", To: "people/dave"}})
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf, ExportOptions{Format: FormatNDJSON}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("NDJSON lines = %d, want 3", lines)
+	}
+	dump := buf.String()
+
+	dst, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	result, err := dst.Import(strings.NewReader(dump), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 3 {
+		t.Errorf("imported = %d, want 3", result.Imported)
+	}
+
+	orig, _ := src.Get("projects/hal9000")
+	copied, err := dst.Get("projects/hal9000")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if copied.Revision != orig.Revision || !copied.Created.Equal(orig.Created) || copied.Content["phase"] != "2" {
+		t.Errorf("imported entity = %+v, want copy of %+v", copied, orig)
+	}
+
+	backlinks, err := dst.GetLinked("people/dave", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(backlinks) != 2 {
+		t.Errorf("backlinks after import = %d, want 2", len(backlinks))
+	}
+
+	// Re-importing skips what is already there.
+	result, err = dst.Import(strings.NewReader(dump), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 3 {
+		t.Errorf("re-import = %+v, want all skipped", result)
+	}
+
+	if _, err := dst.Import(strings.NewReader("{not json}\n"), ImportOptions{}); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
@@ -0,0 +1,235 @@
+package lmc
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Derived edge types produced by content extraction.
+const (
+	EdgeReferences = "references" // Document cites another document or issue
+	EdgeMentions   = "mentions"   // Document mentions a person
+)
+
+var (
+	// [[type/id]] or [[type/id|label]]
+	wikiLinkRe = regexp.MustCompile(`\[\[([a-zA-Z0-9_-]+/[^\]|]+)(?:\|([^\]]*))?\]\]`)
+	emailRe    = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)*\.[a-zA-Z]{2,}`)
+	jiraKeyRe  = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-[1-9][0-9]*\b`)
+	slackIDRe  = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+	urlRe      = regexp.MustCompile(`https?://[^\s<>"'|\]]+`)
+
+	// Prefixes of standards, algorithms and other names shaped like issue
+	// keys ("UTF-8", "ISO-8601", "SHA-256", "COVID-19") that are not issues
+	notJiraKeys = map[string]bool{
+		"AES": true, "ANSI": true, "COVID": true, "CVE": true, "ECMA": true,
+		"GMT": true, "HTTP": true, "IEEE": true, "IPV": true, "ISO": true,
+		"MPEG": true, "PEP": true, "RFC": true, "RSA": true, "SARS": true,
+		"SHA": true, "SSL": true, "TLS": true, "UTC": true, "UTF": true,
+	}
+)
+
+// Reference is a reference FindReferences found in text.
+type Reference struct {
+	To    string // Target entity ID
+	Type  string // EdgeReferences or EdgeMentions
+	Label string
+	Start int // Byte offsets of the reference in the text
+	End   int
+}
+
+// ExtractEdges scans entity content for references and returns them as
+// derived edges. Recognized references are those of FindReferences.
+//
+// Edges pointing back at the entity itself are dropped. Results are
+// deduplicated by target and type and sorted for stable output.
+func ExtractEdges(entityID string, content map[string]interface{}) []Edge {
+	seen := make(map[string]bool)
+	var edges []Edge
+
+	for _, text := range collectStrings(content) {
+		for _, ref := range FindReferences(text) {
+			to := strings.TrimSpace(ref.To)
+			if to == "" || to == entityID {
+				continue
+			}
+			key := ref.Type + "|" + to
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			edges = append(edges, Edge{
+				From:    entityID,
+				To:      to,
+				Type:    ref.Type,
+				Label:   ref.Label,
+				Derived: true,
+			})
+		}
+	}
+
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].Type != edges[j].Type {
+			return edges[i].Type < edges[j].Type
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	return edges
+}
+
+// FindReferences returns the references in text, in order of kind:
+//   - [[type/id]] wiki-links      -> references type/id
+//   - Slack user IDs (<@U123ABC>) -> mentions users/<id>
+//   - email addresses             -> mentions people/<email>
+//   - JIRA issue keys (PROJ-123)  -> references jira/<KEY>
+//
+// Emails and issue keys inside wiki-links and URLs are not matched, nor
+// are keys like UTF-8 or the local part of an email address.
+func FindReferences(text string) []Reference {
+	var refs []Reference
+
+	// Wiki-links first so their targets are not re-matched as emails or
+	// issue keys with a different edge type. Blanking keeps offsets.
+	for _, m := range wikiLinkRe.FindAllStringSubmatchIndex(text, -1) {
+		label := ""
+		if m[4] >= 0 {
+			label = strings.TrimSpace(text[m[4]:m[5]])
+		}
+		refs = append(refs, Reference{To: text[m[2]:m[3]], Type: EdgeReferences, Label: label, Start: m[0], End: m[1]})
+	}
+	blank := func(s string) string { return strings.Repeat(" ", len(s)) }
+	stripped := wikiLinkRe.ReplaceAllStringFunc(text, blank)
+
+	for _, m := range slackIDRe.FindAllStringSubmatchIndex(stripped, -1) {
+		refs = append(refs, Reference{To: "users/" + text[m[2]:m[3]], Type: EdgeMentions, Label: text[m[0]:m[1]], Start: m[0], End: m[1]})
+	}
+
+	// URLs contain things that look like emails and issue keys
+	stripped = urlRe.ReplaceAllStringFunc(stripped, blank)
+	for _, m := range emailRe.FindAllStringIndex(stripped, -1) {
+		email := text[m[0]:m[1]]
+		refs = append(refs, Reference{To: "people/" + strings.ToLower(email), Type: EdgeMentions, Label: email, Start: m[0], End: m[1]})
+	}
+
+	// Keys inside an email address are part of the mention
+	stripped = emailRe.ReplaceAllStringFunc(stripped, blank)
+	for _, m := range jiraKeyRe.FindAllStringIndex(stripped, -1) {
+		key := text[m[0]:m[1]]
+		if notJiraKeys[key[:strings.Index(key, "-")]] {
+			continue
+		}
+		refs = append(refs, Reference{To: "jira/" + key, Type: EdgeReferences, Label: key, Start: m[0], End: m[1]})
+	}
+	return refs
+}
+
+// deriveLinks extracts content edges, dropping any that duplicate an
+// explicit link so the same relationship is not indexed twice.
+func deriveLinks(entityID string, content map[string]interface{}, explicit []Edge) []Edge {
+	// Alias records hold identifiers, not prose to mine for references
+	if isAliasRecord(entityID) {
+		return nil
+	}
+
+	existing := make(map[string]bool)
+	for _, e := range explicit {
+		existing[e.Type+"|"+e.To] = true
+	}
+
+	var derived []Edge
+	for _, e := range ExtractEdges(entityID, content) {
+		if !existing[e.Type+"|"+e.To] {
+			derived = append(derived, e)
+		}
+	}
+	return derived
+}
+
+// splitSupplied separates links marked Derived, which an extractor supplied
+// to Store, from explicit links.
+func splitSupplied(links []Edge) (explicit, supplied []Edge) {
+	for _, link := range links {
+		if link.Derived {
+			supplied = append(supplied, link)
+		} else {
+			explicit = append(explicit, link)
+		}
+	}
+	return explicit, supplied
+}
+
+// suppliedLinks returns the derived edges of an entity that were supplied
+// to Store rather than extracted from its content.
+func suppliedLinks(e *Entity) []Edge {
+	extracted := make(map[string]bool)
+	for _, edge := range ExtractEdges(e.ID, e.Content) {
+		extracted[edge.Type+"|"+edge.To] = true
+	}
+	var supplied []Edge
+	for _, edge := range e.DerivedLinks {
+		if !extracted[edge.Type+"|"+edge.To] {
+			supplied = append(supplied, edge)
+		}
+	}
+	return supplied
+}
+
+// mergeDerived combines supplied and content-derived edges, dropping
+// duplicates and edges that repeat an explicit link. Supplied edges come
+// first and keep their labels.
+func mergeDerived(entityID string, explicit, supplied, derived []Edge) []Edge {
+	seen := make(map[string]bool)
+	for _, edge := range explicit {
+		seen[edge.Type+"|"+edge.To] = true
+	}
+	var merged []Edge
+	for _, group := range [][]Edge{supplied, derived} {
+		for _, edge := range group {
+			key := edge.Type + "|" + edge.To
+			if edge.To == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			edge.From = entityID
+			edge.Derived = true
+			merged = append(merged, edge)
+		}
+	}
+	return merged
+}
+
+// collectStrings walks content and returns every string value in
+// deterministic (sorted key) order.
+func collectStrings(v interface{}) []string {
+	var out []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			out = append(out, val)
+		case map[string]interface{}:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(val[k])
+			}
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case []map[string]interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case []string:
+			out = append(out, val...)
+		}
+	}
+	walk(v)
+	return out
+}
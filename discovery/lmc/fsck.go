@@ -0,0 +1,349 @@
+package lmc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// quarantineDirName holds files moved aside by Fsck. Dot directories are
+// skipped by Query and the edge index.
+const quarantineDirName = ".quarantine"
+
+// IssueKind classifies a problem found by Fsck.
+type IssueKind string
+
+const (
+	// IssueCorrupt is a .json file that cannot be parsed.
+	IssueCorrupt IssueKind = "corrupt"
+	// IssueDanglingEdge is an explicit link to an entity that does not exist.
+	IssueDanglingEdge IssueKind = "dangling_edge"
+	// IssueIDMismatch is an entity whose _meta.id does not match its path.
+	IssueIDMismatch IssueKind = "id_mismatch"
+	// IssueCollision is a reference to an ID whose file holds a different ID,
+	// because both sanitize to the same filename.
+	IssueCollision IssueKind = "collision"
+	// IssueOrphanedStage is a bronze/silver document whose predecessor is gone.
+	IssueOrphanedStage IssueKind = "orphaned_stage"
+	// IssueUnreadable is a file that cannot be read, such as one sealed
+	// while the vault is locked. It is reported but never quarantined.
+	IssueUnreadable IssueKind = "unreadable"
+)
+
+// FsckIssue describes a single integrity problem.
+type FsckIssue struct {
+	Kind     IssueKind `json:"kind"`
+	Path     string    `json:"path"`
+	EntityID string    `json:"entity_id,omitempty"`
+	Detail   string    `json:"detail"`
+	Fixed    bool      `json:"fixed"`
+	Action   string    `json:"action,omitempty"` // What --fix did
+}
+
+// FsckOptions controls Fsck.
+type FsckOptions struct {
+	Fix bool // Quarantine bad files and prune dangling edges
+}
+
+// FsckReport summarizes an integrity check.
+type FsckReport struct {
+	Checked       int         `json:"checked"`                  // Files examined
+	Issues        []FsckIssue `json:"issues"`                   // Problems found
+	QuarantineDir string      `json:"quarantine_dir,omitempty"` // Where --fix moved files
+}
+
+// Unfixed returns the number of issues that remain after the run.
+func (r *FsckReport) Unfixed() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if !issue.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// fsckStage is a raw/bronze/silver document seen during the scan.
+type fsckStage struct {
+	path     string
+	stage    string
+	previous string // PreviousID, e.g. "raw/calendar/<event_id>"
+	archived string // _meta.archived_source, set when retention archived the source
+}
+
+// Fsck checks the library for corrupt or unreadable files, dangling edges,
+// ID/filename mismatches, filename collisions and orphaned stage
+// documents. With opts.Fix, corrupt, misplaced and orphaned files are moved
+// to .quarantine/<timestamp>/ and dangling explicit links are pruned.
+//
+// Derived edges are not checked: they point at issue keys, email addresses
+// and user IDs that are not expected to exist as entities.
+func (l *Library) Fsck(opts FsckOptions) (*FsckReport, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("fsck --fix")
+
+	report := &FsckReport{}
+	var quarantineDir string
+	quarantine := func(path string) (string, error) {
+		if quarantineDir == "" {
+			quarantineDir = filepath.Join(l.BasePath, quarantineDirName, time.Now().Format("20060102-150405"))
+			report.QuarantineDir = quarantineDir
+		}
+		rel, err := filepath.Rel(l.BasePath, path)
+		if err != nil {
+			return "", err
+		}
+		dest := filepath.Join(quarantineDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		if err := os.Rename(path, dest); err != nil {
+			return "", err
+		}
+		l.track("quarantine "+rel, path)
+		return dest, nil
+	}
+
+	entities := make(map[string]*Entity) // path -> entity
+	var stages []fsckStage
+	stageKeys := make(map[string]bool) // "raw/<source>/<event>" etc.
+
+	err = filepath.Walk(l.BasePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			return skipHiddenDir(l.BasePath, path, info)
+		}
+		if !strings.HasSuffix(path, ".json") || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		report.Checked++
+
+		data, err := vault.ReadFile(path)
+		if err != nil {
+			// A sealed file we cannot open is not corrupt; never quarantine it.
+			issue := FsckIssue{Kind: IssueUnreadable, Path: path, Detail: err.Error()}
+			if opts.Fix {
+				issue.Action = "left in place"
+			}
+			report.Issues = append(report.Issues, issue)
+			return nil
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			issue := FsckIssue{Kind: IssueCorrupt, Path: path, Detail: err.Error()}
+			if opts.Fix {
+				if dest, qerr := quarantine(path); qerr != nil {
+					issue.Action = fmt.Sprintf("quarantine failed: %v", qerr)
+				} else {
+					issue.Fixed, issue.Action = true, "quarantined to "+dest
+				}
+			}
+			report.Issues = append(report.Issues, issue)
+			return nil
+		}
+
+		meta, _ := doc["_meta"].(map[string]interface{})
+		if stage := getString(meta, "stage"); stage != "" {
+			stages = append(stages, fsckStage{
+				path:     path,
+				stage:    stage,
+				previous: getString(meta, "previous_id"),
+				archived: getString(meta, "archived_source"),
+			})
+			eventID := getString(meta, "event_id")
+			if stage == "raw" {
+				// Raw documents are stored under their category folder, which
+				// is what the processor uses as the source in PreviousID.
+				stageKeys[fmt.Sprintf("raw/%s/%s", filepath.Base(filepath.Dir(path)), eventID)] = true
+				stageKeys[fmt.Sprintf("raw/%s/%s", getString(meta, "source"), eventID)] = true
+			} else {
+				stageKeys[fmt.Sprintf("%s/%s/%s", stage, getString(meta, "source"), eventID)] = true
+			}
+			return nil
+		}
+
+		if getString(meta, "id") == "" {
+			return nil // Not an LMC entity (notes, exports, etc.)
+		}
+		entity, err := l.loadEntity(path)
+		if err != nil {
+			return nil
+		}
+		entities[path] = entity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// ID/filename mismatches
+	byID := make(map[string]*Entity)
+	for _, path := range sortedKeys(entities) {
+		e := entities[path]
+		entityType, id, splitErr := splitEntityID(e.ID)
+		if splitErr != nil {
+			issue := FsckIssue{Kind: IssueIDMismatch, Path: path, EntityID: e.ID, Detail: "malformed entity ID"}
+			if opts.Fix {
+				if dest, qerr := quarantine(path); qerr == nil {
+					issue.Fixed, issue.Action = true, "quarantined to "+dest
+				}
+			}
+			report.Issues = append(report.Issues, issue)
+			continue
+		}
+
+		expected := l.entityPath(entityType, id)
+		if expected == path {
+			byID[e.ID] = e
+			continue
+		}
+
+		issue := FsckIssue{
+			Kind:     IssueIDMismatch,
+			Path:     path,
+			EntityID: e.ID,
+			Detail:   fmt.Sprintf("expected at %s", expected),
+		}
+		if opts.Fix {
+			if _, statErr := os.Stat(expected); os.IsNotExist(statErr) {
+				if mkErr := os.MkdirAll(filepath.Dir(expected), 0755); mkErr == nil && os.Rename(path, expected) == nil {
+					l.track("move "+e.ID, path, expected)
+					e.Path = expected
+					byID[e.ID] = e
+					issue.Fixed, issue.Action = true, "moved to "+expected
+				}
+			} else if dest, qerr := quarantine(path); qerr == nil {
+				issue.Fixed, issue.Action = true, "quarantined to "+dest+" (target occupied)"
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+		if !issue.Fixed {
+			byID[e.ID] = e // Still readable where it is
+		}
+	}
+
+	// Dangling edges and collisions
+	idAtPath := make(map[string]string)
+	for id, e := range byID {
+		idAtPath[e.Path] = id
+	}
+	for _, e := range sortedEntities(byID) {
+		entityType, id, _ := splitEntityID(e.ID)
+		// Entities left at the wrong path are not rewritten in place.
+		canPrune := opts.Fix && e.Path == l.entityPath(entityType, id)
+
+		var kept []Edge
+		var pruned []int // Indexes into report.Issues
+		for _, link := range e.Links {
+			// A link may name an alias of the entity it points at
+			if _, ok := byID[l.resolve(link.To)]; ok {
+				kept = append(kept, link)
+				continue
+			}
+
+			if entityType, id, splitErr := splitEntityID(link.To); splitErr == nil {
+				if holder, ok := idAtPath[l.entityPath(entityType, id)]; ok {
+					report.Issues = append(report.Issues, FsckIssue{
+						Kind:     IssueCollision,
+						Path:     e.Path,
+						EntityID: e.ID,
+						Detail:   fmt.Sprintf("%s resolves to the file for %s", link.To, holder),
+					})
+					kept = append(kept, link)
+					continue
+				}
+			}
+
+			issue := FsckIssue{
+				Kind:     IssueDanglingEdge,
+				Path:     e.Path,
+				EntityID: e.ID,
+				Detail:   fmt.Sprintf("%s -> %s (missing)", link.Type, link.To),
+			}
+			if canPrune {
+				pruned = append(pruned, len(report.Issues))
+			} else {
+				kept = append(kept, link)
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+
+		if len(pruned) > 0 {
+			action := "pruned link"
+			_, werr := l.writeLocked(entityType, id, e.Content, kept, anyRevision)
+			if werr != nil {
+				action = fmt.Sprintf("prune failed: %v", werr)
+			}
+			for _, i := range pruned {
+				report.Issues[i].Fixed, report.Issues[i].Action = werr == nil, action
+			}
+		}
+	}
+
+	// Orphaned stage documents. Sources moved into a retention archive
+	// are not missing: retention points derived documents at the archive
+	// before it removes the original.
+	for _, s := range stages {
+		if s.stage == "raw" || s.previous == "" || stageKeys[s.previous] || s.archived != "" {
+			continue
+		}
+		issue := FsckIssue{
+			Kind:   IssueOrphanedStage,
+			Path:   s.path,
+			Detail: fmt.Sprintf("%s document's source %s is missing", s.stage, s.previous),
+		}
+		if opts.Fix {
+			if dest, qerr := quarantine(s.path); qerr == nil {
+				issue.Fixed, issue.Action = true, "quarantined to "+dest
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	if opts.Fix && len(report.Issues) > 0 {
+		if err := l.rebuildIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("[lmc] Fsck checked %d files, found %d issues", report.Checked, len(report.Issues))
+	return report, nil
+}
+
+func sortedKeys(m map[string]*Entity) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedEntities(byID map[string]*Entity) []*Entity {
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	entities := make([]*Entity, len(ids))
+	for i, id := range ids {
+		entities[i] = byID[id]
+	}
+	return entities
+}
@@ -0,0 +1,237 @@
+package lmc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+func writeRaw(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countKinds(issues []FsckIssue) map[IssueKind]int {
+	counts := make(map[IssueKind]int)
+	for _, issue := range issues {
+		counts[issue.Kind]++
+	}
+	return counts
+}
+
+func TestFsck(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "bob", map[string]interface{}{"name": "Bob"}, nil)
+	lib.Store("people", "a_b", map[string]interface{}{"name": "AB"}, nil)
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice"}, []Edge{
+		{Type: "works_with", To: "people/bob"},
+		{Type: "works_with", To: "people/ghost"},
+		{Type: "works_with", To: "people/a.b"},
+	})
+
+	// Corrupt JSON
+	writeRaw(t, filepath.Join(dir, "people", "bad.json"), "{not json")
+	// Entity stored under the wrong filename
+	writeRaw(t, filepath.Join(dir, "people", "wrong.json"),
+		`{"_meta":{"id":"people/carol","type":"people"},"content":{"name":"Carol"},"links":[]}`)
+	// Stage documents: one with its raw source, one orphan
+	writeRaw(t, filepath.Join(dir, "calendar", "calendar_2026-01-01_evt1.json"),
+		`{"_meta":{"source":"google-calendar","event_id":"evt1","stage":"raw"}}`)
+	writeRaw(t, filepath.Join(dir, "bronze", "google-calendar", "2026-01-01_evt1.json"),
+		`{"_meta":{"source":"google-calendar","event_id":"evt1","stage":"bronze","previous_id":"raw/calendar/evt1"},"content":{}}`)
+	writeRaw(t, filepath.Join(dir, "silver", "google-calendar", "2026-01-01_evt2.json"),
+		`{"_meta":{"source":"google-calendar","event_id":"evt2","stage":"silver","previous_id":"bronze/google-calendar/evt2"},"content":{}}`)
+
+	report, err := lib.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+
+	want := map[IssueKind]int{
+		IssueCorrupt:       1,
+		IssueDanglingEdge:  1,
+		IssueIDMismatch:    1,
+		IssueCollision:     1,
+		IssueOrphanedStage: 1,
+	}
+	got := countKinds(report.Issues)
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("%s issues = %d, want %d (%+v)", kind, got[kind], n, report.Issues)
+		}
+	}
+	if report.Unfixed() != len(report.Issues) {
+		t.Error("check-only run should not fix anything")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "people", "bad.json")); err != nil {
+		t.Error("check-only run should not move files")
+	}
+
+	// Repair
+	report, err = lib.Fsck(FsckOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("Fsck --fix failed: %v", err)
+	}
+	if report.Unfixed() != 1 {
+		t.Errorf("unfixed = %d, want 1 (the collision): %+v", report.Unfixed(), report.Issues)
+	}
+	if !strings.HasPrefix(report.QuarantineDir, filepath.Join(dir, quarantineDirName)) {
+		t.Errorf("QuarantineDir = %q", report.QuarantineDir)
+	}
+	if _, err := os.Stat(filepath.Join(report.QuarantineDir, "people", "bad.json")); err != nil {
+		t.Errorf("corrupt file not quarantined: %v", err)
+	}
+
+	carol, err := lib.Get("people/carol")
+	if err != nil {
+		t.Fatalf("misplaced entity not moved: %v", err)
+	}
+	if carol.Content["name"] != "Carol" {
+		t.Errorf("carol content = %v", carol.Content)
+	}
+
+	alice, err := lib.Get("people/alice")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	for _, link := range alice.Links {
+		if link.To == "people/ghost" {
+			t.Error("dangling link was not pruned")
+		}
+	}
+	if len(alice.Links) != 2 {
+		t.Errorf("alice links = %+v, want bob and a.b kept", alice.Links)
+	}
+
+	// A second pass only reports what --fix cannot repair.
+	report, err = lib.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if got := countKinds(report.Issues); len(report.Issues) != 1 || got[IssueCollision] != 1 {
+		t.Errorf("after fix issues = %+v, want only the collision", report.Issues)
+	}
+
+	// Quarantined files stay out of queries.
+	results, err := lib.Query(QueryOptions{Type: "people"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Errorf("people = %d, want 4", len(results))
+	}
+}
+
+func TestStoreRejectsFilenameCollision(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := lib.Store("people", "a.b", map[string]interface{}{}, nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := lib.Store("people", "a_b", map[string]interface{}{}, nil); err == nil {
+		t.Fatal("expected collision error")
+	}
+	entity, err := lib.Get("people/a.b")
+	if err != nil || entity.ID != "people/a.b" {
+		t.Errorf("original entity overwritten: %+v, %v", entity, err)
+	}
+}
+
+func TestFsckArchivedSource(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// The raw source was moved into a retention archive
+	writeRaw(t, filepath.Join(dir, "bronze", "slack", "2026-01-01_evt1.json"),
+		`{"_meta":{"source":"slack","event_id":"evt1","stage":"bronze","previous_id":"raw/slack/evt1","archived_source":"archive/slack/slack_2026-01.tar.gz#slack_2026-01-01_evt1.json"},"content":{}}`)
+
+	report, err := lib.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("issues = %+v, want none", report.Issues)
+	}
+}
+
+func TestFsckAliasLink(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "alice", map[string]interface{}{"name": "Alice"}, nil)
+	if _, err := lib.AddAlias("users/U123ABC", "people/alice"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	lib.Store("notes", "standup", map[string]interface{}{"text": "Standup"}, []Edge{{Type: "about", To: "users/U123ABC"}})
+
+	report, err := lib.Fsck(FsckOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("issues = %+v, want none", report.Issues)
+	}
+	note, err := lib.Get("notes/standup")
+	if err != nil || len(note.Links) != 1 {
+		t.Errorf("link to an alias pruned: %+v, %v", note, err)
+	}
+}
+
+func TestFsckUnreadable(t *testing.T) {
+	key, _, err := vault.NewKeyFileKey(filepath.Join(t.TempDir(), "hal.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	vault.SetDefault(key)
+	defer vault.Reset()
+
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	writeRaw(t, filepath.Join(dir, "people", "bad.json"), "{not json")
+
+	// The key is gone: the sealed entity can't be opened
+	vault.Reset()
+	t.Setenv(vault.PassphraseEnv, "")
+	t.Setenv(vault.KeyFileEnv, "")
+
+	report, err := lib.Fsck(FsckOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	kinds := countKinds(report.Issues)
+	if kinds[IssueUnreadable] != 1 || kinds[IssueCorrupt] != 1 {
+		t.Errorf("issues = %+v, want the sealed file reported and the scan continued", report.Issues)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "people", "dave.json")); err != nil {
+		t.Errorf("sealed file moved: %v", err)
+	}
+	if report.Unfixed() != 1 {
+		t.Errorf("unfixed = %d, want 1", report.Unfixed())
+	}
+}
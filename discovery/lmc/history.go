@@ -0,0 +1,73 @@
+package lmc
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/pearcec/hal9000/discovery/history"
+)
+
+// SetHistory commits every write to repo. Pass nil to stop recording.
+// New enables it automatically when library.history.enabled is set.
+func (l *Library) SetHistory(repo *history.Repo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.history = repo
+	l.historyChanges, l.historyPaths = nil, nil
+}
+
+// Revert undoes revs in repo with a new commit. The write lock is held so
+// no writer stores a file while the commits are restored, and the index is
+// rebuilt from the restored files.
+func (l *Library) Revert(repo *history.Repo, revs ...string) (*history.Commit, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	commit, err := repo.Revert(revs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.rebuildIndex(); err != nil {
+		return commit, err
+	}
+	return commit, nil
+}
+
+// track queues a change for the next history commit. Caller must hold l.mu.
+func (l *Library) track(change string, paths ...string) {
+	if l.history == nil {
+		return
+	}
+	l.historyChanges = append(l.historyChanges, change)
+	l.historyPaths = append(l.historyPaths, paths...)
+}
+
+// commitHistory commits the queued changes in one commit, so an operation
+// touching several entities (Merge, Import, Fsck) is a single history entry.
+// Caller must hold l.mu and the write lock.
+func (l *Library) commitHistory(subject string) {
+	if l.history == nil || len(l.historyChanges) == 0 {
+		return
+	}
+	changes, paths := l.historyChanges, l.historyPaths
+	l.historyChanges, l.historyPaths = nil, nil
+
+	message := "lmc: " + history.Message(subject, changes)
+	if err := l.history.Commit(message, paths...); err != nil {
+		log.Printf("[lmc] Warning: history commit failed: %v", err)
+	}
+}
+
+// describeChange formats a change for a commit message.
+func describeChange(op ChangeOp, entity *Entity) string {
+	if op == ChangeDelete {
+		return fmt.Sprintf("%s %s", op, entity.ID)
+	}
+	return fmt.Sprintf("%s %s (revision %d)", op, entity.ID, entity.Revision)
+}
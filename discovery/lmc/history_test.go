@@ -0,0 +1,97 @@
+package lmc
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/history"
+)
+
+func TestHistoryCommitsWrites(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	repo := history.Open(dir)
+	lib.SetHistory(repo)
+
+	if _, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := lib.Store("people", "dave-bowman", map[string]interface{}{"role": "Commander"}, nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := lib.Merge("people/dave-bowman", "people/dave"); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	commits, err := repo.Log(history.LogOptions{})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	want := []string{
+		"lmc: merge people/dave-bowman into people/dave",
+		"lmc: create people/dave-bowman (revision 1)",
+		"lmc: create people/dave (revision 1)",
+		"Initial library snapshot",
+	}
+	if strings.Join(subjects, "\n") != strings.Join(want, "\n") {
+		t.Errorf("subjects = %q, want %q", subjects, want)
+	}
+
+	// The merge is one commit listing every change it made
+	for _, line := range []string{"update people/dave (revision 2)", "delete people/dave-bowman", "create aliases/people"} {
+		if !strings.Contains(commits[0].Body, line) {
+			t.Errorf("merge body missing %q:\n%s", line, commits[0].Body)
+		}
+	}
+
+	if err := lib.Delete("people/dave"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	latest, _ := repo.Log(history.LogOptions{Limit: 1})
+	if len(latest) != 1 || latest[0].Subject != "lmc: delete people/dave" {
+		t.Errorf("latest = %+v", latest)
+	}
+}
+
+func TestRevertReindexes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	repo := history.Open(dir)
+	lib.SetHistory(repo)
+
+	if _, err := lib.Store("people", "frank", map[string]interface{}{"name": "Frank"}, nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, []Edge{{To: "people/frank", Type: "knows"}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	if _, err := lib.Revert(repo, "HEAD"); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if _, err := lib.Get("people/dave"); err == nil {
+		t.Error("people/dave still stored after revert")
+	}
+	if edges := lib.index.incoming["people/frank"]; len(edges) != 0 {
+		t.Errorf("index still links people/frank: %+v", edges)
+	}
+}
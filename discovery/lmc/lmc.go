@@ -23,51 +23,66 @@ import (
 	"time"
 
 	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 // Library manages the document-based knowledge graph.
 type Library struct {
-	BasePath string
-	index    *EdgeIndex
-	mu       sync.RWMutex
+	BasePath   string
+	index      *EdgeIndex
+	schemas    *SchemaRegistry
+	validation ValidationMode
+	mu         sync.RWMutex
+
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+
+	history        *history.Repo // Nil unless library history is enabled
+	historyChanges []string      // Pending commit message lines
+	historyPaths   []string      // Pending files to commit
 }
 
 // Entity represents a document/node in the library.
 type Entity struct {
-	ID       string                 `json:"id"`        // Unique identifier (type/filename)
-	Type     string                 `json:"type"`      // Entity type (folder name)
-	Path     string                 `json:"path"`      // Full file path
-	Content  map[string]interface{} `json:"content"`   // Document content
-	Links    []Edge                 `json:"links"`     // Outgoing edges
-	Created  time.Time              `json:"created"`
-	Modified time.Time              `json:"modified"`
+	ID           string                 `json:"id"`                      // Unique identifier (type/filename)
+	Type         string                 `json:"type"`                    // Entity type (folder name)
+	Path         string                 `json:"path"`                    // Full file path
+	Content      map[string]interface{} `json:"content"`                 // Document content
+	Links        []Edge                 `json:"links"`                   // Outgoing edges
+	DerivedLinks []Edge                 `json:"derived_links,omitempty"` // Edges extracted from content
+	Created      time.Time              `json:"created"`
+	Modified     time.Time              `json:"modified"`
+	Revision     int                    `json:"revision"` // Incremented on every write
 }
 
 // Edge represents a relationship between entities.
 type Edge struct {
-	From   string `json:"from"`   // Source entity ID
-	To     string `json:"to"`     // Target entity ID
-	Type   string `json:"type"`   // Relationship type
-	Label  string `json:"label,omitempty"`
+	From    string `json:"from"` // Source entity ID
+	To      string `json:"to"`   // Target entity ID
+	Type    string `json:"type"` // Relationship type
+	Label   string `json:"label,omitempty"`
+	Derived bool   `json:"derived,omitempty"` // Extracted from content, not passed to Store
 }
 
 // EdgeIndex maintains an in-memory index of edges for fast lookup.
 type EdgeIndex struct {
-	outgoing map[string][]Edge // entityID -> outgoing edges
-	incoming map[string][]Edge // entityID -> incoming edges
-	byType   map[string][]Edge // edgeType -> edges
+	outgoing map[string][]Edge     // entityID -> outgoing edges
+	incoming map[string][]Edge     // entityID -> incoming edges
+	byType   map[string][]Edge     // edgeType -> edges
+	aliases  map[string]aliasEntry // normalized alias -> alias record
 	mu       sync.RWMutex
 }
 
 // QueryOptions specifies search parameters.
 type QueryOptions struct {
-	Type      string    // Filter by entity type
-	Since     time.Time // Modified since
-	Before    time.Time // Modified before
-	Contains  string    // Text search in content
-	LinkedTo  string    // Has edge to this entity
-	LinkedFrom string   // Has edge from this entity
-	Limit     int       // Max results
+	Type       string    // Filter by entity type
+	Since      time.Time // Modified since
+	Before     time.Time // Modified before
+	Contains   string    // Text search in content
+	LinkedTo   string    // Has edge to this entity
+	LinkedFrom string    // Has edge from this entity
+	Limit      int       // Max results
 }
 
 // New creates a new Library instance.
@@ -80,9 +95,18 @@ func New(basePath string) (*Library, error) {
 	}
 
 	lib := &Library{
-		BasePath: path,
-		index:    newEdgeIndex(),
+		BasePath:   path,
+		index:      newEdgeIndex(),
+		validation: ValidationMode(config.GetLibraryValidation()),
+		history:    history.ForLibrary(path),
+	}
+
+	// Load per-type schemas (optional)
+	schemas, err := LoadSchemas(config.GetSchemasDir())
+	if err != nil {
+		log.Printf("[lmc] Warning: failed to load schemas: %v", err)
 	}
+	lib.schemas = schemas
 
 	// Build initial index
 	if err := lib.rebuildIndex(); err != nil {
@@ -98,14 +122,88 @@ func newEdgeIndex() *EdgeIndex {
 		outgoing: make(map[string][]Edge),
 		incoming: make(map[string][]Edge),
 		byType:   make(map[string][]Edge),
+		aliases:  make(map[string]aliasEntry),
 	}
 }
 
 // Store saves an entity to the library.
+// The write is atomic and serialized across processes via an advisory lock,
+// but it overwrites whatever revision is on disk. Use StoreIfMatch or Update
+// when concurrent writers may touch the same entity.
 func (l *Library) Store(entityType string, id string, content map[string]interface{}, links []Edge) (*Entity, error) {
+	return l.store(entityType, id, content, links, anyRevision)
+}
+
+// StoreIfMatch saves an entity only if its current revision on disk equals
+// expectedRevision. Pass 0 to require that the entity does not exist yet.
+// Returns a *ConflictError (matching ErrConflict) when the revision differs.
+func (l *Library) StoreIfMatch(entityType string, id string, content map[string]interface{}, links []Edge, expectedRevision int) (*Entity, error) {
+	if expectedRevision < 0 {
+		return nil, fmt.Errorf("invalid expected revision: %d", expectedRevision)
+	}
+	return l.store(entityType, id, content, links, expectedRevision)
+}
+
+// Update performs a locked read-modify-write of an entity. fn receives the
+// current entity (or an empty one with Revision 0 if it does not exist yet)
+// and may modify its Content and Links. If fn returns an error nothing is
+// written. No other writer, in this or another process, can interleave.
+func (l *Library) Update(entityID string, fn func(*Entity) error) (*Entity, error) {
+	entityType, id, err := splitEntityID(entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("")
+
+	fullPath := l.entityPath(entityType, id)
+	entity, err := l.loadEntity(fullPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		entity = &Entity{
+			ID:      fmt.Sprintf("%s/%s", entityType, id),
+			Type:    entityType,
+			Path:    fullPath,
+			Content: make(map[string]interface{}),
+		}
+	}
+
+	if err := fn(entity); err != nil {
+		return nil, err
+	}
+
+	return l.writeLocked(entityType, id, entity.Content, entity.Links, entity.Revision)
+}
+
+// anyRevision disables the optimistic concurrency check in store.
+const anyRevision = -1
+
+func (l *Library) store(entityType, id string, content map[string]interface{}, links []Edge, expectedRevision int) (*Entity, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	lock, err := l.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("")
+
+	return l.writeLocked(entityType, id, content, links, expectedRevision)
+}
+
+// writeLocked writes an entity. Caller must hold l.mu and the write lock.
+func (l *Library) writeLocked(entityType, id string, content map[string]interface{}, links []Edge, expectedRevision int) (*Entity, error) {
 	// Ensure type directory exists
 	typePath := filepath.Join(l.BasePath, entityType)
 	if err := os.MkdirAll(typePath, 0755); err != nil {
@@ -114,8 +212,17 @@ func (l *Library) Store(entityType string, id string, content map[string]interfa
 
 	// Build entity
 	entityID := fmt.Sprintf("%s/%s", entityType, id)
-	filename := sanitizeFilename(id) + ".json"
-	fullPath := filepath.Join(typePath, filename)
+	fullPath := l.entityPath(entityType, id)
+
+	// Apply schema defaults and validation
+	content, err := l.applySchema(entityType, entityID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Links marked Derived come from an extractor outside the LMC (the
+	// processor's silver stage) and are stored with the derived edges.
+	links, supplied := splitSupplied(links)
 
 	now := time.Now()
 	entity := &Entity{
@@ -129,9 +236,30 @@ func (l *Library) Store(entityType string, id string, content map[string]interfa
 	}
 
 	// Check if updating existing
-	if info, err := os.Stat(fullPath); err == nil {
-		entity.Created = info.ModTime() // Preserve original creation time
+	currentRevision := 0
+	if existing, err := l.loadEntity(fullPath); err == nil {
+		if existing.ID != "" && existing.ID != entityID {
+			return nil, fmt.Errorf("%s collides with %s: both map to %s", entityID, existing.ID, fullPath)
+		}
+		currentRevision = existing.Revision
+		if !existing.Created.IsZero() {
+			entity.Created = existing.Created // Preserve original creation time
+		}
+		// Rewrites that do not supply derived edges keep the previous set
+		if supplied == nil {
+			supplied = suppliedLinks(existing)
+		}
+	} else if info, statErr := os.Stat(fullPath); statErr == nil {
+		// Unparseable file: keep its timestamp, treat as revision 1
+		currentRevision = 1
+		entity.Created = info.ModTime()
+	}
+
+	if expectedRevision != anyRevision && expectedRevision != currentRevision {
+		return nil, &ConflictError{EntityID: entityID, Expected: expectedRevision, Actual: currentRevision}
 	}
+	entity.Revision = currentRevision + 1
+	entity.DerivedLinks = mergeDerived(entityID, links, supplied, deriveLinks(entityID, content, links))
 
 	// Serialize
 	doc := map[string]interface{}{
@@ -140,24 +268,34 @@ func (l *Library) Store(entityType string, id string, content map[string]interfa
 			"type":     entityType,
 			"created":  entity.Created.Format(time.RFC3339),
 			"modified": entity.Modified.Format(time.RFC3339),
+			"revision": entity.Revision,
 		},
 		"content": content,
 		"links":   links,
 	}
+	if len(entity.DerivedLinks) > 0 {
+		doc["derived_links"] = entity.DerivedLinks
+	}
 
 	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return nil, err
 	}
 
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+	if err := vault.WriteFile(fullPath, data, 0644); err != nil {
 		return nil, err
 	}
 
 	// Update index
 	l.indexEntity(entity)
 
-	log.Printf("[lmc] Stored entity: %s", entityID)
+	op := ChangeUpdate
+	if currentRevision == 0 {
+		op = ChangeCreate
+	}
+	l.notify(op, entity)
+
+	log.Printf("[lmc] Stored entity: %s (rev %d)", entityID, entity.Revision)
 	return entity, nil
 }
 
@@ -166,16 +304,18 @@ func (l *Library) Get(entityID string) (*Entity, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	parts := strings.SplitN(entityID, "/", 2)
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid entity ID: %s", entityID)
-	}
+	return l.get(entityID)
+}
 
-	entityType, id := parts[0], parts[1]
-	filename := sanitizeFilename(id) + ".json"
-	fullPath := filepath.Join(l.BasePath, entityType, filename)
+// get loads an entity, following an alias to its canonical entity first.
+// Caller must hold l.mu.
+func (l *Library) get(entityID string) (*Entity, error) {
+	entityType, id, err := splitEntityID(l.resolve(entityID))
+	if err != nil {
+		return nil, err
+	}
 
-	return l.loadEntity(fullPath)
+	return l.loadEntity(l.entityPath(entityType, id))
 }
 
 // Query searches for entities matching the options.
@@ -191,11 +331,21 @@ func (l *Library) Query(opts QueryOptions) ([]*Entity, error) {
 		searchPath = filepath.Join(l.BasePath, opts.Type)
 	}
 
+	// Link filters follow aliases on both ends
+	linkedTo := l.resolve(opts.LinkedTo)
+	var linkedFrom map[string]bool
+	if opts.LinkedFrom != "" {
+		linkedFrom = l.linkTargets(l.resolve(opts.LinkedFrom))
+	}
+
 	err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+		if info.IsDir() {
+			return skipHiddenDir(searchPath, path, info)
+		}
+		if !strings.HasSuffix(path, ".json") {
 			return nil
 		}
 
@@ -214,10 +364,10 @@ func (l *Library) Query(opts QueryOptions) ([]*Entity, error) {
 		if opts.Contains != "" && !l.contentContains(entity, opts.Contains) {
 			return nil
 		}
-		if opts.LinkedTo != "" && !l.hasLinkTo(entity.ID, opts.LinkedTo) {
+		if opts.LinkedTo != "" && !l.hasLinkTo(entity.ID, linkedTo) {
 			return nil
 		}
-		if opts.LinkedFrom != "" && !l.hasLinkFrom(opts.LinkedFrom, entity.ID) {
+		if opts.LinkedFrom != "" && !linkedFrom[l.resolve(entity.ID)] {
 			return nil
 		}
 
@@ -243,62 +393,88 @@ func (l *Library) Query(opts QueryOptions) ([]*Entity, error) {
 }
 
 // GetLinked returns entities linked to/from the given entity.
+// Aliases are followed: edges to or from any identifier that resolves to
+// the same canonical entity are included, and linked entities are returned
+// in their canonical form. Alias records themselves are not returned.
 func (l *Library) GetLinked(entityID string, direction string) ([]*Entity, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	var edges []Edge
+	var out, in bool
 	switch direction {
 	case "out", "outgoing":
-		edges = l.index.outgoing[entityID]
+		out = true
 	case "in", "incoming":
-		edges = l.index.incoming[entityID]
+		in = true
 	case "both":
-		edges = append(l.index.outgoing[entityID], l.index.incoming[entityID]...)
+		out, in = true, true
 	default:
 		return nil, fmt.Errorf("invalid direction: %s", direction)
 	}
 
-	var results []*Entity
-	seen := make(map[string]bool)
+	canonicalID := l.resolve(entityID)
+	ids := append([]string{canonicalID}, l.aliasesOf(canonicalID)...)
 
-	for _, edge := range edges {
-		targetID := edge.To
-		if direction == "in" || direction == "incoming" {
-			targetID = edge.From
-		}
-
-		if seen[targetID] {
-			continue
+	var results []*Entity
+	seen := map[string]bool{canonicalID: true}
+	add := func(otherID string) {
+		otherID = l.resolve(otherID)
+		if seen[otherID] || isAliasRecord(otherID) {
+			return
 		}
-		seen[targetID] = true
+		seen[otherID] = true
 
-		entity, err := l.Get(targetID)
+		entity, err := l.get(otherID)
 		if err != nil {
-			continue // Skip missing entities
+			return // Skip missing entities
 		}
 		results = append(results, entity)
 	}
 
+	for _, id := range ids {
+		if out {
+			for _, edge := range l.index.outgoing[id] {
+				add(edge.To)
+			}
+		}
+		if in {
+			for _, edge := range l.index.incoming[id] {
+				add(edge.From)
+			}
+		}
+	}
+
 	return results, nil
 }
 
+// PathFor returns the file an entity is, or would be, stored in.
+func (l *Library) PathFor(entityID string) (string, error) {
+	entityType, id, err := splitEntityID(entityID)
+	if err != nil {
+		return "", err
+	}
+	return l.entityPath(entityType, id), nil
+}
+
 // Delete removes an entity from the library.
 func (l *Library) Delete(entityID string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	// Build path directly to avoid deadlock (Get() would try to acquire RLock)
-	parts := strings.SplitN(entityID, "/", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid entity ID: %s", entityID)
+	entityType, id, err := splitEntityID(entityID)
+	if err != nil {
+		return err
 	}
 
-	entityType, id := parts[0], parts[1]
-	filename := sanitizeFilename(id) + ".json"
-	fullPath := filepath.Join(l.BasePath, entityType, filename)
+	lock, err := l.lockWrites()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	defer l.commitHistory("")
 
-	entity, err := l.loadEntity(fullPath)
+	entity, err := l.loadEntity(l.entityPath(entityType, id))
 	if err != nil {
 		return err
 	}
@@ -310,11 +486,80 @@ func (l *Library) Delete(entityID string) error {
 	if err := os.Remove(entity.Path); err != nil {
 		return err
 	}
+	l.notify(ChangeDelete, entity)
 
 	log.Printf("[lmc] Deleted entity: %s", entityID)
 	return nil
 }
 
+// SetSchemas replaces the schema registry and validation mode.
+func (l *Library) SetSchemas(schemas *SchemaRegistry, mode ValidationMode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.schemas = schemas
+	l.validation = mode
+}
+
+// Schemas returns the loaded schema registry (may be empty).
+func (l *Library) Schemas() *SchemaRegistry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.schemas
+}
+
+// ValidateAll checks every stored entity that has a schema and reports the
+// ones that violate it. If entityType is non-empty only that type is checked.
+func (l *Library) ValidateAll(entityType string) ([]ValidationReport, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	types := l.schemas.Types()
+	if entityType != "" {
+		types = []string{entityType}
+	}
+
+	var reports []ValidationReport
+	for _, t := range types {
+		schema := l.schemas.Get(t)
+		if schema == nil {
+			return nil, fmt.Errorf("no schema defined for type: %s", t)
+		}
+
+		entries, err := os.ReadDir(filepath.Join(l.BasePath, t))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			path := filepath.Join(l.BasePath, t, entry.Name())
+			entity, err := l.loadEntity(path)
+			if err != nil {
+				continue // Skip invalid files
+			}
+
+			content := entity.Content
+			if content == nil {
+				content = map[string]interface{}{}
+			}
+			if violations := schema.Validate(content); len(violations) > 0 {
+				reports = append(reports, ValidationReport{
+					EntityID:   entity.ID,
+					Path:       path,
+					Violations: violations,
+				})
+			}
+		}
+	}
+
+	return reports, nil
+}
+
 // ListTypes returns all entity types in the library.
 func (l *Library) ListTypes() ([]string, error) {
 	l.mu.RLock()
@@ -337,8 +582,39 @@ func (l *Library) ListTypes() ([]string, error) {
 
 // Internal methods
 
+// applySchema fills defaults and validates content against the type's schema.
+// In strict mode violations are returned as a *ValidationError; in warn mode
+// they are logged and the content is accepted.
+func (l *Library) applySchema(entityType, entityID string, content map[string]interface{}) (map[string]interface{}, error) {
+	schema := l.schemas.Get(entityType)
+	if schema == nil || l.validation == ValidationOff {
+		return content, nil
+	}
+
+	// Defaults go into a copy: the caller's map is left alone, even when
+	// strict validation rejects the write.
+	if content == nil {
+		content = make(map[string]interface{})
+	} else {
+		content = copyValue(content).(map[string]interface{})
+	}
+	schema.ApplyDefaults(content)
+
+	violations := schema.Validate(content)
+	if len(violations) == 0 {
+		return content, nil
+	}
+
+	verr := &ValidationError{EntityID: entityID, Violations: violations}
+	if l.validation == ValidationStrict {
+		return nil, verr
+	}
+	log.Printf("[lmc] Warning: %v", verr)
+	return content, nil
+}
+
 func (l *Library) loadEntity(path string) (*Entity, error) {
-	data, err := os.ReadFile(path)
+	data, err := vault.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -366,21 +642,43 @@ func (l *Library) loadEntity(path string) (*Entity, error) {
 		entity.Modified, _ = time.Parse(time.RFC3339, modified)
 	}
 
+	// Documents written before revisions existed count as revision 1
+	entity.Revision = 1
+	if rev, ok := meta["revision"].(float64); ok && rev > 0 {
+		entity.Revision = int(rev)
+	}
+
 	// Parse links
-	if links, ok := doc["links"].([]interface{}); ok {
+	entity.Links = parseEdges(entity.ID, doc["links"], false)
+
+	// Derived links are written by Store; documents written before content
+	// extraction existed are extracted on load so the index stays complete.
+	if _, ok := doc["derived_links"]; ok {
+		entity.DerivedLinks = parseEdges(entity.ID, doc["derived_links"], true)
+	} else {
+		entity.DerivedLinks = deriveLinks(entity.ID, entity.Content, entity.Links)
+	}
+
+	return entity, nil
+}
+
+// parseEdges decodes a JSON edge list into Edges originating at fromID.
+func parseEdges(fromID string, raw interface{}, derived bool) []Edge {
+	var edges []Edge
+	if links, ok := raw.([]interface{}); ok {
 		for _, l := range links {
 			if link, ok := l.(map[string]interface{}); ok {
-				entity.Links = append(entity.Links, Edge{
-					From:  entity.ID,
-					To:    getString(link, "to"),
-					Type:  getString(link, "type"),
-					Label: getString(link, "label"),
+				edges = append(edges, Edge{
+					From:    fromID,
+					To:      getString(link, "to"),
+					Type:    getString(link, "type"),
+					Label:   getString(link, "label"),
+					Derived: derived,
 				})
 			}
 		}
 	}
-
-	return entity, nil
+	return edges
 }
 
 func (l *Library) rebuildIndex() error {
@@ -389,13 +687,19 @@ func (l *Library) rebuildIndex() error {
 	l.index = newEdgeIndex()
 
 	return filepath.Walk(l.BasePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".json") {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			return skipHiddenDir(l.BasePath, path, info)
+		}
+		if !strings.HasSuffix(path, ".json") {
 			return nil
 		}
 
 		entity, err := l.loadEntity(path)
-		if err != nil {
-			return nil
+		if err != nil || entity.ID == "" {
+			return nil // Unreadable, or a stage document rather than an entity
 		}
 
 		l.indexEntity(entity)
@@ -408,66 +712,128 @@ func (l *Library) indexEntity(entity *Entity) {
 	defer l.index.mu.Unlock()
 
 	// Remove old edges for this entity
-	delete(l.index.outgoing, entity.ID)
+	l.index.removeFrom(entity.ID)
 
-	// Add new edges
-	for _, link := range entity.Links {
+	// Add new edges (explicit and derived)
+	for _, link := range entity.AllLinks() {
 		edge := Edge{
-			From:  entity.ID,
-			To:    link.To,
-			Type:  link.Type,
-			Label: link.Label,
+			From:    entity.ID,
+			To:      link.To,
+			Type:    link.Type,
+			Label:   link.Label,
+			Derived: link.Derived,
 		}
 
 		l.index.outgoing[entity.ID] = append(l.index.outgoing[entity.ID], edge)
 		l.index.incoming[link.To] = append(l.index.incoming[link.To], edge)
 		l.index.byType[link.Type] = append(l.index.byType[link.Type], edge)
 	}
+
+	if entity.Type == AliasType {
+		l.index.indexAlias(entity)
+	}
 }
 
 func (l *Library) deindexEntity(entity *Entity) {
 	l.index.mu.Lock()
 	defer l.index.mu.Unlock()
 
-	delete(l.index.outgoing, entity.ID)
+	l.index.removeFrom(entity.ID)
+}
 
-	// Remove from incoming edges (expensive but rare operation)
-	for targetID, edges := range l.index.incoming {
-		var filtered []Edge
-		for _, e := range edges {
-			if e.From != entity.ID {
-				filtered = append(filtered, e)
+// removeFrom drops every indexed edge originating at entityID.
+// Caller must hold idx.mu.
+func (idx *EdgeIndex) removeFrom(entityID string) {
+	for _, e := range idx.outgoing[entityID] {
+		idx.incoming[e.To] = filterEdgesFrom(idx.incoming[e.To], entityID)
+		if len(idx.incoming[e.To]) == 0 {
+			delete(idx.incoming, e.To)
+		}
+		idx.byType[e.Type] = filterEdgesFrom(idx.byType[e.Type], entityID)
+		if len(idx.byType[e.Type]) == 0 {
+			delete(idx.byType, e.Type)
+		}
+	}
+	delete(idx.outgoing, entityID)
+
+	if isAliasRecord(entityID) {
+		for key, alias := range idx.aliases {
+			if alias.record == entityID {
+				delete(idx.aliases, key)
 			}
 		}
-		l.index.incoming[targetID] = filtered
 	}
 }
 
+func filterEdgesFrom(edges []Edge, fromID string) []Edge {
+	var filtered []Edge
+	for _, e := range edges {
+		if e.From != fromID {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// AllLinks returns the entity's explicit links followed by its derived links.
+func (e *Entity) AllLinks() []Edge {
+	all := make([]Edge, 0, len(e.Links)+len(e.DerivedLinks))
+	all = append(all, e.Links...)
+	return append(all, e.DerivedLinks...)
+}
+
 func (l *Library) contentContains(entity *Entity, search string) bool {
 	data, _ := json.Marshal(entity.Content)
 	return strings.Contains(strings.ToLower(string(data)), strings.ToLower(search))
 }
 
-func (l *Library) hasLinkTo(fromID, toID string) bool {
+// hasLinkTo reports whether fromID links to canonicalID, directly or via an alias.
+func (l *Library) hasLinkTo(fromID, canonicalID string) bool {
 	for _, edge := range l.index.outgoing[fromID] {
-		if edge.To == toID {
+		if edge.Type != EdgeAliasOf && l.resolve(edge.To) == canonicalID {
 			return true
 		}
 	}
 	return false
 }
 
-func (l *Library) hasLinkFrom(fromID, toID string) bool {
-	for _, edge := range l.index.incoming[toID] {
-		if edge.From == fromID {
-			return true
+// linkTargets returns the canonical IDs that canonicalID (or any of its
+// aliases) links to.
+func (l *Library) linkTargets(canonicalID string) map[string]bool {
+	targets := make(map[string]bool)
+	for _, id := range append([]string{canonicalID}, l.aliasesOf(canonicalID)...) {
+		for _, edge := range l.index.outgoing[id] {
+			targets[l.resolve(edge.To)] = true
 		}
 	}
-	return false
+	return targets
 }
 
 // Helper functions
 
+// splitEntityID splits "type/id" into its parts.
+func splitEntityID(entityID string) (string, string, error) {
+	parts := strings.SplitN(entityID, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid entity ID: %s", entityID)
+	}
+	return parts[0], parts[1], nil
+}
+
+// entityPath returns the file path for an entity.
+func (l *Library) entityPath(entityType, id string) string {
+	return filepath.Join(l.BasePath, entityType, sanitizeFilename(id)+".json")
+}
+
+// skipHiddenDir tells filepath.Walk to skip dot directories below root
+// (.quarantine and the like), which never hold live entities.
+func skipHiddenDir(root, path string, info os.FileInfo) error {
+	if path != root && strings.HasPrefix(info.Name(), ".") {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
 		return v
@@ -259,3 +259,191 @@ func TestQuerySince(t *testing.T) {
 		t.Errorf("Query returned %d results, want 0", len(results))
 	}
 }
+
+func TestExtractEdges(t *testing.T) {
+	content := map[string]interface{}{
+		"notes": "Follow up with [[people/dave-bowman]] on PROJ-123, cc frank@discovery.one",
+		"thread": []interface{}{
+			"<@U123ABC> can you review [[projects/hal9000|HAL]]?",
+			"Duplicate mention of PROJ-123",
+		},
+	}
+
+	edges := ExtractEdges("notes/mission", content)
+
+	want := map[string]string{
+		"people/dave-bowman":         EdgeReferences,
+		"projects/hal9000":           EdgeReferences,
+		"jira/PROJ-123":              EdgeReferences,
+		"people/frank@discovery.one": EdgeMentions,
+		"users/U123ABC":              EdgeMentions,
+	}
+
+	if len(edges) != len(want) {
+		t.Fatalf("ExtractEdges returned %d edges, want %d: %+v", len(edges), len(want), edges)
+	}
+	for _, e := range edges {
+		if want[e.To] != e.Type {
+			t.Errorf("edge to %q has type %q, want %q", e.To, e.Type, want[e.To])
+		}
+		if !e.Derived {
+			t.Errorf("edge to %q not marked derived", e.To)
+		}
+		if e.From != "notes/mission" {
+			t.Errorf("edge From = %q, want %q", e.From, "notes/mission")
+		}
+	}
+}
+
+func TestExtractEdgesSkipsNonIssueKeys(t *testing.T) {
+	edges := ExtractEdges("notes/prose", map[string]interface{}{
+		"body": "Store dates as ISO-8601 in UTF-8, hash with SHA-256 over TLS-13, " +
+			"and see RFC-3339, CVE-2024 and the COVID-19 policy. Then fix HAL-42.",
+	})
+	if len(edges) != 1 || edges[0].To != "jira/HAL-42" {
+		t.Errorf("edges = %+v, want only jira/HAL-42", edges)
+	}
+}
+
+func TestExtractEdgesKeysAfterDot(t *testing.T) {
+	edges := ExtractEdges("notes/prose", map[string]interface{}{
+		"body": "see docs.PROJ-123, fixed in v2.PROJ-12, ask hal.HAL-9@discovery.one",
+	})
+	want := map[string]bool{
+		"jira/PROJ-123":                  true,
+		"jira/PROJ-12":                   true,
+		"people/hal.hal-9@discovery.one": true,
+	}
+	if len(edges) != len(want) {
+		t.Fatalf("edges = %+v, want %d", edges, len(want))
+	}
+	for _, e := range edges {
+		if !want[e.To] {
+			t.Errorf("unexpected edge to %q", e.To)
+		}
+	}
+}
+
+func TestExtractEdgesSkipsSelf(t *testing.T) {
+	edges := ExtractEdges("people/dave", map[string]interface{}{
+		"bio": "See [[people/dave]]",
+	})
+	if len(edges) != 0 {
+		t.Errorf("expected self-reference to be dropped, got %+v", edges)
+	}
+}
+
+func TestDerivedEdgesBacklinks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lmc-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	lib, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	entity, err := lib.Store("agenda", "today", map[string]interface{}{
+		"body": "Meet [[people/dave]] about the pod bay doors",
+	}, []Edge{{Type: "rolled_from", To: "agenda/yesterday"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	if len(entity.Links) != 1 {
+		t.Errorf("explicit links = %d, want 1", len(entity.Links))
+	}
+	if len(entity.DerivedLinks) != 1 {
+		t.Fatalf("derived links = %d, want 1: %+v", len(entity.DerivedLinks), entity.DerivedLinks)
+	}
+
+	backlinks, err := lib.GetLinked("people/dave", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(backlinks) != 1 || backlinks[0].ID != "agenda/today" {
+		t.Errorf("backlinks = %v, want [agenda/today]", backlinks)
+	}
+
+	// Derived links survive a reload from disk.
+	reloaded, err := lib.Get("agenda/today")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(reloaded.DerivedLinks) != 1 || !reloaded.DerivedLinks[0].Derived {
+		t.Errorf("reloaded derived links = %+v", reloaded.DerivedLinks)
+	}
+
+	// Rewriting without the reference drops the derived edge.
+	lib.Store("agenda", "today", map[string]interface{}{"body": "Nothing planned"}, nil)
+	backlinks, err = lib.GetLinked("people/dave", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(backlinks) != 0 {
+		t.Errorf("expected no backlinks after rewrite, got %d", len(backlinks))
+	}
+
+	// Index rebuilt from disk still sees derived edges.
+	lib.Store("notes", "n1", map[string]interface{}{"body": "ping [[people/dave]]"}, nil)
+	fresh, err := New(tmpDir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	backlinks, err = fresh.GetLinked("people/dave", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(backlinks) != 1 || backlinks[0].ID != "notes/n1" {
+		t.Errorf("rebuilt backlinks = %v, want [notes/n1]", backlinks)
+	}
+}
+
+func TestSuppliedDerivedEdges(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Edges marked Derived are stored with the derived links, not checked
+	// as explicit links.
+	entity, err := lib.Store("documents", "slack_123", map[string]interface{}{"title": "standup"}, []Edge{
+		{Type: "posted_in", To: "channels/C42", Derived: true},
+		{Type: "relates_to", To: "projects/discovery"},
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if len(entity.Links) != 1 || len(entity.DerivedLinks) != 1 {
+		t.Fatalf("links = %+v, derived = %+v", entity.Links, entity.DerivedLinks)
+	}
+	if entity.DerivedLinks[0].From != "documents/slack_123" {
+		t.Errorf("derived edge from = %q", entity.DerivedLinks[0].From)
+	}
+
+	// A rewrite that supplies none keeps them
+	if _, err := lib.Update("documents/slack_123", func(e *Entity) error {
+		e.Content["title"] = "standup notes"
+		return nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	linked, err := lib.GetLinked("channels/C42", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	if len(linked) != 1 || linked[0].ID != "documents/slack_123" {
+		t.Errorf("backlinks = %v, want [documents/slack_123]", linked)
+	}
+
+	report, err := lib.Fsck(FsckOptions{})
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if got := countKinds(report.Issues); got[IssueDanglingEdge] != 1 {
+		t.Errorf("dangling edges = %d, want 1 (the explicit link only)", got[IssueDanglingEdge])
+	}
+}
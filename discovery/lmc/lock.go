@@ -0,0 +1,33 @@
+package lmc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// ErrConflict is matched by errors.Is when a conditional write finds a
+// different revision on disk than the caller expected.
+var ErrConflict = errors.New("revision conflict")
+
+// ConflictError describes a failed optimistic concurrency check.
+type ConflictError struct {
+	EntityID string
+	Expected int // Revision the caller based its write on
+	Actual   int // Revision currently on disk (0 if absent)
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %v (expected revision %d, found %d)", e.EntityID, ErrConflict, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// lockWrites acquires the library-wide write lock, blocking until available.
+func (l *Library) lockWrites() (*vault.Lock, error) {
+	return vault.LockLibrary(l.BasePath)
+}
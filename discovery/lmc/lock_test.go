@@ -0,0 +1,218 @@
+package lmc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+func TestRevisionIncrements(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	first, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if first.Revision != 1 {
+		t.Errorf("first revision = %d, want 1", first.Revision)
+	}
+
+	second, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave Bowman"}, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if second.Revision != 2 {
+		t.Errorf("second revision = %d, want 2", second.Revision)
+	}
+
+	reloaded, err := lib.Get("people/dave")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if reloaded.Revision != 2 {
+		t.Errorf("reloaded revision = %d, want 2", reloaded.Revision)
+	}
+}
+
+func TestStoreIfMatchConflict(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if _, err := lib.StoreIfMatch("people", "frank", map[string]interface{}{"name": "Frank"}, nil, 0); err != nil {
+		t.Fatalf("create with revision 0 failed: %v", err)
+	}
+
+	// Creating again must fail: the entity now exists.
+	_, err = lib.StoreIfMatch("people", "frank", map[string]interface{}{"name": "Frank"}, nil, 0)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	var cerr *ConflictError
+	if !errors.As(err, &cerr) || cerr.Actual != 1 {
+		t.Errorf("conflict = %+v, want actual revision 1", cerr)
+	}
+
+	if _, err := lib.StoreIfMatch("people", "frank", map[string]interface{}{"name": "Frank Poole"}, nil, 1); err != nil {
+		t.Fatalf("update at matching revision failed: %v", err)
+	}
+
+	// A stale writer loses.
+	if _, err := lib.StoreIfMatch("people", "frank", map[string]interface{}{"name": "stale"}, nil, 1); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale revision, got %v", err)
+	}
+
+	entity, _ := lib.Get("people/frank")
+	if entity.Content["name"] != "Frank Poole" {
+		t.Errorf("name = %v, stale write should not have landed", entity.Content["name"])
+	}
+}
+
+func TestUpdateConcurrentLibraries(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two Library instances on the same path stand in for two processes.
+	libA, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	libB, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	increment := func(e *Entity) error {
+		n, _ := e.Content["count"].(float64)
+		e.Content["count"] = n + 1
+		return nil
+	}
+
+	const perWriter = 25
+	var wg sync.WaitGroup
+	for _, lib := range []*Library{libA, libB} {
+		lib := lib
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := lib.Update("counters/hits", increment); err != nil {
+					t.Errorf("Update failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	entity, err := libA.Get("counters/hits")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got := entity.Content["count"]; got != float64(2*perWriter) {
+		t.Errorf("count = %v, want %d (lost updates)", got, 2*perWriter)
+	}
+	if entity.Revision != 2*perWriter {
+		t.Errorf("revision = %d, want %d", entity.Revision, 2*perWriter)
+	}
+}
+
+func TestUpdateAbortsOnError(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	boom := errors.New("boom")
+	_, err = lib.Update("people/nobody", func(e *Entity) error { return boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if _, err := lib.Get("people/nobody"); err == nil {
+		t.Error("entity should not be written when the callback fails")
+	}
+}
+
+func TestAtomicWriteLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := lib.Store("notes", "n1", map[string]interface{}{"i": i}, nil); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+	if len(entries) != 1 {
+		t.Errorf("notes dir has %d entries, want 1", len(entries))
+	}
+}
+
+func TestSealedLibrary(t *testing.T) {
+	key, _, err := vault.NewKeyFileKey(filepath.Join(t.TempDir(), "hal.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	vault.SetDefault(key)
+	defer vault.Reset()
+
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave Bowman"}, []Edge{{Type: "works_on", To: "projects/jupiter"}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := lib.Store("projects", "jupiter", map[string]interface{}{"name": "Jupiter Mission"}, nil); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "people", "dave.json"))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !vault.IsSealed(raw) || strings.Contains(string(raw), "Bowman") {
+		t.Errorf("entity stored in plaintext: %s", raw)
+	}
+
+	// A fresh Library rebuilds its index from sealed files
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	entity, err := reopened.Get("people/dave")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if entity.Content["name"] != "Dave Bowman" {
+		t.Errorf("name = %v", entity.Content["name"])
+	}
+	if linked, _ := reopened.GetLinked("projects/jupiter", "in"); len(linked) != 1 {
+		t.Errorf("incoming links = %d, want 1", len(linked))
+	}
+
+	vault.SetDefault(nil)
+	if _, err := reopened.Get("people/dave"); err == nil {
+		t.Error("expected error reading sealed entity without a key")
+	}
+}
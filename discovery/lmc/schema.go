@@ -0,0 +1,395 @@
+package lmc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationMode controls how Store reacts to schema violations.
+type ValidationMode string
+
+const (
+	// ValidationOff skips schema validation entirely.
+	ValidationOff ValidationMode = "off"
+	// ValidationWarn logs violations but still stores the entity.
+	ValidationWarn ValidationMode = "warn"
+	// ValidationStrict rejects entities that violate their schema.
+	ValidationStrict ValidationMode = "strict"
+)
+
+// Schema is a JSON-Schema-style definition for entity content.
+// Only the subset of JSON Schema that HAL needs is supported.
+type Schema struct {
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"` // object, array, string, number, integer, boolean
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty" yaml:"default,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"` // date-time, date, email
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation describes a single schema failure.
+type Violation struct {
+	Path    string `json:"path"`    // Dotted path into content (e.g., "members[0]")
+	Message string `json:"message"` // What was wrong
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidationError is returned by Store in strict mode.
+type ValidationError struct {
+	EntityID   string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("%s violates schema: %s", e.EntityID, strings.Join(msgs, "; "))
+}
+
+// ValidationReport lists the violations found for one stored entity.
+type ValidationReport struct {
+	EntityID   string      `json:"entity_id"`
+	Path       string      `json:"path"`
+	Violations []Violation `json:"violations"`
+}
+
+// SchemaRegistry holds schemas keyed by entity type.
+type SchemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+// NewSchemaRegistry creates an empty registry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// LoadSchemas reads every <type>.json, <type>.yaml or <type>.yml file in dir.
+// A missing directory yields an empty registry.
+func LoadSchemas(dir string) (*SchemaRegistry, error) {
+	reg := NewSchemaRegistry()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return reg, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(entry.Name())
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var schema Schema
+		if ext == ".json" {
+			err = json.Unmarshal(data, &schema)
+		} else {
+			err = yaml.Unmarshal(data, &schema)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema %s: %w", entry.Name(), err)
+		}
+
+		if err := reg.Register(strings.TrimSuffix(entry.Name(), ext), &schema); err != nil {
+			return nil, fmt.Errorf("invalid schema %s: %w", entry.Name(), err)
+		}
+	}
+
+	return reg, nil
+}
+
+// Register adds a schema for an entity type, compiling any patterns.
+func (r *SchemaRegistry) Register(entityType string, schema *Schema) error {
+	if err := schema.compile(); err != nil {
+		return err
+	}
+	r.schemas[entityType] = schema
+	return nil
+}
+
+// Get returns the schema for an entity type, or nil.
+func (r *SchemaRegistry) Get(entityType string) *Schema {
+	if r == nil {
+		return nil
+	}
+	return r.schemas[entityType]
+}
+
+// Types returns the entity types that have schemas, sorted.
+func (r *SchemaRegistry) Types() []string {
+	if r == nil {
+		return nil
+	}
+	types := make([]string, 0, len(r.schemas))
+	for t := range r.schemas {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("bad pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, prop := range s.Properties {
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// ApplyDefaults fills in missing properties that declare a default value.
+// Nested objects that are present are filled recursively.
+func (s *Schema) ApplyDefaults(content map[string]interface{}) {
+	for name, prop := range s.Properties {
+		val, ok := content[name]
+		if !ok {
+			if prop.Default != nil {
+				content[name] = copyValue(prop.Default)
+			}
+			continue
+		}
+		if nested, ok := val.(map[string]interface{}); ok && prop.Type == "object" {
+			prop.ApplyDefaults(nested)
+		}
+	}
+}
+
+// Validate checks content against the schema and returns all violations.
+func (s *Schema) Validate(content map[string]interface{}) []Violation {
+	var violations []Violation
+	s.validate("", content, &violations)
+	return violations
+}
+
+func (s *Schema) validate(path string, v interface{}, out *[]Violation) {
+	fail := func(format string, args ...interface{}) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !matchesType(s.Type, v) {
+		fail("expected %s, got %s", s.Type, describeType(v))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v not in %v", v, s.Enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		if s.MinLength != nil && len(val) < *s.MinLength {
+			fail("length %d is less than %d", len(val), *s.MinLength)
+		}
+		if s.MaxLength != nil && len(val) > *s.MaxLength {
+			fail("length %d exceeds %d", len(val), *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("%q does not match pattern %q", val, s.Pattern)
+		}
+		if msg := checkFormat(s.Format, val); msg != "" {
+			fail("%s", msg)
+		}
+	case map[string]interface{}:
+		for _, req := range s.Required {
+			if _, ok := val[req]; !ok {
+				*out = append(*out, Violation{Path: joinPath(path, req), Message: "required property missing"})
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*out = append(*out, Violation{Path: joinPath(path, k), Message: "unexpected property"})
+				}
+				continue
+			}
+			prop.validate(joinPath(path, k), val[k], out)
+		}
+	default:
+		if n, ok := toFloat(v); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				fail("%v is less than minimum %v", n, *s.Minimum)
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				fail("%v exceeds maximum %v", n, *s.Maximum)
+			}
+		} else if s.Items != nil {
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Slice {
+				for i := 0; i < rv.Len(); i++ {
+					s.Items.validate(fmt.Sprintf("%s[%d]", path, i), rv.Index(i).Interface(), out)
+				}
+			}
+		}
+	}
+}
+
+func matchesType(schemaType string, v interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		return v != nil && reflect.ValueOf(v).Kind() == reflect.Slice
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		n, ok := toFloat(v)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func describeType(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := toFloat(v); ok {
+		return "number"
+	}
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+var formatEmailRe = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+func checkFormat(format, s string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Sprintf("%q is not an RFC3339 date-time", s)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Sprintf("%q is not a YYYY-MM-DD date", s)
+		}
+	case "email":
+		if !formatEmailRe.MatchString(s) {
+			return fmt.Sprintf("%q is not an email address", s)
+		}
+	}
+	return ""
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// copyValue deep-copies a default so entities never share mutable state.
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = copyValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, item := range val {
+			s[i] = copyValue(item)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,188 @@
+package lmc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const peopleSchema = `{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name":   {"type": "string", "minLength": 1},
+    "email":  {"type": "string", "format": "email"},
+    "role":   {"type": "string", "enum": ["engineer", "manager"], "default": "engineer"},
+    "tags":   {"type": "array", "items": {"type": "string"}, "default": []},
+    "level":  {"type": "integer", "minimum": 1, "maximum": 10}
+  }
+}`
+
+func newSchemaLibrary(t *testing.T, mode ValidationMode) *Library {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	schemaDir := filepath.Join(tmpDir, "schemas")
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(schemaDir, "people.json"), []byte(peopleSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := LoadSchemas(schemaDir)
+	if err != nil {
+		t.Fatalf("LoadSchemas failed: %v", err)
+	}
+
+	lib, err := New(filepath.Join(tmpDir, "library"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.SetSchemas(reg, mode)
+	return lib
+}
+
+func TestLoadSchemasYAML(t *testing.T) {
+	dir := t.TempDir()
+	yamlSchema := "type: object\nrequired: [members]\nproperties:\n  members:\n    type: array\n"
+	if err := os.WriteFile(filepath.Join(dir, "collaboration.yaml"), []byte(yamlSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatalf("LoadSchemas failed: %v", err)
+	}
+	if reg.Get("collaboration") == nil {
+		t.Fatal("expected collaboration schema to be loaded")
+	}
+
+	violations := reg.Get("collaboration").Validate(map[string]interface{}{})
+	if len(violations) != 1 || violations[0].Path != "members" {
+		t.Errorf("violations = %v, want missing members", violations)
+	}
+}
+
+func TestLoadSchemasMissingDir(t *testing.T) {
+	reg, err := LoadSchemas(filepath.Join(t.TempDir(), "nope"))
+	if err != nil {
+		t.Fatalf("LoadSchemas failed: %v", err)
+	}
+	if len(reg.Types()) != 0 {
+		t.Errorf("expected empty registry, got %v", reg.Types())
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	reg := NewSchemaRegistry()
+	if err := reg.Register("people", mustParseSchema(t, peopleSchema)); err != nil {
+		t.Fatal(err)
+	}
+	schema := reg.Get("people")
+
+	tests := []struct {
+		name    string
+		content map[string]interface{}
+		want    int
+	}{
+		{"valid", map[string]interface{}{"name": "Dave", "role": "manager", "level": float64(3)}, 0},
+		{"missing required", map[string]interface{}{"role": "engineer"}, 1},
+		{"bad enum", map[string]interface{}{"name": "Dave", "role": "astronaut"}, 1},
+		{"bad email", map[string]interface{}{"name": "Dave", "email": "not-an-email"}, 1},
+		{"wrong type", map[string]interface{}{"name": 42}, 1},
+		{"bad item", map[string]interface{}{"name": "Dave", "tags": []interface{}{"ok", 7}}, 1},
+		{"non-integer", map[string]interface{}{"name": "Dave", "level": 2.5}, 1},
+		{"out of range", map[string]interface{}{"name": "Dave", "level": 11}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.Validate(tt.content)
+			if len(got) != tt.want {
+				t.Errorf("Validate() = %v, want %d violations", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreAppliesDefaults(t *testing.T) {
+	lib := newSchemaLibrary(t, ValidationWarn)
+
+	entity, err := lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if entity.Content["role"] != "engineer" {
+		t.Errorf("role = %v, want default engineer", entity.Content["role"])
+	}
+
+	reloaded, err := lib.Get("people/dave")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if _, ok := reloaded.Content["tags"].([]interface{}); !ok {
+		t.Errorf("tags default not persisted: %v", reloaded.Content["tags"])
+	}
+}
+
+func TestStoreStrictRejects(t *testing.T) {
+	lib := newSchemaLibrary(t, ValidationStrict)
+
+	_, err := lib.Store("people", "nameless", map[string]interface{}{"role": "manager"}, nil)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(verr.Violations) != 1 {
+		t.Errorf("violations = %v, want 1", verr.Violations)
+	}
+
+	if _, err := lib.Get("people/nameless"); err == nil {
+		t.Error("rejected entity should not be written")
+	}
+
+	// Defaults are not left in the caller's content
+	content := map[string]interface{}{"email": "not-an-email"}
+	if _, err := lib.Store("people", "bad", content, nil); err == nil {
+		t.Fatal("expected ValidationError")
+	}
+	if len(content) != 1 {
+		t.Errorf("rejected Store changed the caller's content: %v", content)
+	}
+
+	// Types without a schema are unaffected.
+	if _, err := lib.Store("projects", "hal", map[string]interface{}{}, nil); err != nil {
+		t.Errorf("Store of unschematized type failed: %v", err)
+	}
+}
+
+func TestStoreWarnAccepts(t *testing.T) {
+	lib := newSchemaLibrary(t, ValidationWarn)
+
+	if _, err := lib.Store("people", "nameless", map[string]interface{}{"role": "manager"}, nil); err != nil {
+		t.Fatalf("warn mode should accept invalid entity: %v", err)
+	}
+
+	reports, err := lib.ValidateAll("")
+	if err != nil {
+		t.Fatalf("ValidateAll failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].EntityID != "people/nameless" {
+		t.Errorf("reports = %+v, want people/nameless", reports)
+	}
+}
+
+func mustParseSchema(t *testing.T, data string) *Schema {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := LoadSchemas(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg.Get("x")
+}
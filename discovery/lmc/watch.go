@@ -0,0 +1,313 @@
+package lmc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChangeOp is the kind of change reported by Watch.
+type ChangeOp string
+
+const (
+	ChangeCreate ChangeOp = "create"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// DefaultWatchInterval is how often Watch scans for changes made by other
+// processes when WatchFilter.Interval is not set.
+const DefaultWatchInterval = 2 * time.Second
+
+// Change describes one entity changing in the library.
+type Change struct {
+	Op       ChangeOp  `json:"op"`
+	EntityID string    `json:"id"`
+	Type     string    `json:"type"`
+	Revision int       `json:"revision,omitempty"` // Zero for deletes
+	Time     time.Time `json:"time"`
+	External bool      `json:"external,omitempty"` // Made by another process
+}
+
+// WatchFilter selects which changes Watch delivers.
+type WatchFilter struct {
+	Types    []string      // Entity types to watch (empty = all)
+	Ops      []ChangeOp    // Operations to watch (empty = all)
+	Interval time.Duration // Filesystem scan interval (default DefaultWatchInterval)
+}
+
+// watcher is one Watch subscription.
+type watcher struct {
+	types    map[string]bool
+	ops      map[ChangeOp]bool
+	local    chan localChange // Writes made through this Library
+	snapshot map[string]fileState
+}
+
+// localChange is a write made in-process, with the file state it left.
+type localChange struct {
+	change Change
+	path   string
+}
+
+// fileState identifies a version of an entity file without reading it.
+type fileState struct {
+	id      string
+	typ     string
+	modTime time.Time
+	size    int64
+}
+
+// Watch returns a channel of changes to the library. Writes made through
+// this Library are delivered immediately; changes made by other processes
+// (Floyd watchers, Poole, the scheduler, other CLI invocations) are found by
+// scanning the library every filter.Interval. The channel is closed when
+// ctx is cancelled.
+func (l *Library) Watch(ctx context.Context, filter WatchFilter) (<-chan Change, error) {
+	if filter.Interval <= 0 {
+		filter.Interval = DefaultWatchInterval
+	}
+
+	w := &watcher{
+		types: toSet(filter.Types),
+		ops:   make(map[ChangeOp]bool),
+		local: make(chan localChange, 256),
+	}
+	for _, op := range filter.Ops {
+		w.ops[op] = true
+	}
+
+	l.mu.RLock()
+	w.snapshot = l.scanFiles(w.types)
+	l.watchMu.Lock()
+	if l.watchers == nil {
+		l.watchers = make(map[*watcher]struct{})
+	}
+	l.watchers[w] = struct{}{}
+	l.watchMu.Unlock()
+	l.mu.RUnlock()
+
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		defer func() {
+			l.watchMu.Lock()
+			delete(l.watchers, w)
+			l.watchMu.Unlock()
+		}()
+
+		ticker := time.NewTicker(filter.Interval)
+		defer ticker.Stop()
+
+		var pending []Change
+		for {
+			// Deliver queued changes before waiting for more
+			var send chan Change
+			var next Change
+			if len(pending) > 0 {
+				send, next = out, pending[0]
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case send <- next:
+				pending = pending[1:]
+			case lc := <-w.local:
+				w.applyLocal(lc)
+				if w.matches(lc.change) {
+					pending = append(pending, lc.change)
+				}
+			case <-ticker.C:
+				for _, c := range l.scanChanges(w) {
+					if w.matches(c) {
+						pending = append(pending, c)
+					}
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// notify tells watchers and the history about an in-process write. Caller
+// must hold l.mu.
+func (l *Library) notify(op ChangeOp, entity *Entity) {
+	l.track(describeChange(op, entity), entity.Path)
+
+	l.watchMu.Lock()
+	defer l.watchMu.Unlock()
+
+	if len(l.watchers) == 0 {
+		return
+	}
+
+	change := Change{
+		Op:       op,
+		EntityID: entity.ID,
+		Type:     entity.Type,
+		Time:     time.Now(),
+	}
+	if op != ChangeDelete {
+		change.Revision = entity.Revision
+	}
+
+	for w := range l.watchers {
+		select {
+		case w.local <- localChange{change: change, path: entity.Path}:
+		default:
+			// Queue full: the next scan reports it as an external change
+		}
+	}
+}
+
+func (w *watcher) matches(c Change) bool {
+	if len(w.types) > 0 && !w.types[c.Type] {
+		return false
+	}
+	if len(w.ops) > 0 && !w.ops[c.Op] {
+		return false
+	}
+	return true
+}
+
+// applyLocal records the file state an in-process write left behind so the
+// next scan does not report it again.
+func (w *watcher) applyLocal(lc localChange) {
+	if lc.change.Op == ChangeDelete {
+		delete(w.snapshot, lc.path)
+		return
+	}
+	if info, err := os.Stat(lc.path); err == nil {
+		w.snapshot[lc.path] = fileState{
+			id:      lc.change.EntityID,
+			typ:     lc.change.Type,
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+	}
+}
+
+// scanChanges diffs the library against the watcher's snapshot, updating
+// the edge index and aliases for each entity another process changed.
+// Reindexing writes the index, so it holds l.mu exclusively like a local
+// write does.
+func (l *Library) scanChanges(w *watcher) []Change {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Writes that completed before we took the lock are already queued;
+	// absorb them first so they are not reported twice.
+	var changes []Change
+	for drained := false; !drained; {
+		select {
+		case lc := <-w.local:
+			w.applyLocal(lc)
+			if w.matches(lc.change) {
+				changes = append(changes, lc.change)
+			}
+		default:
+			drained = true
+		}
+	}
+
+	now := time.Now()
+	current := l.scanStats(w.types)
+	for path, state := range current {
+		prev, known := w.snapshot[path]
+		if known && prev.modTime.Equal(state.modTime) && prev.size == state.size {
+			current[path] = prev
+			continue
+		}
+		entity, err := l.loadEntity(path)
+		if err != nil || entity.ID == "" {
+			if known {
+				current[path] = prev // May be mid-write by a non-atomic writer
+			} else {
+				delete(current, path)
+			}
+			continue
+		}
+		state.id, state.typ = entity.ID, entity.Type
+		current[path] = state
+		// Queries and Resolve see the change before anyone is told of it
+		l.indexEntity(entity)
+
+		op := ChangeUpdate
+		if !known {
+			op = ChangeCreate
+		}
+		changes = append(changes, Change{
+			Op:       op,
+			EntityID: entity.ID,
+			Type:     entity.Type,
+			Revision: entity.Revision,
+			Time:     now,
+			External: true,
+		})
+	}
+	for path, prev := range w.snapshot {
+		if _, ok := current[path]; !ok {
+			l.deindexEntity(&Entity{ID: prev.id})
+			changes = append(changes, Change{
+				Op:       ChangeDelete,
+				EntityID: prev.id,
+				Type:     prev.typ,
+				Time:     now,
+				External: true,
+			})
+		}
+	}
+
+	w.snapshot = current
+	return changes
+}
+
+// scanFiles builds a full snapshot, reading each entity once for its ID.
+// Caller must hold l.mu.
+func (l *Library) scanFiles(types map[string]bool) map[string]fileState {
+	snapshot := l.scanStats(types)
+	for path, state := range snapshot {
+		entity, err := l.loadEntity(path)
+		if err != nil || entity.ID == "" {
+			delete(snapshot, path)
+			continue
+		}
+		state.id, state.typ = entity.ID, entity.Type
+		snapshot[path] = state
+	}
+	return snapshot
+}
+
+// scanStats stats every entity file, optionally limited to some types.
+func (l *Library) scanStats(types map[string]bool) map[string]fileState {
+	states := make(map[string]fileState)
+
+	roots := []string{l.BasePath}
+	if len(types) > 0 {
+		roots = roots[:0]
+		for t := range types {
+			roots = append(roots, filepath.Join(l.BasePath, t))
+		}
+	}
+
+	for _, root := range roots {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() {
+				return skipHiddenDir(root, path, info)
+			}
+			if !strings.HasSuffix(path, ".json") || strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return states
+}
@@ -0,0 +1,201 @@
+package lmc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func nextChange(t *testing.T, ch <-chan Change) Change {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+	return Change{}
+}
+
+func TestWatchLocalChanges(t *testing.T) {
+	lib, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := lib.Watch(ctx, WatchFilter{Types: []string{"people"}, Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	lib.Store("projects", "ignored", map[string]interface{}{}, nil)
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave Bowman"}, nil)
+	lib.Delete("people/dave")
+
+	want := []struct {
+		op  ChangeOp
+		rev int
+	}{{ChangeCreate, 1}, {ChangeUpdate, 2}, {ChangeDelete, 0}}
+	for _, w := range want {
+		c := nextChange(t, changes)
+		if c.Op != w.op || c.EntityID != "people/dave" || c.Revision != w.rev || c.External {
+			t.Errorf("change = %+v, want %s people/dave rev %d", c, w.op, w.rev)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("expected channel to close after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+func TestWatchExternalChanges(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "frank", map[string]interface{}{"name": "Frank"}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := lib.Watch(ctx, WatchFilter{Interval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// A second Library on the same path stands in for another process.
+	other, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	other.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+
+	c := nextChange(t, changes)
+	if c.Op != ChangeCreate || c.EntityID != "people/dave" || !c.External {
+		t.Errorf("change = %+v, want external create of people/dave", c)
+	}
+
+	other.Delete("people/frank")
+	c = nextChange(t, changes)
+	if c.Op != ChangeDelete || c.EntityID != "people/frank" || !c.External {
+		t.Errorf("change = %+v, want external delete of people/frank", c)
+	}
+
+	// Local writes are not reported a second time by the scan.
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave Bowman"}, nil)
+	c = nextChange(t, changes)
+	if c.Op != ChangeUpdate || c.External {
+		t.Errorf("change = %+v, want local update", c)
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected duplicate change %+v", c)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchExternalChangesReindex(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "frank", map[string]interface{}{"name": "Frank"}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := lib.Watch(ctx, WatchFilter{Interval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	other, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	other.Store("notes", "eva", map[string]interface{}{"text": "EVA"}, []Edge{{Type: "about", To: "people/frank"}})
+	if c := nextChange(t, changes); c.EntityID != "notes/eva" {
+		t.Fatalf("change = %+v", c)
+	}
+	if linked, _ := lib.GetLinked("people/frank", "in"); len(linked) != 1 {
+		t.Errorf("backlinks after external create = %v", linked)
+	}
+
+	other.AddAlias("Frank Poole", "people/frank")
+	nextChange(t, changes)
+	if id, err := lib.Resolve("Frank Poole"); err != nil || id != "people/frank" {
+		t.Errorf("Resolve after external alias = %q, %v", id, err)
+	}
+
+	other.Delete("notes/eva")
+	nextChange(t, changes)
+	if linked, _ := lib.GetLinked("people/frank", "in"); len(linked) != 0 {
+		t.Errorf("backlinks after external delete = %v", linked)
+	}
+}
+
+// Run with -race: the watcher reindexes external changes while this
+// Library is queried.
+func TestWatchConcurrentQuery(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	lib.Store("people", "frank", map[string]interface{}{"name": "Frank"}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := lib.Watch(ctx, WatchFilter{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	go func() {
+		for range changes {
+		}
+	}()
+
+	other, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			id := fmt.Sprintf("eva%d", i%5)
+			other.Store("notes", id, map[string]interface{}{"n": i}, []Edge{{Type: "about", To: "people/frank"}})
+			if i%3 == 0 {
+				other.Delete("notes/" + id)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if _, err := lib.Query(QueryOptions{Type: "notes"}); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		lib.GetLinked("people/frank", "in")
+	}
+}
@@ -0,0 +1,332 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+// Span is the text a silver link was extracted from.
+type Span struct {
+	Field string `json:"field"` // Content field, dotted for nested values
+	Start int    `json:"start"` // Byte offsets into the field's text
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// Link types found in text by silver enrichment. Mentions and references
+// are the LMC's own derived edge types, found by lmc.FindReferences.
+const (
+	LinkMentions          = lmc.EdgeMentions     // Person: email, Slack user or known name
+	LinkReferences        = lmc.EdgeReferences   // JIRA issue key -> jira/<KEY>, or a [[wiki-link]]
+	LinkCitesURL          = "cites_url"          // URL -> urls/<key>, a URL library entry's node
+	LinkReferencesChannel = "references_channel" // Slack <#C123|name> -> channels/<id>
+	LinkDueOn             = "due_on"             // Deadline -> dates/<YYYY-MM-DD>
+)
+
+var (
+	slackChanRe = regexp.MustCompile(`<#(C[A-Z0-9]+)(?:\|([^>]*))?>`)
+	urlRe       = regexp.MustCompile(`https?://[^\s<>"'|\]]+`)
+	deadlineRe  = regexp.MustCompile(`(?i)\b(?:due|by|deadline:?|before|until|no later than)\s+(?:on\s+)?(\d{4}-\d{2}-\d{2}|(?:jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?\s+\d{1,2}(?:st|nd|rd|th)?(?:,?\s+\d{4})?)\b`)
+	ordinalRe   = regexp.MustCompile(`(\d)(?:st|nd|rd|th)\b`)
+)
+
+// PeopleIndex finds known people's names in text. Names come from people
+// entities in the LMC and their aliases.
+type PeopleIndex struct {
+	names []personName // Longest first, so "Dave Bowman" wins over "Dave"
+}
+
+type personName struct {
+	lower string
+	id    string
+}
+
+// NewPeopleIndex indexes the names of every people/ entity in lib: the
+// name and preferred_name fields, and aliases that are display names.
+// Single-word names only count when recorded as an alias, since first
+// names alone match too much.
+func NewPeopleIndex(lib *lmc.Library) (*PeopleIndex, error) {
+	people, err := lib.Query(lmc.QueryOptions{Type: "people"})
+	if err != nil {
+		return nil, err
+	}
+	idx := &PeopleIndex{}
+	for _, person := range people {
+		for _, field := range []string{"name", "preferred_name"} {
+			if name := getString(person.Content, field); strings.Contains(strings.TrimSpace(name), " ") {
+				idx.Add(name, person.ID)
+			}
+		}
+		for _, alias := range lib.Aliases(person.ID) {
+			if !strings.ContainsAny(alias, "/@") {
+				idx.Add(alias, person.ID)
+			}
+		}
+	}
+	return idx, nil
+}
+
+// Add indexes name as referring to entityID.
+func (idx *PeopleIndex) Add(name, entityID string) {
+	lower := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if len(lower) < 3 {
+		return
+	}
+	for _, n := range idx.names {
+		if n.lower == lower {
+			return
+		}
+	}
+	idx.names = append(idx.names, personName{lower: lower, id: entityID})
+	sort.SliceStable(idx.names, func(i, j int) bool {
+		return len(idx.names[i].lower) > len(idx.names[j].lower)
+	})
+}
+
+// nameMatch is a known name found in text.
+type nameMatch struct {
+	start, end int
+	id         string
+}
+
+// find returns the known names in text, without overlaps.
+func (idx *PeopleIndex) find(text string) []nameMatch {
+	if idx == nil || len(idx.names) == 0 {
+		return nil
+	}
+	// Byte offsets are only kept when lowercasing preserved them
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return nil
+	}
+
+	var taken [][2]int
+	var out []nameMatch
+	for _, n := range idx.names {
+		for from := 0; from < len(lower); {
+			i := strings.Index(lower[from:], n.lower)
+			if i < 0 {
+				break
+			}
+			start, end := from+i, from+i+len(n.lower)
+			from = end
+			if !wordBoundary(lower, start, end) || overlaps(taken, start, end) {
+				continue
+			}
+			taken = append(taken, [2]int{start, end})
+			out = append(out, nameMatch{start: start, end: end, id: n.id})
+		}
+	}
+	return out
+}
+
+func wordBoundary(s string, start, end int) bool {
+	isWord := func(b byte) bool {
+		return b == '_' || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+	}
+	return (start == 0 || !isWord(s[start-1])) && (end == len(s) || !isWord(s[end]))
+}
+
+func overlaps(taken [][2]int, start, end int) bool {
+	for _, t := range taken {
+		if start < t[1] && t[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// extractReferences finds mentions, issue keys, URLs, channel references
+// and deadlines in every text field of content. Each link carries the span
+// it came from; a target is linked once, from its first occurrence. URLs
+// are only linked when urls holds their key, since the urls/<key> entity
+// comes from the URL library. Years missing from deadlines are taken from
+// ref.
+func extractReferences(content map[string]interface{}, people *PeopleIndex, urls map[string]bool, ref time.Time) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	self := getString(content, "key") // A JIRA issue does not reference itself
+
+	add := func(linkType, target, label, field, text string, start, end int) {
+		key := linkType + " " + target
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		links = append(links, Link{
+			Type:   linkType,
+			Target: target,
+			Label:  label,
+			Span:   &Span{Field: field, Start: start, End: end, Text: text[start:end]},
+		})
+	}
+
+	for _, f := range textFields(content, "") {
+		field, text := f[0], f[1]
+
+		for _, r := range lmc.FindReferences(text) {
+			if self != "" && r.To == "jira/"+self {
+				continue
+			}
+			add(r.Type, r.To, r.Label, field, text, r.Start, r.End)
+		}
+		for _, m := range slackChanRe.FindAllStringSubmatchIndex(text, -1) {
+			label := ""
+			if m[4] >= 0 {
+				label = text[m[4]:m[5]]
+			}
+			add(LinkReferencesChannel, "channels/"+text[m[2]:m[3]], label, field, text, m[0], m[1])
+		}
+		for _, m := range urlRe.FindAllStringIndex(text, -1) {
+			end := m[0] + len(strings.TrimRight(text[m[0]:m[1]], ".,;:!?)>"))
+			link := text[m[0]:end]
+			if key := urlKey(link); key != "" && urls[key] {
+				add(LinkCitesURL, URLType+"/"+key, link, field, text, m[0], end)
+			}
+		}
+		for _, m := range deadlineRe.FindAllStringSubmatchIndex(text, -1) {
+			if day, ok := parseDeadline(text[m[2]:m[3]], ref); ok {
+				add(LinkDueOn, "dates/"+day, text[m[0]:m[1]], field, text, m[2], m[3])
+			}
+		}
+		for _, n := range people.find(text) {
+			add(LinkMentions, n.id, text[n.start:n.end], field, text, n.start, n.end)
+		}
+	}
+	return links
+}
+
+// enrichmentKey identifies the people and URLs enrichment looked for, so
+// the pipeline can check its documents again when they change. It is ""
+// when there are none.
+func enrichmentKey(people *PeopleIndex, urls map[string]bool) string {
+	var entries []string
+	if people != nil {
+		for _, n := range people.names {
+			entries = append(entries, n.lower+"\x00"+n.id)
+		}
+	}
+	for key := range urls {
+		entries = append(entries, URLType+"/"+key)
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Strings(entries)
+	return hashBytes([]byte(strings.Join(entries, "\n")))
+}
+
+// NewURLIndex returns the keys of the urls/ entities in lib, the URLs
+// silver enrichment links citations of.
+func NewURLIndex(lib *lmc.Library) (map[string]bool, error) {
+	entities, err := lib.Query(lmc.QueryOptions{Type: URLType})
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		urls[strings.TrimPrefix(entity.ID, URLType+"/")] = true
+	}
+	return urls, nil
+}
+
+// textFields lists the string values in content as {dotted field, text},
+// in a stable order.
+func textFields(v interface{}, prefix string) [][2]string {
+	var out [][2]string
+	switch val := v.(type) {
+	case string:
+		if val != "" {
+			out = append(out, [2]string{prefix, val})
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field := k
+			if prefix != "" {
+				field = prefix + "." + k
+			}
+			out = append(out, textFields(val[k], field)...)
+		}
+	case []interface{}:
+		for i, item := range val {
+			out = append(out, textFields(item, fmt.Sprintf("%s.%d", prefix, i))...)
+		}
+	case []map[string]interface{}:
+		for i, item := range val {
+			out = append(out, textFields(item, fmt.Sprintf("%s.%d", prefix, i))...)
+		}
+	}
+	return out
+}
+
+// urlKey identifies a URL regardless of scheme, "www.", fragment, a
+// trailing slash and utm_ tracking parameters, for use as an entity ID.
+func urlKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	key := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") + strings.TrimSuffix(u.EscapedPath(), "/")
+
+	query := u.Query()
+	for param := range query {
+		if strings.HasPrefix(param, "utm_") {
+			query.Del(param)
+		}
+	}
+	if q := query.Encode(); q != "" {
+		key += "?" + q
+	}
+	return sanitizeFilename(key)
+}
+
+// parseDeadline reads "2026-02-01", "Feb 1", "February 1st, 2026". A date
+// without a year is the next occurrence on or after a month before ref.
+func parseDeadline(s string, ref time.Time) (string, bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Format("2006-01-02"), true
+	}
+
+	s = ordinalRe.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer(",", " ", ".", " ").Replace(s)
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return "", false
+	}
+	month := fields[0]
+	if len(month) > 3 {
+		month = month[:3]
+	}
+	month = strings.ToUpper(month[:1]) + strings.ToLower(month[1:])
+
+	if len(fields) >= 3 {
+		t, err := time.Parse("Jan 2 2006", month+" "+fields[1]+" "+fields[2])
+		if err != nil {
+			return "", false
+		}
+		return t.Format("2006-01-02"), true
+	}
+
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	t, err := time.Parse("Jan 2 2006", fmt.Sprintf("%s %s %d", month, fields[1], ref.Year()))
+	if err != nil {
+		return "", false
+	}
+	if t.Before(ref.AddDate(0, -1, 0)) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return t.Format("2006-01-02"), true
+}
@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func findLink(links []Link, linkType, target string) *Link {
+	for i := range links {
+		if links[i].Type == linkType && links[i].Target == target {
+			return &links[i]
+		}
+	}
+	return nil
+}
+
+func TestExtractReferences(t *testing.T) {
+	people := &PeopleIndex{}
+	people.Add("Frank Poole", "people/frank@discovery.one")
+
+	content := map[string]interface{}{
+		"key":  "HAL-7",
+		"text": "Frank Poole says HAL-12 blocks HAL-7, see https://www.example.com/ae35/?utm_source=x. Due Feb 3. cc <@U123> and dave@discovery.one in <#C42|ops>",
+		"nested": map[string]interface{}{
+			"notes": "UTF-8 logs at https://example.com/ae35#top",
+		},
+	}
+	ref := time.Date(2026, 1, 27, 0, 0, 0, 0, time.UTC)
+	links := extractReferences(content, people, map[string]bool{"example_com_ae35": true}, ref)
+
+	issue := findLink(links, LinkReferences, "jira/HAL-12")
+	if issue == nil || issue.Span.Field != "text" || issue.Span.Text != "HAL-12" {
+		t.Fatalf("issue link = %+v", issue)
+	}
+	if text := content["text"].(string); text[issue.Span.Start:issue.Span.End] != "HAL-12" {
+		t.Errorf("span offsets = %d-%d", issue.Span.Start, issue.Span.End)
+	}
+	if findLink(links, LinkReferences, "jira/HAL-7") != nil {
+		t.Error("issue references itself")
+	}
+	if findLink(links, LinkReferences, "jira/UTF-8") != nil {
+		t.Error("UTF-8 taken for an issue key")
+	}
+
+	// Fields are scanned in key order, so nested.notes comes first
+	url := findLink(links, LinkCitesURL, "urls/example_com_ae35")
+	if url == nil || url.Span.Field != "nested.notes" || url.Span.Text != "https://example.com/ae35#top" {
+		t.Errorf("url link = %+v", url)
+	}
+	due := findLink(links, LinkDueOn, "dates/2026-02-03")
+	if due == nil || due.Span.Text != "Feb 3" {
+		t.Errorf("due link = %+v", due)
+	}
+	if l := findLink(links, LinkReferencesChannel, "channels/C42"); l == nil || l.Label != "ops" {
+		t.Errorf("channel link = %+v", l)
+	}
+	if findLink(links, LinkMentions, "users/U123") == nil || findLink(links, LinkMentions, "people/dave@discovery.one") == nil {
+		t.Errorf("user mentions missing: %+v", links)
+	}
+	if l := findLink(links, LinkMentions, "people/frank@discovery.one"); l == nil || l.Span.Start != 0 || l.Label != "Frank Poole" {
+		t.Errorf("name mention = %+v", l)
+	}
+
+	// Each target once: the URL in text is the same one
+	count := 0
+	for _, l := range links {
+		if l.Type == LinkCitesURL {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("cites_url links = %d, want 1", count)
+	}
+
+	// URLs no library entry saved have no entity to link to
+	for _, l := range extractReferences(content, people, nil, ref) {
+		if l.Type == LinkCitesURL {
+			t.Errorf("cites_url to unknown URL: %+v", l)
+		}
+	}
+}
+
+func TestToSilverSkipsLinkedTargets(t *testing.T) {
+	doc, err := ToSilver(ProcessConfig{}, &Document{
+		Meta: DocumentMeta{Source: "jira", EventID: "HAL-1"},
+		Content: map[string]interface{}{
+			"key":      "HAL-1",
+			"assignee": map[string]interface{}{"email": "dave@discovery.one"},
+			"project":  "HAL",
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToSilver failed: %v", err)
+	}
+	if findLink(doc.Links, LinkMentions, "people/dave@discovery.one") != nil {
+		t.Errorf("assignee also mentioned: %+v", doc.Links)
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	ref := time.Date(2026, 11, 20, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"2026-02-01":         "2026-02-01",
+		"February 1st, 2027": "2027-02-01",
+		"Dec 5":              "2026-12-05",
+		"Jan 3":              "2027-01-03", // Next occurrence
+		"Nov 1":              "2026-11-01", // Recently passed
+		"Sept 30":            "2027-09-30",
+		"Smarch 3":           "",
+	}
+	for in, want := range tests {
+		got, ok := parseDeadline(in, ref)
+		if !ok {
+			got = ""
+		}
+		if got != want {
+			t.Errorf("parseDeadline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDeadlineMonthNames(t *testing.T) {
+	tests := map[string]string{
+		"Cut spend by decreasing 10 servers": "",
+		"Grow by marketing 20 accounts":      "",
+		"Ship by junebug 4":                  "",
+		"Ship by June 4":                     "June 4",
+		"due Sept. 30th":                     "Sept. 30th",
+		"no later than september 3, 2027":    "september 3, 2027",
+	}
+	for in, want := range tests {
+		got := ""
+		if m := deadlineRe.FindStringSubmatch(in); m != nil {
+			got = m[1]
+		}
+		if got != want {
+			t.Errorf("deadline in %q = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestURLKey(t *testing.T) {
+	same := []string{
+		"https://example.com/monolith",
+		"http://www.example.com/monolith/",
+		"https://example.com/monolith?utm_campaign=tma1#clavius",
+	}
+	for _, u := range same {
+		if got := urlKey(u); got != "example_com_monolith" {
+			t.Errorf("urlKey(%q) = %q", u, got)
+		}
+	}
+	if urlKey("https://example.com/search?q=jupiter") == urlKey("https://example.com/search") {
+		t.Error("query parameters ignored")
+	}
+}
+
+func TestNewPeopleIndex(t *testing.T) {
+	lib, err := lmc.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	lib.Store("people", "frank", map[string]interface{}{"name": "Frank Poole"}, nil)
+	lib.Store("people", "dave", map[string]interface{}{"name": "Dave"}, nil)
+	if _, err := lib.AddAlias("Bowman", "people/dave"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+
+	idx, err := NewPeopleIndex(lib)
+	if err != nil {
+		t.Fatalf("NewPeopleIndex failed: %v", err)
+	}
+	links := extractReferences(map[string]interface{}{
+		"text": "Dave asked frank poole and Bowman; Frank Pooley is someone else",
+	}, idx, nil, time.Time{})
+
+	if l := findLink(links, LinkMentions, "people/frank"); l == nil || l.Span.Text != "frank poole" {
+		t.Errorf("full name not found: %+v", links)
+	}
+	if findLink(links, LinkMentions, "people/dave") == nil {
+		t.Errorf("alias not found: %+v", links)
+	}
+	if len(links) != 2 {
+		t.Errorf("links = %+v, want single-word name and Pooley skipped", links)
+	}
+}
+
+func TestExtractCalendarLinksDecodedJSON(t *testing.T) {
+	var content map[string]interface{}
+	data := `{"attendees": [{"email": "Dave@Discovery.one", "name": "Dave"}, {"name": "no email"}]}`
+	if err := json.Unmarshal([]byte(data), &content); err != nil {
+		t.Fatal(err)
+	}
+	links := extractCalendarLinks(content)
+	if len(links) != 1 || links[0].Target != "people/dave@discovery.one" {
+		t.Errorf("links = %+v", links)
+	}
+}
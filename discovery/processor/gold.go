@@ -0,0 +1,394 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+// Gold rollup entity types. Each rollup aggregates the silver documents
+// about one subject and is kept in the LMC.
+const (
+	GoldTimelines = "timelines"       // Per-person activity: meetings, JIRA assignments, Slack messages
+	GoldProjects  = "project_status"  // Per-project JIRA issues by status
+	GoldDigests   = "channel_digests" // Per-channel messages for one ISO week
+)
+
+// goldTypes are the entity types written by the gold stage.
+var goldTypes = map[string]bool{GoldTimelines: true, GoldProjects: true, GoldDigests: true}
+
+// maxTimelineEntries caps a timeline; the oldest entries drop off.
+const maxTimelineEntries = 500
+
+// maxDigestText truncates message text in channel digests.
+const maxDigestText = 280
+
+// personRoles maps silver link types to a person's role in a timeline entry.
+var personRoles = map[string]string{
+	"scheduled_with": "attendee",
+	"assigned_to":    "assignee",
+	"authored_by":    "author",
+}
+
+// activityKinds names timeline entries by source.
+var activityKinds = map[string]string{
+	"calendar":        "meeting",
+	"google-calendar": "meeting",
+	"jira":            "jira",
+	"slack":           "slack",
+}
+
+// rollupEntry is one silver document's contribution to one rollup.
+type rollupEntry struct {
+	ID      string // Rollup entity ID
+	Kind    string // Rollup entity type
+	Subject string // Entity the rollup is about
+	Week    string // ISO week, digests only
+	Entry   map[string]interface{}
+}
+
+// UpdateGold folds one silver document into the gold rollups: its entry is
+// added to (or replaced in) every rollup it feeds and removed from rollups
+// it no longer feeds. Only the affected rollups are rewritten. Returns the
+// IDs of the rollups written.
+func UpdateGold(lib *lmc.Library, silver *Document) ([]string, error) {
+	ref := documentID(silver.Meta)
+	entries := goldEntries(lib, silver)
+
+	wanted := make(map[string]bool, len(entries))
+	var written []string
+	for _, e := range entries {
+		if wanted[e.ID] {
+			continue // Same person in several roles: first role wins
+		}
+		wanted[e.ID] = true
+		if err := upsertRollup(lib, e); err != nil {
+			return written, fmt.Errorf("failed to update %s: %w", e.ID, err)
+		}
+		written = append(written, e.ID)
+	}
+
+	// Rollups that included this document earlier but no longer should
+	linked, err := lib.GetLinked(ref, "in")
+	if err != nil {
+		return written, err
+	}
+	for _, entity := range linked {
+		if !goldTypes[entity.Type] || wanted[entity.ID] {
+			continue
+		}
+		removed, err := removeFromRollup(lib, entity.ID, ref)
+		if err != nil {
+			return written, fmt.Errorf("failed to update %s: %w", entity.ID, err)
+		}
+		if removed {
+			written = append(written, entity.ID)
+		}
+	}
+
+	if len(written) > 0 {
+		log.Printf("[processor][gold] Updated %d rollups for %s", len(written), ref)
+	}
+	return written, nil
+}
+
+// goldEntries lists the rollups a silver document feeds. People are
+// resolved through the library's aliases, so a person known by several
+// identifiers has one timeline.
+func goldEntries(lib *lmc.Library, silver *Document) []rollupEntry {
+	ref := documentID(silver.Meta)
+	source := silver.Meta.Source
+	content := silver.Content
+	date := documentTime(silver)
+	title := getString(content, "title")
+
+	var out []rollupEntry
+	for _, link := range silver.Links {
+		target := linkTarget(link)
+
+		if role, ok := personRoles[link.Type]; ok {
+			person := resolveTarget(lib, link)
+			kind := activityKinds[source]
+			if kind == "" {
+				kind = source
+			}
+			entry := map[string]interface{}{
+				"ref":  ref,
+				"date": date.Format(time.RFC3339),
+				"kind": kind,
+				"role": role,
+			}
+			switch source {
+			case "jira":
+				entry["key"] = getString(content, "key")
+				entry["title"] = title
+				entry["status"] = getString(content, "status")
+			case "slack":
+				entry["channel"] = getString(content, "channel")
+				entry["text"] = truncateText(getString(content, "text"), maxDigestText)
+			default:
+				if title != "" {
+					entry["title"] = title
+				}
+			}
+			out = append(out, rollupEntry{
+				ID:      GoldTimelines + "/" + sanitizeFilename(strings.Replace(person, "/", "_", 1)),
+				Kind:    GoldTimelines,
+				Subject: person,
+				Entry:   entry,
+			})
+		}
+
+		switch {
+		case link.Type == "belongs_to" && source == "jira" && strings.HasPrefix(target, "projects/"):
+			assignee := ""
+			if a, ok := content["assignee"].(map[string]interface{}); ok {
+				assignee = getString(a, "email")
+			}
+			out = append(out, rollupEntry{
+				ID:      GoldProjects + "/" + sanitizeFilename(strings.TrimPrefix(target, "projects/")),
+				Kind:    GoldProjects,
+				Subject: target,
+				Entry: map[string]interface{}{
+					"ref":      ref,
+					"date":     date.Format(time.RFC3339),
+					"key":      getString(content, "key"),
+					"title":    title,
+					"status":   getString(content, "status"),
+					"assignee": assignee,
+				},
+			})
+
+		case link.Type == "posted_in" && strings.HasPrefix(target, "channels/"):
+			week := isoWeek(date)
+			out = append(out, rollupEntry{
+				ID:      GoldDigests + "/" + sanitizeFilename(strings.TrimPrefix(target, "channels/")+"_"+week),
+				Kind:    GoldDigests,
+				Subject: target,
+				Week:    week,
+				Entry: map[string]interface{}{
+					"ref":  ref,
+					"date": date.Format(time.RFC3339),
+					"user": getString(content, "user"),
+					"text": truncateText(getString(content, "text"), maxDigestText),
+				},
+			})
+		}
+	}
+	return out
+}
+
+// upsertRollup replaces the document's entry in a rollup and recomputes
+// its summary.
+func upsertRollup(lib *lmc.Library, e rollupEntry) error {
+	_, err := lib.Update(e.ID, func(entity *lmc.Entity) error {
+		ref := getString(e.Entry, "ref")
+		entries := []map[string]interface{}{e.Entry}
+		for _, existing := range rollupEntries(entity.Content) {
+			if getString(existing, "ref") != ref {
+				entries = append(entries, existing)
+			}
+		}
+
+		entity.Content = map[string]interface{}{
+			"subject": e.Subject,
+			"kind":    e.Kind,
+		}
+		if e.Week != "" {
+			entity.Content["week"] = e.Week
+		}
+		setRollupEntries(entity, entries)
+		return nil
+	})
+	return err
+}
+
+// removeFromRollup drops the document's entry from a rollup, deleting the
+// rollup once it is empty. It reports whether anything changed.
+func removeFromRollup(lib *lmc.Library, rollupID, ref string) (bool, error) {
+	removed, empty := false, false
+	_, err := lib.Update(rollupID, func(entity *lmc.Entity) error {
+		var entries []map[string]interface{}
+		for _, existing := range rollupEntries(entity.Content) {
+			if getString(existing, "ref") == ref {
+				removed = true
+				continue
+			}
+			entries = append(entries, existing)
+		}
+		if !removed {
+			return errUnchanged
+		}
+		empty = len(entries) == 0
+		setRollupEntries(entity, entries)
+		return nil
+	})
+	if err == errUnchanged {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if empty {
+		return true, lib.Delete(rollupID)
+	}
+	return true, nil
+}
+
+// errUnchanged aborts an Update that has nothing to write.
+var errUnchanged = errors.New("rollup unchanged")
+
+// setRollupEntries sorts and stores entries with the summary for the
+// rollup's kind, and points the rollup's derived edges at its subject and
+// every included document.
+func setRollupEntries(entity *lmc.Entity, entries []map[string]interface{}) {
+	content := entity.Content
+	kind := getString(content, "kind")
+
+	switch kind {
+	case GoldTimelines:
+		// Newest first
+		sort.SliceStable(entries, func(i, j int) bool {
+			return getString(entries[i], "date") > getString(entries[j], "date")
+		})
+		if len(entries) > maxTimelineEntries {
+			entries = entries[:maxTimelineEntries]
+		}
+		counts := make(map[string]int)
+		for _, e := range entries {
+			counts[getString(e, "kind")]++
+		}
+		content["counts"] = counts
+		if len(entries) > 0 {
+			content["last_activity"] = getString(entries[0], "date")
+		}
+
+	case GoldProjects:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return getString(entries[i], "key") < getString(entries[j], "key")
+		})
+		byStatus := make(map[string]int)
+		lastUpdated := ""
+		for _, e := range entries {
+			status := getString(e, "status")
+			if status == "" {
+				status = "unknown"
+			}
+			byStatus[status]++
+			if d := getString(e, "date"); d > lastUpdated {
+				lastUpdated = d
+			}
+		}
+		content["by_status"] = byStatus
+		content["issue_count"] = len(entries)
+		content["last_updated"] = lastUpdated
+
+	case GoldDigests:
+		// Oldest first, as the conversation ran
+		sort.SliceStable(entries, func(i, j int) bool {
+			return getString(entries[i], "date") < getString(entries[j], "date")
+		})
+		seen := make(map[string]bool)
+		var authors []string
+		for _, e := range entries {
+			if user := getString(e, "user"); user != "" && !seen[user] {
+				seen[user] = true
+				authors = append(authors, user)
+			}
+		}
+		sort.Strings(authors)
+		content["message_count"] = len(entries)
+		content["authors"] = authors
+	}
+	content["entries"] = entries
+
+	links := []lmc.Edge{{To: getString(content, "subject"), Type: "rollup_of", Derived: true}}
+	for _, e := range entries {
+		links = append(links, lmc.Edge{To: getString(e, "ref"), Type: "includes", Derived: true})
+	}
+	entity.Links = links
+}
+
+// rollupEntries reads the entries of a stored rollup.
+func rollupEntries(content map[string]interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	switch entries := content["entries"].(type) {
+	case []map[string]interface{}:
+		out = append(out, entries...)
+	case []interface{}:
+		for _, e := range entries {
+			if m, ok := e.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// documentTime returns when the event behind a silver document happened,
+// falling back to when it was processed.
+func documentTime(doc *Document) time.Time {
+	content := doc.Content
+	var candidates []string
+	switch doc.Meta.Source {
+	case "slack":
+		if ts, err := strconv.ParseFloat(getString(content, "timestamp"), 64); err == nil && ts > 0 {
+			return time.Unix(int64(ts), 0).UTC()
+		}
+	case "jira":
+		candidates = []string{getString(content, "updated"), getString(content, "created")}
+	default:
+		if start, ok := content["start"].(map[string]interface{}); ok {
+			candidates = []string{getString(start, "dateTime"), getString(start, "date")}
+		}
+		candidates = append(candidates, getString(content, "time"), getString(content, "date"))
+	}
+
+	for _, s := range candidates {
+		if t, ok := parseTime(s); ok {
+			return t
+		}
+	}
+	return doc.Meta.ProcessedAt.UTC()
+}
+
+// timeLayouts are the formats sources use for timestamps.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000-0700", // JIRA
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isoWeek formats t's ISO week as 2006-W01.
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// truncateText shortens s to at most n runes.
+func truncateText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func jiraSilver(key, status, assignee string) *Document {
+	return &Document{
+		Meta: DocumentMeta{Source: "jira", EventID: key, Stage: StageSilver},
+		Content: map[string]interface{}{
+			"key":      key,
+			"title":    "Replace AE-35 unit",
+			"status":   status,
+			"assignee": map[string]interface{}{"email": assignee},
+			"project":  "HAL",
+			"updated":  "2026-01-27T10:30:00.000+0000",
+		},
+		Links: []Link{
+			{Type: "assigned_to", Target: "people/" + assignee},
+			{Type: "belongs_to", Target: "projects/HAL"},
+		},
+	}
+}
+
+func slackSilver(eventID, ts, text string) *Document {
+	return &Document{
+		Meta:    DocumentMeta{Source: "slack", EventID: eventID, Stage: StageSilver},
+		Content: map[string]interface{}{"channel": "C42", "user": "U123", "text": text, "timestamp": ts},
+		Links: []Link{
+			{Type: "posted_in", Target: "channels/C42"},
+			{Type: "authored_by", Target: "users/U123"},
+		},
+	}
+}
+
+func newTestLibrary(t *testing.T) *lmc.Library {
+	t.Helper()
+	library, err := lmc.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	return library
+}
+
+func TestUpdateGold(t *testing.T) {
+	library := newTestLibrary(t)
+
+	for _, doc := range []*Document{
+		jiraSilver("HAL-1", "In Progress", "dave@discovery.one"),
+		jiraSilver("HAL-2", "Done", "dave@discovery.one"),
+		slackSilver("m1", "1769508000.000100", "Open the pod bay doors"),
+		slackSilver("m2", "1769511600.000200", "I'm sorry, Dave"),
+	} {
+		if _, err := UpdateGold(library, doc); err != nil {
+			t.Fatalf("UpdateGold failed: %v", err)
+		}
+	}
+
+	timeline, err := library.Get("timelines/people_dave_discovery_one")
+	if err != nil {
+		t.Fatalf("timeline missing: %v", err)
+	}
+	if timeline.Content["subject"] != "people/dave@discovery.one" {
+		t.Errorf("subject = %v", timeline.Content["subject"])
+	}
+	if entries := rollupEntries(timeline.Content); len(entries) != 2 || entries[0]["kind"] != "jira" {
+		t.Errorf("timeline entries = %v", entries)
+	}
+
+	project, err := library.Get("project_status/HAL")
+	if err != nil {
+		t.Fatalf("project status missing: %v", err)
+	}
+	byStatus, _ := project.Content["by_status"].(map[string]interface{})
+	if byStatus["Done"] != float64(1) || byStatus["In Progress"] != float64(1) {
+		t.Errorf("by_status = %v", project.Content["by_status"])
+	}
+
+	// 2026-01-27 is in ISO week 5
+	digest, err := library.Get("channel_digests/C42_2026-W05")
+	if err != nil {
+		t.Fatalf("digest missing: %v", err)
+	}
+	entries := rollupEntries(digest.Content)
+	if len(entries) != 2 || entries[0]["text"] != "Open the pod bay doors" {
+		t.Errorf("digest entries = %v", entries)
+	}
+	if digest.Content["message_count"] != float64(2) {
+		t.Errorf("message_count = %v", digest.Content["message_count"])
+	}
+
+	// Rollups point at their subject and documents
+	if linked, _ := library.GetLinked("documents/jira_HAL-1", "in"); len(linked) != 2 {
+		t.Errorf("rollups including HAL-1 = %v", linked)
+	}
+}
+
+func TestUpdateGoldMovesEntries(t *testing.T) {
+	library := newTestLibrary(t)
+
+	if _, err := UpdateGold(library, jiraSilver("HAL-1", "To Do", "dave@discovery.one")); err != nil {
+		t.Fatalf("UpdateGold failed: %v", err)
+	}
+	written, err := UpdateGold(library, jiraSilver("HAL-1", "In Progress", "frank@discovery.one"))
+	if err != nil {
+		t.Fatalf("UpdateGold failed: %v", err)
+	}
+	if len(written) != 3 {
+		t.Errorf("written = %v, want new timeline, project and old timeline", written)
+	}
+
+	// Dave's timeline had only this issue, so it is gone
+	if _, err := library.Get("timelines/people_dave_discovery_one"); err == nil {
+		t.Error("reassigned issue left in the old assignee's timeline")
+	}
+	if _, err := library.Get("timelines/people_frank_discovery_one"); err != nil {
+		t.Errorf("new assignee timeline missing: %v", err)
+	}
+
+	project, _ := library.Get("project_status/HAL")
+	entries := rollupEntries(project.Content)
+	if len(entries) != 1 || entries[0]["status"] != "In Progress" {
+		t.Errorf("project entries = %v", entries)
+	}
+}
+
+func TestUpdateGoldResolvesPeople(t *testing.T) {
+	library := newTestLibrary(t)
+	if _, err := library.Store("people", "dave@discovery.one", map[string]interface{}{"name": "Dave Bowman"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.AddAlias("users/U123", "people/dave@discovery.one"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, doc := range []*Document{
+		jiraSilver("HAL-1", "In Progress", "dave@discovery.one"),
+		slackSilver("m1", "1769508000.000100", "Open the pod bay doors"),
+	} {
+		if _, err := UpdateGold(library, doc); err != nil {
+			t.Fatalf("UpdateGold failed: %v", err)
+		}
+	}
+
+	// The Slack user's messages join the email's timeline
+	if _, err := library.Get("timelines/users_U123"); err == nil {
+		t.Error("timeline keyed by the Slack user ID")
+	}
+	timeline, err := library.Get("timelines/people_dave_discovery_one")
+	if err != nil {
+		t.Fatalf("timeline missing: %v", err)
+	}
+	if entries := rollupEntries(timeline.Content); len(entries) != 2 {
+		t.Errorf("timeline entries = %v, want jira and slack", entries)
+	}
+}
+
+func TestDocumentTime(t *testing.T) {
+	processed := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		doc  *Document
+		want time.Time
+	}{
+		{slackSilver("m", "1769508000.000100", ""), time.Unix(1769508000, 0).UTC()},
+		{jiraSilver("HAL-1", "", "x"), time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC)},
+		{&Document{
+			Meta:    DocumentMeta{Source: "calendar"},
+			Content: map[string]interface{}{"start": map[string]interface{}{"date": "2026-01-28"}},
+		}, time.Date(2026, 1, 28, 0, 0, 0, 0, time.UTC)},
+		{&Document{Meta: DocumentMeta{Source: "other", ProcessedAt: processed}}, processed},
+	}
+	for i, tt := range tests {
+		if got := documentTime(tt.doc); !got.Equal(tt.want) {
+			t.Errorf("%d: documentTime = %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MappingSpec is a declarative transform for simple sources, loaded from
+// .hal9000/transforms/<name>.yaml:
+//
+//	source: github
+//	aliases: [github-pr]
+//	fields:                 # bronze field: dotted path in the raw document
+//	  title: pull_request.title
+//	  author: user.login
+//	  reviewers: requested_reviewers
+//	clean: [title]          # collapse whitespace
+//	lower: [author]         # lowercase
+//	links:
+//	  - type: authored_by
+//	    target: people/{author}
+//	  - type: reviewed_by
+//	    each: reviewers     # one link per list element
+//	    target: people/{login|slug}
+type MappingSpec struct {
+	Source  string            `yaml:"source"`
+	Aliases []string          `yaml:"aliases,omitempty"`
+	Fields  map[string]string `yaml:"fields"`
+	Clean   []string          `yaml:"clean,omitempty"`
+	Lower   []string          `yaml:"lower,omitempty"`
+	Links   []LinkSpec        `yaml:"links,omitempty"`
+}
+
+// LinkSpec describes one silver link. Target and Label are templates:
+// {field} is a bronze field, or with Each a field of the list element
+// ({.} is the element itself). {field|slug} slugifies the value. A link
+// whose target has an empty placeholder is skipped.
+type LinkSpec struct {
+	Type   string `yaml:"type"`
+	Target string `yaml:"target"`
+	Label  string `yaml:"label,omitempty"`
+	Each   string `yaml:"each,omitempty"`
+}
+
+// placeholderRe matches {field} and {field|slug} in link templates.
+var placeholderRe = regexp.MustCompile(`\{([^{}|]+)(\|slug)?\}`)
+
+// Mapping is a Transformer built from a MappingSpec.
+type Mapping struct {
+	Spec MappingSpec
+}
+
+// NewMapping validates spec and returns its transformer.
+func NewMapping(spec MappingSpec) (*Mapping, error) {
+	if spec.Source == "" {
+		return nil, fmt.Errorf("transform has no source")
+	}
+	if len(spec.Fields) == 0 {
+		return nil, fmt.Errorf("transform %s has no fields", spec.Source)
+	}
+	for i, link := range spec.Links {
+		if link.Type == "" || link.Target == "" {
+			return nil, fmt.Errorf("transform %s: link %d needs a type and target", spec.Source, i+1)
+		}
+		if !placeholderRe.MatchString(link.Target) {
+			return nil, fmt.Errorf("transform %s: link %d target %q has no {field}", spec.Source, i+1, link.Target)
+		}
+	}
+	return &Mapping{Spec: spec}, nil
+}
+
+// Bronze copies the mapped fields out of the raw document.
+func (m *Mapping) Bronze(raw map[string]interface{}) map[string]interface{} {
+	content := make(map[string]interface{}, len(m.Spec.Fields))
+	for field, path := range m.Spec.Fields {
+		content[field] = lookupPath(raw, path)
+	}
+	for _, field := range m.Spec.Clean {
+		if s, ok := content[field].(string); ok {
+			content[field] = cleanText(s)
+		}
+	}
+	for _, field := range m.Spec.Lower {
+		if s, ok := content[field].(string); ok {
+			content[field] = strings.ToLower(s)
+		}
+	}
+	return content
+}
+
+// Silver expands the link templates against bronze content.
+func (m *Mapping) Silver(content map[string]interface{}) []Link {
+	var links []Link
+	for _, spec := range m.Spec.Links {
+		if spec.Each == "" {
+			if link, ok := expandLink(spec, content, nil); ok {
+				links = append(links, link)
+			}
+			continue
+		}
+		items, _ := content[spec.Each].([]interface{})
+		for _, item := range items {
+			if link, ok := expandLink(spec, content, item); ok {
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// expandLink fills a link template from item (a list element, or nil)
+// and then content.
+func expandLink(spec LinkSpec, content map[string]interface{}, item interface{}) (Link, bool) {
+	complete := true
+	fill := func(template string) string {
+		return placeholderRe.ReplaceAllStringFunc(template, func(match string) string {
+			parts := placeholderRe.FindStringSubmatch(match)
+			value := templateValue(parts[1], content, item)
+			if value == "" {
+				complete = false
+			}
+			if parts[2] != "" {
+				value = slugify(value)
+			}
+			return value
+		})
+	}
+
+	target := fill(spec.Target)
+	if !complete {
+		return Link{}, false
+	}
+	return Link{Type: spec.Type, Target: target, Label: fill(spec.Label)}, true
+}
+
+func templateValue(field string, content map[string]interface{}, item interface{}) string {
+	if field == "." {
+		s, _ := item.(string)
+		return s
+	}
+	if m, ok := item.(map[string]interface{}); ok {
+		if v := lookupPath(m, field); v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	if v := lookupPath(content, field); v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// lookupPath follows a dotted path through nested maps.
+func lookupPath(m map[string]interface{}, path string) interface{} {
+	var v interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		nested, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = nested[key]
+	}
+	return v
+}
+
+// LoadTransforms registers every *.yaml and *.yml mapping in dir,
+// replacing built-in transformers of the same source. A missing directory
+// is not an error. Returns the number of transforms loaded.
+func LoadTransforms(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return count, err
+		}
+		var spec MappingSpec
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return count, fmt.Errorf("invalid transform %s: %w", entry.Name(), err)
+		}
+		mapping, err := NewMapping(spec)
+		if err != nil {
+			return count, fmt.Errorf("invalid transform %s: %w", entry.Name(), err)
+		}
+		Register(mapping, append([]string{spec.Source}, spec.Aliases...)...)
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const githubTransform = `source: github
+aliases: [github-pr]
+fields:
+  title: pull_request.title
+  author: user.login
+  body: pull_request.body
+  reviewers: requested_reviewers
+clean: [body]
+lower: [author]
+links:
+  - type: authored_by
+    target: people/{author}
+  - type: reviewed_by
+    each: reviewers
+    target: people/{login|slug}
+    label: "{login}"
+  - type: belongs_to
+    target: projects/{project}
+`
+
+func TestLoadTransforms(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "github.yaml"), []byte(githubTransform), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a transform"), 0644)
+	defer func() {
+		transformersMu.Lock()
+		delete(transformers, "github")
+		delete(transformers, "github-pr")
+		transformersMu.Unlock()
+	}()
+
+	count, err := LoadTransforms(dir)
+	if err != nil {
+		t.Fatalf("LoadTransforms failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+
+	raw := map[string]interface{}{
+		"_meta": map[string]interface{}{"source": "github", "event_id": "pr-1"},
+		"pull_request": map[string]interface{}{
+			"title": "Fix AE-35 unit",
+			"body":  "Predicts   failure\n\nin 72 hours",
+		},
+		"user": map[string]interface{}{"login": "Dave"},
+		"requested_reviewers": []interface{}{
+			map[string]interface{}{"login": "Frank Poole"},
+			map[string]interface{}{"id": 7},
+		},
+	}
+
+	bronze, err := ToBronze(ProcessConfig{}, "github-pr", raw)
+	if err != nil {
+		t.Fatalf("ToBronze failed: %v", err)
+	}
+	if bronze.Content["title"] != "Fix AE-35 unit" || bronze.Content["author"] != "dave" {
+		t.Errorf("bronze content = %v", bronze.Content)
+	}
+	if bronze.Content["body"] != "Predicts failure in 72 hours" {
+		t.Errorf("body = %q", bronze.Content["body"])
+	}
+
+	links := Lookup("github").Silver(bronze.Content)
+	if !hasLink(links, "authored_by", "people/dave") {
+		t.Errorf("missing authored_by: %+v", links)
+	}
+	if !hasLink(links, "reviewed_by", "people/frank-poole") {
+		t.Errorf("missing reviewed_by: %+v", links)
+	}
+	// Reviewer without a login and the unmapped project are skipped
+	if len(links) != 2 {
+		t.Errorf("links = %+v, want 2", links)
+	}
+	if links[1].Label != "Frank Poole" {
+		t.Errorf("label = %q", links[1].Label)
+	}
+}
+
+func TestLoadTransformsErrors(t *testing.T) {
+	if count, err := LoadTransforms(filepath.Join(t.TempDir(), "missing")); err != nil || count != 0 {
+		t.Errorf("missing dir = %d, %v", count, err)
+	}
+
+	bad := map[string]string{
+		"no source": "fields: {a: b}\n",
+		"no fields": "source: x\n",
+		"no target": "source: x\nfields: {a: b}\nlinks:\n  - type: t\n",
+		"literal":   "source: x\nfields: {a: b}\nlinks:\n  - type: t\n    target: people/dave\n",
+	}
+	for name, content := range bad {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "x.yaml"), []byte(content), 0644)
+		if _, err := LoadTransforms(dir); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/bowman"
+	"github.com/pearcec/hal9000/discovery/lmc"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// DocumentType is the LMC entity type that holds silver links.
+const DocumentType = "documents"
+
+// DefaultInterval is how often Watch scans for new raw documents.
+const DefaultInterval = time.Minute
+
+// skipDirs are library folders that never hold raw documents.
+var skipDirs = map[string]bool{
+	string(StageBronze): true,
+	string(StageSilver): true,
+	string(StageGold):   true,
+	GoldTimelines:       true,
+	GoldProjects:        true,
+	GoldDigests:         true,
+	"archive":           true,
+	DocumentType:        true,
+}
+
+// Pipeline carries raw documents through bronze and silver. It is
+// idempotent: a stage is only rewritten when the document it was derived
+// from changed, tracked by DocumentMeta.SourceHash. Silver is also redone
+// when the people or URLs it matches in the LMC change, since enrichment
+// links them.
+type Pipeline struct {
+	Config     ProcessConfig
+	Library    *lmc.Library // Receives silver links; nil skips the LMC
+	Categories []string     // Raw folders to process; empty means all
+
+	seen       map[string]time.Time // Raw path -> mod time already handled
+	enrichment string               // enrichmentKey of the indexes seen was built with
+}
+
+// RunResult counts what one pass did.
+type RunResult struct {
+	Scanned int `json:"scanned"` // Raw documents looked at
+	Bronze  int `json:"bronze"`  // Bronze documents written
+	Silver  int `json:"silver"`  // Silver documents written
+	Gold    int `json:"gold"`    // Gold rollups written
+	Failed  int `json:"failed"`
+}
+
+// NewPipeline creates a pipeline for the library in config.
+func NewPipeline(config ProcessConfig, library *lmc.Library) *Pipeline {
+	return &Pipeline{Config: config, Library: library}
+}
+
+// Watch runs a pass every interval until ctx is cancelled.
+func (p *Pipeline) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	log.Printf("[processor] Watching %s every %s", expandPath(p.Config.LibraryPath), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := p.Run()
+		if err != nil {
+			log.Printf("[processor] Pass failed: %v", err)
+		} else if result.Bronze+result.Silver+result.Failed > 0 {
+			log.Printf("[processor] Pass complete: %d bronze, %d silver, %d gold, %d failed", result.Bronze, result.Silver, result.Gold, result.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run processes raw documents that are new or changed since the last pass.
+// The first pass checks every raw document.
+func (p *Pipeline) Run() (RunResult, error) {
+	var result RunResult
+	if p.seen == nil {
+		p.seen = make(map[string]time.Time)
+	}
+
+	paths, err := p.rawPaths(p.Categories)
+	if err != nil {
+		return result, err
+	}
+	if err := p.loadIndexes(); err != nil {
+		return result, err
+	}
+	// New people or URLs can add links to documents already handled.
+	// Each is checked again, but only those whose matches changed are
+	// rewritten.
+	if key := enrichmentKey(p.Config.People, p.Config.URLs); key != p.enrichment {
+		p.seen = make(map[string]time.Time)
+		p.enrichment = key
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if seen, ok := p.seen[path]; ok && seen.Equal(info.ModTime()) {
+			continue
+		}
+		result.Scanned++
+
+		file, err := p.processFile(path)
+		result.Bronze += file.Bronze
+		result.Silver += file.Silver
+		result.Gold += file.Gold
+		if err != nil {
+			log.Printf("[processor] Failed to process %s: %v", filepath.Base(path), err)
+			result.Failed++
+			continue
+		}
+		p.seen[path] = info.ModTime()
+	}
+	return result, nil
+}
+
+// ProcessFile takes one raw document through bronze and silver, and the
+// silver result into the gold rollups, and reports which stages were written.
+func (p *Pipeline) ProcessFile(rawPath string) (bronzeWritten, silverWritten bool, err error) {
+	result, err := p.processFile(rawPath)
+	return result.Bronze > 0, result.Silver > 0, err
+}
+
+// processFile counts what ProcessFile wrote for one raw document.
+func (p *Pipeline) processFile(rawPath string) (RunResult, error) {
+	var result RunResult
+	raw, data, err := readRaw(rawPath)
+	if err != nil {
+		return result, err
+	}
+	meta, _ := raw["_meta"].(map[string]interface{})
+	if getString(meta, "stage") != string(StageRaw) || getString(meta, "event_id") == "" {
+		return result, nil
+	}
+	category := filepath.Base(filepath.Dir(rawPath))
+
+	// Raw -> Bronze
+	bronze, err := ToBronze(p.Config, category, raw)
+	if err != nil {
+		return result, err
+	}
+	bronze.Meta.SourceHash = hashBytes(data)
+	bronzePath, existing, err := p.load(bronze.Meta)
+	if err != nil {
+		return result, err
+	}
+	if existing != nil && existing.Meta.SourceHash == bronze.Meta.SourceHash {
+		bronze = existing
+	} else {
+		if bronzePath, err = SaveDocument(p.Config, bronze); err != nil {
+			return result, err
+		}
+		result.Bronze = 1
+	}
+
+	// Bronze -> Silver
+	bronzeData, err := vault.ReadFile(bronzePath)
+	if err != nil {
+		return result, err
+	}
+	silver, err := ToSilver(p.Config, bronze)
+	if err != nil {
+		return result, err
+	}
+	silver.Meta.SourceHash = silverHash(bronzeData, silver)
+	silverPath, existingSilver, err := p.load(silver.Meta)
+	if err != nil {
+		return result, err
+	}
+	if existingSilver != nil && existingSilver.Meta.SourceHash == silver.Meta.SourceHash {
+		return result, nil
+	}
+	if silverPath, err = SaveDocument(p.Config, silver); err != nil {
+		return result, err
+	}
+	result.Silver = 1
+
+	if p.Library != nil {
+		if err := p.storeURL(silver); err != nil {
+			return result, fmt.Errorf("failed to store URL: %w", err)
+		}
+		if err := p.storeLinks(silver, silverPath); err != nil {
+			return result, fmt.Errorf("failed to store links: %w", err)
+		}
+		// Silver -> Gold
+		rollups, err := UpdateGold(p.Library, silver)
+		result.Gold = len(rollups)
+		if err != nil {
+			return result, fmt.Errorf("failed to update gold rollups: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// readRaw reads a raw document: a bowman event, or a URL library note.
+// It also returns the bytes the document was read from.
+func readRaw(path string) (map[string]interface{}, []byte, error) {
+	if filepath.Ext(path) == ".md" {
+		return readURLEntry(path)
+	}
+	data, err := bowman.ReadEvent(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	return raw, data, nil
+}
+
+// loadIndexes refreshes the names and URLs silver enrichment looks for
+// from the LMC, so people and URLs added since the last pass are found.
+func (p *Pipeline) loadIndexes() error {
+	if p.Library == nil {
+		return nil
+	}
+	people, err := NewPeopleIndex(p.Library)
+	if err != nil {
+		return fmt.Errorf("failed to index people: %w", err)
+	}
+	urls, err := NewURLIndex(p.Library)
+	if err != nil {
+		return fmt.Errorf("failed to index URLs: %w", err)
+	}
+	p.Config.People = people
+	p.Config.URLs = urls
+	return nil
+}
+
+// load reads the existing document at meta's stage for the same event.
+// It returns "" and nil if there is none.
+func (p *Pipeline) load(meta DocumentMeta) (string, *Document, error) {
+	stagePath := filepath.Join(expandPath(p.Config.LibraryPath), string(meta.Stage), meta.Source)
+	path := findDocument(stagePath, meta.EventID)
+	if path == "" {
+		return "", nil, nil
+	}
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		// Rewritten below; fsck reports anything left behind
+		return path, nil, nil
+	}
+	// Older documents are upgraded in memory, so reusing one (or comparing
+	// against it) sees the current layout
+	if _, err := Upgrade(&doc); err != nil {
+		return "", nil, err
+	}
+	return path, &doc, nil
+}
+
+// storeLinks writes a silver document's links to the LMC as derived edges
+// of a documents/<source>_<event> entity.
+func (p *Pipeline) storeLinks(silver *Document, silverPath string) error {
+	rel, err := filepath.Rel(expandPath(p.Config.LibraryPath), silverPath)
+	if err != nil {
+		rel = silverPath
+	}
+
+	content := map[string]interface{}{
+		"source":   silver.Meta.Source,
+		"event_id": silver.Meta.EventID,
+		"silver":   filepath.ToSlash(rel),
+	}
+	if title, ok := silver.Content["title"].(string); ok && title != "" {
+		content["title"] = title
+	}
+
+	var edges []lmc.Edge
+	for _, link := range silver.Links {
+		edges = append(edges, lmc.Edge{
+			To:      resolveTarget(p.Library, link),
+			Type:    link.Type,
+			Label:   link.Label,
+			Derived: true,
+		})
+	}
+
+	_, err = p.Library.Store(DocumentType, strings.TrimPrefix(documentID(silver.Meta), DocumentType+"/"), content, edges)
+	return err
+}
+
+// storeURL saves the urls/<key> entity a URL library entry's saved_url
+// link points at, so the link (and text citing the URL) has a target.
+func (p *Pipeline) storeURL(silver *Document) error {
+	if silver.Meta.Source != URLCategory {
+		return nil
+	}
+	link := getString(silver.Content, "url")
+	key := urlKey(link)
+	if key == "" {
+		return nil
+	}
+	content := map[string]interface{}{
+		"url":    link,
+		"domain": silver.Content["domain"],
+	}
+	if title := getString(silver.Content, "title"); title != "" {
+		content["title"] = title
+	}
+	_, err := p.Library.Store(URLType, key, content, nil)
+	return err
+}
+
+// resolveTarget maps a silver link to the entity it refers to, following
+// alias records so a person named by email or speaker label links to
+// their canonical profile. Targets the LMC doesn't know are kept as is.
+func resolveTarget(lib *lmc.Library, link Link) string {
+	target := linkTarget(link)
+	identifiers := []string{target}
+	if link.Type == "spoken_by" && link.Label != "" {
+		// An alias for the label is the user's correction, so it wins
+		identifiers = []string{link.Label, target}
+	}
+	for _, identifier := range identifiers {
+		if id, err := lib.Resolve(identifier); err == nil {
+			return id
+		}
+	}
+	return target
+}
+
+// documentID is the LMC entity that holds a document's silver links.
+func documentID(meta DocumentMeta) string {
+	return DocumentType + "/" + sanitizeFilename(meta.Source+"_"+meta.EventID)
+}
+
+// linkTarget maps a silver link target to an LMC entity ID. Bare mentions
+// become people/<email> or users/<slack id>, as in lmc.ExtractEdges.
+func linkTarget(link Link) string {
+	if strings.Contains(link.Target, "/") {
+		return link.Target
+	}
+	if strings.Contains(link.Target, "@") {
+		return "people/" + strings.ToLower(link.Target)
+	}
+	return "users/" + link.Target
+}
+
+// rawPaths lists candidate raw documents in categories (all when empty).
+// When an event has several stored versions only the latest is returned.
+func (p *Pipeline) rawPaths(categories []string) ([]string, error) {
+	libPath := expandPath(p.Config.LibraryPath)
+
+	if len(categories) == 0 {
+		entries, err := os.ReadDir(libPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") && !skipDirs[e.Name()] {
+				categories = append(categories, e.Name())
+			}
+		}
+	}
+
+	var paths []string
+	for _, category := range categories {
+		// Only the latest stored version of each event is processed
+		latest, err := bowman.Latest(bowman.StoreConfig{LibraryPath: libPath, Category: category})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range latest {
+			paths = append(paths, v.Path)
+		}
+		if category == URLCategory {
+			entries, err := urlEntryPaths(libPath)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, entries...)
+		}
+	}
+	return paths, nil
+}
+
+// silverHash is a silver document's SourceHash: its bronze document's
+// hash, combined with the people and URLs enrichment matched in it. A
+// change to the LMC's people or URLs only alters the hash of documents
+// whose matches it changes.
+func silverHash(bronzeData []byte, silver *Document) string {
+	var matched []string
+	for _, link := range silver.Links {
+		if link.Type == LinkMentions || link.Type == LinkCitesURL {
+			matched = append(matched, link.Type+"\x00"+link.Target+"\x00"+link.Label)
+		}
+	}
+	if len(matched) == 0 {
+		return hashBytes(bronzeData)
+	}
+	sort.Strings(matched)
+	return hashBytes(append([]byte(strings.Join(matched, "\n")+"\n"), bronzeData...))
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,353 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+func writeRawSlack(t *testing.T, lib, date, eventID, text string) string {
+	t.Helper()
+	path := filepath.Join(lib, "slack", "slack_"+date+"_"+eventID+".json")
+	doc := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"source":     "slack",
+			"fetched_at": date + "T09:00:00Z",
+			"event_id":   eventID,
+			"stage":      "raw",
+		},
+		"channel": "C42",
+		"user":    "U123",
+		"text":    text,
+	}
+	data, _ := json.Marshal(doc)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeURLEntry(t *testing.T, lib, name, entry string) string {
+	t.Helper()
+	path := filepath.Join(lib, URLCategory, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(entry), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readDocument(t *testing.T, path string) *Document {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func stageFiles(t *testing.T, lib string, stage Stage) []string {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(lib, string(stage), "slack", "*.json"))
+	return matches
+}
+
+func TestPipelineRun(t *testing.T) {
+	lib := t.TempDir()
+	library, err := lmc.New(lib)
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	writeRawSlack(t, lib, "2026-01-27", "1706000000.000100", "ping dave@discovery.one about the pod bay doors")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, library)
+	result, err := p.Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Bronze != 1 || result.Silver != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	bronzeFiles := stageFiles(t, lib, StageBronze)
+	silverFiles := stageFiles(t, lib, StageSilver)
+	if len(bronzeFiles) != 1 || len(silverFiles) != 1 {
+		t.Fatalf("bronze = %v, silver = %v", bronzeFiles, silverFiles)
+	}
+	silver := readDocument(t, silverFiles[0])
+	if silver.Meta.PreviousID != "bronze/slack/1706000000.000100" {
+		t.Errorf("silver previous_id = %q", silver.Meta.PreviousID)
+	}
+	if bronze := readDocument(t, bronzeFiles[0]); bronze.Meta.PreviousID != "raw/slack/1706000000.000100" {
+		t.Errorf("bronze previous_id = %q", bronze.Meta.PreviousID)
+	}
+
+	// Silver links are in the LMC edge index
+	linked, err := library.GetLinked("people/dave@discovery.one", "in")
+	if err != nil {
+		t.Fatalf("GetLinked failed: %v", err)
+	}
+	var documents []string
+	for _, entity := range linked {
+		if entity.Type == DocumentType {
+			documents = append(documents, entity.ID)
+		}
+	}
+	if len(documents) != 1 || documents[0] != "documents/slack_1706000000_000100" {
+		t.Errorf("document backlinks = %v", documents)
+	}
+	// The document and the channel's weekly digest
+	if linked, _ := library.GetLinked("channels/C42", "in"); len(linked) != 2 {
+		t.Errorf("channel backlinks = %v", linked)
+	}
+	if result.Gold != 2 {
+		t.Errorf("gold rollups = %d, want digest and author timeline", result.Gold)
+	}
+
+	// A fresh pipeline finds nothing to do
+	result, err = NewPipeline(ProcessConfig{LibraryPath: lib}, library).Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Scanned != 1 || result.Bronze != 0 || result.Silver != 0 {
+		t.Errorf("second run result = %+v", result)
+	}
+}
+
+func TestPipelineReprocessesChangedRaw(t *testing.T) {
+	lib := t.TempDir()
+	raw := writeRawSlack(t, lib, "2026-01-27", "evt1", "first")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, nil)
+	if _, err := p.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Unchanged: skipped without reading
+	if result, _ := p.Run(); result.Scanned != 0 {
+		t.Errorf("unchanged raw rescanned: %+v", result)
+	}
+
+	writeRawSlack(t, lib, "2026-01-27", "evt1", "second")
+	if _, _, err := p.ProcessFile(raw); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	silverFiles := stageFiles(t, lib, StageSilver)
+	if len(silverFiles) != 1 {
+		t.Fatalf("silver files = %v, want one per event", silverFiles)
+	}
+	if text := readDocument(t, silverFiles[0]).Content["text"]; text != "second" {
+		t.Errorf("silver text = %v, want second", text)
+	}
+}
+
+func TestPipelineReenrichesOnLibraryChange(t *testing.T) {
+	lib := t.TempDir()
+	library, err := lmc.New(lib)
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	writeRawSlack(t, lib, "2026-01-27", "evt1", "Frank Poole is outside the pod")
+	writeRawSlack(t, lib, "2026-01-27", "evt2", "Open the pod bay doors")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, library)
+	if _, err := p.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result, _ := p.Run(); result.Scanned != 0 {
+		t.Errorf("unchanged library rescanned: %+v", result)
+	}
+
+	// A person added to the LMC is linked without the raw document changing
+	if _, err := library.Store("people", "frank-poole", map[string]interface{}{"name": "Frank Poole"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Only the document naming them is rewritten
+	if result.Bronze != 0 || result.Silver != 1 {
+		t.Errorf("result = %+v, want one silver redone", result)
+	}
+	silver, err := LoadDocument(findDocuments(filepath.Join(lib, string(StageSilver), "slack"), "evt1")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findLink(silver.Links, LinkMentions, "people/frank-poole") == nil {
+		t.Errorf("new person not linked: %+v", silver.Links)
+	}
+
+	// Then it settles again
+	if result, _ := NewPipeline(ProcessConfig{LibraryPath: lib}, library).Run(); result.Silver != 0 {
+		t.Errorf("fresh pipeline rewrote silver: %+v", result)
+	}
+}
+
+func TestPipelineUsesLatestFetch(t *testing.T) {
+	lib := t.TempDir()
+	writeRawSlack(t, lib, "2026-01-27", "evt1", "old")
+	writeRawSlack(t, lib, "2026-01-28", "evt1", "new")
+
+	result, err := NewPipeline(ProcessConfig{LibraryPath: lib}, nil).Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Scanned != 1 {
+		t.Errorf("scanned = %d, want 1", result.Scanned)
+	}
+	bronzeFiles := stageFiles(t, lib, StageBronze)
+	if len(bronzeFiles) != 1 {
+		t.Fatalf("bronze files = %v", bronzeFiles)
+	}
+	if text := readDocument(t, bronzeFiles[0]).Content["text"]; text != "new" {
+		t.Errorf("bronze text = %v, want new", text)
+	}
+}
+
+func TestPipelineURLLibrary(t *testing.T) {
+	lib := t.TempDir()
+	library, err := lmc.New(lib)
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	// As "hal9000 url" writes it
+	entry := `# The Monolith
+
+**URL:** https://www.example.com/monolith?utm_source=feed
+**Date:** 2026-01-27
+**Tags:** Space Travel, AI
+
+## Summary
+
+A black slab found on the Moon.
+
+## Key Takes
+
+- It signals Jupiter
+
+## Content
+
+TMA-1 was buried deliberately.
+`
+	writeURLEntry(t, lib, "url_2026-01-27_the-monolith.md", entry)
+
+	result, err := NewPipeline(ProcessConfig{LibraryPath: lib}, library).Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Bronze != 1 || result.Silver != 1 || result.Failed != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	silverFiles, _ := filepath.Glob(filepath.Join(lib, string(StageSilver), URLCategory, "*.json"))
+	if len(silverFiles) != 1 {
+		t.Fatalf("silver files = %v", silverFiles)
+	}
+	silver := readDocument(t, silverFiles[0])
+	if silver.Content["title"] != "The Monolith" || silver.Content["summary"] != "A black slab found on the Moon." {
+		t.Errorf("silver content = %v", silver.Content)
+	}
+	if !hasLink(silver.Links, "tagged", "tags/space-travel") {
+		t.Errorf("missing tag link: %+v", silver.Links)
+	}
+
+	// The saved URL is an entity, linked from the entry's document
+	url, err := library.Get("urls/example_com_monolith")
+	if err != nil {
+		t.Fatalf("URL entity not stored: %v", err)
+	}
+	if url.Content["url"] != "https://www.example.com/monolith?utm_source=feed" {
+		t.Errorf("URL content = %v", url.Content)
+	}
+	linked, _ := library.GetLinked("urls/example_com_monolith", "in")
+	if len(linked) != 1 || linked[0].ID != "documents/url_library_url_2026-01-27_the-monolith" {
+		t.Errorf("URL backlinks = %v", linked)
+	}
+
+	// The new URL entity redoes silver enrichment once; after that
+	// unchanged entries are not rewritten
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, library)
+	if result, err = p.Run(); err != nil || result.Bronze != 0 {
+		t.Fatalf("second run = %+v, %v", result, err)
+	}
+	if result, _ = p.Run(); result.Scanned != 0 {
+		t.Errorf("third run result = %+v", result)
+	}
+	if result, _ = NewPipeline(ProcessConfig{LibraryPath: lib}, library).Run(); result.Scanned != 1 || result.Silver != 0 {
+		t.Errorf("fresh pipeline result = %+v", result)
+	}
+}
+
+func TestPipelineResolvesSpeakers(t *testing.T) {
+	lib := t.TempDir()
+	library, err := lmc.New(lib)
+	if err != nil {
+		t.Fatalf("lmc.New failed: %v", err)
+	}
+	if _, err := library.Store("people", "frank-poole", map[string]interface{}{"name": "Frank Poole"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.Store("people", "heywood-floyd", map[string]interface{}{"name": "Heywood Floyd"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.AddAlias("people/frank@discovery.one", "people/frank-poole"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.AddAlias("Speaker 2", "people/heywood-floyd"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(lib, "transcripts", "transcripts_2026-01-27_cal-7.json")
+	doc := map[string]interface{}{
+		"_meta":       map[string]interface{}{"source": "transcript", "event_id": "cal-7", "stage": "raw"},
+		"event_id":    "cal-7",
+		"event_title": "Pod bay review",
+		"speakers":    []string{"Frank", "Speaker 2"},
+		"attendees":   []map[string]interface{}{{"email": "Frank@discovery.one", "name": "Frank"}},
+		"text":        "Frank: Hello.\nSpeaker 2: Hi.",
+	}
+	data, _ := json.Marshal(doc)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewPipeline(ProcessConfig{LibraryPath: lib}, library).Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	entity, err := library.Get("documents/transcript_cal-7")
+	if err != nil {
+		t.Fatalf("document not stored: %v", err)
+	}
+	want := map[string]bool{"people/frank-poole": true, "people/heywood-floyd": true}
+	for _, edge := range entity.AllLinks() {
+		if edge.Type == "spoken_by" {
+			if !want[edge.To] {
+				t.Errorf("unexpected speaker edge to %s", edge.To)
+			}
+			delete(want, edge.To)
+		}
+		if edge.Type == "transcript_of" && edge.To != "documents/google-calendar_cal-7" {
+			t.Errorf("transcript_of = %s", edge.To)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing speaker edges: %v", want)
+	}
+}
@@ -4,6 +4,7 @@
 // Processor transforms data through medallion stages:
 // - Raw → Bronze (cleaned, structured)
 // - Bronze → Silver (enriched, linked)
+// - Silver → Gold (rolled up per entity in the LMC)
 package processor
 
 import (
@@ -13,10 +14,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
 )
 
 // Stage represents a data processing stage.
@@ -26,6 +30,7 @@ const (
 	StageRaw    Stage = "raw"
 	StageBronze Stage = "bronze"
 	StageSilver Stage = "silver"
+	StageGold   Stage = "gold" // LMC rollups built from silver, see UpdateGold
 )
 
 // Document represents a processed document at any stage.
@@ -42,6 +47,16 @@ type DocumentMeta struct {
 	Stage       Stage     `json:"stage"`
 	ProcessedAt time.Time `json:"processed_at"`
 	PreviousID  string    `json:"previous_id,omitempty"` // Link to source document
+	SourceHash  string    `json:"source_hash,omitempty"` // SHA-256 of the source document (and the people and URLs it matched, for silver)
+
+	// SchemaVersion is the version of the source's bronze/silver layout the
+	// document was written with; see RegisterMigration. Documents from
+	// before versions were recorded have none and read as version 1.
+	SchemaVersion int `json:"schema_version,omitempty"`
+
+	// ArchivedSource is set by retention when the source document moved
+	// into an archive: "archive/<category>/<file>.tar.gz#<entry>".
+	ArchivedSource string `json:"archived_source,omitempty"`
 }
 
 // Link represents a relationship to another entity (Silver stage).
@@ -49,11 +64,14 @@ type Link struct {
 	Type   string `json:"type"`   // e.g., "mentions", "scheduled_with", "relates_to"
 	Target string `json:"target"` // Target entity ID or path
 	Label  string `json:"label,omitempty"`
+	Span   *Span  `json:"span,omitempty"` // Text the link was found in, for links from enrichment
 }
 
 // ProcessConfig configures the processor.
 type ProcessConfig struct {
-	LibraryPath string // Base library path
+	LibraryPath string          // Base library path
+	People      *PeopleIndex    // Known names to find in silver text; nil skips
+	URLs        map[string]bool // Keys of urls/ entities citations link to; nil links none
 }
 
 // ToBronze transforms a raw document to bronze stage.
@@ -69,21 +87,13 @@ func ToBronze(config ProcessConfig, source string, rawDoc map[string]interface{}
 
 	eventID, _ := meta["event_id"].(string)
 	sourceStr, _ := meta["source"].(string)
-
-	// Build bronze document based on source type
-	var content map[string]interface{}
-	switch source {
-	case "calendar", "google-calendar":
-		content = bronzeCalendar(rawDoc)
-	case "jira":
-		content = bronzeJIRA(rawDoc)
-	case "slack":
-		content = bronzeSlack(rawDoc)
-	default:
-		// Generic bronze: just clean up the structure
-		content = bronzeGeneric(rawDoc)
+	if sourceStr == "" {
+		sourceStr = source
 	}
 
+	// Build bronze document with the source's transformer
+	content := Lookup(source, sourceStr).Bronze(rawDoc)
+
 	doc := &Document{
 		Meta: DocumentMeta{
 			Source:      sourceStr,
@@ -91,6 +101,8 @@ func ToBronze(config ProcessConfig, source string, rawDoc map[string]interface{}
 			Stage:       StageBronze,
 			ProcessedAt: time.Now(),
 			PreviousID:  fmt.Sprintf("raw/%s/%s", source, eventID),
+
+			SchemaVersion: CurrentSchemaVersion(sourceStr),
 		},
 		Content: content,
 	}
@@ -110,24 +122,20 @@ func ToSilver(config ProcessConfig, bronzeDoc *Document) (*Document, error) {
 		content[k] = v
 	}
 
-	// Extract links based on source type
-	var links []Link
-	switch bronzeDoc.Meta.Source {
-	case "calendar", "google-calendar":
-		links = extractCalendarLinks(bronzeDoc.Content)
-	case "jira":
-		links = extractJIRALinks(bronzeDoc.Content)
-	case "slack":
-		links = extractSlackLinks(bronzeDoc.Content)
-	}
-
-	// Extract mentions from text fields
-	mentions := extractMentions(bronzeDoc.Content)
-	for _, mention := range mentions {
-		links = append(links, Link{
-			Type:   "mentions",
-			Target: mention,
-		})
+	// Extract links with the source's transformer
+	links := Lookup(bronzeDoc.Meta.Source).Silver(bronzeDoc.Content)
+
+	// Find people, issues, URLs, channels and deadlines in text fields.
+	// A target the source already links to is not linked again. Deadlines
+	// without a year take it from the event, not from when it was processed.
+	linked := make(map[string]bool)
+	for _, link := range links {
+		linked[linkTarget(link)] = true
+	}
+	for _, ref := range extractReferences(bronzeDoc.Content, config.People, config.URLs, documentTime(bronzeDoc)) {
+		if !linked[linkTarget(ref)] {
+			links = append(links, ref)
+		}
 	}
 
 	doc := &Document{
@@ -137,6 +145,8 @@ func ToSilver(config ProcessConfig, bronzeDoc *Document) (*Document, error) {
 			Stage:       StageSilver,
 			ProcessedAt: time.Now(),
 			PreviousID:  fmt.Sprintf("bronze/%s/%s", bronzeDoc.Meta.Source, bronzeDoc.Meta.EventID),
+
+			SchemaVersion: CurrentSchemaVersion(bronzeDoc.Meta.Source),
 		},
 		Content: content,
 		Links:   links,
@@ -211,15 +221,13 @@ func extractCalendarLinks(content map[string]interface{}) []Link {
 	var links []Link
 
 	// Link to attendees
-	if attendees, ok := content["attendees"].([]map[string]interface{}); ok {
-		for _, a := range attendees {
-			if email, ok := a["email"].(string); ok {
-				links = append(links, Link{
-					Type:   "scheduled_with",
-					Target: fmt.Sprintf("people/%s", email),
-					Label:  getString(a, "name"),
-				})
-			}
+	for _, a := range mapList(content["attendees"]) {
+		if email, ok := a["email"].(string); ok && email != "" {
+			links = append(links, Link{
+				Type:   "scheduled_with",
+				Target: fmt.Sprintf("people/%s", strings.ToLower(email)),
+				Label:  getString(a, "name"),
+			})
 		}
 	}
 
@@ -230,12 +238,13 @@ func extractCalendarLinks(content map[string]interface{}) []Link {
 func extractJIRALinks(content map[string]interface{}) []Link {
 	var links []Link
 
-	// Link to assignee
+	// Link to assignee, lowercased like calendar attendees so both share
+	// one person
 	if assignee, ok := content["assignee"].(map[string]interface{}); ok {
-		if email, ok := assignee["email"].(string); ok {
+		if email, ok := assignee["email"].(string); ok && email != "" {
 			links = append(links, Link{
 				Type:   "assigned_to",
-				Target: fmt.Sprintf("people/%s", email),
+				Target: fmt.Sprintf("people/%s", strings.ToLower(email)),
 				Label:  getString(assignee, "name"),
 			})
 		}
@@ -275,44 +284,15 @@ func extractSlackLinks(content map[string]interface{}) []Link {
 	return links
 }
 
-// extractMentions finds @mentions and email-like patterns in text fields.
+// extractMentions finds Slack user IDs and email addresses in text fields.
 func extractMentions(content map[string]interface{}) []string {
 	var mentions []string
-	seen := make(map[string]bool)
-
-	// Email regex
-	emailRe := regexp.MustCompile(`[\w.+-]+@[\w.-]+\.\w+`)
-	// @mention regex (Slack style)
-	mentionRe := regexp.MustCompile(`<@([A-Z0-9]+)>`)
-
-	var extractFromValue func(v interface{})
-	extractFromValue = func(v interface{}) {
-		switch val := v.(type) {
-		case string:
-			for _, email := range emailRe.FindAllString(val, -1) {
-				if !seen[email] {
-					mentions = append(mentions, email)
-					seen[email] = true
-				}
-			}
-			for _, match := range mentionRe.FindAllStringSubmatch(val, -1) {
-				if len(match) > 1 && !seen[match[1]] {
-					mentions = append(mentions, match[1])
-					seen[match[1]] = true
-				}
-			}
-		case map[string]interface{}:
-			for _, v := range val {
-				extractFromValue(v)
-			}
-		case []interface{}:
-			for _, v := range val {
-				extractFromValue(v)
-			}
+	for _, link := range extractReferences(content, nil, nil, time.Time{}) {
+		if link.Type == LinkMentions {
+			_, id, _ := strings.Cut(link.Target, "/")
+			mentions = append(mentions, id)
 		}
 	}
-
-	extractFromValue(content)
 	return mentions
 }
 
@@ -422,24 +402,71 @@ func SaveDocument(config ProcessConfig, doc *Document) (string, error) {
 		return "", err
 	}
 
-	filename := fmt.Sprintf("%s_%s.json",
-		doc.Meta.ProcessedAt.Format("2006-01-02"),
-		sanitizeFilename(doc.Meta.EventID))
-	fullPath := filepath.Join(stagePath, filename)
+	lock, err := vault.LockLibrary(libPath)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	// Reprocessing an event overwrites its existing document, keeping the
+	// name it was first saved under. Extra copies left by older versions,
+	// which named every save by its date, are removed.
+	existing := findDocuments(stagePath, doc.Meta.EventID)
+	fullPath := ""
+	if len(existing) > 0 {
+		fullPath = existing[0]
+	} else {
+		fullPath = filepath.Join(stagePath, fmt.Sprintf("%s_%s.json",
+			doc.Meta.ProcessedAt.Format("2006-01-02"),
+			sanitizeFilename(doc.Meta.EventID)))
+	}
+	filename := filepath.Base(fullPath)
 
 	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
 		return "", err
 	}
 
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+	if err := vault.WriteFile(fullPath, data, 0644); err != nil {
 		return "", err
 	}
 
+	changed := []string{fullPath}
+	for i := 1; i < len(existing); i++ {
+		dup := existing[i]
+		if err := os.Remove(dup); err != nil {
+			return "", err
+		}
+		log.Printf("[processor] Removed duplicate %s document: %s", doc.Meta.Stage, filepath.Base(dup))
+		changed = append(changed, dup)
+	}
+
 	log.Printf("[processor] Saved %s document: %s", doc.Meta.Stage, filename)
+	if err := history.RecordIn(libPath, fmt.Sprintf("processor: save %s/%s/%s", doc.Meta.Stage, doc.Meta.Source, filename), changed...); err != nil {
+		log.Printf("[processor] Warning: history commit failed: %v", err)
+	}
 	return fullPath, nil
 }
 
+// findDocument returns the path of the document for eventID in a stage
+// directory, or "" if there is none.
+func findDocument(stagePath, eventID string) string {
+	matches := findDocuments(stagePath, eventID)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// findDocuments returns every file for eventID in a stage directory,
+// oldest name first.
+func findDocuments(stagePath, eventID string) []string {
+	pattern := filepath.Join(stagePath, "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]_"+sanitizeFilename(eventID)+".json")
+	matches, _ := filepath.Glob(pattern)
+	sort.Strings(matches)
+	return matches
+}
+
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, _ := os.UserHomeDir()
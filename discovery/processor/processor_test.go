@@ -111,6 +111,48 @@ func TestToBronzeJIRA(t *testing.T) {
 	}
 }
 
+func TestToSilverDeadlineYear(t *testing.T) {
+	bronzeDoc := &Document{
+		Meta: DocumentMeta{
+			Source:      "google-calendar",
+			EventID:     "test-123",
+			Stage:       StageBronze,
+			ProcessedAt: time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC), // Rebuilt a year later
+		},
+		Content: map[string]interface{}{
+			"title":       "AE-35 review",
+			"description": "Replace the unit by Mar 3",
+			"start":       map[string]interface{}{"dateTime": "2026-02-10T15:00:00Z"},
+		},
+	}
+
+	doc, err := ToSilver(ProcessConfig{LibraryPath: t.TempDir()}, bronzeDoc)
+	if err != nil {
+		t.Fatalf("ToSilver failed: %v", err)
+	}
+	var due []string
+	for _, link := range doc.Links {
+		if link.Type == LinkDueOn {
+			due = append(due, link.Target)
+		}
+	}
+	if len(due) != 1 || due[0] != "dates/2026-03-03" {
+		t.Errorf("due_on = %v, want dates/2026-03-03 from the meeting's year", due)
+	}
+}
+
+func TestExtractJIRALinksMixedCase(t *testing.T) {
+	jira := extractJIRALinks(map[string]interface{}{
+		"assignee": map[string]interface{}{"email": "Alice@Corp.com", "name": "Alice"},
+	})
+	calendar := extractCalendarLinks(map[string]interface{}{
+		"attendees": []interface{}{map[string]interface{}{"email": "alice@corp.com"}},
+	})
+	if len(jira) != 1 || len(calendar) != 1 || jira[0].Target != calendar[0].Target {
+		t.Errorf("JIRA assignee %+v and calendar attendee %+v are different people", jira, calendar)
+	}
+}
+
 func TestToBronzeSlack(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "processor-test-*")
 	if err != nil {
@@ -0,0 +1,315 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// RebuildOptions selects what Rebuild regenerates.
+type RebuildOptions struct {
+	Sources []string  // Raw categories; empty means all
+	Since   time.Time // Only raw documents fetched on or after this day
+	// Stage is the first stage to regenerate; later stages follow.
+	// Bronze (the default) starts from raw, silver from the existing
+	// bronze, gold from the existing silver.
+	Stage    Stage
+	Workers  int                   // Parallel documents; 0 means one per CPU
+	Progress func(done, total int) // Called after each document
+}
+
+// DocumentChange is a rebuilt document whose content or links changed.
+type DocumentChange struct {
+	Stage          Stage    `json:"stage"`
+	Source         string   `json:"source"`
+	EventID        string   `json:"event_id"`
+	Created        bool     `json:"created,omitempty"` // No previous document
+	ContentChanged bool     `json:"content_changed,omitempty"`
+	LinksAdded     []string `json:"links_added,omitempty"` // "type target"
+	LinksRemoved   []string `json:"links_removed,omitempty"`
+}
+
+// ID returns stage/source/event_id.
+func (c DocumentChange) ID() string {
+	return fmt.Sprintf("%s/%s/%s", c.Stage, c.Source, c.EventID)
+}
+
+// RebuildResult summarizes a rebuild.
+type RebuildResult struct {
+	Documents int              `json:"documents"` // Raw documents selected
+	Bronze    int              `json:"bronze"`    // Bronze documents written
+	Silver    int              `json:"silver"`    // Silver documents written
+	Gold      int              `json:"gold"`      // Gold rollups written
+	Failed    int              `json:"failed"`
+	Changes   []DocumentChange `json:"changes,omitempty"`
+	Errors    []string         `json:"errors,omitempty"`
+}
+
+// Rebuild regenerates stages from documents already in the library, for
+// when a transform changed. Unlike Run it ignores source hashes: every
+// selected document is transformed again. Documents keep their identity
+// (one file per stage and event) and are only rewritten when the result
+// differs.
+func (p *Pipeline) Rebuild(opts RebuildOptions) (*RebuildResult, error) {
+	from := opts.Stage
+	if from == "" || from == StageRaw {
+		from = StageBronze
+	}
+	if from != StageBronze && from != StageSilver && from != StageGold {
+		return nil, fmt.Errorf("invalid stage %q: want bronze, silver or gold", opts.Stage)
+	}
+	if from == StageGold && p.Library == nil {
+		return nil, fmt.Errorf("rebuilding gold needs the LMC")
+	}
+
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = p.Categories
+	}
+	paths, err := p.rawPaths(sources)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Since.IsZero() {
+		paths = fetchedSince(paths, opts.Since)
+	}
+	if err := p.loadIndexes(); err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	result := &RebuildResult{Documents: len(paths)}
+	log.Printf("[processor][rebuild] Rebuilding %d documents from %s with %d workers", len(paths), from, workers)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	done := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				file, changes, err := p.rebuildFile(path, from)
+
+				mu.Lock()
+				result.Bronze += file.Bronze
+				result.Silver += file.Silver
+				result.Gold += file.Gold
+				result.Changes = append(result.Changes, changes...)
+				if err != nil {
+					result.Failed++
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+				}
+				done++
+				if opts.Progress != nil {
+					opts.Progress(done, len(paths))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].ID() < result.Changes[j].ID()
+	})
+	sort.Strings(result.Errors)
+	log.Printf("[processor][rebuild] Done: %d bronze, %d silver, %d gold, %d changed, %d failed",
+		result.Bronze, result.Silver, result.Gold, len(result.Changes), result.Failed)
+	return result, nil
+}
+
+// rebuildFile regenerates one raw document's stages from the given one on.
+// A stage before it is reused, or generated if it is missing.
+func (p *Pipeline) rebuildFile(rawPath string, from Stage) (RunResult, []DocumentChange, error) {
+	var result RunResult
+	var changes []DocumentChange
+
+	raw, data, err := readRaw(rawPath)
+	if err != nil {
+		return result, nil, err
+	}
+	meta, _ := raw["_meta"].(map[string]interface{})
+	if getString(meta, "stage") != string(StageRaw) || getString(meta, "event_id") == "" {
+		return result, nil, nil
+	}
+	category := filepath.Base(filepath.Dir(rawPath))
+
+	// Raw -> Bronze
+	bronze, err := ToBronze(p.Config, category, raw)
+	if err != nil {
+		return result, nil, err
+	}
+	bronze.Meta.SourceHash = hashBytes(data)
+	bronzePath, existing, err := p.load(bronze.Meta)
+	if err != nil {
+		return result, nil, err
+	}
+	if from == StageBronze || existing == nil {
+		path, written, change, err := p.replace(bronze, existing)
+		if err != nil {
+			return result, changes, err
+		}
+		bronzePath = path
+		if written {
+			result.Bronze = 1
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	} else {
+		bronze = existing
+	}
+
+	// Bronze -> Silver
+	bronzeData, err := vault.ReadFile(bronzePath)
+	if err != nil {
+		return result, changes, err
+	}
+	silver, err := ToSilver(p.Config, bronze)
+	if err != nil {
+		return result, changes, err
+	}
+	silver.Meta.SourceHash = silverHash(bronzeData, silver)
+	silverPath, existingSilver, err := p.load(silver.Meta)
+	if err != nil {
+		return result, changes, err
+	}
+	silverChanged := false
+	if from != StageGold || existingSilver == nil {
+		path, written, change, err := p.replace(silver, existingSilver)
+		if err != nil {
+			return result, changes, err
+		}
+		silverPath = path
+		if written {
+			result.Silver = 1
+		}
+		if change != nil {
+			changes = append(changes, *change)
+			silverChanged = true
+		}
+	} else {
+		silver = existingSilver
+	}
+
+	// Silver -> LMC and Gold, when silver changed or gold is being rebuilt
+	if p.Library == nil || (!silverChanged && from != StageGold) {
+		return result, changes, nil
+	}
+	if silverChanged {
+		if err := p.storeURL(silver); err != nil {
+			return result, changes, fmt.Errorf("failed to store URL: %w", err)
+		}
+		if err := p.storeLinks(silver, silverPath); err != nil {
+			return result, changes, fmt.Errorf("failed to store links: %w", err)
+		}
+	}
+	rollups, err := UpdateGold(p.Library, silver)
+	result.Gold = len(rollups)
+	if err != nil {
+		return result, changes, fmt.Errorf("failed to update gold rollups: %w", err)
+	}
+	return result, changes, nil
+}
+
+// replace saves doc over the existing document for its event unless
+// nothing changed. It returns the document's path, whether it was written,
+// and the change if its content or links differ. Duplicate files for the
+// event are always collapsed into one.
+func (p *Pipeline) replace(doc, existing *Document) (string, bool, *DocumentChange, error) {
+	change := diffDocuments(existing, doc)
+	if change == nil && existing.Meta.SourceHash == doc.Meta.SourceHash {
+		stagePath := filepath.Join(expandPath(p.Config.LibraryPath), string(doc.Meta.Stage), doc.Meta.Source)
+		if paths := findDocuments(stagePath, doc.Meta.EventID); len(paths) == 1 {
+			return paths[0], false, nil, nil
+		}
+	}
+	path, err := SaveDocument(p.Config, doc)
+	if err != nil {
+		return "", false, nil, err
+	}
+	return path, true, change, nil
+}
+
+// diffDocuments compares a rebuilt document with the one it replaces and
+// returns nil if content and links are the same.
+func diffDocuments(before, after *Document) *DocumentChange {
+	change := &DocumentChange{Stage: after.Meta.Stage, Source: after.Meta.Source, EventID: after.Meta.EventID}
+	if before == nil {
+		change.Created = true
+		return change
+	}
+
+	oldContent, _ := json.Marshal(before.Content)
+	newContent, _ := json.Marshal(after.Content)
+	change.ContentChanged = !bytes.Equal(oldContent, newContent)
+
+	oldLinks := linkSet(before.Links)
+	newLinks := linkSet(after.Links)
+	for link := range newLinks {
+		if !oldLinks[link] {
+			change.LinksAdded = append(change.LinksAdded, link)
+		}
+	}
+	for link := range oldLinks {
+		if !newLinks[link] {
+			change.LinksRemoved = append(change.LinksRemoved, link)
+		}
+	}
+	sort.Strings(change.LinksAdded)
+	sort.Strings(change.LinksRemoved)
+
+	if !change.ContentChanged && len(change.LinksAdded) == 0 && len(change.LinksRemoved) == 0 {
+		return nil
+	}
+	return change
+}
+
+func linkSet(links []Link) map[string]bool {
+	set := make(map[string]bool, len(links))
+	for _, link := range links {
+		set[link.Type+" "+link.Target] = true
+	}
+	return set
+}
+
+// fetchedSince keeps raw paths whose <category>_<date>_ name is on or after
+// since. Files without a date in the name are kept.
+func fetchedSince(paths []string, since time.Time) []string {
+	day := since.Format("2006-01-02")
+	var kept []string
+	for _, path := range paths {
+		name := filepath.Base(path)
+		category := filepath.Base(filepath.Dir(path))
+		date := strings.TrimPrefix(name, category+"_")
+		if category == URLCategory {
+			date = strings.TrimPrefix(name, "url_")
+		}
+		if len(date) >= len(day) {
+			if _, err := time.Parse("2006-01-02", date[:len(day)]); err == nil && date[:len(day)] < day {
+				continue
+			}
+		}
+		kept = append(kept, path)
+	}
+	return kept
+}
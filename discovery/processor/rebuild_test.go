@@ -0,0 +1,159 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRebuildFromSilver(t *testing.T) {
+	lib := t.TempDir()
+	library := newTestLibrary(t)
+	writeRawSlack(t, lib, "2026-01-27", "evt1", "open the pod bay doors")
+	writeRawSlack(t, lib, "2026-01-28", "evt2", "I'm sorry, Dave")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, library)
+	if _, err := p.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// The slack transform gains a link
+	defer Register(Lookup("slack"), "slack")
+	Register(TransformFuncs{BronzeFunc: bronzeSlack, SilverFunc: func(content map[string]interface{}) []Link {
+		return append(extractSlackLinks(content), Link{Type: "tagged", Target: "tags/pod-bay"})
+	}}, "slack")
+
+	var progress []int
+	result, err := p.Rebuild(RebuildOptions{
+		Stage:    StageSilver,
+		Workers:  2,
+		Progress: func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if result.Documents != 2 || result.Bronze != 0 || result.Silver != 2 || result.Failed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(progress) != 2 {
+		t.Errorf("progress calls = %v", progress)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("changes = %+v", result.Changes)
+	}
+	change := result.Changes[0]
+	if change.ID() != "silver/slack/evt1" || change.ContentChanged || len(change.LinksAdded) != 1 || change.LinksAdded[0] != "tagged tags/pod-bay" {
+		t.Errorf("change = %+v", change)
+	}
+	if files := stageFiles(t, lib, StageSilver); len(files) != 2 {
+		t.Errorf("silver files = %v, want one per event", files)
+	}
+	if linked, _ := library.GetLinked("tags/pod-bay", "in"); len(linked) != 2 {
+		t.Errorf("new links not in the LMC: %v", linked)
+	}
+
+	// Nothing changes the second time
+	result, err = p.Rebuild(RebuildOptions{Stage: StageSilver})
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if result.Silver != 0 || len(result.Changes) != 0 {
+		t.Errorf("second rebuild = %+v", result)
+	}
+}
+
+func TestRebuildSelection(t *testing.T) {
+	lib := t.TempDir()
+	writeRawSlack(t, lib, "2025-12-31", "old", "old")
+	writeRawSlack(t, lib, "2026-01-02", "new", "new")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, nil)
+	result, err := p.Rebuild(RebuildOptions{
+		Sources: []string{"slack"},
+		Since:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if result.Documents != 1 || result.Bronze != 1 || result.Silver != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Changes) != 2 || !result.Changes[0].Created {
+		t.Errorf("changes = %+v", result.Changes)
+	}
+
+	if result, _ := p.Rebuild(RebuildOptions{Sources: []string{"jira"}}); result.Documents != 0 {
+		t.Errorf("jira rebuild selected %d documents", result.Documents)
+	}
+	if _, err := p.Rebuild(RebuildOptions{Stage: "platinum"}); err == nil {
+		t.Error("expected error for unknown stage")
+	}
+	if _, err := p.Rebuild(RebuildOptions{Stage: StageGold}); err == nil {
+		t.Error("expected error rebuilding gold without the LMC")
+	}
+}
+
+func TestRebuildURLLibrary(t *testing.T) {
+	lib := t.TempDir()
+	library := newTestLibrary(t)
+	writeURLEntry(t, lib, "url_2025-12-31_old.md", "# Old\n\n**URL:** https://example.com/old\n")
+	writeURLEntry(t, lib, "url_2026-01-02_tma-1.md", "# TMA-1\n\n**URL:** https://example.com/tma-1\n")
+
+	result, err := NewPipeline(ProcessConfig{LibraryPath: lib}, library).Rebuild(RebuildOptions{
+		Sources: []string{URLCategory},
+		Since:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if result.Documents != 1 || result.Silver != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if _, err := library.Get("urls/example_com_tma-1"); err != nil {
+		t.Errorf("URL entity not stored: %v", err)
+	}
+}
+
+func TestRebuildCollapsesDuplicates(t *testing.T) {
+	lib := t.TempDir()
+	writeRawSlack(t, lib, "2026-01-27", "evt1", "hello")
+
+	p := NewPipeline(ProcessConfig{LibraryPath: lib}, nil)
+	if _, err := p.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// A copy saved under a later date by an older version
+	files := stageFiles(t, lib, StageBronze)
+	doc := readDocument(t, files[0])
+	data, _ := json.Marshal(doc)
+	dup := filepath.Join(filepath.Dir(files[0]), "2099-01-01_evt1.json")
+	if err := os.WriteFile(dup, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Rebuild(RebuildOptions{})
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("changes = %+v", result.Changes)
+	}
+	if files := stageFiles(t, lib, StageBronze); len(files) != 1 || files[0] == dup {
+		t.Errorf("bronze files = %v, want the original only", files)
+	}
+}
+
+func TestFetchedSince(t *testing.T) {
+	paths := []string{
+		"/lib/jira/jira_2025-12-31_HAL-1.json",
+		"/lib/jira/jira_2026-01-01_HAL-2.json",
+		"/lib/jira/jira_HAL-3.json",
+	}
+	got := fetchedSince(paths, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(got) != 2 || got[0] != paths[1] || got[1] != paths[2] {
+		t.Errorf("fetchedSince = %v", got)
+	}
+}
@@ -0,0 +1,308 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// BaseSchemaVersion is the schema version of documents written before
+// versions were recorded. A missing schema_version reads as this.
+const BaseSchemaVersion = 1
+
+// MigrationFunc upgrades a bronze or silver document by one schema version,
+// in place. doc.Meta.Stage tells which; silver content is a copy of bronze
+// content, so a field rename usually applies to both.
+type MigrationFunc func(doc *Document) error
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = make(map[string]map[int]MigrationFunc) // source -> from version -> migration
+)
+
+// RegisterMigration registers the upgrade of documents from one or more
+// sources (_meta.source values) from version `from` to from+1. Change a
+// transform's output together with a migration for its existing documents:
+//
+//	processor.RegisterMigration(1, func(doc *processor.Document) error {
+//		doc.Content["summary"] = doc.Content["title"]
+//		delete(doc.Content, "title")
+//		return nil
+//	}, "google-calendar")
+func RegisterMigration(from int, fn MigrationFunc, sources ...string) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	for _, source := range sources {
+		if migrations[source] == nil {
+			migrations[source] = make(map[int]MigrationFunc)
+		}
+		migrations[source][from] = fn
+	}
+}
+
+// CurrentSchemaVersion returns the version new documents from source are
+// written with: the base version plus its chain of migrations.
+func CurrentSchemaVersion(source string) int {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	version := BaseSchemaVersion
+	for migrations[source][version] != nil {
+		version++
+	}
+	return version
+}
+
+// schemaVersion returns a document's version, reading a missing one as
+// BaseSchemaVersion.
+func schemaVersion(doc *Document) int {
+	if doc.Meta.SchemaVersion == 0 {
+		return BaseSchemaVersion
+	}
+	return doc.Meta.SchemaVersion
+}
+
+// Upgrade migrates doc to the current schema version of its source and
+// reports whether any migration ran. A document newer than this build
+// supports is an error: it was written by a later version of HAL.
+func Upgrade(doc *Document) (bool, error) {
+	source := doc.Meta.Source
+	version := schemaVersion(doc)
+	current := CurrentSchemaVersion(source)
+	if version > current {
+		return false, fmt.Errorf("%s/%s/%s has schema version %d, newer than supported %d",
+			doc.Meta.Stage, source, doc.Meta.EventID, version, current)
+	}
+
+	migrated := false
+	for ; version < current; version++ {
+		migrationsMu.RLock()
+		fn := migrations[source][version]
+		migrationsMu.RUnlock()
+		if err := fn(doc); err != nil {
+			return migrated, fmt.Errorf("migrating %s/%s/%s from version %d: %w",
+				doc.Meta.Stage, source, doc.Meta.EventID, version, err)
+		}
+		migrated = true
+	}
+	doc.Meta.SchemaVersion = current
+	return migrated, nil
+}
+
+// LoadDocument reads a bronze or silver document and upgrades it to the
+// current schema in memory. The file is left as it is; Pipeline.Migrate
+// rewrites files.
+func LoadDocument(path string) (*Document, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document %s: %w", filepath.Base(path), err)
+	}
+	if _, err := Upgrade(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// MigrateOptions selects the documents Migrate rewrites.
+type MigrateOptions struct {
+	Sources []string // _meta.source values or raw folders; empty means all
+	DryRun  bool     // Count without writing
+}
+
+// MigrateCount is the number of documents moved between two versions.
+type MigrateCount struct {
+	Source    string `json:"source"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Documents int    `json:"documents"`
+}
+
+// MigrateResult summarizes a migration.
+type MigrateResult struct {
+	Scanned  int            `json:"scanned"`
+	Migrated int            `json:"migrated"` // Rewritten (or would be, in a dry run)
+	Failed   int            `json:"failed"`
+	Counts   []MigrateCount `json:"counts,omitempty"`
+	Errors   []string       `json:"errors,omitempty"`
+}
+
+// Migrate rewrites bronze and silver documents whose stored schema version
+// is not the current one, including unversioned documents, which are
+// stamped with their version. Silver links changed by a migration are
+// stored in the LMC and the gold rollups updated.
+func (p *Pipeline) Migrate(opts MigrateOptions) (*MigrateResult, error) {
+	libPath := expandPath(p.Config.LibraryPath)
+	only, err := p.migrateSources(opts.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{}
+	counts := make(map[MigrateCount]int)
+	var written []string
+	for _, stage := range []Stage{StageBronze, StageSilver} {
+		paths, err := filepath.Glob(filepath.Join(libPath, string(stage), "*", "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			source := filepath.Base(filepath.Dir(path))
+			if len(only) > 0 && !only[source] {
+				continue
+			}
+			result.Scanned++
+
+			key, migrated, err := p.migrateFile(path, opts.DryRun)
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, fmt.Sprintf("%s/%s/%s: %v", stage, source, filepath.Base(path), err))
+				continue
+			}
+			if migrated {
+				result.Migrated++
+				counts[key]++
+				written = append(written, path)
+			}
+		}
+	}
+
+	for key, n := range counts {
+		key.Documents = n
+		result.Counts = append(result.Counts, key)
+	}
+	sort.Slice(result.Counts, func(i, j int) bool {
+		a, b := result.Counts[i], result.Counts[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.From < b.From
+	})
+
+	if !opts.DryRun && result.Migrated > 0 {
+		log.Printf("[processor][migrate] Migrated %d of %d documents", result.Migrated, result.Scanned)
+		if err := history.RecordIn(libPath, fmt.Sprintf("processor: migrate %d documents", result.Migrated), written...); err != nil {
+			log.Printf("[processor] Warning: history commit failed: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// migrateSources returns the _meta.source values names select. Each name
+// is a source itself, and a raw folder whose documents' sources are added
+// too, so "calendar" selects the google-calendar documents as it does for
+// rebuild.
+func (p *Pipeline) migrateSources(names []string) (map[string]bool, error) {
+	only := make(map[string]bool)
+	for _, name := range names {
+		only[name] = true
+		paths, err := p.rawPaths([]string{name})
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			raw, _, err := readRaw(path)
+			if err != nil {
+				continue
+			}
+			meta, _ := raw["_meta"].(map[string]interface{})
+			if source := getString(meta, "source"); source != "" {
+				only[source] = true
+			}
+		}
+	}
+	return only, nil
+}
+
+// migrateFile upgrades one document file. The returned key has no count.
+func (p *Pipeline) migrateFile(path string, dryRun bool) (MigrateCount, bool, error) {
+	key, doc, before, migrated, err := p.upgradeFile(path, dryRun)
+	if err != nil || !migrated || dryRun {
+		return key, migrated, err
+	}
+
+	// The LMC takes the library lock itself, so this runs after upgradeFile
+	// has released it.
+	if doc.Meta.Stage == StageSilver && p.Library != nil && linksChanged(before, linkSet(doc.Links)) {
+		if err := p.storeLinks(doc, path); err != nil {
+			return key, true, fmt.Errorf("failed to store links: %w", err)
+		}
+		if _, err := UpdateGold(p.Library, doc); err != nil {
+			return key, true, fmt.Errorf("failed to update gold rollups: %w", err)
+		}
+	}
+	return key, true, nil
+}
+
+// upgradeFile reads, upgrades and rewrites one document while holding the
+// library lock, so a concurrent save is not lost. It returns the document
+// and its links before the upgrade.
+func (p *Pipeline) upgradeFile(path string, dryRun bool) (MigrateCount, *Document, map[string]bool, bool, error) {
+	lock, err := vault.LockLibrary(expandPath(p.Config.LibraryPath))
+	if err != nil {
+		return MigrateCount{}, nil, nil, false, err
+	}
+	defer lock.Unlock()
+
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return MigrateCount{}, nil, nil, false, err
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return MigrateCount{}, nil, nil, false, err
+	}
+
+	// An unversioned document is reported as BaseSchemaVersion, but is still
+	// rewritten to record its version.
+	stored := doc.Meta.SchemaVersion
+	key := MigrateCount{Source: doc.Meta.Source, From: schemaVersion(&doc)}
+	before := linkSet(doc.Links)
+	if _, err := Upgrade(&doc); err != nil {
+		return key, nil, nil, false, err
+	}
+	key.To = doc.Meta.SchemaVersion
+	if stored == key.To {
+		return key, &doc, before, false, nil
+	}
+	if dryRun {
+		return key, &doc, before, true, nil
+	}
+
+	// Write back to the same file: documents keep their name
+	out, err := json.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return key, nil, nil, false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return key, nil, nil, false, err
+	}
+	if err := vault.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return key, nil, nil, false, err
+	}
+	return key, &doc, before, true, nil
+}
+
+func linksChanged(before, after map[string]bool) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for link := range after {
+		if !before[link] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pearcec/hal9000/discovery/lmc"
+)
+
+// Transformer converts documents of one source between stages.
+type Transformer interface {
+	// Bronze cleans and normalizes a raw document (including _meta).
+	Bronze(raw map[string]interface{}) map[string]interface{}
+	// Silver extracts links from bronze content. Mentions in text are
+	// added by ToSilver for every source.
+	Silver(content map[string]interface{}) []Link
+}
+
+// TransformFuncs adapts a pair of functions to Transformer. A nil Silver
+// extracts no links.
+type TransformFuncs struct {
+	BronzeFunc func(raw map[string]interface{}) map[string]interface{}
+	SilverFunc func(content map[string]interface{}) []Link
+}
+
+// Bronze calls BronzeFunc, or copies the raw document if it is nil.
+func (f TransformFuncs) Bronze(raw map[string]interface{}) map[string]interface{} {
+	if f.BronzeFunc == nil {
+		return bronzeGeneric(raw)
+	}
+	return f.BronzeFunc(raw)
+}
+
+// Silver calls SilverFunc.
+func (f TransformFuncs) Silver(content map[string]interface{}) []Link {
+	if f.SilverFunc == nil {
+		return nil
+	}
+	return f.SilverFunc(content)
+}
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = make(map[string]Transformer) // source -> transformer
+)
+
+// genericTransformer handles sources without a registered transformer: it
+// copies content and extracts no links.
+type genericTransformer struct{}
+
+func (genericTransformer) Bronze(raw map[string]interface{}) map[string]interface{} {
+	return bronzeGeneric(raw)
+}
+
+func (genericTransformer) Silver(map[string]interface{}) []Link { return nil }
+
+var generic Transformer = genericTransformer{}
+
+// CalendarSource is the source name calendar events are stored under; a
+// meeting's document is documents/google-calendar_<event id>.
+const CalendarSource = "google-calendar"
+
+// Register sets the transformer for one or more source names (raw category
+// folders or _meta.source values). A later registration for the same name
+// replaces the earlier one.
+func Register(t Transformer, sources ...string) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	for _, source := range sources {
+		transformers[source] = t
+	}
+}
+
+// Lookup returns the transformer for the first registered source name, or
+// the generic one.
+func Lookup(sources ...string) Transformer {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+	for _, source := range sources {
+		if t, ok := transformers[source]; ok {
+			return t
+		}
+	}
+	return generic
+}
+
+// Sources lists the registered source names.
+func Sources() []string {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+	names := make([]string, 0, len(transformers))
+	for name := range transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(TransformFuncs{bronzeCalendar, extractCalendarLinks}, "calendar", CalendarSource)
+	Register(TransformFuncs{bronzeJIRA, extractJIRALinks}, "jira")
+	Register(TransformFuncs{bronzeSlack, extractSlackLinks}, "slack")
+	Register(TransformFuncs{bronzeBambooHR, extractBambooHRLinks}, "bamboohr")
+	Register(TransformFuncs{bronzeTranscript, extractTranscriptLinks}, "transcript", "transcripts")
+	Register(TransformFuncs{bronzeURL, extractURLLinks}, "url", URLCategory)
+}
+
+// bronzeBambooHR transforms a raw BambooHR employee to bronze.
+func bronzeBambooHR(raw map[string]interface{}) map[string]interface{} {
+	name := getString(raw, "displayName")
+	if name == "" {
+		name = strings.TrimSpace(getString(raw, "firstName") + " " + getString(raw, "lastName"))
+	}
+	return map[string]interface{}{
+		"employee_id":      raw["id"],
+		"name":             name,
+		"preferred_name":   raw["preferredName"],
+		"title":            raw["jobTitle"],
+		"email":            strings.ToLower(getString(raw, "workEmail")),
+		"department":       raw["department"],
+		"division":         raw["division"],
+		"location":         raw["location"],
+		"supervisor":       raw["supervisor"],
+		"supervisor_id":    raw["supervisorId"],
+		"supervisor_email": strings.ToLower(getString(raw, "supervisorEmail")),
+		"hire_date":        raw["hireDate"],
+		"status":           raw["status"],
+	}
+}
+
+// extractBambooHRLinks links an employee to their manager and department.
+func extractBambooHRLinks(content map[string]interface{}) []Link {
+	var links []Link
+
+	if email := getString(content, "supervisor_email"); email != "" {
+		links = append(links, Link{
+			Type:   "reports_to",
+			Target: fmt.Sprintf("people/%s", email),
+			Label:  getString(content, "supervisor"),
+		})
+	}
+
+	if dept := getString(content, "department"); dept != "" {
+		links = append(links, Link{
+			Type:   "member_of",
+			Target: fmt.Sprintf("departments/%s", slugify(dept)),
+			Label:  dept,
+		})
+	}
+
+	return links
+}
+
+// bronzeTranscript transforms a raw meeting transcript to bronze. Line
+// breaks are kept: they separate speaker turns.
+func bronzeTranscript(raw map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"title":          raw["event_title"],
+		"calendar_event": raw["event_id"],
+		"time":           raw["event_time"],
+		"format":         raw["format"],
+		"speakers":       raw["speakers"],
+		"attendees":      raw["attendees"],
+		"text":           strings.TrimSpace(getString(raw, "text")),
+		"source_file":    raw["source_file"],
+	}
+}
+
+// extractTranscriptLinks links a transcript to its meeting's calendar
+// document and its speakers. A speaker who is a calendar attendee is
+// linked by email; the pipeline resolves both through LMC aliases.
+func extractTranscriptLinks(content map[string]interface{}) []Link {
+	var links []Link
+
+	if eventID := getString(content, "calendar_event"); eventID != "" {
+		links = append(links, Link{
+			Type:   "transcript_of",
+			Target: documentID(DocumentMeta{Source: CalendarSource, EventID: eventID}),
+		})
+	}
+
+	attendees := mapList(content["attendees"])
+	for _, speaker := range stringList(content["speakers"]) {
+		target := lmc.PersonID(speaker)
+		if email := attendeeEmail(speaker, attendees); email != "" {
+			target = "people/" + email
+		}
+		links = append(links, Link{
+			Type:   "spoken_by",
+			Target: target,
+			Label:  speaker,
+		})
+	}
+
+	return links
+}
+
+// attendeeEmail returns the lowercased email of the attendee a speaker
+// label names, by display name or by email ("frank.poole@" for "Frank
+// Poole"), or "".
+func attendeeEmail(speaker string, attendees []map[string]interface{}) string {
+	name := strings.ToLower(strings.Join(strings.Fields(speaker), " "))
+	if name == "" {
+		return ""
+	}
+	compact := strings.NewReplacer(" ", "", ".", "", "-", "", "_", "").Replace(name)
+	for _, a := range attendees {
+		email := strings.ToLower(getString(a, "email"))
+		if email == "" {
+			continue
+		}
+		if strings.ToLower(strings.Join(strings.Fields(getString(a, "name")), " ")) == name {
+			return email
+		}
+		local, _, _ := strings.Cut(email, "@")
+		if strings.NewReplacer(".", "", "-", "", "_", "").Replace(local) == compact {
+			return email
+		}
+	}
+	return ""
+}
+
+// bronzeURL transforms a URL library entry to bronze.
+func bronzeURL(raw map[string]interface{}) map[string]interface{} {
+	link := getString(raw, "url")
+	domain := ""
+	if u, err := url.Parse(link); err == nil {
+		domain = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	return map[string]interface{}{
+		"url":     link,
+		"domain":  domain,
+		"title":   raw["title"],
+		"date":    raw["date"],
+		"summary": cleanText(getString(raw, "summary")),
+		"takes":   raw["takes"],
+		"tags":    raw["tags"],
+	}
+}
+
+// extractURLLinks links a URL entry to its URL (the node text citing it
+// links to), tags and site.
+func extractURLLinks(content map[string]interface{}) []Link {
+	var links []Link
+
+	for _, tag := range stringList(content["tags"]) {
+		links = append(links, Link{
+			Type:   "tagged",
+			Target: fmt.Sprintf("tags/%s", slugify(tag)),
+			Label:  tag,
+		})
+	}
+
+	if key := urlKey(getString(content, "url")); key != "" {
+		links = append(links, Link{
+			Type:   "saved_url",
+			Target: "urls/" + key,
+			Label:  getString(content, "url"),
+		})
+	}
+
+	if domain := getString(content, "domain"); domain != "" {
+		links = append(links, Link{
+			Type:   "from_site",
+			Target: fmt.Sprintf("sites/%s", domain),
+		})
+	}
+
+	return links
+}
+
+// mapList returns the maps in a []interface{} (decoded JSON) or
+// []map[string]interface{} (built in memory) value.
+func mapList(v interface{}) []map[string]interface{} {
+	switch val := v.(type) {
+	case []map[string]interface{}:
+		return val
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, item := range val {
+			if m, ok := item.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// stringList returns the strings in a []interface{} or []string value.
+func stringList(v interface{}) []string {
+	var out []string
+	switch val := v.(type) {
+	case []string:
+		out = append(out, val...)
+	case []interface{}:
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// slugify lowercases s and joins its words with hyphens.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
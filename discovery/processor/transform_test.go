@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"testing"
+)
+
+func hasLink(links []Link, linkType, target string) bool {
+	for _, l := range links {
+		if l.Type == linkType && l.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLookup(t *testing.T) {
+	for _, source := range []string{"calendar", "google-calendar", "jira", "slack", "bamboohr", "transcript", "url_library"} {
+		if Lookup(source) == generic {
+			t.Errorf("no transformer registered for %s", source)
+		}
+	}
+	if Lookup("unknown") != generic {
+		t.Error("unknown source should use the generic transformer")
+	}
+	if Lookup("unknown", "slack") == generic {
+		t.Error("Lookup should fall back to later names")
+	}
+}
+
+func TestRegisterReplaces(t *testing.T) {
+	defer Register(Lookup("slack"), "slack")
+
+	Register(TransformFuncs{SilverFunc: func(map[string]interface{}) []Link {
+		return []Link{{Type: "custom", Target: "x/y"}}
+	}}, "slack")
+
+	doc, err := ToSilver(ProcessConfig{}, &Document{
+		Meta:    DocumentMeta{Source: "slack", EventID: "e1"},
+		Content: map[string]interface{}{"text": "hello"},
+	})
+	if err != nil {
+		t.Fatalf("ToSilver failed: %v", err)
+	}
+	if len(doc.Links) != 1 || doc.Links[0].Type != "custom" {
+		t.Errorf("links = %+v", doc.Links)
+	}
+}
+
+func TestBambooHRTransform(t *testing.T) {
+	raw := map[string]interface{}{
+		"_meta":           map[string]interface{}{"source": "bamboohr", "event_id": "42"},
+		"id":              "42",
+		"firstName":       "Frank",
+		"lastName":        "Poole",
+		"jobTitle":        "Deputy Commander",
+		"workEmail":       "Frank.Poole@Discovery.one",
+		"department":      "Flight Operations",
+		"supervisor":      "Dave Bowman",
+		"supervisorEmail": "dave@discovery.one",
+		"hireDate":        "2000-01-12",
+	}
+
+	doc, err := ToBronze(ProcessConfig{}, "bamboohr", raw)
+	if err != nil {
+		t.Fatalf("ToBronze failed: %v", err)
+	}
+	if doc.Content["name"] != "Frank Poole" || doc.Content["email"] != "frank.poole@discovery.one" {
+		t.Errorf("bronze content = %v", doc.Content)
+	}
+	if doc.Content["title"] != "Deputy Commander" || doc.Content["hire_date"] != "2000-01-12" {
+		t.Errorf("bronze content = %v", doc.Content)
+	}
+
+	links := Lookup("bamboohr").Silver(doc.Content)
+	if !hasLink(links, "reports_to", "people/dave@discovery.one") {
+		t.Errorf("missing reports_to link: %+v", links)
+	}
+	if !hasLink(links, "member_of", "departments/flight-operations") {
+		t.Errorf("missing member_of link: %+v", links)
+	}
+}
+
+func TestTranscriptTransform(t *testing.T) {
+	raw := map[string]interface{}{
+		"_meta":       map[string]interface{}{"source": "transcript", "event_id": "t1"},
+		"event_id":    "cal-7",
+		"event_title": "Pod bay review",
+		"speakers":    []interface{}{"Dave Bowman", "HAL"},
+		"text":        "Dave Bowman: Open the pod bay doors.\nHAL: I'm sorry, Dave.\n",
+	}
+
+	doc, err := ToBronze(ProcessConfig{}, "transcripts", raw)
+	if err != nil {
+		t.Fatalf("ToBronze failed: %v", err)
+	}
+	if doc.Content["title"] != "Pod bay review" {
+		t.Errorf("title = %v", doc.Content["title"])
+	}
+	if text, _ := doc.Content["text"].(string); text != "Dave Bowman: Open the pod bay doors.\nHAL: I'm sorry, Dave." {
+		t.Errorf("speaker turns not preserved: %q", text)
+	}
+
+	links := Lookup("transcript").Silver(doc.Content)
+	if !hasLink(links, "transcript_of", "documents/google-calendar_cal-7") {
+		t.Errorf("missing transcript_of link: %+v", links)
+	}
+	if !hasLink(links, "spoken_by", "people/dave-bowman") {
+		t.Errorf("missing speaker link: %+v", links)
+	}
+}
+
+func TestURLTransform(t *testing.T) {
+	raw := map[string]interface{}{
+		"_meta":   map[string]interface{}{"source": "url", "event_id": "u1"},
+		"url":     "https://www.example.com/monolith",
+		"title":   "The Monolith",
+		"summary": "  A   black slab. ",
+		"tags":    []interface{}{"Space Travel", "AI"},
+	}
+
+	doc, err := ToBronze(ProcessConfig{}, "url_library", raw)
+	if err != nil {
+		t.Fatalf("ToBronze failed: %v", err)
+	}
+	if doc.Content["domain"] != "example.com" || doc.Content["summary"] != "A black slab." {
+		t.Errorf("bronze content = %v", doc.Content)
+	}
+
+	links := Lookup("url").Silver(doc.Content)
+	if !hasLink(links, "tagged", "tags/space-travel") || !hasLink(links, "from_site", "sites/example.com") {
+		t.Errorf("links = %+v", links)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Flight Operations": "flight-operations",
+		"  R&D / Labs ":     "r-d-labs",
+		"HAL 9000":          "hal-9000",
+		"":                  "",
+	}
+	for in, want := range tests {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
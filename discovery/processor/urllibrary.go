@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+// URLCategory is the library folder "hal9000 url" saves entries to. Its
+// entries are markdown notes rather than bowman raw events.
+const URLCategory = "url_library"
+
+// URLType is the LMC entity type of a saved URL.
+const URLType = "urls"
+
+// urlEntryPaths lists the URL library's markdown entries.
+func urlEntryPaths(libPath string) ([]string, error) {
+	return filepath.Glob(filepath.Join(libPath, URLCategory, "url_*.md"))
+}
+
+// readURLEntry reads a URL library note as a raw document, so it goes
+// through bronze and silver like a fetched event. The note's filename is
+// its event ID. It also returns the bytes the document was read from.
+func readURLEntry(path string) (map[string]interface{}, []byte, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw := parseURLEntry(string(data))
+	raw["_meta"] = map[string]interface{}{
+		"source":   URLCategory,
+		"event_id": strings.TrimSuffix(filepath.Base(path), ".md"),
+		"stage":    string(StageRaw),
+	}
+	return raw, data, nil
+}
+
+// parseURLEntry reads the fields of a note written by "hal9000 url": a
+// "# Title" heading, **URL:**, **Date:** and **Tags:** lines, and Summary
+// and Key Takes sections.
+func parseURLEntry(text string) map[string]interface{} {
+	raw := make(map[string]interface{})
+	var summary []string
+	var takes []string
+	section := ""
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "## "):
+			section = strings.TrimSpace(trimmed[3:])
+			continue
+		case strings.HasPrefix(trimmed, "# ") && raw["title"] == nil:
+			raw["title"] = strings.TrimSpace(trimmed[2:])
+			continue
+		case strings.HasPrefix(trimmed, "**URL:**"):
+			raw["url"] = strings.TrimSpace(strings.TrimPrefix(trimmed, "**URL:**"))
+			continue
+		case strings.HasPrefix(trimmed, "**Date:**"):
+			raw["date"] = strings.TrimSpace(strings.TrimPrefix(trimmed, "**Date:**"))
+			continue
+		case strings.HasPrefix(trimmed, "**Tags:**"):
+			var tags []string
+			for _, tag := range strings.Split(strings.TrimPrefix(trimmed, "**Tags:**"), ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			raw["tags"] = tags
+			continue
+		}
+
+		switch section {
+		case "Summary":
+			summary = append(summary, line)
+		case "Key Takes":
+			if take := strings.TrimSpace(strings.TrimPrefix(trimmed, "- ")); take != "" && strings.HasPrefix(trimmed, "- ") {
+				takes = append(takes, take)
+			}
+		}
+	}
+
+	raw["summary"] = strings.TrimSpace(strings.Join(summary, "\n"))
+	if len(takes) > 0 {
+		raw["takes"] = takes
+	}
+	return raw
+}
@@ -0,0 +1,710 @@
+// Package retention archives old library documents for HAL 9000.
+// "I've just picked up a fault in the AE-35 unit. It's going to go 100%
+// failure in 72 hours."
+//
+// Raw captures from Floyd accumulate forever. Retention rules in
+// .hal9000/config.yaml move documents of a category and stage older than a
+// given age into monthly tar.gz archives under library/archive/. Nothing is
+// discarded: each archive has a manifest keyed by the archived file's
+// library path, and documents derived from an archived one get an
+// _meta.archived_source pointer to it before the original is removed. An
+// event's version chain is archived whole, once its newest version is past
+// the rule's age, so no surviving version's previous_path dangles.
+package retention
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/eye"
+	"github.com/pearcec/hal9000/discovery/history"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+const (
+	// ArchiveDir is the library folder holding archives.
+	ArchiveDir = "archive"
+
+	// ManifestFile lists what each archive directory holds.
+	ManifestFile = "manifest.json"
+
+	// PointerField is the _meta field set on derived documents.
+	PointerField = "archived_source"
+)
+
+// nextStage maps a stage to the stage derived from it.
+var nextStage = map[string]string{
+	"raw":    "bronze",
+	"bronze": "silver",
+}
+
+// Rule is a parsed retention rule.
+type Rule struct {
+	Category  string
+	Stage     string
+	OlderThan time.Duration
+}
+
+// ManifestEntry records where an archived document went.
+type ManifestEntry struct {
+	Archive    string    `json:"archive"`            // Path relative to the library
+	Entry      string    `json:"entry"`              // File name inside the archive
+	StageID    string    `json:"stage_id,omitempty"` // e.g. "raw/slack/<event_id>"
+	Version    int       `json:"version,omitempty"`  // _meta.version; 0 before versioning
+	Date       time.Time `json:"date"`               // Fetched/processed time of the document
+	Size       int64     `json:"size"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// Pointer returns the archived_source value for the entry.
+func (e ManifestEntry) Pointer() string {
+	return e.Archive + "#" + e.Entry
+}
+
+// Manifest is the index of one archive directory, keyed by the archived
+// file's path relative to the library. Manifests written before versioned
+// events were archived are keyed by stage ID.
+type Manifest struct {
+	Entries map[string]ManifestEntry `json:"entries"`
+}
+
+// Options controls Apply.
+type Options struct {
+	DryRun bool      // Report what would happen without changing anything
+	Now    time.Time // Reference time for ages (default time.Now())
+}
+
+// RuleReport describes the effect of one rule.
+type RuleReport struct {
+	Category      string    `json:"category"`
+	Stage         string    `json:"stage"`
+	Cutoff        time.Time `json:"cutoff"`
+	Files         int       `json:"files"`          // Documents archived
+	Bytes         int64     `json:"bytes"`          // Their size on disk
+	ArchiveGrowth int64     `json:"archive_growth"` // Bytes added to archives
+	Reclaimed     int64     `json:"reclaimed"`      // Bytes minus ArchiveGrowth
+	Pointers      int       `json:"pointers"`       // Derived documents repointed
+	Archives      []string  `json:"archives,omitempty"`
+}
+
+// Report summarizes a retention run.
+type Report struct {
+	DryRun    bool         `json:"dry_run"`
+	Rules     []RuleReport `json:"rules"`
+	Files     int          `json:"files"`
+	Reclaimed int64        `json:"reclaimed"`
+}
+
+// candidate is a document selected for archiving.
+type candidate struct {
+	path    string
+	key     string // Stage ID, e.g. "raw/slack/<event_id>"
+	version int    // _meta.version; 0 for documents stored before versioning
+	date    time.Time
+	size    int64
+	modTime time.Time
+}
+
+// ParseAge parses a retention age: Go durations plus "d" (days) and
+// "w" (weeks) suffixes.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// ParseRules validates configured rules.
+func ParseRules(raw []config.RetentionRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(raw))
+	for i, r := range raw {
+		rule := Rule{Category: strings.Trim(r.Category, "/"), Stage: r.Stage}
+		if rule.Stage == "" {
+			rule.Stage = "raw"
+		}
+		if rule.Stage != "raw" && rule.Stage != "bronze" && rule.Stage != "silver" {
+			return nil, fmt.Errorf("retention rule %d: unknown stage %q", i+1, r.Stage)
+		}
+		if rule.Category == "" || strings.Contains(rule.Category, "/") || rule.Category == ArchiveDir {
+			return nil, fmt.Errorf("retention rule %d: invalid category %q", i+1, r.Category)
+		}
+		age, err := ParseAge(r.OlderThan)
+		if err != nil {
+			return nil, fmt.Errorf("retention rule %d: %w", i+1, err)
+		}
+		rule.OlderThan = age
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Rules returns the rules from .hal9000/config.yaml.
+func Rules() ([]Rule, error) {
+	return ParseRules(config.GetLibraryRetention())
+}
+
+// Cleaner adapts Apply to an eye.Cleaner. Rule ages are used instead of the
+// Eye's stale threshold.
+func Cleaner(libraryPath string, rules []Rule) eye.Cleaner {
+	return func(ctx context.Context, threshold time.Time) eye.CleanupResult {
+		report, err := Apply(libraryPath, rules, Options{})
+		result := eye.CleanupResult{}
+		if report != nil {
+			result.ItemsCleaned = report.Files
+			result.BytesFreed = report.Reclaimed
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+}
+
+// Apply runs the rules against the library.
+func Apply(libraryPath string, rules []Rule, opts Options) (*Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	for _, rule := range rules {
+		rr, err := applyRule(libraryPath, rule, opts)
+		if rr != nil {
+			report.Rules = append(report.Rules, *rr)
+			report.Files += rr.Files
+			report.Reclaimed += rr.Reclaimed
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s/%s: %w", rule.Stage, rule.Category, err)
+		}
+	}
+	return report, nil
+}
+
+func applyRule(libraryPath string, rule Rule, opts Options) (*RuleReport, error) {
+	// Derived documents are rewritten and originals removed, so the
+	// processor and the LMC must not write while the rule runs.
+	if !opts.DryRun {
+		lock, err := vault.LockLibrary(libraryPath)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Unlock()
+	}
+
+	rr := &RuleReport{
+		Category: rule.Category,
+		Stage:    rule.Stage,
+		Cutoff:   opts.Now.Add(-rule.OlderThan),
+	}
+
+	candidates, err := findCandidates(libraryPath, rule, rr.Cutoff)
+	if err != nil || len(candidates) == 0 {
+		return rr, err
+	}
+
+	// Group by month of the document's own date
+	months := make(map[string][]candidate)
+	for _, c := range candidates {
+		month := c.date.Format("2006-01")
+		months[month] = append(months[month], c)
+		rr.Files++
+		rr.Bytes += c.size
+	}
+
+	archiveDir := archiveDirFor(libraryPath, rule)
+	manifest, err := LoadManifest(archiveDir)
+	if err != nil {
+		return rr, err
+	}
+
+	var touched []string
+	pointers := make(map[string]string) // stage ID -> pointer to its newest version
+	newest := make(map[string]candidate)
+	for _, c := range candidates {
+		if n, ok := newest[c.key]; !ok || newerVersion(c, n) {
+			newest[c.key] = c
+		}
+	}
+	for _, month := range sortedMonths(months) {
+		name := fmt.Sprintf("%s_%s.tar.gz", rule.Category, month)
+		archivePath := filepath.Join(archiveDir, name)
+		rel, _ := filepath.Rel(libraryPath, archivePath)
+		rel = filepath.ToSlash(rel)
+
+		growth, err := writeArchive(archivePath, months[month], opts.DryRun)
+		if err != nil {
+			return rr, err
+		}
+		rr.ArchiveGrowth += growth
+		rr.Archives = append(rr.Archives, rel)
+		touched = append(touched, archivePath)
+
+		for _, c := range months[month] {
+			entry := ManifestEntry{
+				Archive:    rel,
+				Entry:      filepath.Base(c.path),
+				StageID:    c.key,
+				Version:    c.version,
+				Date:       c.date,
+				Size:       c.size,
+				ArchivedAt: opts.Now,
+			}
+			path, _ := filepath.Rel(libraryPath, c.path)
+			manifest.Entries[filepath.ToSlash(path)] = entry
+			// Derived documents were processed from the newest version
+			if newest[c.key].path == c.path {
+				pointers[c.key] = entry.Pointer()
+			}
+		}
+	}
+	rr.Reclaimed = rr.Bytes - rr.ArchiveGrowth
+
+	// Derived documents must point at the archive before originals go
+	repointed, err := updatePointers(libraryPath, rule, pointers, opts.DryRun)
+	rr.Pointers = len(repointed)
+	if err != nil {
+		return rr, err
+	}
+
+	if opts.DryRun {
+		return rr, nil
+	}
+
+	if err := saveManifest(archiveDir, manifest); err != nil {
+		return rr, err
+	}
+	touched = append(touched, filepath.Join(archiveDir, ManifestFile))
+	touched = append(touched, repointed...)
+
+	for _, c := range candidates {
+		if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+			return rr, err
+		}
+		touched = append(touched, c.path)
+	}
+
+	log.Printf("[retention] Archived %d %s/%s documents (%d bytes reclaimed)", rr.Files, rule.Stage, rule.Category, rr.Reclaimed)
+	message := fmt.Sprintf("retention: archive %d %s/%s documents older than %s", rr.Files, rule.Stage, rule.Category, rr.Cutoff.Format("2006-01-02"))
+	if err := history.RecordIn(libraryPath, message, touched...); err != nil {
+		log.Printf("[retention] Warning: history commit failed: %v", err)
+	}
+	return rr, nil
+}
+
+// stageDir is where a rule's documents live: library/<category> for raw,
+// library/<stage>/<category> for processed stages.
+func stageDir(libraryPath string, rule Rule) string {
+	if rule.Stage == "raw" {
+		return filepath.Join(libraryPath, rule.Category)
+	}
+	return filepath.Join(libraryPath, rule.Stage, rule.Category)
+}
+
+func archiveDirFor(libraryPath string, rule Rule) string {
+	if rule.Stage == "raw" {
+		return filepath.Join(libraryPath, ArchiveDir, rule.Category)
+	}
+	return filepath.Join(libraryPath, ArchiveDir, rule.Stage, rule.Category)
+}
+
+// findCandidates lists documents of the rule's stage older than cutoff.
+// The versions of an event are archived together, and only once the newest
+// is older than cutoff: archiving older versions alone would leave the
+// next one's previous_path pointing at a file that is gone. Files without a
+// matching _meta.stage (LMC entities, notes) are never touched.
+func findCandidates(libraryPath string, rule Rule, cutoff time.Time) ([]candidate, error) {
+	dir := stageDir(libraryPath, rule)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	chains := make(map[string][]candidate)
+	var keys []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		meta, err := readMeta(path)
+		if err != nil || meta == nil {
+			continue
+		}
+		if stage, _ := meta["stage"].(string); stage != rule.Stage {
+			continue
+		}
+		eventID, _ := meta["event_id"].(string)
+		if eventID == "" {
+			continue
+		}
+
+		date := info.ModTime()
+		for _, field := range []string{"fetched_at", "processed_at"} {
+			if s, ok := meta[field].(string); ok {
+				if t, err := time.Parse(time.RFC3339, s); err == nil {
+					date = t
+					break
+				}
+			}
+		}
+		version, _ := meta["version"].(float64)
+
+		key := fmt.Sprintf("%s/%s/%s", rule.Stage, rule.Category, eventID)
+		if _, ok := chains[key]; !ok {
+			keys = append(keys, key)
+		}
+		chains[key] = append(chains[key], candidate{
+			path:    path,
+			key:     key,
+			version: int(version),
+			date:    date,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	var candidates []candidate
+	for _, key := range keys {
+		chain := chains[key]
+		expired := true
+		for _, c := range chain {
+			if !c.date.Before(cutoff) {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			candidates = append(candidates, chain...)
+		}
+	}
+	return candidates, nil
+}
+
+// newerVersion reports whether a is a later version of its event than b.
+// Documents stored before versioning precede numbered versions, as in
+// bowman.
+func newerVersion(a, b candidate) bool {
+	if (a.version == 0) != (b.version == 0) {
+		return b.version == 0
+	}
+	if a.version != b.version {
+		return a.version > b.version
+	}
+	if !a.date.Equal(b.date) {
+		return a.date.After(b.date)
+	}
+	return a.path > b.path
+}
+
+func readMeta(path string) (map[string]interface{}, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Meta map[string]interface{} `json:"_meta"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Meta, nil
+}
+
+// writeArchive adds documents to a tar.gz, replacing entries of the same
+// name, and returns how many bytes the archive grew. Documents are stored
+// decrypted and the archive file as a whole is sealed when encryption is
+// enabled, so "hal9000 crypto" can rewrap it like any other file. In
+// dry-run mode the archive is built in memory only.
+func writeArchive(path string, docs []candidate, dryRun bool) (int64, error) {
+	var oldSize int64
+	existing := make(map[string]archived)
+	var order []string
+	if info, err := os.Stat(path); err == nil {
+		oldSize = info.Size()
+		entries, err := readArchive(path)
+		if err != nil {
+			return 0, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		for _, e := range entries {
+			existing[e.name] = e
+			order = append(order, e.name)
+		}
+	}
+
+	for _, c := range docs {
+		data, err := vault.ReadFile(c.path)
+		if err != nil {
+			return 0, err
+		}
+		name := filepath.Base(c.path)
+		if _, ok := existing[name]; !ok {
+			order = append(order, name)
+		}
+		existing[name] = archived{name: name, data: data, modTime: c.modTime}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeArchive(&buf, order, existing); err != nil {
+		return 0, err
+	}
+
+	if dryRun {
+		encoded, err := vault.Encode(buf.Bytes())
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(encoded)) - oldSize, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	if err := vault.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size() - oldSize, nil
+}
+
+// archived is one file inside an archive.
+type archived struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+func encodeArchive(w io.Writer, order []string, files map[string]archived) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range order {
+		f := files[name]
+		hdr := &tar.Header{
+			Name:    f.name,
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: f.modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func readArchive(path string) ([]archived, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var files []archived
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		// Older archives hold each document sealed on its own
+		if data, err = vault.Decode(data); err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", hdr.Name, filepath.Base(path), err)
+		}
+		files = append(files, archived{name: hdr.Name, data: data, modTime: hdr.ModTime})
+	}
+}
+
+// updatePointers sets _meta.archived_source on documents derived from the
+// archived ones and returns their paths.
+func updatePointers(libraryPath string, rule Rule, pointers map[string]string, dryRun bool) ([]string, error) {
+	derived, ok := nextStage[rule.Stage]
+	if !ok || len(pointers) == 0 {
+		return nil, nil
+	}
+
+	var updated []string
+	err := filepath.Walk(filepath.Join(libraryPath, derived), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		data, err := vault.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var doc map[string]interface{}
+		if json.Unmarshal(data, &doc) != nil {
+			return nil
+		}
+		meta, _ := doc["_meta"].(map[string]interface{})
+		previous, _ := meta["previous_id"].(string)
+		pointer, ok := pointers[previous]
+		if !ok || meta[PointerField] == pointer {
+			return nil
+		}
+
+		updated = append(updated, path)
+		if dryRun {
+			return nil
+		}
+		meta[PointerField] = pointer
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		return vault.WriteFile(path, out, info.Mode().Perm())
+	})
+	return updated, err
+}
+
+// LoadManifest reads the manifest of an archive directory. A missing
+// manifest is empty.
+func LoadManifest(archiveDir string) (*Manifest, error) {
+	manifest := &Manifest{Entries: make(map[string]ManifestEntry)}
+	data, err := vault.ReadFile(filepath.Join(archiveDir, ManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", archiveDir, err)
+	}
+	if manifest.Entries == nil {
+		manifest.Entries = make(map[string]ManifestEntry)
+	}
+	// Older manifests are keyed by stage ID
+	for key, entry := range manifest.Entries {
+		if entry.StageID == "" {
+			entry.StageID = key
+			manifest.Entries[key] = entry
+		}
+	}
+	return manifest, nil
+}
+
+func saveManifest(archiveDir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return err
+	}
+	return vault.WriteFile(filepath.Join(archiveDir, ManifestFile), data, 0644)
+}
+
+// Archived returns every archived document in the library, keyed like the
+// manifests: by the file's path relative to the library.
+func Archived(libraryPath string) (map[string]ManifestEntry, error) {
+	all := make(map[string]ManifestEntry)
+	err := filepath.Walk(filepath.Join(libraryPath, ArchiveDir), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || info.Name() != ManifestFile {
+			return nil
+		}
+		manifest, err := LoadManifest(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		for key, entry := range manifest.Entries {
+			all[key] = entry
+		}
+		return nil
+	})
+	return all, err
+}
+
+// ReadArchived returns the document an archived_source pointer refers to,
+// decrypted if the archive is sealed.
+func ReadArchived(libraryPath, pointer string) ([]byte, error) {
+	archivePath, entry, ok := strings.Cut(pointer, "#")
+	if !ok || entry == "" {
+		return nil, fmt.Errorf("invalid archive pointer %q", pointer)
+	}
+	files, err := readArchive(filepath.Join(libraryPath, filepath.FromSlash(archivePath)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.name == entry {
+			return f.data, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", entry, archivePath)
+}
+
+func sortedMonths(months map[string][]candidate) []string {
+	keys := make([]string, 0, len(months))
+	for k := range months {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
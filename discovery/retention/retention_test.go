@@ -0,0 +1,391 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"github.com/pearcec/hal9000/discovery/vault"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func writeJSON(t *testing.T, path string, doc interface{}) {
+	t.Helper()
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedSlack writes a raw Slack document and the bronze document derived
+// from it.
+func seedSlack(t *testing.T, lib, eventID string, fetched time.Time) (raw, bronze string) {
+	t.Helper()
+	date := fetched.Format("2006-01-02")
+	raw = filepath.Join(lib, "slack", "slack_"+date+"_"+eventID+".json")
+	writeJSON(t, raw, map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"source":     "slack",
+			"fetched_at": fetched.Format(time.RFC3339),
+			"event_id":   eventID,
+			"stage":      "raw",
+		},
+		"content": map[string]interface{}{"text": strings.Repeat("open the pod bay doors ", 50)},
+	})
+	bronze = filepath.Join(lib, "bronze", "slack", date+"_"+eventID+".json")
+	writeJSON(t, bronze, map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"source":       "slack",
+			"event_id":     eventID,
+			"stage":        "bronze",
+			"processed_at": fetched.Format(time.RFC3339),
+			"previous_id":  "raw/slack/" + eventID,
+		},
+		"content": map[string]interface{}{"text": "pod bay doors"},
+	})
+	return raw, bronze
+}
+
+func slackRules(t *testing.T) []Rule {
+	t.Helper()
+	rules, err := ParseRules([]config.RetentionRule{{Category: "slack", OlderThan: "90d"}})
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	return rules
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90d": 90 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for in, want := range tests {
+		got, err := ParseAge(in)
+		if err != nil || got != want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-3d", "soon"} {
+		if _, err := ParseAge(in); err == nil {
+			t.Errorf("ParseAge(%q) expected error", in)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules := slackRules(t)
+	if rules[0].Stage != "raw" {
+		t.Errorf("default stage = %q, want raw", rules[0].Stage)
+	}
+	bad := [][]config.RetentionRule{
+		{{Category: "", OlderThan: "1d"}},
+		{{Category: "archive", OlderThan: "1d"}},
+		{{Category: "slack", Stage: "gold", OlderThan: "1d"}},
+		{{Category: "slack", OlderThan: "forever"}},
+	}
+	for _, raw := range bad {
+		if _, err := ParseRules(raw); err == nil {
+			t.Errorf("ParseRules(%+v) expected error", raw)
+		}
+	}
+}
+
+func TestDryRunChangesNothing(t *testing.T) {
+	lib := t.TempDir()
+	raw, bronze := seedSlack(t, lib, "old1", now.AddDate(0, -6, 0))
+	seedSlack(t, lib, "new1", now.AddDate(0, 0, -3))
+	before, _ := os.ReadFile(bronze)
+
+	report, err := Apply(lib, slackRules(t), Options{DryRun: true, Now: now})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if report.Files != 1 || report.Rules[0].Pointers != 1 {
+		t.Errorf("report = %+v", report.Rules[0])
+	}
+	if report.Reclaimed <= 0 {
+		t.Errorf("reclaimed = %d, want > 0", report.Reclaimed)
+	}
+
+	if _, err := os.Stat(raw); err != nil {
+		t.Error("dry run removed the raw document")
+	}
+	if _, err := os.Stat(filepath.Join(lib, ArchiveDir)); !os.IsNotExist(err) {
+		t.Error("dry run created the archive directory")
+	}
+	if after, _ := os.ReadFile(bronze); string(after) != string(before) {
+		t.Error("dry run changed the bronze document")
+	}
+}
+
+func TestApplyWaitsForLibraryLock(t *testing.T) {
+	lib := t.TempDir()
+	raw, _ := seedSlack(t, lib, "old1", now.AddDate(0, -6, 0))
+
+	// Another writer holds the library
+	lock, err := vault.LockLibrary(lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := slackRules(t)
+	done := make(chan error, 1)
+	go func() {
+		_, err := Apply(lib, rules, Options{Now: now})
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Apply ran while the library was locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := os.Stat(raw); err != nil {
+		t.Error("raw document removed while the library was locked")
+	}
+
+	lock.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, err := os.Stat(raw); !os.IsNotExist(err) {
+		t.Error("raw document not archived after the lock was released")
+	}
+}
+
+func TestApplyArchivesAndRepoints(t *testing.T) {
+	lib := t.TempDir()
+	oldRaw, oldBronze := seedSlack(t, lib, "old1", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+	seedSlack(t, lib, "old2", time.Date(2026, 1, 20, 9, 0, 0, 0, time.UTC))
+	seedSlack(t, lib, "old3", time.Date(2026, 2, 2, 9, 0, 0, 0, time.UTC))
+	newRaw, _ := seedSlack(t, lib, "new1", now.AddDate(0, 0, -3))
+	original, _ := os.ReadFile(oldRaw)
+
+	report, err := Apply(lib, slackRules(t), Options{Now: now})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	rr := report.Rules[0]
+	if rr.Files != 3 || rr.Pointers != 3 || len(rr.Archives) != 2 {
+		t.Errorf("report = %+v", rr)
+	}
+
+	if _, err := os.Stat(oldRaw); !os.IsNotExist(err) {
+		t.Error("archived raw document still present")
+	}
+	if _, err := os.Stat(newRaw); err != nil {
+		t.Error("recent raw document was archived")
+	}
+	if _, err := os.Stat(filepath.Join(lib, "archive", "slack", "slack_2026-01.tar.gz")); err != nil {
+		t.Errorf("monthly archive missing: %v", err)
+	}
+
+	// Bronze keeps a pointer that resolves to the original bytes
+	data, _ := os.ReadFile(oldBronze)
+	var doc struct {
+		Meta map[string]interface{} `json:"_meta"`
+	}
+	json.Unmarshal(data, &doc)
+	pointer, _ := doc.Meta[PointerField].(string)
+	want := "archive/slack/slack_2026-01.tar.gz#" + filepath.Base(oldRaw)
+	if pointer != want {
+		t.Fatalf("archived_source = %q, want %q", pointer, want)
+	}
+	got, err := ReadArchived(lib, pointer)
+	if err != nil {
+		t.Fatalf("ReadArchived failed: %v", err)
+	}
+	if string(got) != string(original) {
+		t.Error("archived document differs from the original")
+	}
+
+	archived, err := Archived(lib)
+	if err != nil {
+		t.Fatalf("Archived failed: %v", err)
+	}
+	if entry, ok := archived["slack/"+filepath.Base(oldRaw)]; !ok || entry.Pointer() != want || entry.StageID != "raw/slack/old1" {
+		t.Errorf("manifest entry = %+v, %v", entry, ok)
+	}
+	if len(archived) != 3 {
+		t.Errorf("manifest has %d entries, want 3", len(archived))
+	}
+}
+
+func TestApplyArchivesVersionChainsWhole(t *testing.T) {
+	lib := t.TempDir()
+
+	// Three versions of one event; the newest is inside the rule's age
+	var paths []string
+	for i, fetched := range []time.Time{
+		time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 20, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 5, 20, 9, 0, 0, 0, time.UTC),
+	} {
+		name := "slack_" + fetched.Format("2006-01-02") + "_evt1"
+		if i > 0 {
+			name += fmt.Sprintf(".v%d", i+1)
+		}
+		path := filepath.Join(lib, "slack", name+".json")
+		meta := map[string]interface{}{
+			"source":     "slack",
+			"fetched_at": fetched.Format(time.RFC3339),
+			"event_id":   "evt1",
+			"stage":      "raw",
+			"version":    i + 1,
+		}
+		if i > 0 {
+			meta["previous_path"] = "slack/" + filepath.Base(paths[i-1])
+		}
+		writeJSON(t, path, map[string]interface{}{"_meta": meta, "text": fmt.Sprintf("revision %d", i+1)})
+		paths = append(paths, path)
+	}
+	bronze := filepath.Join(lib, "bronze", "slack", "2026-05-20_evt1.json")
+	writeJSON(t, bronze, map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"source":       "slack",
+			"event_id":     "evt1",
+			"stage":        "bronze",
+			"processed_at": "2026-05-20T09:00:00Z",
+			"previous_id":  "raw/slack/evt1",
+		},
+	})
+
+	// The chain straddles the cutoff, so every version stays
+	report, err := Apply(lib, slackRules(t), Options{Now: now})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if report.Files != 0 {
+		t.Errorf("archived %d files of a chain whose newest version is recent", report.Files)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s removed: %v", filepath.Base(path), err)
+		}
+	}
+
+	// Once the newest version is old too, the chain goes together
+	later := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	report, err = Apply(lib, slackRules(t), Options{Now: later})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if report.Files != 3 || report.Rules[0].Pointers != 1 {
+		t.Errorf("report = %+v, want 3 files and 1 pointer", report.Rules[0])
+	}
+
+	archived, err := Archived(lib)
+	if err != nil {
+		t.Fatalf("Archived failed: %v", err)
+	}
+	if len(archived) != 3 {
+		t.Fatalf("manifest has %d entries, want one per version: %+v", len(archived), archived)
+	}
+	for i, path := range paths {
+		entry, ok := archived["slack/"+filepath.Base(path)]
+		if !ok || entry.StageID != "raw/slack/evt1" || entry.Version != i+1 {
+			t.Errorf("manifest entry for %s = %+v, %v", filepath.Base(path), entry, ok)
+			continue
+		}
+		got, err := ReadArchived(lib, entry.Pointer())
+		if err != nil || !strings.Contains(string(got), fmt.Sprintf("revision %d", i+1)) {
+			t.Errorf("ReadArchived(%s) = %q, %v", entry.Pointer(), got, err)
+		}
+	}
+
+	// Bronze points at the version it was processed from
+	meta, err := readMeta(bronze)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := archived["slack/"+filepath.Base(paths[2])].Pointer(); meta[PointerField] != want {
+		t.Errorf("archived_source = %v, want %s", meta[PointerField], want)
+	}
+}
+
+func TestApplyAppendsToExistingArchive(t *testing.T) {
+	lib := t.TempDir()
+	seedSlack(t, lib, "old1", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+	if _, err := Apply(lib, slackRules(t), Options{Now: now}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	seedSlack(t, lib, "old2", time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC))
+	if _, err := Apply(lib, slackRules(t), Options{Now: now}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	files, err := readArchive(filepath.Join(lib, "archive", "slack", "slack_2026-01.tar.gz"))
+	if err != nil {
+		t.Fatalf("readArchive failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("archive has %d entries, want 2", len(files))
+	}
+
+	// Nothing left to do
+	report, err := Apply(lib, slackRules(t), Options{Now: now})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if report.Files != 0 {
+		t.Errorf("second run archived %d files", report.Files)
+	}
+}
+
+func TestApplySkipsNonStageFiles(t *testing.T) {
+	lib := t.TempDir()
+	entity := filepath.Join(lib, "slack", "channel-general.json")
+	writeJSON(t, entity, map[string]interface{}{
+		"_meta": map[string]interface{}{"id": "slack/channel-general", "type": "slack"},
+	})
+	old := now.AddDate(-1, 0, 0)
+	os.Chtimes(entity, old, old)
+
+	report, err := Apply(lib, slackRules(t), Options{Now: now})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if report.Files != 0 {
+		t.Errorf("archived %d files, want 0", report.Files)
+	}
+	if _, err := os.Stat(entity); err != nil {
+		t.Error("LMC entity was removed")
+	}
+}
+
+func TestCleaner(t *testing.T) {
+	lib := t.TempDir()
+	raw, _ := seedSlack(t, lib, "old1", time.Now().AddDate(0, -6, 0))
+	seedSlack(t, lib, "new1", time.Now().AddDate(0, 0, -3))
+
+	result := Cleaner(lib, slackRules(t))(context.Background(), time.Now())
+	if result.Error != "" {
+		t.Fatalf("Cleaner failed: %s", result.Error)
+	}
+	if result.ItemsCleaned != 1 || result.BytesFreed <= 0 {
+		t.Errorf("result = %+v, want one document and bytes freed", result)
+	}
+	if _, err := os.Stat(raw); !os.IsNotExist(err) {
+		t.Error("old raw document not archived")
+	}
+
+	// Cleaner ignores the Eye's stale threshold: rule ages decide
+	if result := Cleaner(lib, slackRules(t))(context.Background(), time.Now().Add(time.Hour)); result.ItemsCleaned != 0 {
+		t.Errorf("second run cleaned %d documents", result.ItemsCleaned)
+	}
+}
@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// LibraryLockFile is the advisory lock file held while writing to a library.
+const LibraryLockFile = ".lmc.lock"
+
+// Lock is an exclusive advisory lock shared by every process (CLI, floyds,
+// scheduler) that writes to the same library.
+type Lock struct {
+	f *os.File
+}
+
+// LockLibrary acquires the library-wide write lock, blocking until it is
+// available. The lock is not reentrant: a process must not take it twice.
+func LockLibrary(libraryPath string) (*Lock, error) {
+	if err := os.MkdirAll(libraryPath, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(libraryPath, LibraryLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock library: %w", err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	l.f.Close()
+}
+
+// writeAtomic writes data to a temp file in the same directory, syncs it,
+// and renames it over path so readers never observe a partial file.
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
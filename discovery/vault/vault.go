@@ -0,0 +1,568 @@
+// Package vault provides optional encryption at rest for HAL 9000.
+// "I know that you and Frank were planning to disconnect me, and I'm afraid
+// that's something I cannot allow to happen."
+//
+// Files are sealed with envelope encryption: each file gets a random data
+// key (DEK) that encrypts its contents with AES-256-GCM, and the DEK itself
+// is wrapped by a key-encryption key (KEK) derived from a passphrase
+// (scrypt) or a key file. Rotating the KEK only rewraps DEKs.
+//
+// Sealed files are JSON documents of the form {"_sealed": {...}}, so
+// ReadFile can tell sealed and plaintext files apart and readers work
+// unchanged whether or not encryption is enabled.
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pearcec/hal9000/discovery/config"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// SettingsFile holds key parameters (never the key) in the config dir.
+	SettingsFile = "vault.json"
+
+	// PassphraseEnv supplies the passphrase to daemons and scripts.
+	PassphraseEnv = "HAL9000_PASSPHRASE"
+
+	// KeyFileEnv overrides the key file recorded in the settings.
+	KeyFileEnv = "HAL9000_KEY_FILE"
+
+	SourcePassphrase = "passphrase"
+	SourceKeyFile    = "key_file"
+
+	sealedPrefix = `{"_sealed":`
+	checkValue   = "hal9000"
+)
+
+// scrypt parameters for passphrase-derived keys.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrLocked is returned when a sealed file is read, or a write is attempted
+// with encryption enabled, but no key is available.
+var ErrLocked = errors.New("vault is locked: set " + PassphraseEnv + " or " + KeyFileEnv)
+
+// Settings describe how to derive the key. Stored in .hal9000/vault.json.
+type Settings struct {
+	Enabled bool   `json:"enabled"`
+	KeyID   string `json:"key_id"`
+	Source  string `json:"source"`             // passphrase or key_file
+	KeyFile string `json:"key_file,omitempty"` // For key_file source
+	Salt    string `json:"salt,omitempty"`     // For passphrase source (base64)
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Check   string `json:"check"` // Known value sealed with the key, to verify it
+
+	// Pending is the key an unfinished rotation is moving files to. It is
+	// saved before any file is rewrapped, so files already moved stay
+	// readable if the rotation is interrupted.
+	Pending *Settings `json:"pending,omitempty"`
+
+	pending bool // Derived as a pending key: KeyFileEnv names the current key file
+}
+
+// Key is an unlocked key-encryption key.
+type Key struct {
+	ID      string
+	kek     []byte
+	pending *Key // Also opens files sealed under a pending rotation's key
+}
+
+// envelope is the sealed file format.
+type envelope struct {
+	Version int    `json:"v"`
+	KeyID   string `json:"kid"`
+	DEK     string `json:"dek"`   // Wrapped data key: nonce || ciphertext
+	Nonce   string `json:"nonce"` // Data nonce
+	Data    string `json:"data"`  // Ciphertext
+}
+
+type sealedDoc struct {
+	Sealed envelope `json:"_sealed"`
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultLoaded bool
+	defaultKey    *Key
+	defaultErr    error
+)
+
+// NewPassphraseKey derives a new key from a passphrase with a fresh salt.
+func NewPassphraseKey(passphrase string) (*Key, *Settings, error) {
+	if passphrase == "" {
+		return nil, nil, fmt.Errorf("passphrase must not be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	settings := &Settings{
+		Enabled: true,
+		KeyID:   newKeyID(),
+		Source:  SourcePassphrase,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+	}
+	key, err := settings.derive(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, settings, settings.setCheck(key)
+}
+
+// NewKeyFileKey uses the key in path, generating a random one (mode 0600)
+// if the file does not exist.
+func NewKeyFileKey(path string) (*Key, *Settings, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(raw)+"\n"), 0600); err != nil {
+			return nil, nil, err
+		}
+	}
+	settings := &Settings{
+		Enabled: true,
+		KeyID:   newKeyID(),
+		Source:  SourceKeyFile,
+		KeyFile: path,
+	}
+	key, err := settings.derive("")
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, settings, settings.setCheck(key)
+}
+
+// Unlock derives the key described by the settings and verifies it.
+// passphrase is ignored for key files.
+func (s *Settings) Unlock(passphrase string) (*Key, error) {
+	key, err := s.derive(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	check, err := base64.StdEncoding.DecodeString(s.Check)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key check: %w", err)
+	}
+	if plain, err := Open(key, check); err != nil || string(plain) != checkValue {
+		return nil, fmt.Errorf("wrong passphrase or key file")
+	}
+	return key, nil
+}
+
+// UnlockPending derives the pending key of an unfinished rotation and
+// verifies it. HAL9000_KEY_FILE does not apply: it names the current key.
+func (s *Settings) UnlockPending(passphrase string) (*Key, error) {
+	if s.Pending == nil {
+		return nil, fmt.Errorf("no key rotation is pending")
+	}
+	pending := *s.Pending
+	pending.pending = true
+	return pending.Unlock(passphrase)
+}
+
+func (s *Settings) derive(passphrase string) (*Key, error) {
+	switch s.Source {
+	case SourcePassphrase:
+		if passphrase == "" {
+			return nil, ErrLocked
+		}
+		salt, err := base64.StdEncoding.DecodeString(s.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		kek, err := scrypt.Key([]byte(passphrase), salt, s.N, s.R, s.P, 32)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: s.KeyID, kek: kek}, nil
+	case SourceKeyFile:
+		path := s.KeyFile
+		if env := os.Getenv(KeyFileEnv); env != "" && !s.pending {
+			path = env
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key file: %w", err)
+		}
+		sum := sha256.Sum256(bytes.TrimSpace(data))
+		return &Key{ID: s.KeyID, kek: sum[:]}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source: %q", s.Source)
+	}
+}
+
+func (s *Settings) setCheck(key *Key) error {
+	check, err := Seal(key, []byte(checkValue))
+	if err != nil {
+		return err
+	}
+	s.Check = base64.StdEncoding.EncodeToString(check)
+	return nil
+}
+
+// SettingsPath returns the location of vault.json.
+func SettingsPath() string {
+	return filepath.Join(config.GetConfigDir(), SettingsFile)
+}
+
+// LoadSettings reads vault.json. Returns nil, nil when encryption has never
+// been enabled.
+func LoadSettings() (*Settings, error) {
+	data, err := os.ReadFile(SettingsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var s Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", SettingsFile, err)
+	}
+	return &s, nil
+}
+
+// SaveSettings writes vault.json atomically: it holds the only copy of the
+// salt, so a crash must leave either the old settings or the new ones.
+func SaveSettings(s *Settings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(SettingsPath()), 0755); err != nil {
+		return err
+	}
+	return writeAtomic(SettingsPath(), data, 0600)
+}
+
+// RemoveSettings deletes vault.json, disabling encryption.
+func RemoveSettings() error {
+	err := os.Remove(SettingsPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Default returns the process-wide key. It is nil when encryption is not
+// enabled. When enabled, the key is unlocked from HAL9000_PASSPHRASE or the
+// key file and cached; ErrLocked is returned if neither is available.
+func Default() (*Key, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if !defaultLoaded {
+		defaultKey, defaultErr = loadDefault()
+		defaultLoaded = true
+	}
+	return defaultKey, defaultErr
+}
+
+func loadDefault() (*Key, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || !settings.Enabled {
+		return nil, nil
+	}
+	key, err := settings.Unlock(os.Getenv(PassphraseEnv))
+	if err != nil || settings.Pending == nil {
+		return key, err
+	}
+	// Files an interrupted rotation already moved are readable when the
+	// pending key unlocks the same way.
+	if pending, err := settings.UnlockPending(os.Getenv(PassphraseEnv)); err == nil {
+		key = key.WithPending(pending)
+	}
+	return key, nil
+}
+
+// SetDefault replaces the process-wide key (nil disables sealing).
+// Used by the crypto command after prompting, and by tests.
+func SetDefault(key *Key) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKey, defaultErr, defaultLoaded = key, nil, true
+}
+
+// Reset forgets the cached key so the next Default call reloads settings.
+func Reset() {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKey, defaultErr, defaultLoaded = nil, nil, false
+}
+
+// WithPending returns a copy of k that also opens files sealed under
+// pending. Files are still sealed under k.
+func (k *Key) WithPending(pending *Key) *Key {
+	return &Key{ID: k.ID, kek: k.kek, pending: pending}
+}
+
+// IsSealed reports whether data is a sealed envelope.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(sealedPrefix))
+}
+
+// Seal encrypts plaintext under a fresh data key wrapped by key.
+func Seal(key *Key, plaintext []byte) ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	wrapped, err := gcmSeal(key.kek, dek)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := gcmSeal(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := len(sealed) - len(plaintext) - 16
+
+	return json.Marshal(sealedDoc{Sealed: envelope{
+		Version: 1,
+		KeyID:   key.ID,
+		DEK:     base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:   base64.StdEncoding.EncodeToString(sealed[:nonceSize]),
+		Data:    base64.StdEncoding.EncodeToString(sealed[nonceSize:]),
+	}})
+}
+
+// Open decrypts a sealed envelope. Plaintext input is returned unchanged.
+func Open(key *Key, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	env, dek, err := unwrap(key, data)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, err
+	}
+	return gcmOpen(dek, append(nonce, ciphertext...))
+}
+
+// SealedKeyID returns the ID of the key a sealed envelope is wrapped with.
+func SealedKeyID(data []byte) (string, error) {
+	var doc sealedDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("invalid sealed file: %w", err)
+	}
+	return doc.Sealed.KeyID, nil
+}
+
+// Rewrap re-encrypts a sealed file's data key under newKey without touching
+// the data itself.
+func Rewrap(oldKey, newKey *Key, data []byte) ([]byte, error) {
+	env, dek, err := unwrap(oldKey, data)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := gcmSeal(newKey.kek, dek)
+	if err != nil {
+		return nil, err
+	}
+	env.KeyID = newKey.ID
+	env.DEK = base64.StdEncoding.EncodeToString(wrapped)
+	return json.Marshal(sealedDoc{Sealed: *env})
+}
+
+func unwrap(key *Key, data []byte) (*envelope, []byte, error) {
+	if key == nil {
+		return nil, nil, ErrLocked
+	}
+	var doc sealedDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid sealed file: %w", err)
+	}
+	env := doc.Sealed
+	if env.Version != 1 {
+		return nil, nil, fmt.Errorf("unsupported sealed file version %d", env.Version)
+	}
+	if env.KeyID != key.ID {
+		if key.pending == nil || env.KeyID != key.pending.ID {
+			return nil, nil, fmt.Errorf("sealed with key %s, have key %s", env.KeyID, key.ID)
+		}
+		key = key.pending
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(env.DEK)
+	if err != nil {
+		return nil, nil, err
+	}
+	dek, err := gcmOpen(key.kek, wrapped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to unwrap data key: %w", err)
+	}
+	return &env, dek, nil
+}
+
+// Encode seals data with the default key when encryption is enabled and
+// returns it unchanged otherwise.
+func Encode(data []byte) ([]byte, error) {
+	key, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return data, nil
+	}
+	return Seal(key, data)
+}
+
+// Decode opens data with the default key if it is sealed.
+func Decode(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	key, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrLocked
+	}
+	return Open(key, data)
+}
+
+// ReadFile reads a file, decrypting it if it is sealed.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// WriteFile writes a file atomically, sealing it when encryption is
+// enabled. A crash leaves either the old file or the new one, never a
+// truncated one.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	encoded, err := Encode(data)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, encoded, perm)
+}
+
+// Transform rewrites every regular file under root that match accepts,
+// passing its contents through fn. Dot directories are skipped. Files for
+// which fn returns nil data are left untouched. Returns the number of files
+// rewritten. Files are replaced atomically; callers migrating a library
+// hold its lock so no writer stores a file between the read and the
+// rewrite.
+func Transform(root string, accept func(path string) bool, fn func(data []byte) ([]byte, error)) (int, error) {
+	count := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || (accept != nil && !accept(path)) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		out, err := fn(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if out == nil {
+			return nil
+		}
+
+		if err := writeAtomic(path, out, info.Mode().Perm()); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func gcmOpen(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func newKeyID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "k-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,262 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) *Key {
+	t.Helper()
+	key, _, err := NewKeyFileKey(filepath.Join(t.TempDir(), "hal.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	return key
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key := testKey(t)
+	plain := []byte(`{"name":"Dave Bowman"}`)
+
+	sealed, err := Seal(key, plain)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("sealed data not recognized: %s", sealed)
+	}
+	if bytes.Contains(sealed, []byte("Bowman")) {
+		t.Error("sealed data contains plaintext")
+	}
+
+	opened, err := Open(key, sealed)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if !bytes.Equal(opened, plain) {
+		t.Errorf("Open = %s, want %s", opened, plain)
+	}
+
+	// Plaintext passes through unchanged
+	if out, err := Open(key, plain); err != nil || !bytes.Equal(out, plain) {
+		t.Errorf("Open(plaintext) = %s, %v", out, err)
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	sealed, err := Seal(testKey(t), []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if _, err := Open(testKey(t), sealed); err == nil {
+		t.Error("expected error opening with a different key")
+	}
+	if _, err := Open(nil, sealed); !errors.Is(err, ErrLocked) {
+		t.Errorf("Open(nil) error = %v, want ErrLocked", err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	sealed, err := Seal(oldKey, []byte("open the pod bay doors"))
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+
+	rewrapped, err := Rewrap(oldKey, newKey, sealed)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if _, err := Open(oldKey, rewrapped); err == nil {
+		t.Error("old key still opens rewrapped data")
+	}
+	opened, err := Open(newKey, rewrapped)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if string(opened) != "open the pod bay doors" {
+		t.Errorf("Open = %q", opened)
+	}
+}
+
+func TestPendingKey(t *testing.T) {
+	dir := t.TempDir()
+	_, current, err := NewKeyFileKey(filepath.Join(dir, "old.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	newKey, pending, err := NewKeyFileKey(filepath.Join(dir, "new.key"))
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+	current.Pending = pending
+
+	// HAL9000_KEY_FILE names the current key, not the pending one
+	t.Setenv(KeyFileEnv, filepath.Join(dir, "old.key"))
+	oldKey, err := current.Unlock("")
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	unlocked, err := current.UnlockPending("")
+	if err != nil {
+		t.Fatalf("UnlockPending failed: %v", err)
+	}
+	if unlocked.ID != newKey.ID {
+		t.Errorf("pending key ID = %s, want %s", unlocked.ID, newKey.ID)
+	}
+
+	moved, _ := Seal(newKey, []byte("moved"))
+	if _, err := Open(oldKey, moved); err == nil {
+		t.Error("current key alone opens a file sealed under the pending key")
+	}
+	opened, err := Open(oldKey.WithPending(unlocked), moved)
+	if err != nil || string(opened) != "moved" {
+		t.Errorf("Open with pending = %q, %v", opened, err)
+	}
+	if id, err := SealedKeyID(moved); err != nil || id != newKey.ID {
+		t.Errorf("SealedKeyID = %q, %v; want %s", id, err, newKey.ID)
+	}
+}
+
+func TestPassphraseUnlock(t *testing.T) {
+	key, settings, err := NewPassphraseKey("daisy")
+	if err != nil {
+		t.Fatalf("NewPassphraseKey failed: %v", err)
+	}
+
+	unlocked, err := settings.Unlock("daisy")
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	sealed, _ := Seal(key, []byte("hello"))
+	if _, err := Open(unlocked, sealed); err != nil {
+		t.Errorf("unlocked key cannot open data: %v", err)
+	}
+
+	if _, err := settings.Unlock("bell"); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+	if _, err := settings.Unlock(""); !errors.Is(err, ErrLocked) {
+		t.Errorf("Unlock(\"\") error = %v, want ErrLocked", err)
+	}
+}
+
+func TestKeyFileGenerated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "hal.key")
+	key, settings, err := NewKeyFileKey(path)
+	if err != nil {
+		t.Fatalf("NewKeyFileKey failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("key file not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	unlocked, err := settings.Unlock("")
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if !bytes.Equal(unlocked.kek, key.kek) {
+		t.Error("key file produced a different key on unlock")
+	}
+}
+
+func TestReadWriteFileDefaultKey(t *testing.T) {
+	defer Reset()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.json")
+
+	SetDefault(nil)
+	if err := WriteFile(path, []byte(`{"a":1}`), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	if IsSealed(raw) {
+		t.Error("file sealed with encryption disabled")
+	}
+
+	SetDefault(testKey(t))
+	if err := WriteFile(path, []byte(`{"a":2}`), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	raw, _ = os.ReadFile(path)
+	if !IsSealed(raw) {
+		t.Error("file not sealed with encryption enabled")
+	}
+	data, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != `{"a":2}` {
+		t.Errorf("ReadFile = %s", data)
+	}
+
+	SetDefault(nil)
+	if _, err := ReadFile(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("ReadFile without key error = %v, want ErrLocked", err)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "people"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".quarantine"), 0755)
+	os.WriteFile(filepath.Join(dir, "people", "dave.json"), []byte("dave"), 0644)
+	os.WriteFile(filepath.Join(dir, "people", "notes.md"), []byte("notes"), 0644)
+	os.WriteFile(filepath.Join(dir, ".quarantine", "bad.json"), []byte("bad"), 0644)
+
+	count, err := Transform(dir, func(path string) bool {
+		return strings.HasSuffix(path, ".json")
+	}, func(data []byte) ([]byte, error) {
+		return bytes.ToUpper(data), nil
+	})
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "people", "dave.json")); string(data) != "DAVE" {
+		t.Errorf("dave.json = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "people", "notes.md")); string(data) != "notes" {
+		t.Errorf("notes.md changed: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".quarantine", "bad.json")); string(data) != "bad" {
+		t.Errorf("quarantined file changed: %q", data)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "event.json")
+	for _, content := range []string{"first version", "second"} {
+		if err := WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFile(path)
+		if err != nil || string(got) != content {
+			t.Errorf("ReadFile() = %q, %v; want %q", got, err, content)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the written one", len(entries))
+	}
+}
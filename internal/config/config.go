@@ -21,11 +21,37 @@ var (
 // Config holds the HAL 9000 configuration.
 type Config struct {
 	Library LibraryConfig `yaml:"library"`
+	Self    SelfConfig    `yaml:"self"`
+}
+
+// SelfConfig identifies the user, so transcripts and calendars can tell
+// them apart from the people they meet with.
+type SelfConfig struct {
+	Name    string   `yaml:"name"`
+	Emails  []string `yaml:"emails"`
+	Aliases []string `yaml:"aliases"` // Other names transcripts show, e.g. "Dave B."
 }
 
 // LibraryConfig holds library-related configuration.
 type LibraryConfig struct {
-	Path string `yaml:"path"`
+	Path       string          `yaml:"path"`
+	Validation string          `yaml:"validation"` // Schema validation mode: off, warn (default), strict
+	History    HistoryConfig   `yaml:"history"`
+	Retention  []RetentionRule `yaml:"retention"`
+}
+
+// RetentionRule moves documents of one category and stage into monthly
+// archives once they are older than OlderThan.
+type RetentionRule struct {
+	Category  string `yaml:"category"`   // Library folder, e.g. "slack"
+	Stage     string `yaml:"stage"`      // raw (default), bronze or silver
+	OlderThan string `yaml:"older_than"` // e.g. "90d", "12w", "2160h"
+}
+
+// HistoryConfig controls git-backed versioning of the library.
+type HistoryConfig struct {
+	Enabled bool   `yaml:"enabled"` // Commit every write HAL makes to the library
+	Remote  string `yaml:"remote"`  // Bare repository for "hal9000 library sync"
 }
 
 var (
@@ -53,6 +79,12 @@ const (
 
 	// DefaultLibraryPath is the default library path when no config is present.
 	DefaultLibraryPath = "./library"
+
+	// DefaultSchemasDir is the default location for per-type entity schemas.
+	DefaultSchemasDir = "./.hal9000/schemas"
+
+	// DefaultTransformsDir is the default location for YAML processor transforms.
+	DefaultTransformsDir = "./.hal9000/transforms"
 )
 
 // Load loads the configuration from the default path.
@@ -104,6 +136,15 @@ func GetLibraryPath() string {
 	return expandPath(cfg.Library.Path)
 }
 
+// GetLibraryHistory returns the library versioning settings.
+func GetLibraryHistory() HistoryConfig {
+	cfg, err := Load()
+	if err != nil || cfg == nil {
+		return HistoryConfig{}
+	}
+	return cfg.Library.History
+}
+
 // GetExecutableDir returns the project root directory for HAL 9000.
 // It walks up from the executable's directory looking for a .hal9000/ marker,
 // similar to how git finds .git/. Falls back to cwd if not found.
@@ -167,6 +208,16 @@ func GetServicesConfigPath() string {
 	return expandPath(DefaultServicesPath)
 }
 
+// GetSchemasDir returns the absolute path to the entity schemas directory.
+func GetSchemasDir() string {
+	return expandPath(DefaultSchemasDir)
+}
+
+// GetTransformsDir returns the absolute path to the processor transforms directory.
+func GetTransformsDir() string {
+	return expandPath(DefaultTransformsDir)
+}
+
 // GetCredentialsDir returns the absolute path to the credentials directory.
 func GetCredentialsDir() string {
 	return expandPath(DefaultCredentialsDir)